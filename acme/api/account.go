@@ -1,7 +1,9 @@
 package api
 
 import (
+	"crypto"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 
@@ -83,3 +85,31 @@ func (a *AccountService) Deactivate(accountURL string) error {
 	_, err := a.core.post(accountURL, req, nil)
 	return err
 }
+
+// KeyChange Replaces the account key with newKey.
+// On success, newKey is used to sign all the subsequent requests.
+// Reference: https://www.rfc-editor.org/rfc/rfc8555.html#section-7.3.5
+func (a *AccountService) KeyChange(newKey crypto.PrivateKey) error {
+	keyChangeURL := a.core.GetDirectory().KeyChangeURL
+	if keyChangeURL == "" {
+		return errors.New("account[keyChange]: the server does not support key change")
+	}
+
+	if newKey == nil {
+		return errors.New("account[keyChange]: empty key")
+	}
+
+	keyChangeJWS, err := a.core.signKeyChange(keyChangeURL, newKey)
+	if err != nil {
+		return fmt.Errorf("acme: error signing key change content: %w", err)
+	}
+
+	_, err = a.core.post(keyChangeURL, json.RawMessage(keyChangeJWS), nil)
+	if err != nil {
+		return err
+	}
+
+	a.core.jws.SetPrivateKey(newKey)
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/LukasDeco/lego/v4/acme"
+	"github.com/LukasDeco/lego/v4/platform/tester"
+	"github.com/go-jose/go-jose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountService_KeyChange(t *testing.T) {
+	mux, apiURL := tester.SetupFakeAPI(t)
+
+	// small value keeps test fast
+	oldKey, errK := rsa.GenerateKey(rand.Reader, 512)
+	require.NoError(t, errK, "Could not generate test key")
+
+	newKey, errK := rsa.GenerateKey(rand.Reader, 512)
+	require.NoError(t, errK, "Could not generate test key")
+
+	accountURL := apiURL + "/account"
+
+	mux.HandleFunc("/keyChange", func(w http.ResponseWriter, r *http.Request) {
+		// the outer JWS is signed by the old key.
+		body, err := readSignedBody(r, oldKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// the inner JWS is signed by the new key, which is embedded in the header.
+		inner, err := jose.ParseSigned(string(body))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if inner.Signatures[0].Protected.Nonce != "" {
+			http.Error(w, "unexpected nonce in the inner JWS", http.StatusBadRequest)
+			return
+		}
+
+		if inner.Signatures[0].Protected.ExtraHeaders["url"] != apiURL+"/keyChange" {
+			http.Error(w, "invalid url in the inner JWS", http.StatusBadRequest)
+			return
+		}
+
+		content, err := inner.Verify(&jose.JSONWebKey{Key: newKey.Public(), Algorithm: "RSA"})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var keyChange struct {
+			Account string          `json:"account"`
+			OldKey  jose.JSONWebKey `json:"oldKey"`
+		}
+		err = json.Unmarshal(content, &keyChange)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if keyChange.Account != accountURL {
+			http.Error(w, "invalid account", http.StatusBadRequest)
+			return
+		}
+
+		if !assert.ObjectsAreEqual(oldKey.Public(), keyChange.OldKey.Key) {
+			http.Error(w, "invalid old key", http.StatusBadRequest)
+			return
+		}
+	})
+
+	mux.HandleFunc("/account", func(w http.ResponseWriter, r *http.Request) {
+		// the requests following the key change are signed by the new key.
+		_, err := readSignedBody(r, newKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		err = tester.WriteJSONResponse(w, acme.Account{Status: acme.StatusValid})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+
+	core, err := New(http.DefaultClient, "lego-test", apiURL+"/dir", accountURL, oldKey)
+	require.NoError(t, err)
+
+	err = core.Accounts.KeyChange(newKey)
+	require.NoError(t, err)
+
+	account, err := core.Accounts.Get(accountURL)
+	require.NoError(t, err)
+
+	assert.Equal(t, acme.StatusValid, account.Status)
+}
+
+func TestAccountService_KeyChange_noAccount(t *testing.T) {
+	_, apiURL := tester.SetupFakeAPI(t)
+
+	oldKey, err := rsa.GenerateKey(rand.Reader, 512)
+	require.NoError(t, err, "Could not generate test key")
+
+	newKey, err := rsa.GenerateKey(rand.Reader, 512)
+	require.NoError(t, err, "Could not generate test key")
+
+	core, err := New(http.DefaultClient, "lego-test", apiURL+"/dir", "", oldKey)
+	require.NoError(t, err)
+
+	err = core.Accounts.KeyChange(newKey)
+	require.EqualError(t, err, "acme: error signing key change content: acme: key change requires an account URL")
+}
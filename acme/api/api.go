@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"crypto"
 	"encoding/json"
 	"errors"
@@ -15,8 +16,12 @@ import (
 	"github.com/LukasDeco/lego/v4/acme/api/internal/sender"
 	"github.com/LukasDeco/lego/v4/log"
 	"github.com/cenkalti/backoff/v4"
+	jose "github.com/go-jose/go-jose/v3"
 )
 
+// signFunc signs the content of a request.
+type signFunc func(url string, content []byte) (*jose.JSONWebSignature, error)
+
 // Core ACME/LE core API.
 type Core struct {
 	doer         *sender.Doer
@@ -25,6 +30,8 @@ type Core struct {
 	directory    acme.Directory
 	HTTPClient   *http.Client
 
+	logger *log.FieldLogger
+
 	common         service // Reuse a single struct instead of allocating one for each service on the heap.
 	Accounts       *AccountService
 	Authorizations *AuthorizationService
@@ -58,24 +65,72 @@ func New(httpClient *http.Client, userAgent, caDirURL, kid string, privateKey cr
 	return c, nil
 }
 
+// SetLogger sets the logger of the core.
+// The logger is shared with the components using the core (Certifier, Prober, challenges, ...).
+func (a *Core) SetLogger(logger *log.FieldLogger) {
+	a.logger = logger
+}
+
+// Logger returns the logger of the core, or the default logger when not set.
+func (a *Core) Logger() *log.FieldLogger {
+	if a == nil || a.logger == nil {
+		return log.Default()
+	}
+
+	return a.logger
+}
+
 // post performs an HTTP POST request and parses the response body as JSON,
 // into the provided respBody object.
 func (a *Core) post(uri string, reqBody, response interface{}) (*http.Response, error) {
+	return a.postWithContext(context.Background(), uri, reqBody, response)
+}
+
+// postWithContext performs an HTTP POST request and parses the response body as JSON,
+// into the provided respBody object.
+// The request, and the retries on bad nonces, are aborted if the context is canceled.
+func (a *Core) postWithContext(ctx context.Context, uri string, reqBody, response interface{}) (*http.Response, error) {
 	content, err := json.Marshal(reqBody)
 	if err != nil {
 		return nil, errors.New("failed to marshal message")
 	}
 
-	return a.retrievablePost(uri, content, response)
+	return a.retrievablePost(ctx, uri, content, response)
 }
 
 // postAsGet performs an HTTP POST ("POST-as-GET") request.
 // https://www.rfc-editor.org/rfc/rfc8555.html#section-6.3
 func (a *Core) postAsGet(uri string, response interface{}) (*http.Response, error) {
-	return a.retrievablePost(uri, []byte{}, response)
+	return a.postAsGetWithContext(context.Background(), uri, response)
+}
+
+// postAsGetWithContext performs an HTTP POST ("POST-as-GET") request.
+// The request, and the retries on bad nonces, are aborted if the context is canceled.
+// https://www.rfc-editor.org/rfc/rfc8555.html#section-6.3
+func (a *Core) postAsGetWithContext(ctx context.Context, uri string, response interface{}) (*http.Response, error) {
+	return a.retrievablePost(ctx, uri, []byte{}, response)
 }
 
-func (a *Core) retrievablePost(uri string, content []byte, response interface{}) (*http.Response, error) {
+// postWithKey performs an HTTP POST request signed by another key than the account key (i.e. the key of a certificate),
+// and parses the response body as JSON, into the provided respBody object.
+func (a *Core) postWithKey(uri string, reqBody, response interface{}, privateKey crypto.PrivateKey) (*http.Response, error) {
+	content, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, errors.New("failed to marshal message")
+	}
+
+	sign := func(url string, content []byte) (*jose.JSONWebSignature, error) {
+		return a.jws.SignContentWithKey(url, content, privateKey)
+	}
+
+	return a.retrievableSignedPost(context.Background(), uri, content, response, sign)
+}
+
+func (a *Core) retrievablePost(ctx context.Context, uri string, content []byte, response interface{}) (*http.Response, error) {
+	return a.retrievableSignedPost(ctx, uri, content, response, a.jws.SignContent)
+}
+
+func (a *Core) retrievableSignedPost(ctx context.Context, uri string, content []byte, response interface{}, sign signFunc) (*http.Response, error) {
 	// during tests, allow to support ~90% of bad nonce with a minimum of attempts.
 	bo := backoff.NewExponentialBackOff()
 	bo.InitialInterval = 200 * time.Millisecond
@@ -85,7 +140,7 @@ func (a *Core) retrievablePost(uri string, content []byte, response interface{})
 	var resp *http.Response
 	operation := func() error {
 		var err error
-		resp, err = a.signedPost(uri, content, response)
+		resp, err = a.signedPost(ctx, uri, content, response, sign)
 		if err != nil {
 			// Retry if the nonce was invalidated
 			var e *acme.NonceError
@@ -100,10 +155,10 @@ func (a *Core) retrievablePost(uri string, content []byte, response interface{})
 	}
 
 	notify := func(err error, duration time.Duration) {
-		log.Infof("retry due to: %v", err)
+		a.Logger().Info("retry due to a bad nonce", log.Err(err), log.Any("url", uri))
 	}
 
-	err := backoff.RetryNotify(operation, bo, notify)
+	err := backoff.RetryNotify(operation, backoff.WithContext(bo, ctx), notify)
 	if err != nil {
 		return resp, err
 	}
@@ -111,15 +166,15 @@ func (a *Core) retrievablePost(uri string, content []byte, response interface{})
 	return resp, nil
 }
 
-func (a *Core) signedPost(uri string, content []byte, response interface{}) (*http.Response, error) {
-	signedContent, err := a.jws.SignContent(uri, content)
+func (a *Core) signedPost(ctx context.Context, uri string, content []byte, response interface{}, sign signFunc) (*http.Response, error) {
+	signedContent, err := sign(uri, content)
 	if err != nil {
 		return nil, fmt.Errorf("failed to post JWS message: failed to sign content: %w", err)
 	}
 
 	signedBody := bytes.NewBuffer([]byte(signedContent.FullSerialize()))
 
-	resp, err := a.doer.Post(uri, signedBody, "application/jose+json", response)
+	resp, err := a.doer.PostWithContext(ctx, uri, signedBody, "application/jose+json", response)
 
 	// nonceErr is ignored to keep the root error.
 	nonce, nonceErr := nonces.GetFromResponse(resp)
@@ -139,11 +194,25 @@ func (a *Core) signEABContent(newAccountURL, kid string, hmac []byte) ([]byte, e
 	return []byte(eabJWS.FullSerialize()), nil
 }
 
+func (a *Core) signKeyChange(keyChangeURL string, newKey crypto.PrivateKey) ([]byte, error) {
+	keyChangeJWS, err := a.jws.SignKeyChange(keyChangeURL, newKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(keyChangeJWS.FullSerialize()), nil
+}
+
 // GetKeyAuthorization Gets the key authorization.
 func (a *Core) GetKeyAuthorization(token string) (string, error) {
 	return a.jws.GetKeyAuthorization(token)
 }
 
+// GetAccountURL Gets the URL of the account (the key identifier), empty if the account is not registered yet.
+func (a *Core) GetAccountURL() string {
+	return a.jws.GetKid()
+}
+
 func (a *Core) GetDirectory() acme.Directory {
 	return a.directory
 }
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"errors"
 
 	"github.com/LukasDeco/lego/v4/acme"
@@ -10,25 +11,41 @@ type AuthorizationService service
 
 // Get Gets an authorization.
 func (c *AuthorizationService) Get(authzURL string) (acme.Authorization, error) {
+	authz, err := c.GetWithContext(context.Background(), authzURL)
+	if err != nil {
+		return acme.Authorization{}, err
+	}
+	return authz.Authorization, nil
+}
+
+// GetWithContext Gets an authorization, and the polling interval recommended by the server (Retry-After).
+// The request is aborted if the context is canceled.
+func (c *AuthorizationService) GetWithContext(ctx context.Context, authzURL string) (acme.ExtendedAuthorization, error) {
 	if authzURL == "" {
-		return acme.Authorization{}, errors.New("authorization[get]: empty URL")
+		return acme.ExtendedAuthorization{}, errors.New("authorization[get]: empty URL")
 	}
 
 	var authz acme.Authorization
-	_, err := c.core.postAsGet(authzURL, &authz)
+	resp, err := c.core.postAsGetWithContext(ctx, authzURL, &authz)
 	if err != nil {
-		return acme.Authorization{}, err
+		return acme.ExtendedAuthorization{}, err
 	}
-	return authz, nil
+	return acme.ExtendedAuthorization{Authorization: authz, RetryAfter: getRetryAfter(resp)}, nil
 }
 
 // Deactivate Deactivates an authorization.
 func (c *AuthorizationService) Deactivate(authzURL string) error {
+	return c.DeactivateWithContext(context.Background(), authzURL)
+}
+
+// DeactivateWithContext Deactivates an authorization.
+// The request is aborted if the context is canceled.
+func (c *AuthorizationService) DeactivateWithContext(ctx context.Context, authzURL string) error {
 	if authzURL == "" {
 		return errors.New("authorization[deactivate]: empty URL")
 	}
 
 	var disabledAuth acme.Authorization
-	_, err := c.core.post(authzURL, acme.Authorization{Status: acme.StatusDeactivated}, &disabledAuth)
+	_, err := c.core.postWithContext(ctx, authzURL, acme.Authorization{Status: acme.StatusDeactivated}, &disabledAuth)
 	return err
 }
@@ -1,13 +1,19 @@
 package api
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"encoding/json"
 	"encoding/pem"
+	"io"
 	"net/http"
 	"testing"
 
+	"github.com/LukasDeco/lego/v4/acme"
 	"github.com/LukasDeco/lego/v4/platform/tester"
+	"github.com/go-jose/go-jose/v3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -128,3 +134,102 @@ func TestCertificateService_Get_embeddedIssuer(t *testing.T) {
 	assert.Equal(t, certResponseMock, string(cert), "Certificate")
 	assert.Equal(t, issuerMock, string(issuer), "IssuerCertificate")
 }
+
+func TestCertificateService_RevokeWithKey(t *testing.T) {
+	mux, apiURL := tester.SetupFakeAPI(t)
+
+	accountKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err, "Could not generate test key")
+
+	certKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err, "Could not generate test key")
+
+	reason := uint(acme.CRLReasonKeyCompromise)
+
+	mux.HandleFunc("/revokeCert", func(w http.ResponseWriter, r *http.Request) {
+		reqBody, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		jws, err := jose.ParseSigned(string(reqBody))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		header := jws.Signatures[0].Protected
+
+		// the request is signed by the key of the certificate, embedded in the header, without account URL.
+		if header.KeyID != "" || header.JSONWebKey == nil {
+			http.Error(w, "the JWK must be embedded", http.StatusBadRequest)
+			return
+		}
+
+		if !assert.ObjectsAreEqual(certKey.Public(), header.JSONWebKey.Key) {
+			http.Error(w, "invalid JWK", http.StatusBadRequest)
+			return
+		}
+
+		if header.ExtraHeaders["url"] != apiURL+"/revokeCert" {
+			http.Error(w, "invalid url", http.StatusBadRequest)
+			return
+		}
+
+		body, err := jws.Verify(header.JSONWebKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var msg acme.RevokeCertMessage
+		err = json.Unmarshal(body, &msg)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if msg.Certificate != "cert" || msg.Reason == nil || *msg.Reason != reason {
+			http.Error(w, "invalid message", http.StatusBadRequest)
+			return
+		}
+	})
+
+	mux.HandleFunc("/account", func(w http.ResponseWriter, r *http.Request) {
+		// the other requests are still signed by the account key.
+		_, err := readSignedBody(r, accountKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		err = tester.WriteJSONResponse(w, acme.Account{Status: acme.StatusValid})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+
+	core, err := New(http.DefaultClient, "lego-test", apiURL+"/dir", apiURL+"/account", accountKey)
+	require.NoError(t, err)
+
+	err = core.Certificates.RevokeWithKey(acme.RevokeCertMessage{Certificate: "cert", Reason: &reason}, certKey)
+	require.NoError(t, err)
+
+	_, err = core.Accounts.Get(apiURL + "/account")
+	require.NoError(t, err)
+}
+
+func TestCertificateService_RevokeWithKey_unsupportedKey(t *testing.T) {
+	_, apiURL := tester.SetupFakeAPI(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err, "Could not generate test key")
+
+	core, err := New(http.DefaultClient, "lego-test", apiURL+"/dir", "", key)
+	require.NoError(t, err)
+
+	err = core.Certificates.RevokeWithKey(acme.RevokeCertMessage{Certificate: "cert"}, "foo")
+	require.EqualError(t, err, "failed to post JWS message: failed to sign content: unsupported private key type: string")
+}
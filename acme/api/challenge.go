@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"errors"
 
 	"github.com/LukasDeco/lego/v4/acme"
@@ -10,6 +11,12 @@ type ChallengeService service
 
 // New Creates a challenge.
 func (c *ChallengeService) New(chlgURL string) (acme.ExtendedChallenge, error) {
+	return c.NewWithContext(context.Background(), chlgURL)
+}
+
+// NewWithContext Creates a challenge.
+// The request is aborted if the context is canceled.
+func (c *ChallengeService) NewWithContext(ctx context.Context, chlgURL string) (acme.ExtendedChallenge, error) {
 	if chlgURL == "" {
 		return acme.ExtendedChallenge{}, errors.New("challenge[new]: empty URL")
 	}
@@ -17,7 +24,7 @@ func (c *ChallengeService) New(chlgURL string) (acme.ExtendedChallenge, error) {
 	// Challenge initiation is done by sending a JWS payload containing the trivial JSON object `{}`.
 	// We use an empty struct instance as the postJSON payload here to achieve this result.
 	var chlng acme.ExtendedChallenge
-	resp, err := c.core.post(chlgURL, struct{}{}, &chlng)
+	resp, err := c.core.postWithContext(ctx, chlgURL, struct{}{}, &chlng)
 	if err != nil {
 		return acme.ExtendedChallenge{}, err
 	}
@@ -29,12 +36,18 @@ func (c *ChallengeService) New(chlgURL string) (acme.ExtendedChallenge, error) {
 
 // Get Gets a challenge.
 func (c *ChallengeService) Get(chlgURL string) (acme.ExtendedChallenge, error) {
+	return c.GetWithContext(context.Background(), chlgURL)
+}
+
+// GetWithContext Gets a challenge.
+// The request is aborted if the context is canceled.
+func (c *ChallengeService) GetWithContext(ctx context.Context, chlgURL string) (acme.ExtendedChallenge, error) {
 	if chlgURL == "" {
 		return acme.ExtendedChallenge{}, errors.New("challenge[get]: empty URL")
 	}
 
 	var chlng acme.ExtendedChallenge
-	resp, err := c.core.postAsGet(chlgURL, &chlng)
+	resp, err := c.core.postAsGetWithContext(ctx, chlgURL, &chlng)
 	if err != nil {
 		return acme.ExtendedChallenge{}, err
 	}
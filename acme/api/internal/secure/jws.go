@@ -3,9 +3,12 @@ package secure
 import (
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rsa"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/LukasDeco/lego/v4/acme/api/internal/nonces"
@@ -33,23 +36,36 @@ func (j *JWS) SetKid(kid string) {
 	j.kid = kid
 }
 
+// GetKid Gets the key identifier (the account URL).
+func (j *JWS) GetKid() string {
+	return j.kid
+}
+
+// SetPrivateKey Sets the private key used to sign the requests.
+func (j *JWS) SetPrivateKey(privateKey crypto.PrivateKey) {
+	j.privKey = privateKey
+}
+
 // SignContent Signs a content with the JWS.
 func (j *JWS) SignContent(url string, content []byte) (*jose.JSONWebSignature, error) {
-	var alg jose.SignatureAlgorithm
-	switch k := j.privKey.(type) {
-	case *rsa.PrivateKey:
-		alg = jose.RS256
-	case *ecdsa.PrivateKey:
-		if k.Curve == elliptic.P256() {
-			alg = jose.ES256
-		} else if k.Curve == elliptic.P384() {
-			alg = jose.ES384
-		}
+	return j.sign(url, content, j.privKey, j.kid)
+}
+
+// SignContentWithKey Signs a content with another key than the account key (i.e. the key of a certificate).
+// The public key is embedded in the JWS (jwk header) instead of the account URL.
+// Reference: https://www.rfc-editor.org/rfc/rfc8555.html#section-7.6
+func (j *JWS) SignContentWithKey(url string, content []byte, privateKey crypto.PrivateKey) (*jose.JSONWebSignature, error) {
+	if signatureAlgorithm(privateKey) == "" {
+		return nil, fmt.Errorf("unsupported private key type: %T", privateKey)
 	}
 
+	return j.sign(url, content, privateKey, "")
+}
+
+func (j *JWS) sign(url string, content []byte, privateKey crypto.PrivateKey, kid string) (*jose.JSONWebSignature, error) {
 	signKey := jose.SigningKey{
-		Algorithm: alg,
-		Key:       jose.JSONWebKey{Key: j.privKey, KeyID: j.kid},
+		Algorithm: signatureAlgorithm(privateKey),
+		Key:       jose.JSONWebKey{Key: privateKey, KeyID: kid},
 	}
 
 	options := jose.SignerOptions{
@@ -59,7 +75,7 @@ func (j *JWS) SignContent(url string, content []byte) (*jose.JSONWebSignature, e
 		},
 	}
 
-	if j.kid == "" {
+	if kid == "" {
 		options.EmbedJWK = true
 	}
 
@@ -105,6 +121,48 @@ func (j *JWS) SignEABContent(url, kid string, hmac []byte) (*jose.JSONWebSignatu
 	return signed, nil
 }
 
+// SignKeyChange Signs the inner JWS of a key change request with the new account key.
+// The payload identifies the account and its current key.
+// Reference: https://www.rfc-editor.org/rfc/rfc8555.html#section-7.3.5
+func (j *JWS) SignKeyChange(url string, newKey crypto.PrivateKey) (*jose.JSONWebSignature, error) {
+	if j.kid == "" {
+		return nil, errors.New("acme: key change requires an account URL")
+	}
+
+	oldKey := jose.JSONWebKey{Key: j.privKey}
+
+	content, err := json.Marshal(struct {
+		Account string          `json:"account"`
+		OldKey  jose.JSONWebKey `json:"oldKey"`
+	}{
+		Account: j.kid,
+		OldKey:  oldKey.Public(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("acme: error encoding key change content: %w", err)
+	}
+
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: signatureAlgorithm(newKey), Key: newKey},
+		&jose.SignerOptions{
+			EmbedJWK: true,
+			ExtraHeaders: map[jose.HeaderKey]interface{}{
+				"url": url,
+			},
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create key change jose signer: %w", err)
+	}
+
+	signed, err := signer.Sign(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign key change content: %w", err)
+	}
+
+	return signed, nil
+}
+
 // GetKeyAuthorization Gets the key authorization for a token.
 func (j *JWS) GetKeyAuthorization(token string) (string, error) {
 	var publicKey crypto.PublicKey
@@ -113,6 +171,8 @@ func (j *JWS) GetKeyAuthorization(token string) (string, error) {
 		publicKey = k.Public()
 	case *rsa.PrivateKey:
 		publicKey = k.Public()
+	case ed25519.PrivateKey:
+		publicKey = k.Public()
 	}
 
 	// Generate the Key Authorization for the challenge
@@ -128,3 +188,22 @@ func (j *JWS) GetKeyAuthorization(token string) (string, error) {
 
 	return token + "." + keyThumb, nil
 }
+
+func signatureAlgorithm(privateKey crypto.PrivateKey) jose.SignatureAlgorithm {
+	switch k := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return jose.RS256
+	case *ecdsa.PrivateKey:
+		if k.Curve == elliptic.P256() {
+			return jose.ES256
+		} else if k.Curve == elliptic.P384() {
+			return jose.ES384
+		} else if k.Curve == elliptic.P521() {
+			return jose.ES512
+		}
+	case ed25519.PrivateKey:
+		return jose.EdDSA
+	}
+
+	return ""
+}
@@ -1,6 +1,7 @@
 package secure
 
 import (
+	"crypto"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -9,7 +10,11 @@ import (
 	"github.com/LukasDeco/lego/v4/acme"
 	"github.com/LukasDeco/lego/v4/acme/api/internal/nonces"
 	"github.com/LukasDeco/lego/v4/acme/api/internal/sender"
+	"github.com/LukasDeco/lego/v4/certcrypto"
 	"github.com/LukasDeco/lego/v4/platform/tester"
+	jose "github.com/go-jose/go-jose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNotHoldingLockWhileMakingHTTPRequests(t *testing.T) {
@@ -54,3 +59,94 @@ func TestNotHoldingLockWhileMakingHTTPRequests(t *testing.T) {
 		t.Fatal("JWS is probably holding a lock while making HTTP request")
 	}
 }
+
+func TestJWS_SignContent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Replay-Nonce", "12345")
+	}))
+	t.Cleanup(server.Close)
+
+	testCases := []struct {
+		desc     string
+		keyType  certcrypto.KeyType
+		expected jose.SignatureAlgorithm
+	}{
+		{desc: "RSA 3072", keyType: certcrypto.RSA3072, expected: jose.RS256},
+		{desc: "EC P-256", keyType: certcrypto.EC256, expected: jose.ES256},
+		{desc: "EC P-384", keyType: certcrypto.EC384, expected: jose.ES384},
+		{desc: "EC P-521", keyType: certcrypto.EC521, expected: jose.ES512},
+		{desc: "Ed25519", keyType: certcrypto.ED25519, expected: jose.EdDSA},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			privateKey, err := certcrypto.GeneratePrivateKey(test.keyType)
+			require.NoError(t, err)
+
+			nonceManager := nonces.NewManager(sender.NewDoer(http.DefaultClient, "lego-test"), server.URL)
+
+			j := NewJWS(privateKey, "", nonceManager)
+
+			content, err := j.SignContent(server.URL, []byte("lego"))
+			require.NoError(t, err)
+
+			signed, err := jose.ParseSigned(content.FullSerialize())
+			require.NoError(t, err)
+
+			assert.Equal(t, string(test.expected), signed.Signatures[0].Protected.Algorithm)
+
+			payload, err := signed.Verify(signed.Signatures[0].Protected.JSONWebKey)
+			require.NoError(t, err)
+			assert.Equal(t, "lego", string(payload))
+
+			keyAuth, err := j.GetKeyAuthorization("token")
+			require.NoError(t, err)
+			assert.Regexp(t, `^token\.[\w-]{43}$`, keyAuth)
+		})
+	}
+}
+
+func TestJWS_SignContentWithKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Replay-Nonce", "12345")
+	}))
+	t.Cleanup(server.Close)
+
+	accountKey, err := certcrypto.GeneratePrivateKey(certcrypto.EC256)
+	require.NoError(t, err)
+
+	certKey, err := certcrypto.GeneratePrivateKey(certcrypto.EC384)
+	require.NoError(t, err)
+
+	nonceManager := nonces.NewManager(sender.NewDoer(http.DefaultClient, "lego-test"), server.URL)
+
+	j := NewJWS(accountKey, server.URL+"/account", nonceManager)
+
+	content, err := j.SignContentWithKey(server.URL, []byte("lego"), certKey)
+	require.NoError(t, err)
+
+	signed, err := jose.ParseSigned(content.FullSerialize())
+	require.NoError(t, err)
+
+	protected := signed.Signatures[0].Protected
+	assert.Equal(t, string(jose.ES384), protected.Algorithm)
+	assert.Empty(t, protected.KeyID)
+	require.NotNil(t, protected.JSONWebKey)
+
+	payload, err := signed.Verify(&jose.JSONWebKey{Key: certKey.(crypto.Signer).Public()})
+	require.NoError(t, err)
+	assert.Equal(t, "lego", string(payload))
+
+	// the account key is still used by SignContent.
+	assert.Equal(t, server.URL+"/account", j.GetKid())
+}
+
+func TestJWS_SignContentWithKey_unsupportedKey(t *testing.T) {
+	j := NewJWS(nil, "", nil)
+
+	_, err := j.SignContentWithKey("http://example.com", []byte("lego"), "foo")
+	require.EqualError(t, err, "unsupported private key type: string")
+}
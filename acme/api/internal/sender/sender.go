@@ -1,6 +1,7 @@
 package sender
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -36,7 +37,14 @@ func NewDoer(client *http.Client, userAgent string) *Doer {
 // Get performs a GET request with a proper User-Agent string.
 // If "response" is not provided, callers should close resp.Body when done reading from it.
 func (d *Doer) Get(url string, response interface{}) (*http.Response, error) {
-	req, err := d.newRequest(http.MethodGet, url, nil)
+	return d.GetWithContext(context.Background(), url, response)
+}
+
+// GetWithContext performs a GET request with a proper User-Agent string.
+// The request is aborted if the context is canceled.
+// If "response" is not provided, callers should close resp.Body when done reading from it.
+func (d *Doer) GetWithContext(ctx context.Context, url string, response interface{}) (*http.Response, error) {
+	req, err := d.newRequest(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -47,7 +55,14 @@ func (d *Doer) Get(url string, response interface{}) (*http.Response, error) {
 // Head performs a HEAD request with a proper User-Agent string.
 // The response body (resp.Body) is already closed when this function returns.
 func (d *Doer) Head(url string) (*http.Response, error) {
-	req, err := d.newRequest(http.MethodHead, url, nil)
+	return d.HeadWithContext(context.Background(), url)
+}
+
+// HeadWithContext performs a HEAD request with a proper User-Agent string.
+// The request is aborted if the context is canceled.
+// The response body (resp.Body) is already closed when this function returns.
+func (d *Doer) HeadWithContext(ctx context.Context, url string) (*http.Response, error) {
+	req, err := d.newRequest(ctx, http.MethodHead, url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -58,7 +73,14 @@ func (d *Doer) Head(url string) (*http.Response, error) {
 // Post performs a POST request with a proper User-Agent string.
 // If "response" is not provided, callers should close resp.Body when done reading from it.
 func (d *Doer) Post(url string, body io.Reader, bodyType string, response interface{}) (*http.Response, error) {
-	req, err := d.newRequest(http.MethodPost, url, body, contentType(bodyType))
+	return d.PostWithContext(context.Background(), url, body, bodyType, response)
+}
+
+// PostWithContext performs a POST request with a proper User-Agent string.
+// The request is aborted if the context is canceled.
+// If "response" is not provided, callers should close resp.Body when done reading from it.
+func (d *Doer) PostWithContext(ctx context.Context, url string, body io.Reader, bodyType string, response interface{}) (*http.Response, error) {
+	req, err := d.newRequest(ctx, http.MethodPost, url, body, contentType(bodyType))
 	if err != nil {
 		return nil, err
 	}
@@ -66,8 +88,8 @@ func (d *Doer) Post(url string, body io.Reader, bodyType string, response interf
 	return d.do(req, response)
 }
 
-func (d *Doer) newRequest(method, uri string, body io.Reader, opts ...RequestOption) (*http.Request, error) {
-	req, err := http.NewRequest(method, uri, body)
+func (d *Doer) newRequest(ctx context.Context, method, uri string, body io.Reader, opts ...RequestOption) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, uri, body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -1,27 +1,87 @@
 package api
 
 import (
+	"context"
 	"encoding/base64"
 	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/LukasDeco/lego/v4/acme"
 )
 
+// OrderOptions used to create an order (optional).
+type OrderOptions struct {
+	// A string uniquely identifying a previously-issued certificate which this order is intended to replace.
+	// Only sent if the server advertises a renewal info endpoint.
+	// - https://datatracker.ietf.org/doc/html/draft-ietf-acme-ari-03#section-5
+	ReplacesCertID string
+
+	// The name of the profile which the certificate should be issued with.
+	// The profile must be advertised by the server in the directory meta.
+	// - https://datatracker.ietf.org/doc/draft-aaron-acme-profiles/
+	Profile string
+
+	// The requested value of the notBefore field in the certificate.
+	NotBefore time.Time
+
+	// The requested value of the notAfter field in the certificate.
+	NotAfter time.Time
+}
+
 type OrderService service
 
 // New Creates a new order.
 func (o *OrderService) New(domains []string) (acme.ExtendedOrder, error) {
-	var identifiers []acme.Identifier
-	for _, domain := range domains {
-		identifiers = append(identifiers, acme.Identifier{Type: "dns", Value: domain})
-	}
+	return o.NewWithOptions(domains, nil)
+}
+
+// NewWithOptions Creates a new order.
+func (o *OrderService) NewWithOptions(domains []string, opts *OrderOptions) (acme.ExtendedOrder, error) {
+	return o.NewWithContext(context.Background(), domains, opts)
+}
 
-	orderReq := acme.Order{Identifiers: identifiers}
+// NewWithContext Creates a new order.
+// The request is aborted if the context is canceled.
+func (o *OrderService) NewWithContext(ctx context.Context, domains []string, opts *OrderOptions) (acme.ExtendedOrder, error) {
+	orderReq := acme.Order{Identifiers: createIdentifiers(domains)}
+
+	if opts != nil {
+		if o.core.GetDirectory().RenewalInfo != "" {
+			orderReq.Replaces = opts.ReplacesCertID
+		}
+
+		if opts.Profile != "" {
+			err := checkProfile(o.core.GetDirectory().Meta.Profiles, opts.Profile)
+			if err != nil {
+				return acme.ExtendedOrder{}, err
+			}
+
+			orderReq.Profile = opts.Profile
+		}
+
+		if !opts.NotBefore.IsZero() && !opts.NotAfter.IsZero() && !opts.NotAfter.After(opts.NotBefore) {
+			return acme.ExtendedOrder{}, fmt.Errorf("order[new]: notAfter (%s) must be after notBefore (%s)",
+				opts.NotAfter.Format(time.RFC3339), opts.NotBefore.Format(time.RFC3339))
+		}
+
+		if !opts.NotBefore.IsZero() {
+			orderReq.NotBefore = opts.NotBefore.UTC().Format(time.RFC3339)
+		}
+
+		if !opts.NotAfter.IsZero() {
+			orderReq.NotAfter = opts.NotAfter.UTC().Format(time.RFC3339)
+		}
+	}
 
 	var order acme.Order
-	resp, err := o.core.post(o.core.GetDirectory().NewOrderURL, orderReq, &order)
+	resp, err := o.core.postWithContext(ctx, o.core.GetDirectory().NewOrderURL, orderReq, &order)
 	if err != nil {
-		return acme.ExtendedOrder{}, err
+		return acme.ExtendedOrder{}, validityError(orderReq, err)
 	}
 
 	return acme.ExtendedOrder{
@@ -32,27 +92,39 @@ func (o *OrderService) New(domains []string) (acme.ExtendedOrder, error) {
 
 // Get Gets an order.
 func (o *OrderService) Get(orderURL string) (acme.ExtendedOrder, error) {
+	return o.GetWithContext(context.Background(), orderURL)
+}
+
+// GetWithContext Gets an order.
+// The request is aborted if the context is canceled.
+func (o *OrderService) GetWithContext(ctx context.Context, orderURL string) (acme.ExtendedOrder, error) {
 	if orderURL == "" {
 		return acme.ExtendedOrder{}, errors.New("order[get]: empty URL")
 	}
 
 	var order acme.Order
-	_, err := o.core.postAsGet(orderURL, &order)
+	resp, err := o.core.postAsGetWithContext(ctx, orderURL, &order)
 	if err != nil {
 		return acme.ExtendedOrder{}, err
 	}
 
-	return acme.ExtendedOrder{Order: order}, nil
+	return acme.ExtendedOrder{Order: order, RetryAfter: getRetryAfter(resp)}, nil
 }
 
 // UpdateForCSR Updates an order for a CSR.
 func (o *OrderService) UpdateForCSR(orderURL string, csr []byte) (acme.ExtendedOrder, error) {
+	return o.UpdateForCSRWithContext(context.Background(), orderURL, csr)
+}
+
+// UpdateForCSRWithContext Updates an order for a CSR.
+// The request is aborted if the context is canceled.
+func (o *OrderService) UpdateForCSRWithContext(ctx context.Context, orderURL string, csr []byte) (acme.ExtendedOrder, error) {
 	csrMsg := acme.CSRMessage{
 		Csr: base64.RawURLEncoding.EncodeToString(csr),
 	}
 
 	var order acme.Order
-	_, err := o.core.post(orderURL, csrMsg, &order)
+	resp, err := o.core.postWithContext(ctx, orderURL, csrMsg, &order)
 	if err != nil {
 		return acme.ExtendedOrder{}, err
 	}
@@ -61,5 +133,57 @@ func (o *OrderService) UpdateForCSR(orderURL string, csr []byte) (acme.ExtendedO
 		return acme.ExtendedOrder{}, order.Error
 	}
 
-	return acme.ExtendedOrder{Order: order}, nil
+	return acme.ExtendedOrder{Order: order, RetryAfter: getRetryAfter(resp)}, nil
+}
+
+// createIdentifiers builds the order identifiers from a list of domains.
+// IP addresses are sent as "ip" identifiers (RFC 8738), everything else as "dns" identifiers.
+func createIdentifiers(domains []string) []acme.Identifier {
+	var identifiers []acme.Identifier
+	for _, domain := range domains {
+		if ip := net.ParseIP(domain); ip != nil {
+			identifiers = append(identifiers, acme.Identifier{Type: "ip", Value: ip.String()})
+			continue
+		}
+
+		identifiers = append(identifiers, acme.Identifier{Type: "dns", Value: domain})
+	}
+
+	return identifiers
+}
+
+// checkProfile checks that the profile is advertised by the server.
+func checkProfile(profiles map[string]string, profile string) error {
+	if len(profiles) == 0 {
+		return fmt.Errorf("order[new]: the server does not support profiles (requested: %q)", profile)
+	}
+
+	if _, ok := profiles[profile]; ok {
+		return nil
+	}
+
+	var names []string
+	for name := range profiles {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return fmt.Errorf("order[new]: unsupported profile %q, the server supports: %s", profile, strings.Join(names, ", "))
+}
+
+// validityError explains a new-order rejection when a validity period was requested,
+// as the CA may not support (or may not accept) the requested notBefore/notAfter values.
+func validityError(orderReq acme.Order, err error) error {
+	if orderReq.NotBefore == "" && orderReq.NotAfter == "" {
+		return err
+	}
+
+	var problem *acme.ProblemDetails
+	if !errors.As(err, &problem) || problem.HTTPStatus != http.StatusBadRequest {
+		return err
+	}
+
+	return fmt.Errorf("order[new]: the server rejected the requested validity period (notBefore: %q, notAfter: %q): %w",
+		orderReq.NotBefore, orderReq.NotAfter, err)
 }
@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/LukasDeco/lego/v4/acme"
 	"github.com/LukasDeco/lego/v4/platform/tester"
@@ -87,3 +88,215 @@ func readSignedBody(r *http.Request, privateKey *rsa.PrivateKey) ([]byte, error)
 
 	return body, nil
 }
+
+func TestOrderService_NewWithOptions(t *testing.T) {
+	mux, apiURL := tester.SetupFakeAPI(t)
+
+	// small value keeps test fast
+	privateKey, errK := rsa.GenerateKey(rand.Reader, 512)
+	require.NoError(t, errK, "Could not generate test key")
+
+	mux.HandleFunc("/newOrder", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := readSignedBody(r, privateKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		order := acme.Order{}
+		err = json.Unmarshal(body, &order)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		err = tester.WriteJSONResponse(w, acme.Order{
+			Status:      acme.StatusValid,
+			Identifiers: order.Identifiers,
+			Replaces:    order.Replaces,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+
+	core, err := New(http.DefaultClient, "lego-test", apiURL+"/dir", "", privateKey)
+	require.NoError(t, err)
+
+	order, err := core.Orders.NewWithOptions([]string{"example.com"}, &OrderOptions{ReplacesCertID: "aYhba4dGQEHhs3uEe6CuLN4ByNQ.AIdlQyE"})
+	require.NoError(t, err)
+
+	expected := acme.ExtendedOrder{
+		Order: acme.Order{
+			Status:      "valid",
+			Identifiers: []acme.Identifier{{Type: "dns", Value: "example.com"}},
+			Replaces:    "aYhba4dGQEHhs3uEe6CuLN4ByNQ.AIdlQyE",
+		},
+	}
+	assert.Equal(t, expected, order)
+}
+
+func Test_createIdentifiers(t *testing.T) {
+	domains := []string{"example.com", "192.0.2.1", "2001:DB8::1"}
+
+	expected := []acme.Identifier{
+		{Type: "dns", Value: "example.com"},
+		{Type: "ip", Value: "192.0.2.1"},
+		{Type: "ip", Value: "2001:db8::1"},
+	}
+
+	assert.Equal(t, expected, createIdentifiers(domains))
+}
+
+func TestOrderService_NewWithOptions_profile(t *testing.T) {
+	mux, apiURL := tester.SetupFakeAPI(t)
+
+	// small value keeps test fast
+	privateKey, errK := rsa.GenerateKey(rand.Reader, 512)
+	require.NoError(t, errK, "Could not generate test key")
+
+	mux.HandleFunc("/newOrder", func(w http.ResponseWriter, r *http.Request) {
+		body, err := readSignedBody(r, privateKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		order := acme.Order{}
+		err = json.Unmarshal(body, &order)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		err = tester.WriteJSONResponse(w, acme.Order{
+			Status:      acme.StatusValid,
+			Identifiers: order.Identifiers,
+			Profile:     order.Profile,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+
+	core, err := New(http.DefaultClient, "lego-test", apiURL+"/dir", "", privateKey)
+	require.NoError(t, err)
+
+	order, err := core.Orders.NewWithOptions([]string{"example.com"}, &OrderOptions{Profile: "shortlived"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "shortlived", order.Profile)
+
+	_, err = core.Orders.NewWithOptions([]string{"example.com"}, &OrderOptions{Profile: "foo"})
+	require.EqualError(t, err, `order[new]: unsupported profile "foo", the server supports: classic, shortlived`)
+}
+
+func Test_checkProfile_noProfiles(t *testing.T) {
+	err := checkProfile(nil, "shortlived")
+	require.EqualError(t, err, `order[new]: the server does not support profiles (requested: "shortlived")`)
+}
+
+func TestOrderService_NewWithOptions_validity(t *testing.T) {
+	mux, apiURL := tester.SetupFakeAPI(t)
+
+	// small value keeps test fast
+	privateKey, errK := rsa.GenerateKey(rand.Reader, 512)
+	require.NoError(t, errK, "Could not generate test key")
+
+	mux.HandleFunc("/newOrder", func(w http.ResponseWriter, r *http.Request) {
+		body, err := readSignedBody(r, privateKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		order := acme.Order{}
+		err = json.Unmarshal(body, &order)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		err = tester.WriteJSONResponse(w, acme.Order{
+			Status:      acme.StatusValid,
+			Identifiers: order.Identifiers,
+			NotBefore:   order.NotBefore,
+			NotAfter:    order.NotAfter,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+
+	core, err := New(http.DefaultClient, "lego-test", apiURL+"/dir", "", privateKey)
+	require.NoError(t, err)
+
+	opts := &OrderOptions{
+		NotBefore: time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:  time.Date(2023, time.January, 2, 1, 0, 0, 0, time.FixedZone("UTC+1", 3600)),
+	}
+
+	order, err := core.Orders.NewWithOptions([]string{"example.com"}, opts)
+	require.NoError(t, err)
+
+	assert.Equal(t, "2023-01-01T00:00:00Z", order.NotBefore)
+	assert.Equal(t, "2023-01-02T00:00:00Z", order.NotAfter)
+}
+
+func TestOrderService_NewWithOptions_validityRejected(t *testing.T) {
+	mux, apiURL := tester.SetupFakeAPI(t)
+
+	// small value keeps test fast
+	privateKey, errK := rsa.GenerateKey(rand.Reader, 512)
+	require.NoError(t, errK, "Could not generate test key")
+
+	mux.HandleFunc("/newOrder", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(acme.ProblemDetails{
+			Type:       "urn:ietf:params:acme:error:malformed",
+			Detail:     "NotBefore and NotAfter are not supported",
+			HTTPStatus: http.StatusBadRequest,
+		})
+	})
+
+	core, err := New(http.DefaultClient, "lego-test", apiURL+"/dir", "", privateKey)
+	require.NoError(t, err)
+
+	opts := &OrderOptions{NotAfter: time.Date(2023, time.January, 2, 0, 0, 0, 0, time.UTC)}
+
+	_, err = core.Orders.NewWithOptions([]string{"example.com"}, opts)
+	require.Error(t, err)
+
+	assert.Contains(t, err.Error(), `order[new]: the server rejected the requested validity period (notBefore: "", notAfter: "2023-01-02T00:00:00Z")`)
+
+	var problem *acme.ProblemDetails
+	require.ErrorAs(t, err, &problem)
+	assert.Equal(t, "urn:ietf:params:acme:error:malformed", problem.Type)
+}
+
+func TestOrderService_NewWithOptions_invalidValidity(t *testing.T) {
+	_, apiURL := tester.SetupFakeAPI(t)
+
+	privateKey, errK := rsa.GenerateKey(rand.Reader, 512)
+	require.NoError(t, errK, "Could not generate test key")
+
+	core, err := New(http.DefaultClient, "lego-test", apiURL+"/dir", "", privateKey)
+	require.NoError(t, err)
+
+	opts := &OrderOptions{
+		NotBefore: time.Date(2023, time.January, 2, 0, 0, 0, 0, time.UTC),
+		NotAfter:  time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	_, err = core.Orders.NewWithOptions([]string{"example.com"}, opts)
+	require.EqualError(t, err, "order[new]: notAfter (2023-01-01T00:00:00Z) must be after notBefore (2023-01-02T00:00:00Z)")
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/LukasDeco/lego/v4/acme"
+)
+
+// ErrNoARI is returned when the server does not advertise a renewal info endpoint.
+var ErrNoARI = errors.New("renewalInfo[get]: server does not advertise a renewal info endpoint")
+
+// GetRenewalInfo Gets the renewal information of a certificate.
+// The certID is the unique identifier of the certificate as defined by draft-ietf-acme-ari.
+// - https://datatracker.ietf.org/doc/html/draft-ietf-acme-ari-03#section-4.1
+func (c *CertificateService) GetRenewalInfo(certID string) (acme.ExtendedRenewalInfo, error) {
+	renewalInfoURL := c.core.GetDirectory().RenewalInfo
+	if renewalInfoURL == "" {
+		return acme.ExtendedRenewalInfo{}, ErrNoARI
+	}
+
+	if certID == "" {
+		return acme.ExtendedRenewalInfo{}, errors.New("renewalInfo[get]: empty certID")
+	}
+
+	// The renewalInfo resource is not protected: it's a simple unauthenticated GET.
+	var info acme.RenewalInfo
+	resp, err := c.core.doer.Get(strings.TrimSuffix(renewalInfoURL, "/")+"/"+certID, &info)
+	if err != nil {
+		return acme.ExtendedRenewalInfo{}, err
+	}
+
+	return acme.ExtendedRenewalInfo{RenewalInfo: info, RetryAfter: getRetryAfter(resp)}, nil
+}
@@ -45,6 +45,7 @@ type Directory struct {
 	NewAuthzURL   string `json:"newAuthz"`
 	RevokeCertURL string `json:"revokeCert"`
 	KeyChangeURL  string `json:"keyChange"`
+	RenewalInfo   string `json:"renewalInfo"`
 	Meta          Meta   `json:"meta"`
 }
 
@@ -72,6 +73,11 @@ type Meta struct {
 	// then the CA requires that all new- account requests include an "externalAccountBinding" field
 	// associating the new account with an external account.
 	ExternalAccountRequired bool `json:"externalAccountRequired"`
+
+	// profiles (optional, object):
+	// A map of profile names to human-readable descriptions of the certificate profiles supported by the ACME server.
+	// - https://datatracker.ietf.org/doc/draft-aaron-acme-profiles/
+	Profiles map[string]string `json:"profiles,omitempty"`
 }
 
 // ExtendedAccount a extended Account.
@@ -126,6 +132,9 @@ type ExtendedOrder struct {
 
 	// The order URL, contains the value of the response header `Location`
 	Location string `json:"-"`
+
+	// Contains the value of the response header `Retry-After`
+	RetryAfter string `json:"-"`
 }
 
 // Order the ACME order Object.
@@ -179,6 +188,23 @@ type Order struct {
 	// certificate (optional, string):
 	// A URL for the certificate that has been issued in response to this order
 	Certificate string `json:"certificate,omitempty"`
+
+	// replaces (optional, string):
+	// A string uniquely identifying a previously-issued certificate which this order is intended to replace.
+	// - https://datatracker.ietf.org/doc/html/draft-ietf-acme-ari-03#section-5
+	Replaces string `json:"replaces,omitempty"`
+
+	// profile (optional, string):
+	// The name of the profile which the certificate should be issued with.
+	// - https://datatracker.ietf.org/doc/draft-aaron-acme-profiles/
+	Profile string `json:"profile,omitempty"`
+}
+
+// ExtendedAuthorization a extended Authorization.
+type ExtendedAuthorization struct {
+	Authorization
+	// Contains the value of the response header `Retry-After`
+	RetryAfter string `json:"-"`
 }
 
 // Authorization the ACME authorization object.
@@ -301,6 +327,36 @@ type RevokeCertMessage struct {
 	Reason *uint `json:"reason,omitempty"`
 }
 
+// ExtendedRenewalInfo a extended RenewalInfo.
+type ExtendedRenewalInfo struct {
+	RenewalInfo
+	// Contains the value of the response header `Retry-After`
+	RetryAfter string `json:"-"`
+}
+
+// RenewalInfo the ACME renewal information object.
+// - https://datatracker.ietf.org/doc/html/draft-ietf-acme-ari-03#section-4.2
+type RenewalInfo struct {
+	// suggestedWindow (required, object):
+	// An object with two keys, "start" and "end",
+	// whose values are timestamps, encoded in the format specified in [RFC3339],
+	// which bound the window of time in which the CA recommends renewing the certificate.
+	SuggestedWindow Window `json:"suggestedWindow"`
+
+	// explanationURL (optional, string):
+	// A URL pointing to a page which may explain why the suggested renewal window is what it is.
+	// For example, it may be a page explaining the CA's dynamic load-balancing strategy,
+	// or a page documenting which certificates are affected by a mass revocation event.
+	// Conforming clients SHOULD provide this URL to their operator, if present.
+	ExplanationURL string `json:"explanationURL,omitempty"`
+}
+
+// Window is a window of time.
+type Window struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
 // RawCertificate raw data of a certificate.
 type RawCertificate struct {
 	Cert   []byte
@@ -1,6 +1,7 @@
 package certcrypto
 
 import (
+	"bytes"
 	"crypto"
 	"crypto/ecdsa"
 	"crypto/ed25519"
@@ -14,6 +15,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"net"
 	"strings"
 	"time"
 
@@ -24,7 +26,10 @@ import (
 const (
 	EC256   = KeyType("P256")
 	EC384   = KeyType("P384")
+	EC521   = KeyType("P521")
+	ED25519 = KeyType("ED25519")
 	RSA2048 = KeyType("2048")
+	RSA3072 = KeyType("3072")
 	RSA4096 = KeyType("4096")
 	RSA8192 = KeyType("8192")
 )
@@ -126,8 +131,15 @@ func GeneratePrivateKey(keyType KeyType) (crypto.PrivateKey, error) {
 		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	case EC384:
 		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case EC521:
+		return ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	case ED25519:
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		return privateKey, err
 	case RSA2048:
 		return rsa.GenerateKey(rand.Reader, 2048)
+	case RSA3072:
+		return rsa.GenerateKey(rand.Reader, 3072)
 	case RSA4096:
 		return rsa.GenerateKey(rand.Reader, 4096)
 	case RSA8192:
@@ -137,10 +149,64 @@ func GeneratePrivateKey(keyType KeyType) (crypto.PrivateKey, error) {
 	return nil, fmt.Errorf("invalid KeyType: %s", keyType)
 }
 
+// GetKeyType returns the KeyType of a public key.
+func GetKeyType(publicKey crypto.PublicKey) (KeyType, error) {
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return EC256, nil
+		case elliptic.P384():
+			return EC384, nil
+		case elliptic.P521():
+			return EC521, nil
+		}
+	case ed25519.PublicKey:
+		return ED25519, nil
+	case *rsa.PublicKey:
+		switch key.N.BitLen() {
+		case 2048:
+			return RSA2048, nil
+		case 3072:
+			return RSA3072, nil
+		case 4096:
+			return RSA4096, nil
+		case 8192:
+			return RSA8192, nil
+		}
+	}
+
+	return "", fmt.Errorf("unsupported public key type: %T", publicKey)
+}
+
+// IsMustStaple returns true if the certificate requires OCSP stapling (TLS Feature extension, RFC 7633).
+func IsMustStaple(cert *x509.Certificate) bool {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(tlsFeatureExtensionOID) && bytes.Equal(ext.Value, ocspMustStapleFeature) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GenerateCSR creates a CSR for the given domain and SANs.
+// SANs that are IP addresses are added as iPAddress entries (RFC 8738).
 func GenerateCSR(privateKey crypto.PrivateKey, domain string, san []string, mustStaple bool) ([]byte, error) {
+	var dnsNames []string
+	var ipAddresses []net.IP
+	for _, altName := range san {
+		if ip := net.ParseIP(altName); ip != nil {
+			ipAddresses = append(ipAddresses, ip)
+		} else {
+			dnsNames = append(dnsNames, altName)
+		}
+	}
+
 	template := x509.CertificateRequest{
-		Subject:  pkix.Name{CommonName: domain},
-		DNSNames: san,
+		Subject:     pkix.Name{CommonName: domain},
+		DNSNames:    dnsNames,
+		IPAddresses: ipAddresses,
 	}
 
 	if mustStaple {
@@ -167,6 +233,10 @@ func PEMBlock(data interface{}, pkcsType *PKCSType) *pem.Block {
 	case *ecdsa.PrivateKey:
 		keyBytes, _ := x509.MarshalECPrivateKey(key)
 		pemBlock = &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}
+	case ed25519.PrivateKey:
+		// Ed25519 keys can only be encoded as PKCS#8.
+		keyBytes, _ := x509.MarshalPKCS8PrivateKey(key)
+		pemBlock = &pem.Block{Type: "PRIVATE KEY", Bytes: keyBytes}
 	case *rsa.PrivateKey:
 		if pkcsType == nil {
 			pkcsType = &PKCS1
@@ -235,6 +305,13 @@ func ExtractDomains(cert *x509.Certificate) []string {
 		domains = append(domains, sanDomain)
 	}
 
+	for _, ip := range cert.IPAddresses {
+		if containsSAN(domains, ip.String()) {
+			continue
+		}
+		domains = append(domains, ip.String())
+	}
+
 	return domains
 }
 
@@ -255,6 +332,15 @@ func ExtractDomainsCSR(csr *x509.CertificateRequest) []string {
 		domains = append(domains, sanName)
 	}
 
+	// loop over the SubjectAltName IP addresses
+	for _, ip := range csr.IPAddresses {
+		if containsSAN(domains, ip.String()) {
+			continue
+		}
+
+		domains = append(domains, ip.String())
+	}
+
 	return domains
 }
 
@@ -297,9 +383,14 @@ func generateDerCert(privateKey *rsa.PrivateKey, expiration time.Time, domain st
 
 		KeyUsage:              x509.KeyUsageKeyEncipherment,
 		BasicConstraintsValid: true,
-		DNSNames:              []string{domain},
 		ExtraExtensions:       extensions,
 	}
 
+	if ip := net.ParseIP(domain); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else {
+		template.DNSNames = []string{domain}
+	}
+
 	return x509.CreateCertificate(rand.Reader, &template, &template, &privateKey.PublicKey, privateKey)
 }
@@ -3,8 +3,12 @@ package certcrypto
 import (
 	"bytes"
 	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/pem"
 	"regexp"
 	"testing"
@@ -15,10 +19,34 @@ import (
 )
 
 func TestGeneratePrivateKey(t *testing.T) {
-	key, err := GeneratePrivateKey(RSA2048)
-	require.NoError(t, err, "Error generating private key")
+	testCases := []struct {
+		keyType  KeyType
+		expected crypto.PrivateKey
+	}{
+		{keyType: EC256, expected: &ecdsa.PrivateKey{}},
+		{keyType: EC384, expected: &ecdsa.PrivateKey{}},
+		{keyType: EC521, expected: &ecdsa.PrivateKey{}},
+		{keyType: ED25519, expected: ed25519.PrivateKey{}},
+		{keyType: RSA2048, expected: &rsa.PrivateKey{}},
+		{keyType: RSA3072, expected: &rsa.PrivateKey{}},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(string(test.keyType), func(t *testing.T) {
+			t.Parallel()
+
+			key, err := GeneratePrivateKey(test.keyType)
+			require.NoError(t, err, "Error generating private key")
 
-	assert.NotNil(t, key)
+			assert.IsType(t, test.expected, key)
+		})
+	}
+}
+
+func TestGeneratePrivateKey_invalid(t *testing.T) {
+	_, err := GeneratePrivateKey("foo")
+	require.EqualError(t, err, "invalid KeyType: foo")
 }
 
 func TestGenerateCSR(t *testing.T) {
@@ -104,6 +132,89 @@ func TestGenerateCSR(t *testing.T) {
 	}
 }
 
+func TestGenerateCSR_withIPAddresses(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err, "Error generating private key")
+
+	raw, err := GenerateCSR(privateKey, "lego.acme", []string{"lego.acme", "192.0.2.1", "2001:db8::1"}, false)
+	require.NoError(t, err)
+
+	csr, err := x509.ParseCertificateRequest(raw)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"lego.acme"}, csr.DNSNames)
+	require.Len(t, csr.IPAddresses, 2)
+	assert.Equal(t, "192.0.2.1", csr.IPAddresses[0].String())
+	assert.Equal(t, "2001:db8::1", csr.IPAddresses[1].String())
+
+	assert.Equal(t, []string{"lego.acme", "192.0.2.1", "2001:db8::1"}, ExtractDomainsCSR(csr))
+}
+
+func TestGenerateCSR_keyTypes(t *testing.T) {
+	for _, keyType := range []KeyType{EC521, ED25519, RSA3072} {
+		keyType := keyType
+		t.Run(string(keyType), func(t *testing.T) {
+			t.Parallel()
+
+			privateKey, err := GeneratePrivateKey(keyType)
+			require.NoError(t, err)
+
+			raw, err := GenerateCSR(privateKey, "lego.acme", nil, false)
+			require.NoError(t, err)
+
+			csr, err := x509.ParseCertificateRequest(raw)
+			require.NoError(t, err)
+
+			require.NoError(t, csr.CheckSignature())
+		})
+	}
+}
+
+func TestGetKeyType(t *testing.T) {
+	for _, keyType := range []KeyType{EC256, EC384, ED25519, RSA2048} {
+		keyType := keyType
+		t.Run(string(keyType), func(t *testing.T) {
+			t.Parallel()
+
+			privateKey, err := GeneratePrivateKey(keyType)
+			require.NoError(t, err)
+
+			actual, err := GetKeyType(privateKey.(crypto.Signer).Public())
+			require.NoError(t, err)
+
+			assert.Equal(t, keyType, actual)
+		})
+	}
+}
+
+func TestGetKeyType_unsupported(t *testing.T) {
+	_, err := GetKeyType("foo")
+	require.EqualError(t, err, "unsupported public key type: string")
+}
+
+func TestIsMustStaple(t *testing.T) {
+	privateKey, err := GeneratePrivateKey(RSA2048)
+	require.NoError(t, err)
+
+	extensions := []pkix.Extension{{Id: tlsFeatureExtensionOID, Value: ocspMustStapleFeature}}
+
+	raw, err := generateDerCert(privateKey.(*rsa.PrivateKey), time.Time{}, "lego.acme", extensions)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(raw)
+	require.NoError(t, err)
+
+	assert.True(t, IsMustStaple(cert))
+
+	raw, err = generateDerCert(privateKey.(*rsa.PrivateKey), time.Time{}, "lego.acme", nil)
+	require.NoError(t, err)
+
+	cert, err = x509.ParseCertificate(raw)
+	require.NoError(t, err)
+
+	assert.False(t, IsMustStaple(cert))
+}
+
 func TestPEMEncode(t *testing.T) {
 	buf := bytes.NewBufferString("TestingRSAIsSoMuchFun")
 
@@ -168,6 +279,35 @@ func TestParsePEMPrivateKey(t *testing.T) {
 	require.Errorf(t, err, "Expected to return an error for non-PEM input")
 }
 
+func TestParsePEMPrivateKey_keyTypes(t *testing.T) {
+	testCases := []struct {
+		keyType   KeyType
+		blockType string
+	}{
+		{keyType: EC521, blockType: "EC PRIVATE KEY"},
+		{keyType: ED25519, blockType: "PRIVATE KEY"},
+		{keyType: RSA3072, blockType: "RSA PRIVATE KEY"},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(string(test.keyType), func(t *testing.T) {
+			t.Parallel()
+
+			privateKey, err := GeneratePrivateKey(test.keyType)
+			require.NoError(t, err, "Error generating private key")
+
+			block := PEMBlock(privateKey, nil)
+			require.NotNil(t, block)
+			assert.Equal(t, test.blockType, block.Type)
+
+			decoded, err := ParsePEMPrivateKey(pem.EncodeToMemory(block))
+			require.NoError(t, err)
+			assert.Equal(t, privateKey, decoded)
+		})
+	}
+}
+
 type MockRandReader struct {
 	b *bytes.Buffer
 }
@@ -1,6 +1,7 @@
 package certificate
 
 import (
+	"context"
 	"time"
 
 	"github.com/LukasDeco/lego/v4/acme"
@@ -15,7 +16,7 @@ const (
 	overallRequestLimit = 18
 )
 
-func (c *Certifier) getAuthorizations(order acme.ExtendedOrder) ([]acme.Authorization, error) {
+func (c *Certifier) getAuthorizations(ctx context.Context, order acme.ExtendedOrder) ([]acme.Authorization, error) {
 	resc, errc := make(chan acme.Authorization), make(chan domainError)
 
 	delay := time.Second / overallRequestLimit
@@ -24,13 +25,13 @@ func (c *Certifier) getAuthorizations(order acme.ExtendedOrder) ([]acme.Authoriz
 		time.Sleep(delay)
 
 		go func(authzURL string) {
-			authz, err := c.core.Authorizations.Get(authzURL)
+			authz, err := c.core.Authorizations.GetWithContext(ctx, authzURL)
 			if err != nil {
 				errc <- domainError{Domain: authz.Identifier.Value, Error: err}
 				return
 			}
 
-			resc <- authz
+			resc <- authz.Authorization
 		}(authzURL)
 	}
 
@@ -46,7 +47,7 @@ func (c *Certifier) getAuthorizations(order acme.ExtendedOrder) ([]acme.Authoriz
 	}
 
 	for i, auth := range order.Authorizations {
-		log.Infof("[%s] AuthURL: %s", order.Identifiers[i].Value, auth)
+		c.core.Logger().Info("AuthURL: "+auth, log.Domain(order.Identifiers[i].Value), log.Order(order.Location))
 	}
 
 	close(resc)
@@ -60,22 +61,42 @@ func (c *Certifier) getAuthorizations(order acme.ExtendedOrder) ([]acme.Authoriz
 	return responses, nil
 }
 
-func (c *Certifier) deactivateAuthorizations(order acme.ExtendedOrder, force bool) {
+// deactivateAuthorizations deactivates the authorizations of the order.
+// The deactivation is done even if the context is canceled: the context values are kept, but not the cancellation.
+func (c *Certifier) deactivateAuthorizations(ctx context.Context, order acme.ExtendedOrder, force bool) {
+	ctx = withoutCancel(ctx)
+
 	for _, authzURL := range order.Authorizations {
-		auth, err := c.core.Authorizations.Get(authzURL)
+		auth, err := c.core.Authorizations.GetWithContext(ctx, authzURL)
 		if err != nil {
-			log.Infof("Unable to get the authorization for: %s", authzURL)
+			c.core.Logger().Info("Unable to get the authorization for: "+authzURL, log.Order(order.Location), log.Err(err))
 			continue
 		}
 
 		if auth.Status == acme.StatusValid && !force {
-			log.Infof("Skipping deactivating of valid auth: %s", authzURL)
+			c.core.Logger().Info("Skipping deactivating of valid auth: "+authzURL, log.Domain(auth.Identifier.Value), log.Order(order.Location))
 			continue
 		}
 
-		log.Infof("Deactivating auth: %s", authzURL)
-		if c.core.Authorizations.Deactivate(authzURL) != nil {
-			log.Infof("Unable to deactivate the authorization: %s", authzURL)
+		c.core.Logger().Info("Deactivating auth: "+authzURL, log.Domain(auth.Identifier.Value), log.Order(order.Location))
+		if err := c.core.Authorizations.DeactivateWithContext(ctx, authzURL); err != nil {
+			c.core.Logger().Info("Unable to deactivate the authorization: "+authzURL, log.Domain(auth.Identifier.Value), log.Order(order.Location), log.Err(err))
 		}
 	}
 }
+
+// detachedContext keeps the values of its parent, but is never canceled.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (detachedContext) Done() <-chan struct{} { return nil }
+
+func (detachedContext) Err() error { return nil }
+
+// withoutCancel returns a copy of parent that is not canceled when parent is canceled.
+func withoutCancel(parent context.Context) context.Context {
+	return detachedContext{Context: parent}
+}
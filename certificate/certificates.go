@@ -1,14 +1,13 @@
 package certificate
 
 import (
-	"bytes"
+	"context"
 	"crypto"
 	"crypto/x509"
 	"encoding/base64"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
+	"net"
 	"strings"
 	"time"
 
@@ -53,6 +52,16 @@ type Resource struct {
 //
 // If `AlwaysDeactivateAuthorizations` is true, the authorizations are also relinquished if the obtain request was successful.
 // See https://datatracker.ietf.org/doc/html/rfc8555#section-7.5.2.
+//
+// If `ReplacesCertID` is set, the new order indicates the certificate it replaces (only if the CA supports ARI).
+// See https://datatracker.ietf.org/doc/html/draft-ietf-acme-ari-03#section-5.
+//
+// If `Profile` is set, the certificate is issued with this profile, which must be advertised by the CA.
+// See https://datatracker.ietf.org/doc/draft-aaron-acme-profiles/.
+//
+// If `NotBefore` and/or `NotAfter` are set, they are sent as the requested validity period of the certificate.
+// Not all CAs support it.
+// See https://www.rfc-editor.org/rfc/rfc8555.html#section-7.4.
 type ObtainRequest struct {
 	Domains                        []string
 	Bundle                         bool
@@ -61,6 +70,16 @@ type ObtainRequest struct {
 	MustStaple                     bool
 	PreferredChain                 string
 	AlwaysDeactivateAuthorizations bool
+
+	// A string uniquely identifying a previously-issued certificate which this order is intended to replace.
+	ReplacesCertID string
+
+	// The name of the certificate profile.
+	Profile string
+
+	// The requested validity period of the certificate.
+	NotBefore time.Time
+	NotAfter  time.Time
 }
 
 // ObtainForCSRRequest The request to obtain a certificate matching the CSR passed into it.
@@ -69,20 +88,61 @@ type ObtainRequest struct {
 //
 // If `AlwaysDeactivateAuthorizations` is true, the authorizations are also relinquished if the obtain request was successful.
 // See https://datatracker.ietf.org/doc/html/rfc8555#section-7.5.2.
+//
+// If `ReplacesCertID` is set, the new order indicates the certificate it replaces (only if the CA supports ARI).
+// See https://datatracker.ietf.org/doc/html/draft-ietf-acme-ari-03#section-5.
+//
+// If `Profile` is set, the certificate is issued with this profile, which must be advertised by the CA.
+// See https://datatracker.ietf.org/doc/draft-aaron-acme-profiles/.
+//
+// If `NotBefore` and/or `NotAfter` are set, they are sent as the requested validity period of the certificate.
+// Not all CAs support it.
+// See https://www.rfc-editor.org/rfc/rfc8555.html#section-7.4.
 type ObtainForCSRRequest struct {
 	CSR                            *x509.CertificateRequest
 	Bundle                         bool
 	PreferredChain                 string
 	AlwaysDeactivateAuthorizations bool
+
+	// A string uniquely identifying a previously-issued certificate which this order is intended to replace.
+	ReplacesCertID string
+
+	// The name of the certificate profile.
+	Profile string
+
+	// The requested validity period of the certificate.
+	NotBefore time.Time
+	NotAfter  time.Time
 }
 
 type resolver interface {
 	Solve(authorizations []acme.Authorization) error
 }
 
+// resolverContext a resolver which can be canceled through a context.
+type resolverContext interface {
+	SolveContext(ctx context.Context, authorizations []acme.Authorization) error
+}
+
+// defaultFinalizationPolling the default polling strategy used while the server issues the certificate.
+var defaultFinalizationPolling = wait.Strategy{
+	Timeout:         30 * time.Second,
+	InitialInterval: 500 * time.Millisecond,
+	MaxInterval:     10 * time.Second,
+	Multiplier:      1.5,
+}
+
 type CertifierOptions struct {
 	KeyType certcrypto.KeyType
+
+	// Timeout the maximum duration of the order finalization (i.e. the certificate issuance).
+	// It's used when FinalizationPolling.Timeout is not set.
 	Timeout time.Duration
+
+	// FinalizationPolling the polling strategy used while the server issues the certificate.
+	// The Retry-After header sent by the server takes precedence over the intervals of the strategy.
+	// The zero fields are replaced by the default values.
+	FinalizationPolling wait.Strategy
 }
 
 // Certifier A service to obtain/renew/revoke certificates.
@@ -106,6 +166,16 @@ func NewCertifier(core *api.Core, resolver resolver, options CertifierOptions) *
 // This function will never return a partial certificate.
 // If one domain in the list fails, the whole certificate will fail.
 func (c *Certifier) Obtain(request ObtainRequest) (*Resource, error) {
+	return c.ObtainWithContext(context.Background(), request)
+}
+
+// ObtainWithContext tries to obtain a single certificate using all domains passed into it.
+//
+// This function will never return a partial certificate.
+// If one domain in the list fails, the whole certificate will fail.
+//
+// The process is aborted if the context is canceled.
+func (c *Certifier) ObtainWithContext(ctx context.Context, request ObtainRequest) (*Resource, error) {
 	if len(request.Domains) == 0 {
 		return nil, errors.New("no domains to obtain a certificate for")
 	}
@@ -113,34 +183,41 @@ func (c *Certifier) Obtain(request ObtainRequest) (*Resource, error) {
 	domains := sanitizeDomain(request.Domains)
 
 	if request.Bundle {
-		log.Infof("[%s] acme: Obtaining bundled SAN certificate", strings.Join(domains, ", "))
+		c.core.Logger().Info("acme: Obtaining bundled SAN certificate", log.Domain(strings.Join(domains, ", ")))
 	} else {
-		log.Infof("[%s] acme: Obtaining SAN certificate", strings.Join(domains, ", "))
+		c.core.Logger().Info("acme: Obtaining SAN certificate", log.Domain(strings.Join(domains, ", ")))
 	}
 
-	order, err := c.core.Orders.New(domains)
+	orderOpts := &api.OrderOptions{
+		ReplacesCertID: request.ReplacesCertID,
+		Profile:        request.Profile,
+		NotBefore:      request.NotBefore,
+		NotAfter:       request.NotAfter,
+	}
+
+	order, err := c.core.Orders.NewWithContext(ctx, domains, orderOpts)
 	if err != nil {
 		return nil, err
 	}
 
-	authz, err := c.getAuthorizations(order)
+	authz, err := c.getAuthorizations(ctx, order)
 	if err != nil {
 		// If any challenge fails, return. Do not generate partial SAN certificates.
-		c.deactivateAuthorizations(order, request.AlwaysDeactivateAuthorizations)
+		c.deactivateAuthorizations(ctx, order, request.AlwaysDeactivateAuthorizations)
 		return nil, err
 	}
 
-	err = c.resolver.Solve(authz)
+	err = c.solve(ctx, authz)
 	if err != nil {
 		// If any challenge fails, return. Do not generate partial SAN certificates.
-		c.deactivateAuthorizations(order, request.AlwaysDeactivateAuthorizations)
+		c.deactivateAuthorizations(ctx, order, request.AlwaysDeactivateAuthorizations)
 		return nil, err
 	}
 
-	log.Infof("[%s] acme: Validations succeeded; requesting certificates", strings.Join(domains, ", "))
+	c.core.Logger().Info("acme: Validations succeeded; requesting certificates", log.Domain(strings.Join(domains, ", ")), log.Order(order.Location))
 
 	failures := make(obtainError)
-	cert, err := c.getForOrder(domains, order, request.Bundle, request.PrivateKey, request.MustStaple, request.PreferredChain, request.PKSCType)
+	cert, err := c.getForOrder(ctx, domains, order, request.Bundle, request.PrivateKey, request.MustStaple, request.PreferredChain, request.PKSCType)
 	if err != nil {
 		for _, auth := range authz {
 			failures[challenge.GetTargetedDomain(auth)] = err
@@ -148,7 +225,7 @@ func (c *Certifier) Obtain(request ObtainRequest) (*Resource, error) {
 	}
 
 	if request.AlwaysDeactivateAuthorizations {
-		c.deactivateAuthorizations(order, true)
+		c.deactivateAuthorizations(ctx, order, true)
 	}
 
 	// Do not return an empty failures map, because
@@ -169,6 +246,19 @@ func (c *Certifier) Obtain(request ObtainRequest) (*Resource, error) {
 // This function will never return a partial certificate.
 // If one domain in the list fails, the whole certificate will fail.
 func (c *Certifier) ObtainForCSR(request ObtainForCSRRequest) (*Resource, error) {
+	return c.ObtainForCSRWithContext(context.Background(), request)
+}
+
+// ObtainForCSRWithContext tries to obtain a certificate matching the CSR passed into it.
+//
+// The domains are inferred from the CommonName and SubjectAltNames, if any.
+// The private key for this CSR is not required.
+//
+// This function will never return a partial certificate.
+// If one domain in the list fails, the whole certificate will fail.
+//
+// The process is aborted if the context is canceled.
+func (c *Certifier) ObtainForCSRWithContext(ctx context.Context, request ObtainForCSRRequest) (*Resource, error) {
 	if request.CSR == nil {
 		return nil, errors.New("cannot obtain resource for CSR: CSR is missing")
 	}
@@ -178,34 +268,41 @@ func (c *Certifier) ObtainForCSR(request ObtainForCSRRequest) (*Resource, error)
 	domains := certcrypto.ExtractDomainsCSR(request.CSR)
 
 	if request.Bundle {
-		log.Infof("[%s] acme: Obtaining bundled SAN certificate given a CSR", strings.Join(domains, ", "))
+		c.core.Logger().Info("acme: Obtaining bundled SAN certificate given a CSR", log.Domain(strings.Join(domains, ", ")))
 	} else {
-		log.Infof("[%s] acme: Obtaining SAN certificate given a CSR", strings.Join(domains, ", "))
+		c.core.Logger().Info("acme: Obtaining SAN certificate given a CSR", log.Domain(strings.Join(domains, ", ")))
+	}
+
+	orderOpts := &api.OrderOptions{
+		ReplacesCertID: request.ReplacesCertID,
+		Profile:        request.Profile,
+		NotBefore:      request.NotBefore,
+		NotAfter:       request.NotAfter,
 	}
 
-	order, err := c.core.Orders.New(domains)
+	order, err := c.core.Orders.NewWithContext(ctx, domains, orderOpts)
 	if err != nil {
 		return nil, err
 	}
 
-	authz, err := c.getAuthorizations(order)
+	authz, err := c.getAuthorizations(ctx, order)
 	if err != nil {
 		// If any challenge fails, return. Do not generate partial SAN certificates.
-		c.deactivateAuthorizations(order, request.AlwaysDeactivateAuthorizations)
+		c.deactivateAuthorizations(ctx, order, request.AlwaysDeactivateAuthorizations)
 		return nil, err
 	}
 
-	err = c.resolver.Solve(authz)
+	err = c.solve(ctx, authz)
 	if err != nil {
 		// If any challenge fails, return. Do not generate partial SAN certificates.
-		c.deactivateAuthorizations(order, request.AlwaysDeactivateAuthorizations)
+		c.deactivateAuthorizations(ctx, order, request.AlwaysDeactivateAuthorizations)
 		return nil, err
 	}
 
-	log.Infof("[%s] acme: Validations succeeded; requesting certificates", strings.Join(domains, ", "))
+	c.core.Logger().Info("acme: Validations succeeded; requesting certificates", log.Domain(strings.Join(domains, ", ")), log.Order(order.Location))
 
 	failures := make(obtainError)
-	cert, err := c.getForCSR(domains, order, request.Bundle, request.CSR.Raw, nil, request.PreferredChain)
+	cert, err := c.getForCSR(ctx, domains, order, request.Bundle, request.CSR.Raw, nil, request.PreferredChain)
 	if err != nil {
 		for _, auth := range authz {
 			failures[challenge.GetTargetedDomain(auth)] = err
@@ -213,7 +310,7 @@ func (c *Certifier) ObtainForCSR(request ObtainForCSRRequest) (*Resource, error)
 	}
 
 	if request.AlwaysDeactivateAuthorizations {
-		c.deactivateAuthorizations(order, true)
+		c.deactivateAuthorizations(ctx, order, true)
 	}
 
 	if cert != nil {
@@ -229,7 +326,19 @@ func (c *Certifier) ObtainForCSR(request ObtainForCSRRequest) (*Resource, error)
 	return cert, nil
 }
 
-func (c *Certifier) getForOrder(domains []string, order acme.ExtendedOrder, bundle bool, privateKey crypto.PrivateKey, mustStaple bool, preferredChain string, pkcsType *certcrypto.PKCSType) (*Resource, error) {
+func (c *Certifier) solve(ctx context.Context, authz []acme.Authorization) error {
+	if r, ok := c.resolver.(resolverContext); ok {
+		return r.SolveContext(ctx, authz)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.resolver.Solve(authz)
+}
+
+func (c *Certifier) getForOrder(ctx context.Context, domains []string, order acme.ExtendedOrder, bundle bool, privateKey crypto.PrivateKey, mustStaple bool, preferredChain string, pkcsType *certcrypto.PKCSType) (*Resource, error) {
 	if privateKey == nil {
 		var err error
 		privateKey, err = certcrypto.GeneratePrivateKey(c.options.KeyType)
@@ -260,11 +369,11 @@ func (c *Certifier) getForOrder(domains []string, order acme.ExtendedOrder, bund
 		return nil, err
 	}
 
-	return c.getForCSR(domains, order, bundle, csr, certcrypto.PEMEncodeWithPKCSType(privateKey, pkcsType), preferredChain)
+	return c.getForCSR(ctx, domains, order, bundle, csr, certcrypto.PEMEncodeWithPKCSType(privateKey, pkcsType), preferredChain)
 }
 
-func (c *Certifier) getForCSR(domains []string, order acme.ExtendedOrder, bundle bool, csr, privateKeyPem []byte, preferredChain string) (*Resource, error) {
-	respOrder, err := c.core.Orders.UpdateForCSR(order.Finalize, csr)
+func (c *Certifier) getForCSR(ctx context.Context, domains []string, order acme.ExtendedOrder, bundle bool, csr, privateKeyPem []byte, preferredChain string) (*Resource, error) {
+	respOrder, err := c.core.Orders.UpdateForCSRWithContext(ctx, order.Finalize, csr)
 	if err != nil {
 		return nil, err
 	}
@@ -278,7 +387,7 @@ func (c *Certifier) getForCSR(domains []string, order acme.ExtendedOrder, bundle
 
 	if respOrder.Status == acme.StatusValid {
 		// if the certificate is available right away, short cut!
-		ok, errR := c.checkResponse(respOrder, certRes, bundle, preferredChain)
+		ok, errR := c.checkResponse(ctx, respOrder, certRes, bundle, preferredChain)
 		if errR != nil {
 			return nil, errR
 		}
@@ -288,28 +397,55 @@ func (c *Certifier) getForCSR(domains []string, order acme.ExtendedOrder, bundle
 		}
 	}
 
-	timeout := c.options.Timeout
-	if c.options.Timeout <= 0 {
-		timeout = 30 * time.Second
-	}
+	// The server may ask to wait before polling the order (i.e. the issuance takes time).
+	// An invalid or missing Retry-After is ignored.
+	delay, _ := wait.ParseRetryAfter(respOrder.RetryAfter)
 
-	err = wait.For("certificate", timeout, timeout/60, func() (bool, error) {
-		ord, errW := c.core.Orders.Get(order.Location)
+	err = c.finalizationPolling().Poll(ctx, "finalization", func() (bool, time.Duration, error) {
+		if delay > 0 {
+			// honors the Retry-After of the finalization request before the first poll.
+			d := delay
+			delay = 0
+			return false, d, nil
+		}
+
+		// The transport errors and the errors while fetching the certificate are retried,
+		// only an invalid order stops the polling.
+		ord, errW := c.core.Orders.GetWithContext(ctx, order.Location)
 		if errW != nil {
-			return false, errW
+			return false, 0, errW
 		}
 
-		done, errW := c.checkResponse(ord, certRes, bundle, preferredChain)
+		if ord.Status == acme.StatusInvalid {
+			if ord.Error == nil {
+				return false, 0, wait.Permanent(errors.New("the order is invalid"))
+			}
+
+			return false, 0, wait.Permanent(ord.Error)
+		}
+
+		done, errW := c.checkResponse(ctx, ord, certRes, bundle, preferredChain)
 		if errW != nil {
-			return false, errW
+			return false, 0, errW
 		}
 
-		return done, nil
+		ra, _ := wait.ParseRetryAfter(ord.RetryAfter)
+
+		return done, ra, nil
 	})
 
 	return certRes, err
 }
 
+func (c *Certifier) finalizationPolling() wait.Strategy {
+	strategy := c.options.FinalizationPolling
+	if strategy.Timeout <= 0 {
+		strategy.Timeout = c.options.Timeout
+	}
+
+	return strategy.WithDefaults(defaultFinalizationPolling)
+}
+
 // checkResponse checks to see if the certificate is ready and a link is contained in the response.
 //
 // If so, loads it into certRes and returns true.
@@ -318,13 +454,13 @@ func (c *Certifier) getForCSR(domains []string, order acme.ExtendedOrder, bundle
 // The certRes input should already have the Domain (common name) field populated.
 //
 // If bundle is true, the certificate will be bundled with the issuer's cert.
-func (c *Certifier) checkResponse(order acme.ExtendedOrder, certRes *Resource, bundle bool, preferredChain string) (bool, error) {
+func (c *Certifier) checkResponse(ctx context.Context, order acme.ExtendedOrder, certRes *Resource, bundle bool, preferredChain string) (bool, error) {
 	valid, err := checkOrderStatus(order)
 	if err != nil || !valid {
 		return valid, err
 	}
 
-	certs, err := c.core.Certificates.GetAll(order.Certificate, bundle)
+	certs, err := c.core.Certificates.GetAllWithContext(ctx, order.Certificate, bundle)
 	if err != nil {
 		return false, err
 	}
@@ -336,7 +472,7 @@ func (c *Certifier) checkResponse(order acme.ExtendedOrder, certRes *Resource, b
 	certRes.CertStableURL = order.Certificate
 
 	if preferredChain == "" {
-		log.Infof("[%s] Server responded with a certificate.", certRes.Domain)
+		c.core.Logger().Info("Server responded with a certificate.", log.Domain(certRes.Domain), log.Order(order.Location))
 
 		return true, nil
 	}
@@ -348,7 +484,8 @@ func (c *Certifier) checkResponse(order acme.ExtendedOrder, certRes *Resource, b
 		}
 
 		if ok {
-			log.Infof("[%s] Server responded with a certificate for the preferred certificate chains %q.", certRes.Domain, preferredChain)
+			c.core.Logger().Info(fmt.Sprintf("Server responded with a certificate for the preferred certificate chains %q.", preferredChain),
+				log.Domain(certRes.Domain), log.Order(order.Location))
 
 			certRes.IssuerCertificate = cert.Issuer
 			certRes.Certificate = cert.Cert
@@ -359,7 +496,8 @@ func (c *Certifier) checkResponse(order acme.ExtendedOrder, certRes *Resource, b
 		}
 	}
 
-	log.Infof("lego has been configured to prefer certificate chains with issuer %q, but no chain from the CA matched this issuer. Using the default certificate chain instead.", preferredChain)
+	c.core.Logger().Info(fmt.Sprintf("lego has been configured to prefer certificate chains with issuer %q, but no chain from the CA matched this issuer. Using the default certificate chain instead.", preferredChain),
+		log.Domain(certRes.Domain), log.Order(order.Location))
 
 	return true, nil
 }
@@ -371,22 +509,56 @@ func (c *Certifier) Revoke(cert []byte) error {
 
 // RevokeWithReason takes a PEM encoded certificate or bundle and tries to revoke it at the CA.
 func (c *Certifier) RevokeWithReason(cert []byte, reason *uint) error {
-	certificates, err := certcrypto.ParsePEMBundle(cert)
+	x509Cert, err := parseRevokedCertificate(cert)
+	if err != nil {
+		return err
+	}
+
+	return c.core.Certificates.Revoke(newRevokeCertMessage(x509Cert, reason))
+}
+
+// RevokeWithKey takes a PEM encoded certificate or bundle and tries to revoke it at the CA,
+// with a request signed by the private key of the certificate instead of the account key.
+// It allows to revoke a certificate without its account (i.e. when the private key has been compromised).
+func (c *Certifier) RevokeWithKey(cert []byte, privateKey crypto.PrivateKey, reason *uint) error {
+	x509Cert, err := parseRevokedCertificate(cert)
 	if err != nil {
 		return err
 	}
 
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return fmt.Errorf("unsupported private key type: %T", privateKey)
+	}
+
+	publicKey, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !publicKey.Equal(x509Cert.PublicKey) {
+		return errors.New("the private key doesn't match the certificate")
+	}
+
+	return c.core.Certificates.RevokeWithKey(newRevokeCertMessage(x509Cert, reason), privateKey)
+}
+
+// parseRevokedCertificate returns the first certificate of a PEM encoded certificate or bundle.
+func parseRevokedCertificate(cert []byte) (*x509.Certificate, error) {
+	certificates, err := certcrypto.ParsePEMBundle(cert)
+	if err != nil {
+		return nil, err
+	}
+
 	x509Cert := certificates[0]
 	if x509Cert.IsCA {
-		return errors.New("certificate bundle starts with a CA certificate")
+		return nil, errors.New("certificate bundle starts with a CA certificate")
 	}
 
-	revokeMsg := acme.RevokeCertMessage{
-		Certificate: base64.RawURLEncoding.EncodeToString(x509Cert.Raw),
+	return x509Cert, nil
+}
+
+func newRevokeCertMessage(cert *x509.Certificate, reason *uint) acme.RevokeCertMessage {
+	return acme.RevokeCertMessage{
+		Certificate: base64.RawURLEncoding.EncodeToString(cert.Raw),
 		Reason:      reason,
 	}
-
-	return c.core.Certificates.Revoke(revokeMsg)
 }
 
 // Renew takes a Resource and tries to renew the certificate.
@@ -415,7 +587,7 @@ func (c *Certifier) Renew(certRes Resource, bundle, mustStaple bool, preferredCh
 
 	// This is just meant to be informal for the user.
 	timeLeft := x509Cert.NotAfter.Sub(time.Now().UTC())
-	log.Infof("[%s] acme: Trying renewal with %d hours remaining", certRes.Domain, int(timeLeft.Hours()))
+	c.core.Logger().Info(fmt.Sprintf("acme: Trying renewal with %d hours remaining", int(timeLeft.Hours())), log.Domain(certRes.Domain))
 
 	// We always need to request a new certificate to renew.
 	// Start by checking to see if the certificate was based off a CSR,
@@ -457,78 +629,11 @@ func (c *Certifier) Renew(certRes Resource, bundle, mustStaple bool, preferredCh
 // The returned []byte can be passed directly into the OCSPStaple property of a tls.Certificate.
 // If the bundle only contains the issued certificate,
 // this function will try to get the issuer certificate from the IssuingCertificateURL in the certificate.
+// All the OCSP servers and issuer URLs of the certificate are tried (see FetchOCSP).
 //
 // If the []byte and/or ocsp.Response return values are nil, the OCSP status may be assumed OCSPUnknown.
 func (c *Certifier) GetOCSP(bundle []byte) ([]byte, *ocsp.Response, error) {
-	certificates, err := certcrypto.ParsePEMBundle(bundle)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	// We expect the certificate slice to be ordered downwards the chain.
-	// SRV CRT -> CA. We need to pull the leaf and issuer certs out of it,
-	// which should always be the first two certificates.
-	// If there's no OCSP server listed in the leaf cert, there's nothing to do.
-	// And if we have only one certificate so far, we need to get the issuer cert.
-
-	issuedCert := certificates[0]
-
-	if len(issuedCert.OCSPServer) == 0 {
-		return nil, nil, errors.New("no OCSP server specified in cert")
-	}
-
-	if len(certificates) == 1 {
-		// TODO: build fallback. If this fails, check the remaining array entries.
-		if len(issuedCert.IssuingCertificateURL) == 0 {
-			return nil, nil, errors.New("no issuing certificate URL")
-		}
-
-		resp, errC := c.core.HTTPClient.Get(issuedCert.IssuingCertificateURL[0])
-		if errC != nil {
-			return nil, nil, errC
-		}
-		defer resp.Body.Close()
-
-		issuerBytes, errC := io.ReadAll(http.MaxBytesReader(nil, resp.Body, maxBodySize))
-		if errC != nil {
-			return nil, nil, errC
-		}
-
-		issuerCert, errC := x509.ParseCertificate(issuerBytes)
-		if errC != nil {
-			return nil, nil, errC
-		}
-
-		// Insert it into the slice on position 0
-		// We want it ordered right SRV CRT -> CA
-		certificates = append(certificates, issuerCert)
-	}
-
-	issuerCert := certificates[1]
-
-	// Finally kick off the OCSP request.
-	ocspReq, err := ocsp.CreateRequest(issuedCert, issuerCert, nil)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	resp, err := c.core.HTTPClient.Post(issuedCert.OCSPServer[0], "application/ocsp-request", bytes.NewReader(ocspReq))
-	if err != nil {
-		return nil, nil, err
-	}
-	defer resp.Body.Close()
-
-	ocspResBytes, err := io.ReadAll(http.MaxBytesReader(nil, resp.Body, maxBodySize))
-	if err != nil {
-		return nil, nil, err
-	}
-
-	ocspRes, err := ocsp.ParseResponse(ocspResBytes, issuerCert)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	return ocspResBytes, ocspRes, nil
+	return FetchOCSP(context.Background(), c.core.HTTPClient, bundle)
 }
 
 // Get attempts to fetch the certificate at the supplied URL.
@@ -592,6 +697,12 @@ func checkOrderStatus(order acme.ExtendedOrder) (bool, error) {
 func sanitizeDomain(domains []string) []string {
 	var sanitizedDomains []string
 	for _, domain := range domains {
+		if ip := net.ParseIP(domain); ip != nil {
+			// IP identifiers are not subject to IDNA, only normalized (RFC 8738).
+			sanitizedDomains = append(sanitizedDomains, ip.String())
+			continue
+		}
+
 		sanitizedDomain, err := idna.ToASCII(domain)
 		if err != nil {
 			log.Infof("skip domain %q: unable to sanitize (punnycode): %v", domain, err)
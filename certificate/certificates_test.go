@@ -1,17 +1,24 @@
 package certificate
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
+	"io"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/LukasDeco/lego/v4/acme"
 	"github.com/LukasDeco/lego/v4/acme/api"
 	"github.com/LukasDeco/lego/v4/certcrypto"
 	"github.com/LukasDeco/lego/v4/platform/tester"
+	"github.com/LukasDeco/lego/v4/platform/wait"
+	"github.com/go-jose/go-jose/v3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -201,7 +208,7 @@ func Test_checkResponse(t *testing.T) {
 	}
 	certRes := &Resource{}
 
-	valid, err := certifier.checkResponse(order, certRes, true, "")
+	valid, err := certifier.checkResponse(context.Background(), order, certRes, true, "")
 	require.NoError(t, err)
 	assert.True(t, valid)
 	assert.NotNil(t, certRes)
@@ -251,7 +258,7 @@ func Test_checkResponse_issuerRelUp(t *testing.T) {
 	}
 	certRes := &Resource{}
 
-	valid, err := certifier.checkResponse(order, certRes, true, "")
+	valid, err := certifier.checkResponse(context.Background(), order, certRes, true, "")
 	require.NoError(t, err)
 	assert.True(t, valid)
 	assert.NotNil(t, certRes)
@@ -291,7 +298,7 @@ func Test_checkResponse_no_bundle(t *testing.T) {
 	}
 	certRes := &Resource{}
 
-	valid, err := certifier.checkResponse(order, certRes, false, "")
+	valid, err := certifier.checkResponse(context.Background(), order, certRes, false, "")
 	require.NoError(t, err)
 	assert.True(t, valid)
 	assert.NotNil(t, certRes)
@@ -343,7 +350,7 @@ func Test_checkResponse_alternate(t *testing.T) {
 		Domain: "example.com",
 	}
 
-	valid, err := certifier.checkResponse(order, certRes, true, "DST Root CA X3")
+	valid, err := certifier.checkResponse(context.Background(), order, certRes, true, "DST Root CA X3")
 	require.NoError(t, err)
 
 	assert.True(t, valid)
@@ -396,3 +403,285 @@ type resolverMock struct {
 func (r *resolverMock) Solve(_ []acme.Authorization) error {
 	return r.error
 }
+
+func Test_sanitizeDomain(t *testing.T) {
+	domains := []string{"lego.acme", "münchen.example", "192.0.2.1", "2001:DB8::1"}
+
+	expected := []string{"lego.acme", "xn--mnchen-3ya.example", "192.0.2.1", "2001:db8::1"}
+
+	assert.Equal(t, expected, sanitizeDomain(domains))
+}
+
+func Test_getForCSR_finalization(t *testing.T) {
+	mux, apiURL := tester.SetupFakeAPI(t)
+
+	var polls int
+
+	mux.HandleFunc("/finalize", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Retry-After", "0")
+		err := tester.WriteJSONResponse(w, acme.Order{Status: acme.StatusProcessing})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+
+	mux.HandleFunc("/order", func(w http.ResponseWriter, _ *http.Request) {
+		polls++
+
+		order := acme.Order{Status: acme.StatusProcessing}
+		if polls == 3 {
+			order = acme.Order{Status: acme.StatusValid, Certificate: apiURL + "/certificate"}
+		}
+
+		err := tester.WriteJSONResponse(w, order)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+
+	mux.HandleFunc("/certificate", func(w http.ResponseWriter, _ *http.Request) {
+		_, err := w.Write([]byte(certResponseMock))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err, "Could not generate test key")
+
+	core, err := api.New(http.DefaultClient, "lego-test", apiURL+"/dir", "", key)
+	require.NoError(t, err)
+
+	certifier := NewCertifier(core, &resolverMock{}, CertifierOptions{
+		KeyType:             certcrypto.RSA2048,
+		FinalizationPolling: wait.Strategy{Timeout: 5 * time.Second, InitialInterval: 10 * time.Millisecond},
+	})
+
+	order := acme.ExtendedOrder{
+		Order:    acme.Order{Finalize: apiURL + "/finalize"},
+		Location: apiURL + "/order",
+	}
+
+	certRes, err := certifier.getForCSR(context.Background(), []string{"acme.wtf"}, order, true, []byte("csr"), nil, "")
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, polls)
+	assert.Equal(t, certResponseMock, string(certRes.Certificate))
+}
+
+func Test_getForCSR_finalizationTransientError(t *testing.T) {
+	mux, apiURL := tester.SetupFakeAPI(t)
+
+	var polls int
+
+	mux.HandleFunc("/finalize", func(w http.ResponseWriter, _ *http.Request) {
+		err := tester.WriteJSONResponse(w, acme.Order{Status: acme.StatusProcessing})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+
+	mux.HandleFunc("/order", func(w http.ResponseWriter, _ *http.Request) {
+		polls++
+
+		if polls == 1 {
+			http.Error(w, "oops", http.StatusServiceUnavailable)
+			return
+		}
+
+		err := tester.WriteJSONResponse(w, acme.Order{Status: acme.StatusValid, Certificate: apiURL + "/certificate"})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+
+	mux.HandleFunc("/certificate", func(w http.ResponseWriter, _ *http.Request) {
+		_, err := w.Write([]byte(certResponseMock))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err, "Could not generate test key")
+
+	core, err := api.New(http.DefaultClient, "lego-test", apiURL+"/dir", "", key)
+	require.NoError(t, err)
+
+	certifier := NewCertifier(core, &resolverMock{}, CertifierOptions{
+		KeyType:             certcrypto.RSA2048,
+		FinalizationPolling: wait.Strategy{Timeout: 5 * time.Second, InitialInterval: 10 * time.Millisecond},
+	})
+
+	order := acme.ExtendedOrder{
+		Order:    acme.Order{Finalize: apiURL + "/finalize"},
+		Location: apiURL + "/order",
+	}
+
+	certRes, err := certifier.getForCSR(context.Background(), []string{"acme.wtf"}, order, true, []byte("csr"), nil, "")
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, polls)
+	assert.Equal(t, certResponseMock, string(certRes.Certificate))
+}
+
+func Test_getForCSR_finalizationInvalid(t *testing.T) {
+	mux, apiURL := tester.SetupFakeAPI(t)
+
+	var polls int
+
+	mux.HandleFunc("/finalize", func(w http.ResponseWriter, _ *http.Request) {
+		err := tester.WriteJSONResponse(w, acme.Order{Status: acme.StatusProcessing})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+
+	mux.HandleFunc("/order", func(w http.ResponseWriter, _ *http.Request) {
+		polls++
+
+		err := tester.WriteJSONResponse(w, acme.Order{
+			Status: acme.StatusInvalid,
+			Error:  &acme.ProblemDetails{Type: "urn:ietf:params:acme:error:badCSR", Detail: "oops"},
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err, "Could not generate test key")
+
+	core, err := api.New(http.DefaultClient, "lego-test", apiURL+"/dir", "", key)
+	require.NoError(t, err)
+
+	certifier := NewCertifier(core, &resolverMock{}, CertifierOptions{
+		KeyType:             certcrypto.RSA2048,
+		FinalizationPolling: wait.Strategy{Timeout: 5 * time.Second, InitialInterval: 10 * time.Millisecond},
+	})
+
+	order := acme.ExtendedOrder{
+		Order:    acme.Order{Finalize: apiURL + "/finalize"},
+		Location: apiURL + "/order",
+	}
+
+	_, err = certifier.getForCSR(context.Background(), []string{"acme.wtf"}, order, true, []byte("csr"), nil, "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "oops")
+
+	assert.Equal(t, 1, polls)
+}
+
+func Test_getForCSR_finalizationTimeout(t *testing.T) {
+	mux, apiURL := tester.SetupFakeAPI(t)
+
+	mux.HandleFunc("/finalize", func(w http.ResponseWriter, _ *http.Request) {
+		err := tester.WriteJSONResponse(w, acme.Order{Status: acme.StatusProcessing})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+
+	mux.HandleFunc("/order", func(w http.ResponseWriter, _ *http.Request) {
+		err := tester.WriteJSONResponse(w, acme.Order{Status: acme.StatusProcessing})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err, "Could not generate test key")
+
+	core, err := api.New(http.DefaultClient, "lego-test", apiURL+"/dir", "", key)
+	require.NoError(t, err)
+
+	certifier := NewCertifier(core, &resolverMock{}, CertifierOptions{
+		KeyType: certcrypto.RSA2048,
+		Timeout: 200 * time.Millisecond,
+		FinalizationPolling: wait.Strategy{
+			InitialInterval: 10 * time.Millisecond,
+		},
+	})
+
+	order := acme.ExtendedOrder{
+		Order:    acme.Order{Finalize: apiURL + "/finalize"},
+		Location: apiURL + "/order",
+	}
+
+	_, err = certifier.getForCSR(context.Background(), []string{"acme.wtf"}, order, true, []byte("csr"), nil, "")
+	require.EqualError(t, err, "finalization: time limit exceeded (200ms)")
+}
+
+func TestCertifier_RevokeWithKey(t *testing.T) {
+	mux, apiURL := tester.SetupFakeAPI(t)
+
+	certKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err, "Could not generate test key")
+
+	certPEM, err := certcrypto.GeneratePemCert(certKey, "lego.acme", nil)
+	require.NoError(t, err)
+
+	cert, err := certcrypto.ParsePEMCertificate(certPEM)
+	require.NoError(t, err)
+
+	mux.HandleFunc("/revokeCert", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		jws, err := jose.ParseSigned(string(body))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		content, err := jws.Verify(&jose.JSONWebKey{Key: certKey.Public()})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var msg acme.RevokeCertMessage
+		err = json.Unmarshal(content, &msg)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if msg.Certificate != base64.RawURLEncoding.EncodeToString(cert.Raw) {
+			http.Error(w, "invalid certificate", http.StatusBadRequest)
+			return
+		}
+	})
+
+	accountKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err, "Could not generate test key")
+
+	core, err := api.New(http.DefaultClient, "lego-test", apiURL+"/dir", "", accountKey)
+	require.NoError(t, err)
+
+	certifier := NewCertifier(core, &resolverMock{}, CertifierOptions{KeyType: certcrypto.RSA2048})
+
+	reason := uint(acme.CRLReasonKeyCompromise)
+
+	err = certifier.RevokeWithKey(certPEM, certKey, &reason)
+	require.NoError(t, err)
+
+	err = certifier.RevokeWithKey(certPEM, accountKey, &reason)
+	require.EqualError(t, err, "the private key doesn't match the certificate")
+
+	err = certifier.RevokeWithKey(certPEM, "foo", &reason)
+	require.EqualError(t, err, "unsupported private key type: string")
+}
@@ -0,0 +1,377 @@
+package certificate
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LukasDeco/lego/v4/certcrypto"
+)
+
+// maxCRLSize is the maximum size of a CRL that we will read.
+const maxCRLSize = 64 * 1024 * 1024
+
+const defaultCRLHTTPTimeout = 30 * time.Second
+
+// oidExtensionReasonCode the CRL entry extension containing the reason of the revocation.
+var oidExtensionReasonCode = asn1.ObjectIdentifier{2, 5, 29, 21}
+
+// ErrNoCRL is returned by CRLChecker.Check when the revocation status of a certificate cannot be checked with CRLs:
+// the certificate has no CRL distribution point, and no partitioned CRLs are defined.
+var ErrNoCRL = errors.New("no CRL distribution point")
+
+// ErrCRLCacheMiss is returned by a CRLCache when the URL is not found.
+var ErrCRLCacheMiss = errors.New("CRL cache miss")
+
+// errCRLIssuerMismatch the CRL is not issued by the issuer of the certificate.
+var errCRLIssuerMismatch = errors.New("the CRL is not issued by the issuer of the certificate")
+
+// CRLCache stores the CRLs (DER encoded) by URL.
+type CRLCache interface {
+	// Get returns the CRL, or ErrCRLCacheMiss.
+	Get(ctx context.Context, url string) ([]byte, error)
+
+	// Put stores the CRL.
+	Put(ctx context.Context, url string, data []byte) error
+}
+
+// CRLCheckerOptions the options of a CRLChecker.
+type CRLCheckerOptions struct {
+	// HTTPClient downloads the CRLs and the issuer certificates.
+	// Defaults to a client with a 30 seconds timeout.
+	HTTPClient *http.Client
+
+	// Cache stores the CRLs until their NextUpdate.
+	// If nil, the CRLs are only kept in memory.
+	Cache CRLCache
+
+	// PartitionedCRLs the URLs of the lists of partitioned CRLs of the issuers
+	// (a JSON array of CRL URLs, as published in the CCADB by the CAs using sharded CRLs).
+	// They are used for the certificates without CRL distribution point:
+	// all the CRLs of the lists issued by the issuer of the certificate are checked.
+	PartitionedCRLs []string
+}
+
+// RevocationStatus the revocation status of a certificate.
+type RevocationStatus struct {
+	Revoked   bool
+	RevokedAt time.Time
+
+	// Reason the reason of the revocation (see acme.CRLReasonUnspecified and the other reason codes).
+	Reason uint
+
+	// CRL the URL of the CRL listing the certificate.
+	CRL string
+}
+
+// CRLChecker checks the revocation status of certificates with the CRLs of their issuers.
+//
+// The CRLs are verified (issuer, signature, and validity period),
+// and are kept in memory and in the cache until their NextUpdate.
+type CRLChecker struct {
+	httpClient  *http.Client
+	cache       CRLCache
+	partitioned []string
+
+	now func() time.Time
+
+	mu   sync.Mutex
+	crls map[string]*x509.RevocationList
+}
+
+// NewCRLChecker creates a new CRLChecker.
+func NewCRLChecker(options CRLCheckerOptions) *CRLChecker {
+	httpClient := options.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultCRLHTTPTimeout}
+	}
+
+	return &CRLChecker{
+		httpClient:  httpClient,
+		cache:       options.Cache,
+		partitioned: options.PartitionedCRLs,
+		now:         time.Now,
+		crls:        map[string]*x509.RevocationList{},
+	}
+}
+
+// Check takes a PEM encoded cert or cert bundle, and returns the revocation status of the certificate.
+//
+// The issuer certificate is the certificate of the bundle that signed the certificate,
+// or is downloaded from the IssuingCertificateURL of the certificate.
+//
+// The CRL distribution points of the certificate are alternative locations of the same CRL:
+// they are tried until a valid CRL is obtained.
+// Without CRL distribution point, all the partitioned CRLs of the issuer are checked (see CRLCheckerOptions.PartitionedCRLs).
+func (c *CRLChecker) Check(ctx context.Context, bundle []byte) (*RevocationStatus, error) {
+	certificates, err := certcrypto.ParsePEMBundle(bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	cert := certificates[0]
+
+	if len(cert.CRLDistributionPoints) == 0 && len(c.partitioned) == 0 {
+		return nil, ErrNoCRL
+	}
+
+	issuer, err := c.findIssuer(ctx, cert, certificates[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cert.CRLDistributionPoints) == 0 {
+		return c.checkPartitioned(ctx, cert, issuer)
+	}
+
+	var errs []string
+
+	for _, crlURL := range cert.CRLDistributionPoints {
+		crl, errG := c.getCRL(ctx, crlURL, issuer)
+		if errG != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", crlURL, errG))
+			continue
+		}
+
+		return newRevocationStatus(crlURL, crl, cert), nil
+	}
+
+	return nil, fmt.Errorf("unable to get a valid CRL: %s", strings.Join(errs, "; "))
+}
+
+// checkPartitioned checks all the partitioned CRLs issued by the issuer of the certificate.
+func (c *CRLChecker) checkPartitioned(ctx context.Context, cert, issuer *x509.Certificate) (*RevocationStatus, error) {
+	var checked int
+
+	for _, listURL := range c.partitioned {
+		crlURLs, err := c.fetchPartitionedCRLs(ctx, listURL)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", listURL, err)
+		}
+
+		for _, crlURL := range crlURLs {
+			crl, err := c.getCRL(ctx, crlURL, issuer)
+			if errors.Is(err, errCRLIssuerMismatch) {
+				// The list of another issuer.
+				break
+			}
+
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", crlURL, err)
+			}
+
+			checked++
+
+			status := newRevocationStatus(crlURL, crl, cert)
+			if status.Revoked {
+				return status, nil
+			}
+		}
+	}
+
+	if checked == 0 {
+		return nil, fmt.Errorf("no partitioned CRL issued by %s", issuer.Subject)
+	}
+
+	return &RevocationStatus{}, nil
+}
+
+// getCRL returns a valid CRL from memory, from the cache, or downloaded from the URL.
+func (c *CRLChecker) getCRL(ctx context.Context, crlURL string, issuer *x509.Certificate) (*x509.RevocationList, error) {
+	c.mu.Lock()
+	crl, ok := c.crls[crlURL]
+	c.mu.Unlock()
+
+	if ok && verifyCRL(crl, issuer, c.now()) == nil {
+		return crl, nil
+	}
+
+	if c.cache != nil {
+		crl, err := c.loadFromCache(ctx, crlURL, issuer)
+		if err == nil {
+			return crl, nil
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, crlURL, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := doRequest(c.httpClient, req, maxCRLSize)
+	if err != nil {
+		return nil, err
+	}
+
+	crl, err = parseCRL(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	err = verifyCRL(crl, issuer, c.now())
+	if err != nil {
+		return nil, err
+	}
+
+	if c.cache != nil {
+		// The errors are ignored: the CRL will be downloaded again.
+		_ = c.cache.Put(ctx, crlURL, raw)
+	}
+
+	c.store(crlURL, crl)
+
+	return crl, nil
+}
+
+func (c *CRLChecker) loadFromCache(ctx context.Context, crlURL string, issuer *x509.Certificate) (*x509.RevocationList, error) {
+	raw, err := c.cache.Get(ctx, crlURL)
+	if err != nil {
+		return nil, err
+	}
+
+	crl, err := parseCRL(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	err = verifyCRL(crl, issuer, c.now())
+	if err != nil {
+		return nil, err
+	}
+
+	c.store(crlURL, crl)
+
+	return crl, nil
+}
+
+func (c *CRLChecker) store(crlURL string, crl *x509.RevocationList) {
+	c.mu.Lock()
+	c.crls[crlURL] = crl
+	c.mu.Unlock()
+}
+
+// fetchPartitionedCRLs downloads a list of partitioned CRLs (a JSON array of CRL URLs).
+func (c *CRLChecker) fetchPartitionedCRLs(ctx context.Context, listURL string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, listURL, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := doRequest(c.httpClient, req, maxBodySize)
+	if err != nil {
+		return nil, err
+	}
+
+	var crlURLs []string
+
+	err = json.Unmarshal(raw, &crlURLs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid list of partitioned CRLs: %w", err)
+	}
+
+	return crlURLs, nil
+}
+
+// findIssuer returns the issuer certificate of the bundle,
+// or the first issuer certificate downloaded from the IssuingCertificateURL of the certificate.
+func (c *CRLChecker) findIssuer(ctx context.Context, cert *x509.Certificate, chain []*x509.Certificate) (*x509.Certificate, error) {
+	for _, issuer := range chain {
+		if cert.CheckSignatureFrom(issuer) == nil {
+			return issuer, nil
+		}
+	}
+
+	if len(cert.IssuingCertificateURL) == 0 {
+		return nil, errors.New("no issuing certificate URL")
+	}
+
+	var errs []string
+
+	for _, issuerURL := range cert.IssuingCertificateURL {
+		issuer, err := fetchIssuerCertificate(ctx, c.httpClient, issuerURL)
+		if err == nil {
+			err = cert.CheckSignatureFrom(issuer)
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", issuerURL, err))
+			continue
+		}
+
+		return issuer, nil
+	}
+
+	return nil, fmt.Errorf("unable to get the issuer certificate: %s", strings.Join(errs, "; "))
+}
+
+// parseCRL parses a DER or PEM encoded CRL.
+func parseCRL(raw []byte) (*x509.RevocationList, error) {
+	if block, _ := pem.Decode(raw); block != nil {
+		if block.Type != "X509 CRL" {
+			return nil, fmt.Errorf("invalid PEM block type %q, expected \"X509 CRL\"", block.Type)
+		}
+
+		raw = block.Bytes
+	}
+
+	return x509.ParseRevocationList(raw)
+}
+
+// verifyCRL checks that:
+//   - the CRL is issued and signed by the issuer.
+//   - the CRL is current: ThisUpdate is in the past, and NextUpdate (if any) is in the future.
+func verifyCRL(crl *x509.RevocationList, issuer *x509.Certificate, now time.Time) error {
+	if !bytes.Equal(crl.RawIssuer, issuer.RawSubject) {
+		return errCRLIssuerMismatch
+	}
+
+	err := crl.CheckSignatureFrom(issuer)
+	if err != nil {
+		return fmt.Errorf("bad CRL signature: %w", err)
+	}
+
+	if crl.ThisUpdate.After(now.Add(ocspClockSkew)) {
+		return fmt.Errorf("the CRL is not yet valid (thisUpdate: %s)", crl.ThisUpdate.Format(time.RFC3339))
+	}
+
+	if !crl.NextUpdate.IsZero() && !crl.NextUpdate.After(now.Add(-ocspClockSkew)) {
+		return fmt.Errorf("the CRL is expired (nextUpdate: %s)", crl.NextUpdate.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// newRevocationStatus returns the revocation status of the certificate according to the CRL.
+func newRevocationStatus(crlURL string, crl *x509.RevocationList, cert *x509.Certificate) *RevocationStatus {
+	for _, revoked := range crl.RevokedCertificates {
+		if revoked.SerialNumber.Cmp(cert.SerialNumber) != 0 {
+			continue
+		}
+
+		status := &RevocationStatus{Revoked: true, RevokedAt: revoked.RevocationTime, CRL: crlURL}
+
+		for _, ext := range revoked.Extensions {
+			if !ext.Id.Equal(oidExtensionReasonCode) {
+				continue
+			}
+
+			var reason asn1.Enumerated
+			if _, err := asn1.Unmarshal(ext.Value, &reason); err == nil && reason >= 0 {
+				status.Reason = uint(reason)
+			}
+		}
+
+		return status
+	}
+
+	return &RevocationStatus{}
+}
@@ -0,0 +1,295 @@
+package certificate
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/LukasDeco/lego/v4/platform/tester"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryCRLCache struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func (c *memoryCRLCache) Get(_ context.Context, url string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, ok := c.data[url]
+	if !ok {
+		return nil, ErrCRLCacheMiss
+	}
+
+	return data, nil
+}
+
+func (c *memoryCRLCache) Put(_ context.Context, url string, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.data[url] = data
+
+	return nil
+}
+
+// issueCRL returns a PEM encoded certificate with the CRL distribution points.
+func issueCRL(t *testing.T, ca *tester.CA, serial int64, crlURLs []string) []byte {
+	t.Helper()
+
+	_, certPEM := ca.Issue(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		DNSNames:              []string{"lego.acme"},
+		CRLDistributionPoints: crlURLs,
+	})
+
+	return certPEM
+}
+
+// crl returns a DER encoded CRL revoking the serial numbers for key compromise.
+func createCRL(t *testing.T, ca *tester.CA, thisUpdate, nextUpdate time.Time, serials ...int64) []byte {
+	t.Helper()
+
+	reason, err := asn1.Marshal(asn1.Enumerated(1))
+	require.NoError(t, err)
+
+	var revoked []pkix.RevokedCertificate
+	for _, serial := range serials {
+		revoked = append(revoked, pkix.RevokedCertificate{
+			SerialNumber:   big.NewInt(serial),
+			RevocationTime: thisUpdate.Add(-time.Hour).UTC().Truncate(time.Second),
+			Extensions:     []pkix.Extension{{Id: oidExtensionReasonCode, Value: reason}},
+		})
+	}
+
+	raw, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:              big.NewInt(1),
+		ThisUpdate:          thisUpdate,
+		NextUpdate:          nextUpdate,
+		RevokedCertificates: revoked,
+	}, ca.Cert, ca.Key)
+	require.NoError(t, err)
+
+	return raw
+}
+
+func TestCRLChecker_Check(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	ca := tester.NewCA(t, "Test CA")
+
+	now := time.Now()
+	crl := createCRL(t, ca, now.Add(-time.Hour), now.Add(time.Hour), 2)
+
+	mux.HandleFunc("/failing.crl", func(rw http.ResponseWriter, _ *http.Request) {
+		http.Error(rw, "oops", http.StatusInternalServerError)
+	})
+
+	mux.HandleFunc("/ca.crl", func(rw http.ResponseWriter, _ *http.Request) {
+		_, _ = rw.Write(crl)
+	})
+
+	crlURLs := []string{server.URL + "/failing.crl", server.URL + "/ca.crl"}
+
+	testCases := []struct {
+		desc     string
+		bundle   []byte
+		expected *RevocationStatus
+	}{
+		{
+			desc:     "not revoked",
+			bundle:   append(issueCRL(t, ca, 1, crlURLs), ca.PEM()...),
+			expected: &RevocationStatus{},
+		},
+		{
+			desc:   "revoked",
+			bundle: append(issueCRL(t, ca, 2, crlURLs), ca.PEM()...),
+			expected: &RevocationStatus{
+				Revoked:   true,
+				RevokedAt: now.Add(-2 * time.Hour).UTC().Truncate(time.Second),
+				Reason:    1,
+				CRL:       server.URL + "/ca.crl",
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			checker := NewCRLChecker(CRLCheckerOptions{HTTPClient: server.Client()})
+
+			status, err := checker.Check(context.Background(), test.bundle)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected.Revoked, status.Revoked)
+			assert.True(t, test.expected.RevokedAt.Equal(status.RevokedAt))
+			assert.Equal(t, test.expected.Reason, status.Reason)
+			assert.Equal(t, test.expected.CRL, status.CRL)
+		})
+	}
+}
+
+func TestCRLChecker_Check_cache(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	ca := tester.NewCA(t, "Test CA")
+
+	now := time.Now()
+	crl := createCRL(t, ca, now.Add(-time.Hour), now.Add(time.Hour))
+
+	var downloads int32
+	mux.HandleFunc("/ca.crl", func(rw http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&downloads, 1)
+		_, _ = rw.Write(crl)
+	})
+
+	bundle := append(issueCRL(t, ca, 1, []string{server.URL + "/ca.crl"}), ca.PEM()...)
+
+	cache := &memoryCRLCache{data: map[string][]byte{}}
+
+	checker := NewCRLChecker(CRLCheckerOptions{HTTPClient: server.Client(), Cache: cache})
+
+	_, err := checker.Check(context.Background(), bundle)
+	require.NoError(t, err)
+
+	// kept in memory.
+	_, err = checker.Check(context.Background(), bundle)
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&downloads))
+	assert.Equal(t, crl, cache.data[server.URL+"/ca.crl"])
+
+	// loaded from the cache.
+	checker = NewCRLChecker(CRLCheckerOptions{HTTPClient: server.Client(), Cache: cache})
+
+	_, err = checker.Check(context.Background(), bundle)
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&downloads))
+
+	// downloaded again after the NextUpdate.
+	checker.now = func() time.Time { return now.Add(2 * time.Hour) }
+
+	_, err = checker.Check(context.Background(), bundle)
+	require.Error(t, err)
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&downloads))
+}
+
+func TestCRLChecker_Check_partitioned(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	ca := tester.NewCA(t, "Test CA")
+	other := tester.NewCA(t, "Other CA")
+
+	now := time.Now()
+
+	crls := map[string][]byte{
+		"/other/0.crl": createCRL(t, other, now.Add(-time.Hour), now.Add(time.Hour), 2),
+		"/ca/0.crl":    createCRL(t, ca, now.Add(-time.Hour), now.Add(time.Hour), 3),
+		"/ca/1.crl":    createCRL(t, ca, now.Add(-time.Hour), now.Add(time.Hour), 2),
+	}
+
+	for p, crl := range crls {
+		crl := crl
+		mux.HandleFunc(p, func(rw http.ResponseWriter, _ *http.Request) {
+			_, _ = rw.Write(crl)
+		})
+	}
+
+	lists := map[string][]string{
+		"/other.json": {server.URL + "/other/0.crl"},
+		"/ca.json":    {server.URL + "/ca/0.crl", server.URL + "/ca/1.crl"},
+	}
+
+	for p, list := range lists {
+		list := list
+		mux.HandleFunc(p, func(rw http.ResponseWriter, _ *http.Request) {
+			_ = json.NewEncoder(rw).Encode(list)
+		})
+	}
+
+	checker := NewCRLChecker(CRLCheckerOptions{
+		HTTPClient:      server.Client(),
+		PartitionedCRLs: []string{server.URL + "/other.json", server.URL + "/ca.json"},
+	})
+
+	status, err := checker.Check(context.Background(), append(issueCRL(t, ca, 2, nil), ca.PEM()...))
+	require.NoError(t, err)
+
+	assert.True(t, status.Revoked)
+	assert.Equal(t, server.URL+"/ca/1.crl", status.CRL)
+
+	status, err = checker.Check(context.Background(), append(issueCRL(t, ca, 1, nil), ca.PEM()...))
+	require.NoError(t, err)
+
+	assert.False(t, status.Revoked)
+
+	// no partitioned CRL of the issuer.
+	unknown := tester.NewCA(t, "Unknown CA")
+
+	_, err = checker.Check(context.Background(), append(issueCRL(t, unknown, 1, nil), unknown.PEM()...))
+	require.EqualError(t, err, "no partitioned CRL issued by CN=Unknown CA")
+}
+
+func TestCRLChecker_Check_errors(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	ca := tester.NewCA(t, "Test CA")
+	other := tester.NewCA(t, "Test CA")
+
+	now := time.Now()
+
+	expired := createCRL(t, ca, now.Add(-2*time.Hour), now.Add(-time.Hour))
+	invalidSignature := createCRL(t, other, now.Add(-time.Hour), now.Add(time.Hour))
+
+	mux.HandleFunc("/expired.crl", func(rw http.ResponseWriter, _ *http.Request) {
+		_, _ = rw.Write(expired)
+	})
+
+	mux.HandleFunc("/invalid-signature.crl", func(rw http.ResponseWriter, _ *http.Request) {
+		_, _ = rw.Write(invalidSignature)
+	})
+
+	checker := NewCRLChecker(CRLCheckerOptions{HTTPClient: server.Client()})
+
+	bundle := append(issueCRL(t, ca, 1, []string{server.URL + "/expired.crl", server.URL + "/invalid-signature.crl"}), ca.PEM()...)
+
+	_, err := checker.Check(context.Background(), bundle)
+	require.Error(t, err)
+
+	assert.Contains(t, err.Error(), "unable to get a valid CRL: ")
+	assert.Contains(t, err.Error(), server.URL+"/expired.crl: the CRL is expired")
+	assert.Contains(t, err.Error(), server.URL+"/invalid-signature.crl: bad CRL signature")
+
+	// without CRL distribution point.
+	_, err = checker.Check(context.Background(), append(issueCRL(t, ca, 1, nil), ca.PEM()...))
+	require.ErrorIs(t, err, ErrNoCRL)
+
+	// without issuer.
+	_, err = checker.Check(context.Background(), issueCRL(t, ca, 1, []string{server.URL + "/expired.crl"}))
+	require.EqualError(t, err, "no issuing certificate URL")
+}
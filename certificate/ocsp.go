@@ -0,0 +1,166 @@
+package certificate
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/LukasDeco/lego/v4/certcrypto"
+	"golang.org/x/crypto/ocsp"
+)
+
+// ocspClockSkew the tolerated clock difference with the OCSP responders.
+const ocspClockSkew = 5 * time.Minute
+
+// FetchOCSP takes a PEM encoded cert or cert bundle returning the raw OCSP response,
+// the parsed response, and an error, if any.
+//
+// The issuer certificate is the second certificate of the bundle,
+// or is downloaded from the IssuingCertificateURL of the certificate.
+// All the OCSP servers of the certificate are tried, with all the issuer certificates, until a valid response is obtained:
+// see VerifyOCSPResponse.
+func FetchOCSP(ctx context.Context, httpClient *http.Client, bundle []byte) ([]byte, *ocsp.Response, error) {
+	certificates, err := certcrypto.ParsePEMBundle(bundle)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	issuedCert := certificates[0]
+
+	if len(issuedCert.OCSPServer) == 0 {
+		return nil, nil, errors.New("no OCSP server specified in cert")
+	}
+
+	var errs []string
+
+	tryIssuer := func(issuerCert *x509.Certificate) ([]byte, *ocsp.Response) {
+		for _, server := range issuedCert.OCSPServer {
+			raw, resp, errR := requestOCSP(ctx, httpClient, server, issuedCert, issuerCert)
+			if errR == nil {
+				return raw, resp
+			}
+
+			errs = append(errs, fmt.Sprintf("%s: %v", server, errR))
+		}
+
+		return nil, nil
+	}
+
+	// The issuer certificates of the bundle.
+	for _, issuerCert := range certificates[1:] {
+		if issuedCert.CheckSignatureFrom(issuerCert) != nil {
+			continue
+		}
+
+		if raw, resp := tryIssuer(issuerCert); resp != nil {
+			return raw, resp, nil
+		}
+	}
+
+	// The issuer certificates of the Authority Information Access extension.
+	for _, issuerURL := range issuedCert.IssuingCertificateURL {
+		issuerCert, errI := fetchIssuerCertificate(ctx, httpClient, issuerURL)
+		if errI != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", issuerURL, errI))
+			continue
+		}
+
+		if raw, resp := tryIssuer(issuerCert); resp != nil {
+			return raw, resp, nil
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil, nil, errors.New("no issuing certificate URL")
+	}
+
+	return nil, nil, fmt.Errorf("unable to get a valid OCSP response: %s", strings.Join(errs, "; "))
+}
+
+// VerifyOCSPResponse parses a raw OCSP response, and checks that:
+//   - the response is about the certificate.
+//   - the response is signed by the issuer, or by a responder delegated by the issuer (only if issuer is not nil).
+//   - the response is current: ThisUpdate is in the past, and NextUpdate (if any) is in the future.
+func VerifyOCSPResponse(raw []byte, cert, issuer *x509.Certificate, now time.Time) (*ocsp.Response, error) {
+	resp, err := ocsp.ParseResponseForCert(raw, cert, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.ThisUpdate.After(now.Add(ocspClockSkew)) {
+		return nil, fmt.Errorf("the OCSP response is not yet valid (thisUpdate: %s)", resp.ThisUpdate.Format(time.RFC3339))
+	}
+
+	if !resp.NextUpdate.IsZero() && !resp.NextUpdate.After(now.Add(-ocspClockSkew)) {
+		return nil, fmt.Errorf("the OCSP response is expired (nextUpdate: %s)", resp.NextUpdate.Format(time.RFC3339))
+	}
+
+	return resp, nil
+}
+
+func requestOCSP(ctx context.Context, httpClient *http.Client, server string, issuedCert, issuerCert *x509.Certificate) ([]byte, *ocsp.Response, error) {
+	ocspReq, err := ocsp.CreateRequest(issuedCert, issuerCert, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server, bytes.NewReader(ocspReq))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/ocsp-request")
+
+	ocspResBytes, err := doRequest(httpClient, req, maxBodySize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ocspRes, err := VerifyOCSPResponse(ocspResBytes, issuedCert, issuerCert, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ocspResBytes, ocspRes, nil
+}
+
+// fetchIssuerCertificate downloads an issuer certificate (DER or PEM encoded).
+func fetchIssuerCertificate(ctx context.Context, httpClient *http.Client, issuerURL string) (*x509.Certificate, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuerURL, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	issuerBytes, err := doRequest(httpClient, req, maxBodySize)
+	if err != nil {
+		return nil, err
+	}
+
+	if cert, errP := certcrypto.ParsePEMCertificate(issuerBytes); errP == nil {
+		return cert, nil
+	}
+
+	return x509.ParseCertificate(issuerBytes)
+}
+
+// doRequest sends the request, and returns the body of the response, limited to maxSize bytes.
+func doRequest(httpClient *http.Client, req *http.Request, maxSize int64) ([]byte, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(http.MaxBytesReader(nil, resp.Body, maxSize))
+}
@@ -0,0 +1,233 @@
+package certificate
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/LukasDeco/lego/v4/certcrypto"
+	"github.com/LukasDeco/lego/v4/platform/tester"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ocsp"
+)
+
+// ocspFixture a CA, and a certificate issued by the CA.
+type ocspFixture struct {
+	ca *tester.CA
+
+	cert    *x509.Certificate
+	certPEM []byte
+}
+
+func newOCSPFixture(t *testing.T, ocspServers, issuerURLs []string) *ocspFixture {
+	t.Helper()
+
+	ca := tester.NewCA(t, "Test CA")
+
+	cert, certPEM := ca.Issue(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(42),
+		Subject:               pkix.Name{CommonName: "lego.acme"},
+		DNSNames:              []string{"lego.acme"},
+		OCSPServer:            ocspServers,
+		IssuingCertificateURL: issuerURLs,
+	})
+
+	return &ocspFixture{ca: ca, cert: cert, certPEM: certPEM}
+}
+
+func (f *ocspFixture) bundle() []byte {
+	return append(append([]byte{}, f.certPEM...), f.ca.PEM()...)
+}
+
+func (f *ocspFixture) response(t *testing.T, signer crypto.Signer, thisUpdate, nextUpdate time.Time) []byte {
+	t.Helper()
+
+	raw, err := ocsp.CreateResponse(f.ca.Cert, f.ca.Cert, ocsp.Response{
+		Status:       ocsp.Good,
+		SerialNumber: f.cert.SerialNumber,
+		ThisUpdate:   thisUpdate,
+		NextUpdate:   nextUpdate,
+	}, signer)
+	require.NoError(t, err)
+
+	return raw
+}
+
+func TestFetchOCSP(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	fixture := newOCSPFixture(t,
+		[]string{server.URL + "/ocsp/failing", server.URL + "/ocsp/good"},
+		[]string{server.URL + "/issuer/missing", server.URL + "/issuer/der"},
+	)
+
+	good := fixture.response(t, fixture.ca.Key, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+
+	mux.HandleFunc("/ocsp/failing", func(rw http.ResponseWriter, _ *http.Request) {
+		http.Error(rw, "oops", http.StatusInternalServerError)
+	})
+
+	mux.HandleFunc("/ocsp/good", func(rw http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ocspReq, err := ocsp.ParseRequest(body)
+		if err != nil || ocspReq.SerialNumber.Cmp(fixture.cert.SerialNumber) != 0 {
+			http.Error(rw, "invalid request", http.StatusBadRequest)
+			return
+		}
+
+		_, _ = rw.Write(good)
+	})
+
+	mux.HandleFunc("/issuer/missing", func(rw http.ResponseWriter, _ *http.Request) {
+		http.NotFound(rw, nil)
+	})
+
+	mux.HandleFunc("/issuer/der", func(rw http.ResponseWriter, _ *http.Request) {
+		_, _ = rw.Write(fixture.ca.Cert.Raw)
+	})
+
+	testCases := []struct {
+		desc   string
+		bundle []byte
+	}{
+		{
+			desc:   "issuer in the bundle",
+			bundle: fixture.bundle(),
+		},
+		{
+			desc:   "issuer from the URLs",
+			bundle: fixture.certPEM,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			raw, resp, err := FetchOCSP(context.Background(), server.Client(), test.bundle)
+			require.NoError(t, err)
+
+			assert.Equal(t, good, raw)
+			assert.Equal(t, ocsp.Good, resp.Status)
+			assert.Equal(t, fixture.cert.SerialNumber, resp.SerialNumber)
+		})
+	}
+}
+
+func TestFetchOCSP_errors(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	fixture := newOCSPFixture(t, []string{server.URL + "/ocsp/expired", server.URL + "/ocsp/invalid-signature"}, nil)
+
+	otherKey, err := certcrypto.GeneratePrivateKey(certcrypto.EC256)
+	require.NoError(t, err)
+
+	expired := fixture.response(t, fixture.ca.Key, time.Now().Add(-48*time.Hour), time.Now().Add(-24*time.Hour))
+	invalidSignature := fixture.response(t, otherKey.(crypto.Signer), time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+
+	mux.HandleFunc("/ocsp/expired", func(rw http.ResponseWriter, _ *http.Request) {
+		_, _ = rw.Write(expired)
+	})
+
+	mux.HandleFunc("/ocsp/invalid-signature", func(rw http.ResponseWriter, _ *http.Request) {
+		_, _ = rw.Write(invalidSignature)
+	})
+
+	_, _, err = FetchOCSP(context.Background(), server.Client(), fixture.bundle())
+	require.Error(t, err)
+
+	assert.Contains(t, err.Error(), "unable to get a valid OCSP response: ")
+	assert.Contains(t, err.Error(), server.URL+"/ocsp/expired: the OCSP response is expired")
+	assert.Contains(t, err.Error(), server.URL+"/ocsp/invalid-signature: bad OCSP signature")
+
+	// without issuer
+	_, _, err = FetchOCSP(context.Background(), server.Client(), fixture.certPEM)
+	require.EqualError(t, err, "no issuing certificate URL")
+
+	// without OCSP server
+	fixture = newOCSPFixture(t, nil, nil)
+
+	_, _, err = FetchOCSP(context.Background(), server.Client(), fixture.bundle())
+	require.EqualError(t, err, "no OCSP server specified in cert")
+}
+
+func TestVerifyOCSPResponse(t *testing.T) {
+	fixture := newOCSPFixture(t, nil, nil)
+
+	now := time.Now().Truncate(time.Second)
+
+	testCases := []struct {
+		desc       string
+		thisUpdate time.Time
+		nextUpdate time.Time
+		issuer     *x509.Certificate
+		expected   string
+	}{
+		{
+			desc:       "valid",
+			thisUpdate: now.Add(-time.Hour),
+			nextUpdate: now.Add(time.Hour),
+			issuer:     fixture.ca.Cert,
+		},
+		{
+			desc:       "without issuer",
+			thisUpdate: now.Add(-time.Hour),
+			nextUpdate: now.Add(time.Hour),
+		},
+		{
+			desc:       "without next update",
+			thisUpdate: now.Add(-time.Hour),
+			issuer:     fixture.ca.Cert,
+		},
+		{
+			desc:       "not yet valid",
+			thisUpdate: now.Add(time.Hour),
+			nextUpdate: now.Add(2 * time.Hour),
+			issuer:     fixture.ca.Cert,
+			expected:   "the OCSP response is not yet valid (thisUpdate: " + now.Add(time.Hour).UTC().Format(time.RFC3339) + ")",
+		},
+		{
+			desc:       "expired",
+			thisUpdate: now.Add(-2 * time.Hour),
+			nextUpdate: now.Add(-time.Hour),
+			issuer:     fixture.ca.Cert,
+			expected:   "the OCSP response is expired (nextUpdate: " + now.Add(-time.Hour).UTC().Format(time.RFC3339) + ")",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			raw := fixture.response(t, fixture.ca.Key, test.thisUpdate, test.nextUpdate)
+
+			resp, err := VerifyOCSPResponse(raw, fixture.cert, test.issuer, now)
+			if test.expected != "" {
+				require.EqualError(t, err, test.expected)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, ocsp.Good, resp.Status)
+		})
+	}
+}
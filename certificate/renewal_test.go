@@ -0,0 +1,134 @@
+package certificate
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/LukasDeco/lego/v4/acme"
+	"github.com/LukasDeco/lego/v4/acme/api"
+	"github.com/LukasDeco/lego/v4/platform/tester"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ariLeafCert is the example certificate from draft-ietf-acme-ari-03, section 4.1.
+func ariLeafCert() *x509.Certificate {
+	return &x509.Certificate{
+		SerialNumber:   big.NewInt(0x87654321),
+		AuthorityKeyId: []byte{0x69, 0x88, 0x5b, 0x6b, 0x87, 0x46, 0x40, 0x41, 0xe1, 0xb3, 0x7b, 0x84, 0x7b, 0xa0, 0xae, 0x2c, 0xde, 0x01, 0xc8, 0xd4},
+	}
+}
+
+func TestMakeARICertID(t *testing.T) {
+	certID, err := MakeARICertID(ariLeafCert())
+	require.NoError(t, err)
+
+	assert.Equal(t, "aYhba4dGQEHhs3uEe6CuLN4ByNQ.AIdlQyE", certID)
+}
+
+func TestMakeARICertID_errors(t *testing.T) {
+	_, err := MakeARICertID(nil)
+	require.EqualError(t, err, "leaf certificate is nil")
+
+	_, err = MakeARICertID(&x509.Certificate{SerialNumber: big.NewInt(1)})
+	require.EqualError(t, err, "leaf certificate has no authority key identifier")
+}
+
+func TestCertifier_GetRenewalInfo(t *testing.T) {
+	mux, apiURL := tester.SetupFakeAPI(t)
+
+	windowStart := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	windowEnd := windowStart.Add(24 * time.Hour)
+
+	mux.HandleFunc("/renewalInfo/aYhba4dGQEHhs3uEe6CuLN4ByNQ.AIdlQyE", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Retry-After", "21600")
+
+		err := tester.WriteJSONResponse(w, acme.RenewalInfo{
+			SuggestedWindow: acme.Window{Start: windowStart, End: windowEnd},
+			ExplanationURL:  "https://example.com/docs/example-mass-reissuance-event",
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	core, err := api.New(http.DefaultClient, "lego-test", apiURL+"/dir", "", key)
+	require.NoError(t, err)
+
+	certifier := NewCertifier(core, &resolverMock{}, CertifierOptions{})
+
+	info, err := certifier.GetRenewalInfo(RenewalInfoRequest{Cert: ariLeafCert()})
+	require.NoError(t, err)
+
+	assert.Equal(t, windowStart, info.SuggestedWindow.Start)
+	assert.Equal(t, windowEnd, info.SuggestedWindow.End)
+	assert.Equal(t, "https://example.com/docs/example-mass-reissuance-event", info.ExplanationURL)
+	assert.Equal(t, 6*time.Hour, info.RetryAfter)
+}
+
+func TestRenewalInfoResponse_ShouldRenewAt(t *testing.T) {
+	now := time.Date(2023, time.January, 10, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		desc           string
+		window         acme.Window
+		willingToSleep time.Duration
+		expected       func(t *testing.T, renewAt *time.Time)
+	}{
+		{
+			desc:   "window in the past",
+			window: acme.Window{Start: now.Add(-48 * time.Hour), End: now.Add(-24 * time.Hour)},
+			expected: func(t *testing.T, renewAt *time.Time) {
+				t.Helper()
+
+				require.NotNil(t, renewAt)
+				assert.Equal(t, now, *renewAt)
+			},
+		},
+		{
+			desc:           "window in the future, willing to sleep",
+			window:         acme.Window{Start: now.Add(time.Hour), End: now.Add(2 * time.Hour)},
+			willingToSleep: 3 * time.Hour,
+			expected: func(t *testing.T, renewAt *time.Time) {
+				t.Helper()
+
+				require.NotNil(t, renewAt)
+				assert.False(t, renewAt.Before(now.Add(time.Hour)))
+				assert.True(t, renewAt.Before(now.Add(2*time.Hour)))
+			},
+		},
+		{
+			desc:   "window in the future, not willing to sleep",
+			window: acme.Window{Start: now.Add(time.Hour), End: now.Add(2 * time.Hour)},
+			expected: func(t *testing.T, renewAt *time.Time) {
+				t.Helper()
+
+				assert.Nil(t, renewAt)
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			ri := RenewalInfoResponse{RenewalInfo: acme.RenewalInfo{SuggestedWindow: test.window}}
+
+			test.expected(t, ri.ShouldRenewAt(now, test.willingToSleep))
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package certmanager
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ErrCacheMiss is returned by a Cache when the key is not found.
+var ErrCacheMiss = errors.New("certmanager: cache miss")
+
+// Cache stores the certificates and their private keys.
+// The data of a host is the PEM encoded private key followed by the PEM encoded certificate chain.
+type Cache interface {
+	// Get returns the data of the key, or ErrCacheMiss.
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Put stores the data of the key.
+	Put(ctx context.Context, key string, data []byte) error
+
+	// Delete removes the data of the key.
+	// It is not an error to delete a missing key.
+	Delete(ctx context.Context, key string) error
+}
+
+// DirCache implements Cache using a directory on the local filesystem.
+// The directory is created if it doesn't exist.
+type DirCache string
+
+// Get reads the file of the key.
+func (d DirCache) Get(_ context.Context, key string) ([]byte, error) {
+	data, err := os.ReadFile(d.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrCacheMiss
+	}
+
+	return data, err
+}
+
+// Put writes the file of the key.
+// The data is written to a temporary file first, so the readers never see a partial file.
+func (d DirCache) Put(_ context.Context, key string, data []byte) error {
+	err := os.MkdirAll(string(d), 0o700)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(string(d), "tmp-*")
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), d.path(key))
+}
+
+// Delete removes the file of the key.
+func (d DirCache) Delete(_ context.Context, key string) error {
+	err := os.Remove(d.path(key))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// path returns the path of the file of the key.
+// The key cannot escape the directory.
+func (d DirCache) path(key string) string {
+	return filepath.Join(string(d), filepath.Clean("/"+key))
+}
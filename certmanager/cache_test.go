@@ -0,0 +1,50 @@
+package certmanager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirCache(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "certs")
+	cache := DirCache(dir)
+
+	ctx := context.Background()
+
+	_, err := cache.Get(ctx, "example.com")
+	require.ErrorIs(t, err, ErrCacheMiss)
+
+	err = cache.Put(ctx, "example.com", []byte("data"))
+	require.NoError(t, err)
+
+	data, err := cache.Get(ctx, "example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("data"), data)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "example.com", entries[0].Name())
+
+	err = cache.Delete(ctx, "example.com")
+	require.NoError(t, err)
+
+	_, err = cache.Get(ctx, "example.com")
+	require.ErrorIs(t, err, ErrCacheMiss)
+
+	// deleting a missing key is not an error.
+	err = cache.Delete(ctx, "example.com")
+	require.NoError(t, err)
+}
+
+func TestDirCache_path(t *testing.T) {
+	cache := DirCache(filepath.Join("foo", "bar"))
+
+	assert.Equal(t, filepath.Join("foo", "bar", "example.com"), cache.path("example.com"))
+	assert.Equal(t, filepath.Join("foo", "bar", "passwd"), cache.path("../../passwd"))
+}
@@ -0,0 +1,32 @@
+package certmanager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// HostPolicy decides whether the Manager is allowed to obtain a certificate for a host.
+// It returns an error to deny the host.
+type HostPolicy func(ctx context.Context, host string) error
+
+// HostAllowlist returns a policy allowing only the given hosts.
+// The hosts are compared case-insensitively, without the trailing dot.
+func HostAllowlist(hosts ...string) HostPolicy {
+	allowed := make(map[string]struct{}, len(hosts))
+	for _, host := range hosts {
+		allowed[normalizeHost(host)] = struct{}{}
+	}
+
+	return func(_ context.Context, host string) error {
+		if _, ok := allowed[normalizeHost(host)]; !ok {
+			return fmt.Errorf("host %q not allowed", host)
+		}
+
+		return nil
+	}
+}
+
+func normalizeHost(host string) string {
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
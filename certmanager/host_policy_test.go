@@ -0,0 +1,38 @@
+package certmanager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostAllowlist(t *testing.T) {
+	policy := HostAllowlist("example.com", "WWW.Example.com.")
+
+	testCases := []struct {
+		host     string
+		expected string
+	}{
+		{host: "example.com"},
+		{host: "EXAMPLE.com."},
+		{host: "www.example.com"},
+		{host: "sub.example.com", expected: `host "sub.example.com" not allowed`},
+		{host: "example.org", expected: `host "example.org" not allowed`},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.host, func(t *testing.T) {
+			t.Parallel()
+
+			err := policy(context.Background(), test.host)
+			if test.expected == "" {
+				require.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,382 @@
+// Package certmanager obtains and renews the certificates of a TLS server on demand.
+package certmanager
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LukasDeco/lego/v4/certificate"
+	"github.com/LukasDeco/lego/v4/challenge/http01"
+	"github.com/LukasDeco/lego/v4/challenge/tlsalpn01"
+	"github.com/LukasDeco/lego/v4/lego"
+	"github.com/LukasDeco/lego/v4/log"
+	"github.com/LukasDeco/lego/v4/platform/wait"
+)
+
+const (
+	defaultRenewBefore = 30 * 24 * time.Hour
+)
+
+// Options the options of a Manager.
+type Options struct {
+	// HostPolicy controls the hosts for which the certificates are obtained (required).
+	HostPolicy HostPolicy
+
+	// Cache stores the certificates.
+	// If nil, the certificates are only kept in memory, and obtained again after a restart.
+	Cache Cache
+
+	// RenewBefore how long before their expiration the certificates are renewed.
+	// Defaults to 30 days.
+	RenewBefore time.Duration
+
+	MustStaple     bool
+	PreferredChain string
+	Profile        string
+
+	// DisableHTTP01 doesn't register the in-process HTTP-01 provider (see Manager.HTTPHandler).
+	DisableHTTP01 bool
+
+	// DisableTLSALPN01 doesn't register the in-process TLS-ALPN-01 provider (see Manager.GetCertificate).
+	DisableTLSALPN01 bool
+}
+
+// Manager obtains the certificates of a TLS server during the first handshake of each host,
+// and renews them in the background.
+//
+// The certificates are obtained with a lego.Client:
+// the challenges are solved with the providers of the client (i.e. a DNS provider),
+// and with the in-process HTTP-01 and TLS-ALPN-01 providers of the Manager.
+//
+// The concurrent handshakes of the same host share the same certificate request.
+type Manager struct {
+	options Options
+
+	http01    *http01.SharedServer
+	tlsalpn01 *tlsalpn01.CertificateProvider
+
+	obtain func(ctx context.Context, domain string) (*certificate.Resource, error)
+	now    func() time.Time
+
+	// renewals the background renewals of the certificates, by host.
+	renewals *wait.Scheduler
+
+	mu    sync.Mutex
+	certs map[string]*tls.Certificate
+	calls map[string]*call
+}
+
+// call a certificate request in progress.
+type call struct {
+	done chan struct{}
+	cert *tls.Certificate
+	err  error
+}
+
+// New creates a new Manager.
+// Unless they are disabled, the in-process HTTP-01 and TLS-ALPN-01 providers are registered in the client.
+func New(client *lego.Client, options Options) (*Manager, error) {
+	if client == nil {
+		return nil, errors.New("a client must be provided")
+	}
+
+	if options.HostPolicy == nil {
+		return nil, errors.New("a host policy must be provided")
+	}
+
+	m := newManager(options)
+
+	m.obtain = func(ctx context.Context, domain string) (*certificate.Resource, error) {
+		return client.Certificate.ObtainWithContext(ctx, certificate.ObtainRequest{
+			Domains:        []string{domain},
+			Bundle:         true,
+			MustStaple:     options.MustStaple,
+			PreferredChain: options.PreferredChain,
+			Profile:        options.Profile,
+		})
+	}
+
+	if !options.DisableHTTP01 {
+		err := client.Challenge.SetHTTP01Provider(m.http01)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !options.DisableTLSALPN01 {
+		err := client.Challenge.SetTLSALPN01Provider(m.tlsalpn01)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+func newManager(options Options) *Manager {
+	if options.RenewBefore <= 0 {
+		options.RenewBefore = defaultRenewBefore
+	}
+
+	return &Manager{
+		options:   options,
+		http01:    http01.NewSharedServer("", ""),
+		tlsalpn01: tlsalpn01.NewCertificateProvider(),
+		now:       time.Now,
+		certs:     map[string]*tls.Certificate{},
+		calls:     map[string]*call{},
+		renewals:  wait.NewScheduler(),
+	}
+}
+
+// TLSConfig returns a tls.Config using GetCertificate,
+// with the `acme-tls/1` protocol required by the TLS-ALPN-01 challenge.
+func (m *Manager) TLSConfig() *tls.Config {
+	return &tls.Config{
+		GetCertificate: m.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1", tlsalpn01.ACMETLS1Protocol},
+		MinVersion:     tls.VersionTLS12,
+	}
+}
+
+// GetCertificate implements the tls.Config.GetCertificate hook.
+// It returns the TLS-ALPN-01 challenge certificates to the `acme-tls/1` clients,
+// and the certificate of the requested host to the other clients:
+// the certificate is loaded from the cache or obtained if needed.
+func (m *Manager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return m.tlsalpn01.GetCertificate(m.getCertificate)(hello)
+}
+
+func (m *Manager) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	host := normalizeHost(hello.ServerName)
+	if host == "" {
+		return nil, errors.New("missing server name")
+	}
+
+	if strings.ContainsAny(host, `/\`) {
+		return nil, fmt.Errorf("invalid server name %q", hello.ServerName)
+	}
+
+	ctx := hello.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	m.mu.Lock()
+	cert, ok := m.certs[host]
+	m.mu.Unlock()
+
+	if ok && m.isValid(cert) {
+		return cert, nil
+	}
+
+	err := m.options.HostPolicy(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	c := m.startCall(host)
+
+	select {
+	case <-c.done:
+		return c.cert, c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// HTTPHandler returns a handler serving the HTTP-01 challenges.
+// The other requests are handled by fallback,
+// or redirected to HTTPS when fallback is nil.
+func (m *Manager) HTTPHandler(fallback http.Handler) http.Handler {
+	if fallback == nil {
+		fallback = http.HandlerFunc(redirectHTTPS)
+	}
+
+	challenges := m.http01.Handler()
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if strings.HasPrefix(req.URL.Path, http01.ChallengePath("")) {
+			challenges.ServeHTTP(rw, req)
+			return
+		}
+
+		fallback.ServeHTTP(rw, req)
+	})
+}
+
+// Close stops the background renewals.
+func (m *Manager) Close() {
+	m.renewals.Close()
+}
+
+// startCall returns the certificate request in progress for the host, or starts a new one.
+func (m *Manager) startCall(host string) *call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if c, ok := m.calls[host]; ok {
+		return c
+	}
+
+	c := &call{done: make(chan struct{})}
+	m.calls[host] = c
+
+	go func() {
+		// The request is not bound to the handshake: it is shared with the other handshakes of the host.
+		c.cert, c.err = m.loadOrObtain(context.Background(), host)
+
+		m.mu.Lock()
+		delete(m.calls, host)
+		m.mu.Unlock()
+
+		close(c.done)
+	}()
+
+	return c
+}
+
+// loadOrObtain returns the certificate from the cache, or obtains a new certificate.
+func (m *Manager) loadOrObtain(ctx context.Context, host string) (*tls.Certificate, error) {
+	cert, err := m.loadFromCache(ctx, host)
+	if err != nil && !errors.Is(err, ErrCacheMiss) {
+		log.Default().Warn("certmanager: unable to load the certificate from the cache", log.Domain(host), log.Err(err))
+	}
+
+	if cert == nil {
+		cert, err = m.obtainCertificate(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m.mu.Lock()
+	m.certs[host] = cert
+	m.mu.Unlock()
+
+	m.scheduleRenewal(host, cert)
+
+	return cert, nil
+}
+
+func (m *Manager) loadFromCache(ctx context.Context, host string) (*tls.Certificate, error) {
+	if m.options.Cache == nil {
+		return nil, ErrCacheMiss
+	}
+
+	data, err := m.options.Cache.Get(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := parseCertificate(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if !m.isValid(cert) {
+		return nil, ErrCacheMiss
+	}
+
+	return cert, nil
+}
+
+// obtainCertificate obtains a new certificate, and stores it in the cache.
+func (m *Manager) obtainCertificate(ctx context.Context, host string) (*tls.Certificate, error) {
+	res, err := m.obtain(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 0, len(res.PrivateKey)+len(res.Certificate))
+	data = append(data, res.PrivateKey...)
+	data = append(data, res.Certificate...)
+
+	cert, err := parseCertificate(data)
+	if err != nil {
+		return nil, fmt.Errorf("[%s] certmanager: invalid certificate: %w", host, err)
+	}
+
+	if m.options.Cache != nil {
+		err = m.options.Cache.Put(ctx, host, data)
+		if err != nil {
+			log.Default().Warn("certmanager: unable to store the certificate in the cache", log.Domain(host), log.Err(err))
+		}
+	}
+
+	return cert, nil
+}
+
+// scheduleRenewal schedules the renewal of the certificate of the host, RenewBefore its expiration.
+// The failed renewals are retried, the current certificate is kept in the meantime.
+func (m *Manager) scheduleRenewal(host string, cert *tls.Certificate) {
+	m.renewals.Schedule(host, m.renewalDelay(cert), func() (time.Duration, error) {
+		return m.renew(host)
+	})
+}
+
+// renew obtains a new certificate for the host, and returns the delay before its next renewal.
+func (m *Manager) renew(host string) (time.Duration, error) {
+	log.Default().Info("certmanager: renewing the certificate", log.Domain(host))
+
+	cert, err := m.obtainCertificate(context.Background(), host)
+	if err != nil {
+		log.Default().Warn("certmanager: renewal failed", log.Domain(host), log.Err(err))
+		return 0, err
+	}
+
+	m.mu.Lock()
+	m.certs[host] = cert
+	m.mu.Unlock()
+
+	return m.renewalDelay(cert), nil
+}
+
+// renewalDelay returns the delay before the renewal of the certificate.
+func (m *Manager) renewalDelay(cert *tls.Certificate) time.Duration {
+	return cert.Leaf.NotAfter.Add(-m.options.RenewBefore).Sub(m.now())
+}
+
+// isValid returns true if the certificate is not expired.
+func (m *Manager) isValid(cert *tls.Certificate) bool {
+	return m.now().Before(cert.Leaf.NotAfter)
+}
+
+// parseCertificate parses the PEM encoded private key and certificate chain.
+func parseCertificate(data []byte) (*tls.Certificate, error) {
+	cert, err := tls.X509KeyPair(data, data)
+	if err != nil {
+		return nil, err
+	}
+
+	cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return &cert, nil
+}
+
+func redirectHTTPS(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		http.Error(rw, "Use HTTPS", http.StatusBadRequest)
+		return
+	}
+
+	host, _, err := net.SplitHostPort(req.Host)
+	if err != nil {
+		host = req.Host
+	}
+
+	target := "https://" + host + req.URL.RequestURI()
+
+	http.Redirect(rw, req, target, http.StatusFound)
+}
@@ -0,0 +1,388 @@
+package certmanager
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/LukasDeco/lego/v4/certcrypto"
+	"github.com/LukasDeco/lego/v4/certificate"
+	"github.com/LukasDeco/lego/v4/challenge/http01"
+	"github.com/LukasDeco/lego/v4/challenge/tlsalpn01"
+	"github.com/LukasDeco/lego/v4/lego"
+	"github.com/LukasDeco/lego/v4/platform/tester"
+	"github.com/LukasDeco/lego/v4/registration"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockUser struct {
+	privateKey crypto.PrivateKey
+}
+
+func (u mockUser) GetEmail() string                        { return "test@example.com" }
+func (u mockUser) GetRegistration() *registration.Resource { return nil }
+func (u mockUser) GetPrivateKey() crypto.PrivateKey        { return u.privateKey }
+
+func TestNew(t *testing.T) {
+	_, apiURL := tester.SetupFakeAPI(t)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 512)
+	require.NoError(t, err)
+
+	config := lego.NewConfig(mockUser{privateKey: privateKey})
+	config.CADirURL = apiURL + "/dir"
+
+	client, err := lego.NewClient(config)
+	require.NoError(t, err)
+
+	_, err = New(client, Options{})
+	require.EqualError(t, err, "a host policy must be provided")
+
+	_, err = New(nil, Options{HostPolicy: HostAllowlist("example.com")})
+	require.EqualError(t, err, "a client must be provided")
+
+	manager, err := New(client, Options{HostPolicy: HostAllowlist("example.com")})
+	require.NoError(t, err)
+
+	assert.Equal(t, defaultRenewBefore, manager.options.RenewBefore)
+	assert.NotNil(t, manager.obtain)
+}
+
+func TestManager_GetCertificate(t *testing.T) {
+	manager := newManager(Options{HostPolicy: HostAllowlist("example.com")})
+	t.Cleanup(manager.Close)
+
+	var calls int32
+	release := make(chan struct{})
+
+	manager.obtain = func(_ context.Context, domain string) (*certificate.Resource, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+
+		return newResource(t, domain, time.Now().Add(90*24*time.Hour)), nil
+	}
+
+	// concurrent handshakes share the same request.
+	var wg sync.WaitGroup
+	certs := make([]*tls.Certificate, 5)
+	errs := make([]error, 5)
+
+	for i := range certs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			certs[i], errs[i] = manager.GetCertificate(&tls.ClientHelloInfo{ServerName: "Example.com."})
+		}(i)
+	}
+
+	// let the handshakes reach the request.
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	for i := range certs {
+		require.NoError(t, errs[i])
+		assert.Same(t, certs[0], certs[i])
+	}
+
+	assert.Equal(t, []string{"example.com"}, certs[0].Leaf.DNSNames)
+
+	// the certificate is kept in memory.
+	cert, err := manager.GetCertificate(&tls.ClientHelloInfo{ServerName: "example.com"})
+	require.NoError(t, err)
+	assert.Same(t, certs[0], cert)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestManager_GetCertificate_errors(t *testing.T) {
+	manager := newManager(Options{HostPolicy: HostAllowlist("example.com", "fail.example.com")})
+	t.Cleanup(manager.Close)
+
+	manager.obtain = func(_ context.Context, domain string) (*certificate.Resource, error) {
+		if domain == "fail.example.com" {
+			return nil, errors.New("oops")
+		}
+
+		t.Errorf("unexpected request for %s", domain)
+
+		return nil, errors.New("unexpected")
+	}
+
+	testCases := []struct {
+		desc       string
+		serverName string
+		expected   string
+	}{
+		{
+			desc:     "missing server name",
+			expected: "missing server name",
+		},
+		{
+			desc:       "invalid server name",
+			serverName: "example.com/foo",
+			expected:   `invalid server name "example.com/foo"`,
+		},
+		{
+			desc:       "host not allowed",
+			serverName: "example.org",
+			expected:   `host "example.org" not allowed`,
+		},
+		{
+			desc:       "obtain error",
+			serverName: "fail.example.com",
+			expected:   "oops",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := manager.GetCertificate(&tls.ClientHelloInfo{ServerName: test.serverName})
+			require.EqualError(t, err, test.expected)
+		})
+	}
+}
+
+func TestManager_GetCertificate_cache(t *testing.T) {
+	cache := DirCache(t.TempDir())
+
+	manager := newManager(Options{HostPolicy: HostAllowlist("example.com", "expired.example.com"), Cache: cache})
+	t.Cleanup(manager.Close)
+
+	var obtained []string
+	manager.obtain = func(_ context.Context, domain string) (*certificate.Resource, error) {
+		obtained = append(obtained, domain)
+		return newResource(t, domain, time.Now().Add(90*24*time.Hour)), nil
+	}
+
+	ctx := context.Background()
+
+	res := newResource(t, "example.com", time.Now().Add(90*24*time.Hour))
+	require.NoError(t, cache.Put(ctx, "example.com", append(res.PrivateKey, res.Certificate...)))
+
+	expired := newResource(t, "expired.example.com", time.Now().Add(-time.Hour))
+	require.NoError(t, cache.Put(ctx, "expired.example.com", append(expired.PrivateKey, expired.Certificate...)))
+
+	// loaded from the cache.
+	cert, err := manager.GetCertificate(&tls.ClientHelloInfo{ServerName: "example.com"})
+	require.NoError(t, err)
+
+	leaf, err := certcrypto.ParsePEMCertificate(res.Certificate)
+	require.NoError(t, err)
+	assert.Equal(t, leaf.Raw, cert.Leaf.Raw)
+
+	// expired in the cache: obtained, and stored in the cache.
+	cert, err = manager.GetCertificate(&tls.ClientHelloInfo{ServerName: "expired.example.com"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"expired.example.com"}, obtained)
+
+	data, err := cache.Get(ctx, "expired.example.com")
+	require.NoError(t, err)
+
+	cached, err := parseCertificate(data)
+	require.NoError(t, err)
+	assert.Equal(t, cert.Leaf.Raw, cached.Leaf.Raw)
+}
+
+func TestManager_GetCertificate_tlsALPN01(t *testing.T) {
+	manager := newManager(Options{HostPolicy: HostAllowlist("example.com")})
+	t.Cleanup(manager.Close)
+
+	manager.obtain = func(_ context.Context, domain string) (*certificate.Resource, error) {
+		return nil, errors.New("unexpected")
+	}
+
+	require.NoError(t, manager.tlsalpn01.Present("example.com", "token", "keyAuth"))
+
+	cert, err := manager.GetCertificate(&tls.ClientHelloInfo{ServerName: "example.com", SupportedProtos: []string{tlsalpn01.ACMETLS1Protocol}})
+	require.NoError(t, err)
+
+	expected, err := tlsalpn01.ChallengeCert("example.com", "keyAuth")
+	require.NoError(t, err)
+
+	assert.Equal(t, expected.Leaf.Extensions, cert.Leaf.Extensions)
+}
+
+func TestManager_renewal(t *testing.T) {
+	manager := newManager(Options{HostPolicy: HostAllowlist("example.com"), RenewBefore: 60 * 24 * time.Hour})
+	t.Cleanup(manager.Close)
+
+	var calls int32
+	manager.obtain = func(_ context.Context, domain string) (*certificate.Resource, error) {
+		// the first renewal is immediate (30 days < RenewBefore), the second one is not (90 days).
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return newResource(t, domain, time.Now().Add(30*24*time.Hour)), nil
+		}
+
+		return newResource(t, domain, time.Now().Add(90*24*time.Hour)), nil
+	}
+
+	first, err := manager.GetCertificate(&tls.ClientHelloInfo{ServerName: "example.com"})
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		cert, err := manager.GetCertificate(&tls.ClientHelloInfo{ServerName: "example.com"})
+		return err == nil && cert != first
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestManager_renew_retry(t *testing.T) {
+	manager := newManager(Options{HostPolicy: HostAllowlist("example.com")})
+	t.Cleanup(manager.Close)
+
+	manager.obtain = func(_ context.Context, domain string) (*certificate.Resource, error) {
+		return newResource(t, domain, time.Now().Add(90*24*time.Hour)), nil
+	}
+
+	cert, err := manager.GetCertificate(&tls.ClientHelloInfo{ServerName: "example.com"})
+	require.NoError(t, err)
+
+	manager.obtain = func(_ context.Context, _ string) (*certificate.Resource, error) {
+		return nil, errors.New("oops")
+	}
+
+	scheduled, retrying := manager.renewals.Scheduled("example.com")
+	assert.True(t, scheduled)
+	assert.False(t, retrying)
+
+	// the renewal is due.
+	manager.scheduleRenewal("example.com", &tls.Certificate{Leaf: &x509.Certificate{NotAfter: time.Now()}})
+
+	// the failed renewal is retried, the current certificate is kept.
+	assert.Eventually(t, func() bool {
+		_, retrying := manager.renewals.Scheduled("example.com")
+		return retrying
+	}, time.Second, 10*time.Millisecond)
+
+	manager.mu.Lock()
+	assert.Same(t, cert, manager.certs["example.com"])
+	manager.mu.Unlock()
+}
+
+func TestManager_HTTPHandler(t *testing.T) {
+	manager := newManager(Options{HostPolicy: HostAllowlist("example.com")})
+
+	require.NoError(t, manager.http01.Present("example.com", "token", "keyAuth"))
+
+	testCases := []struct {
+		desc             string
+		fallback         http.Handler
+		method           string
+		target           string
+		expectedStatus   int
+		expectedBody     string
+		expectedLocation string
+	}{
+		{
+			desc:           "challenge",
+			method:         http.MethodGet,
+			target:         "http://example.com" + http01.ChallengePath("token"),
+			expectedStatus: http.StatusOK,
+			expectedBody:   "keyAuth",
+		},
+		{
+			desc:           "unknown challenge",
+			method:         http.MethodGet,
+			target:         "http://example.com" + http01.ChallengePath("unknown"),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			desc:             "redirect",
+			method:           http.MethodGet,
+			target:           "http://example.com:80/foo?bar=baz",
+			expectedStatus:   http.StatusFound,
+			expectedLocation: "https://example.com/foo?bar=baz",
+		},
+		{
+			desc:           "redirect: invalid method",
+			method:         http.MethodPost,
+			target:         "http://example.com/foo",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			desc: "fallback",
+			fallback: http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+				rw.WriteHeader(http.StatusTeapot)
+			}),
+			method:         http.MethodPost,
+			target:         "http://example.com/foo",
+			expectedStatus: http.StatusTeapot,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+
+			manager.HTTPHandler(test.fallback).ServeHTTP(rec, httptest.NewRequest(test.method, test.target, http.NoBody))
+
+			assert.Equal(t, test.expectedStatus, rec.Code)
+			assert.Equal(t, test.expectedLocation, rec.Header().Get("Location"))
+
+			if test.expectedBody != "" {
+				assert.Equal(t, test.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestManager_TLSConfig(t *testing.T) {
+	manager := newManager(Options{HostPolicy: HostAllowlist("example.com")})
+
+	config := manager.TLSConfig()
+
+	assert.Contains(t, config.NextProtos, tlsalpn01.ACMETLS1Protocol)
+	assert.NotNil(t, config.GetCertificate)
+}
+
+// newResource creates a self-signed certificate.
+func newResource(t *testing.T, domain string, notAfter time.Time) *certificate.Resource {
+	t.Helper()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: domain},
+		DNSNames:     []string{domain},
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, privateKey.Public(), privateKey)
+	require.NoError(t, err)
+
+	return &certificate.Resource{
+		Domain:      domain,
+		PrivateKey:  certcrypto.PEMEncode(privateKey),
+		Certificate: certcrypto.PEMEncode(certcrypto.DERCertificateBytes(der)),
+	}
+}
@@ -0,0 +1,120 @@
+package dns01
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"errors"
+	"strings"
+
+	"github.com/LukasDeco/lego/v4/acme/api"
+	"github.com/LukasDeco/lego/v4/challenge"
+)
+
+// NewAccountChallenge creates a solver for the dns-account-01 challenge.
+// The TXT record is created by the DNS provider at `_<account label>._acme-challenge.<domain>`,
+// so several accounts can validate the same domain at the same time.
+// The record is created with challenge.AccountProvider if the provider implements it,
+// otherwise the provider receives the account domain (see AccountDomain) instead of the domain.
+func NewAccountChallenge(core *api.Core, validate ValidateContextFunc, provider challenge.Provider, opts ...ChallengeOption) *Challenge {
+	return newChallenge(challenge.DNSAccount01, core, validate, provider, opts...)
+}
+
+// AccountLabel returns the account label used by the dns-account-01 challenge:
+// an underscore followed by the lowercase base32 encoding of the first 10 bytes of the SHA-256 digest of the account URL.
+// - https://datatracker.ietf.org/doc/html/draft-ietf-acme-dns-account-label-01#section-3.1
+func AccountLabel(accountURL string) string {
+	sum := sha256.Sum256([]byte(accountURL))
+
+	return "_" + strings.ToLower(base32.StdEncoding.EncodeToString(sum[:10]))
+}
+
+// AccountDomain returns the domain given to the providers that don't implement challenge.AccountProvider
+// to create the record of the `dns-account-01` challenge: `_<account label>._acme-challenge.<domain>`.
+// GetChallengeInfo recognizes this domain, and returns the information of the record scoped to the account.
+func AccountDomain(domain, accountURL string) string {
+	return AccountLabel(accountURL) + "." + acmeChallengeLabel + "." + domain
+}
+
+// splitAccountDomain returns the account label and the domain of an account domain (see AccountDomain),
+// or an empty label and the domain as is for the other domains.
+func splitAccountDomain(domain string) (accountLabel, name string) {
+	label, rest, ok := strings.Cut(domain, ".")
+	if !ok || !isAccountLabel(label) || !strings.HasPrefix(rest, acmeChallengeLabel+".") {
+		return "", domain
+	}
+
+	return label, strings.TrimPrefix(rest, acmeChallengeLabel+".")
+}
+
+// isAccountLabel checks if a label has the format of an account label (see AccountLabel).
+func isAccountLabel(label string) bool {
+	// an underscore followed by the base32 encoding of 10 bytes.
+	if len(label) != 17 || label[0] != '_' {
+		return false
+	}
+
+	for _, r := range label[1:] {
+		if (r < 'a' || r > 'z') && (r < '2' || r > '7') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// GetAccountChallengeInfo returns information used to create a DNS record which will fulfill the `dns-account-01` challenge.
+func GetAccountChallengeInfo(domain, accountURL, keyAuth string) ChallengeInfo {
+	return newChallengeInfo(AccountLabel(accountURL), domain, keyAuth)
+}
+
+// GetRecordChallengeInfo returns information used to create the DNS record of a challenge.Record:
+// the record of the `dns-account-01` challenge if the record has an account URL, otherwise the record of the `dns-01` challenge.
+func GetRecordChallengeInfo(record challenge.Record) ChallengeInfo {
+	if record.AccountURL == "" {
+		return GetChallengeInfo(record.Domain, record.KeyAuth)
+	}
+
+	return GetAccountChallengeInfo(record.Domain, record.AccountURL, record.KeyAuth)
+}
+
+// getChallengeInfo returns the information of the TXT record of the challenge.
+func (c *Challenge) getChallengeInfo(domain, keyAuth string) (ChallengeInfo, error) {
+	if c.chlgType != challenge.DNSAccount01 {
+		return GetChallengeInfo(domain, keyAuth), nil
+	}
+
+	accountURL, err := c.accountURL()
+	if err != nil {
+		return ChallengeInfo{}, err
+	}
+
+	return GetAccountChallengeInfo(domain, accountURL, keyAuth), nil
+}
+
+// accountURL returns the URL of the account, required by the dns-account-01 challenge.
+func (c *Challenge) accountURL() (string, error) {
+	accountURL := c.core.GetAccountURL()
+	if accountURL == "" {
+		return "", errors.New("the dns-account-01 challenge requires a registered account")
+	}
+
+	return accountURL, nil
+}
+
+// accountRecord returns the record to present for the dns-account-01 challenge:
+// the record has the URL of the account for the providers implementing challenge.AccountProvider,
+// otherwise its domain is the account domain (see AccountDomain).
+func (c *Challenge) accountRecord(record challenge.Record) (challenge.Record, error) {
+	accountURL, err := c.accountURL()
+	if err != nil {
+		return challenge.Record{}, err
+	}
+
+	if _, ok := c.provider.(challenge.AccountProvider); ok {
+		record.AccountURL = accountURL
+	} else {
+		record.Domain = AccountDomain(record.Domain, accountURL)
+	}
+
+	return record, nil
+}
@@ -0,0 +1,244 @@
+package dns01
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"testing"
+
+	"github.com/LukasDeco/lego/v4/acme"
+	"github.com/LukasDeco/lego/v4/acme/api"
+	"github.com/LukasDeco/lego/v4/challenge"
+	"github.com/LukasDeco/lego/v4/platform/tester"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// providerRecorder records the FQDNs computed by the provider, like a real DNS provider does.
+type providerRecorder struct {
+	presented, cleaned []string
+}
+
+func (p *providerRecorder) Present(domain, _, keyAuth string) error {
+	p.presented = append(p.presented, GetChallengeInfo(domain, keyAuth).EffectiveFQDN)
+	return nil
+}
+
+func (p *providerRecorder) CleanUp(domain, _, keyAuth string) error {
+	p.cleaned = append(p.cleaned, GetChallengeInfo(domain, keyAuth).EffectiveFQDN)
+	return nil
+}
+
+// accountProviderRecorder records the FQDNs computed by a provider supporting the dns-account-01 challenge.
+type accountProviderRecorder struct {
+	providerRecorder
+}
+
+func (p *accountProviderRecorder) PresentAccount(domain, _, keyAuth, accountURL string) error {
+	p.presented = append(p.presented, GetAccountChallengeInfo(domain, accountURL, keyAuth).EffectiveFQDN)
+	return nil
+}
+
+func (p *accountProviderRecorder) CleanUpAccount(domain, _, keyAuth, accountURL string) error {
+	p.cleaned = append(p.cleaned, GetAccountChallengeInfo(domain, accountURL, keyAuth).EffectiveFQDN)
+	return nil
+}
+
+func TestAccountLabel(t *testing.T) {
+	// https://datatracker.ietf.org/doc/html/draft-ietf-acme-dns-account-label-01#section-3.1
+	label := AccountLabel("https://example.com/acme/acct/ExampleAccount")
+
+	assert.Equal(t, "_ujmmovf2vn55tgye", label)
+}
+
+func TestGetAccountChallengeInfo(t *testing.T) {
+	t.Setenv("LEGO_DISABLE_CNAME_SUPPORT", "true")
+
+	info := GetAccountChallengeInfo("example.org", "https://example.com/acme/acct/ExampleAccount", "123d==")
+
+	expected := ChallengeInfo{
+		FQDN:          "_ujmmovf2vn55tgye._acme-challenge.example.org.",
+		EffectiveFQDN: "_ujmmovf2vn55tgye._acme-challenge.example.org.",
+		Value:         GetChallengeInfo("example.org", "123d==").Value,
+	}
+
+	assert.Equal(t, expected, info)
+}
+
+func TestAccountChallenge(t *testing.T) {
+	t.Setenv("LEGO_DISABLE_CNAME_SUPPORT", "true")
+
+	_, apiURL := tester.SetupFakeAPI(t)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 512)
+	require.NoError(t, err)
+
+	core, err := api.New(http.DefaultClient, "lego-test", apiURL+"/dir", "https://example.com/acme/acct/ExampleAccount", privateKey)
+	require.NoError(t, err)
+
+	var checked []string
+	preCheck := func(_, fqdn, _ string, _ PreCheckFunc) (bool, error) {
+		checked = append(checked, fqdn)
+		return true, nil
+	}
+
+	var validated []string
+	validate := func(_ context.Context, _ *api.Core, _ string, chlg acme.Challenge) error {
+		validated = append(validated, chlg.Type)
+		return nil
+	}
+
+	provider := &accountProviderRecorder{}
+
+	chlg := NewAccountChallenge(core, validate, provider, WrapPreCheck(preCheck))
+
+	authz := acme.Authorization{
+		Identifier: acme.Identifier{Value: "example.org"},
+		Challenges: []acme.Challenge{
+			{Type: challenge.DNS01.String(), Token: "dns"},
+			{Type: challenge.DNSAccount01.String(), Token: "account"},
+		},
+	}
+
+	require.NoError(t, chlg.PreSolve(authz))
+	require.NoError(t, chlg.Solve(authz))
+	require.NoError(t, chlg.CleanUp(authz))
+
+	expected := []string{"_ujmmovf2vn55tgye._acme-challenge.example.org."}
+
+	assert.Equal(t, expected, provider.presented)
+	assert.Equal(t, expected, checked)
+	assert.Equal(t, expected, provider.cleaned)
+	assert.Equal(t, []string{challenge.DNSAccount01.String()}, validated)
+}
+
+func TestAccountChallenge_batch(t *testing.T) {
+	_, apiURL := tester.SetupFakeAPI(t)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 512)
+	require.NoError(t, err)
+
+	core, err := api.New(http.DefaultClient, "lego-test", apiURL+"/dir", "https://example.com/acme/acct/ExampleAccount", privateKey)
+	require.NoError(t, err)
+
+	provider := &accountBatchProviderRecorder{}
+
+	chlg := NewAccountChallenge(core, nil, provider)
+
+	authz := acme.Authorization{
+		Identifier: acme.Identifier{Value: "example.org"},
+		Challenges: []acme.Challenge{
+			{Type: challenge.DNSAccount01.String(), Token: "account"},
+		},
+	}
+
+	errs := chlg.PreSolveBatch(context.Background(), []acme.Authorization{authz})
+	require.Equal(t, []error{nil}, errs)
+
+	require.Len(t, provider.records, 1)
+	assert.Equal(t, "https://example.com/acme/acct/ExampleAccount", provider.records[0].AccountURL)
+	assert.Equal(t, "_ujmmovf2vn55tgye._acme-challenge.example.org.", GetRecordChallengeInfo(provider.records[0]).FQDN)
+}
+
+// accountBatchProviderRecorder records the records of a batch provider supporting the dns-account-01 challenge.
+type accountBatchProviderRecorder struct {
+	accountProviderRecorder
+
+	records []challenge.Record
+}
+
+func (p *accountBatchProviderRecorder) PresentBatch(records []challenge.Record) error {
+	p.records = append(p.records, records...)
+	return nil
+}
+
+func (p *accountBatchProviderRecorder) CleanUpBatch(_ []challenge.Record) error { return nil }
+
+func TestAccountChallenge_provider(t *testing.T) {
+	t.Setenv("LEGO_DISABLE_CNAME_SUPPORT", "true")
+
+	_, apiURL := tester.SetupFakeAPI(t)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 512)
+	require.NoError(t, err)
+
+	core, err := api.New(http.DefaultClient, "lego-test", apiURL+"/dir", "https://example.com/acme/acct/ExampleAccount", privateKey)
+	require.NoError(t, err)
+
+	// a provider without PresentAccount and CleanUpAccount receives the account domain.
+	provider := &providerRecorder{}
+
+	chlg := NewAccountChallenge(core, nil, provider)
+
+	authz := acme.Authorization{
+		Identifier: acme.Identifier{Value: "example.org"},
+		Challenges: []acme.Challenge{
+			{Type: challenge.DNSAccount01.String(), Token: "account"},
+		},
+	}
+
+	err = chlg.PreSolve(authz)
+	require.NoError(t, err)
+
+	err = chlg.CleanUp(authz)
+	require.NoError(t, err)
+
+	expected := []string{"_ujmmovf2vn55tgye._acme-challenge.example.org."}
+
+	assert.Equal(t, expected, provider.presented)
+	assert.Equal(t, expected, provider.cleaned)
+}
+
+func TestGetChallengeInfo_accountDomain(t *testing.T) {
+	t.Setenv("LEGO_DISABLE_CNAME_SUPPORT", "true")
+
+	accountURL := "https://example.com/acme/acct/ExampleAccount"
+
+	testCases := []struct {
+		domain   string
+		expected string
+	}{
+		{domain: AccountDomain("example.org", accountURL), expected: "_ujmmovf2vn55tgye._acme-challenge.example.org."},
+		{domain: AccountDomain("*.example.org", accountURL), expected: "_ujmmovf2vn55tgye._acme-challenge.*.example.org."},
+		{domain: "example.org", expected: "_acme-challenge.example.org."},
+		{domain: "_ujmmovf2vn55tgye.example.org", expected: "_acme-challenge._ujmmovf2vn55tgye.example.org."},
+		{domain: "_UJMMOVF2VN55TGYE._acme-challenge.example.org", expected: "_acme-challenge._UJMMOVF2VN55TGYE._acme-challenge.example.org."},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.domain, func(t *testing.T) {
+			info := GetChallengeInfo(test.domain, "123d==")
+
+			assert.Equal(t, test.expected, info.FQDN)
+			assert.Equal(t, GetChallengeInfo("example.org", "123d==").Value, info.Value)
+		})
+	}
+}
+
+func TestAccountChallenge_noAccount(t *testing.T) {
+	_, apiURL := tester.SetupFakeAPI(t)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 512)
+	require.NoError(t, err)
+
+	core, err := api.New(http.DefaultClient, "lego-test", apiURL+"/dir", "", privateKey)
+	require.NoError(t, err)
+
+	provider := &accountProviderRecorder{}
+
+	chlg := NewAccountChallenge(core, nil, provider)
+
+	authz := acme.Authorization{
+		Identifier: acme.Identifier{Value: "example.org"},
+		Challenges: []acme.Challenge{
+			{Type: challenge.DNSAccount01.String(), Token: "account"},
+		},
+	}
+
+	err = chlg.PreSolve(authz)
+	require.EqualError(t, err, "[example.org] acme: the dns-account-01 challenge requires a registered account")
+
+	assert.Empty(t, provider.presented)
+}
@@ -1,11 +1,13 @@
 package dns01
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/LukasDeco/lego/v4/acme"
@@ -27,8 +29,27 @@ const (
 	DefaultTTL = 120
 )
 
+// acmeChallengeLabel the label of the TXT record of the challenges.
+const acmeChallengeLabel = "_acme-challenge"
+
+// ValidateFunc validates a challenge with the ACME server.
 type ValidateFunc func(core *api.Core, domain string, chlng acme.Challenge) error
 
+// ValidateContextFunc validates a challenge with the ACME server.
+// The validation is aborted if the context is canceled.
+type ValidateContextFunc func(ctx context.Context, core *api.Core, domain string, chlng acme.Challenge) error
+
+// withContext adapts a ValidateFunc to a ValidateContextFunc (the context is ignored).
+func (f ValidateFunc) withContext() ValidateContextFunc {
+	if f == nil {
+		return nil
+	}
+
+	return func(_ context.Context, core *api.Core, domain string, chlng acme.Challenge) error {
+		return f(core, domain, chlng)
+	}
+}
+
 type ChallengeOption func(*Challenge) error
 
 // CondOption Conditional challenge option.
@@ -42,22 +63,33 @@ func CondOption(condition bool, opt ChallengeOption) ChallengeOption {
 	return opt
 }
 
-// Challenge implements the dns-01 challenge.
+// Challenge implements the dns-01 challenge (and the dns-account-01 challenge, see NewAccountChallenge).
 type Challenge struct {
 	core       *api.Core
-	validate   ValidateFunc
+	validate   ValidateContextFunc
 	provider   challenge.Provider
 	preCheck   preCheck
 	dnsTimeout time.Duration
+	chlgType   challenge.Type
 }
 
 func NewChallenge(core *api.Core, validate ValidateFunc, provider challenge.Provider, opts ...ChallengeOption) *Challenge {
+	return newChallenge(challenge.DNS01, core, validate.withContext(), provider, opts...)
+}
+
+// NewChallengeContext creates a solver for the DNS-01 challenge, with a validation aborted if the context is canceled.
+func NewChallengeContext(core *api.Core, validate ValidateContextFunc, provider challenge.Provider, opts ...ChallengeOption) *Challenge {
+	return newChallenge(challenge.DNS01, core, validate, provider, opts...)
+}
+
+func newChallenge(chlgType challenge.Type, core *api.Core, validate ValidateContextFunc, provider challenge.Provider, opts ...ChallengeOption) *Challenge {
 	chlg := &Challenge{
 		core:       core,
 		validate:   validate,
 		provider:   provider,
 		preCheck:   newPreCheck(),
 		dnsTimeout: 10 * time.Second,
+		chlgType:   chlgType,
 	}
 
 	for _, opt := range opts {
@@ -73,10 +105,17 @@ func NewChallenge(core *api.Core, validate ValidateFunc, provider challenge.Prov
 // PreSolve just submits the txt record to the dns provider.
 // It does not validate record propagation, or do anything at all with the acme server.
 func (c *Challenge) PreSolve(authz acme.Authorization) error {
+	return c.PreSolveContext(context.Background(), authz)
+}
+
+// PreSolveContext just submits the txt record to the dns provider.
+// It does not validate record propagation, or do anything at all with the acme server.
+// The context is passed to the provider if it implements challenge.ProviderContext.
+func (c *Challenge) PreSolveContext(ctx context.Context, authz acme.Authorization) error {
 	domain := challenge.GetTargetedDomain(authz)
-	log.Infof("[%s] acme: Preparing to solve DNS-01", domain)
+	c.logger(domain).Info("acme: Preparing to solve " + c.name())
 
-	chlng, err := challenge.FindChallenge(challenge.DNS01, authz)
+	chlng, err := challenge.FindChallenge(c.chlgType, authz)
 	if err != nil {
 		return err
 	}
@@ -91,19 +130,53 @@ func (c *Challenge) PreSolve(authz acme.Authorization) error {
 		return err
 	}
 
-	err = c.provider.Present(authz.Identifier.Value, chlng.Token, keyAuth)
+	err = c.present(ctx, challenge.Record{Domain: authz.Identifier.Value, Token: chlng.Token, KeyAuth: keyAuth})
+	if err != nil {
+		return fmt.Errorf("[%s] acme: %w", domain, err)
+	}
+
+	return nil
+}
+
+// present presents the record with the provider.
+// The record of the dns-account-01 challenge is presented with challenge.AccountProvider if the provider implements it.
+func (c *Challenge) present(ctx context.Context, record challenge.Record) error {
+	var err error
+
+	if c.chlgType == challenge.DNSAccount01 {
+		record, err = c.accountRecord(record)
+		if err != nil {
+			return err
+		}
+	}
+
+	if provider, ok := c.provider.(challenge.AccountProvider); ok && record.AccountURL != "" {
+		err = ctx.Err()
+		if err == nil {
+			err = provider.PresentAccount(record.Domain, record.Token, record.KeyAuth, record.AccountURL)
+		}
+	} else {
+		err = challenge.PresentWithContext(ctx, c.provider, record.Domain, record.Token, record.KeyAuth)
+	}
+
 	if err != nil {
-		return fmt.Errorf("[%s] acme: error presenting token: %w", domain, err)
+		return fmt.Errorf("error presenting token: %w", err)
 	}
 
 	return nil
 }
 
 func (c *Challenge) Solve(authz acme.Authorization) error {
+	return c.SolveContext(context.Background(), authz)
+}
+
+// SolveContext waits for the propagation of the TXT record and validates the challenge.
+// The propagation check and the validation are aborted if the context is canceled.
+func (c *Challenge) SolveContext(ctx context.Context, authz acme.Authorization) error {
 	domain := challenge.GetTargetedDomain(authz)
-	log.Infof("[%s] acme: Trying to solve DNS-01", domain)
+	c.logger(domain).Info("acme: Trying to solve " + c.name())
 
-	chlng, err := challenge.FindChallenge(challenge.DNS01, authz)
+	chlng, err := challenge.FindChallenge(c.chlgType, authz)
 	if err != nil {
 		return err
 	}
@@ -114,7 +187,10 @@ func (c *Challenge) Solve(authz acme.Authorization) error {
 		return err
 	}
 
-	info := GetChallengeInfo(authz.Identifier.Value, keyAuth)
+	info, err := c.getChallengeInfo(authz.Identifier.Value, keyAuth)
+	if err != nil {
+		return fmt.Errorf("[%s] acme: %w", domain, err)
+	}
 
 	var timeout, interval time.Duration
 	switch provider := c.provider.(type) {
@@ -124,14 +200,18 @@ func (c *Challenge) Solve(authz acme.Authorization) error {
 		timeout, interval = DefaultPropagationTimeout, DefaultPollingInterval
 	}
 
-	log.Infof("[%s] acme: Checking DNS record propagation using %+v", domain, recursiveNameservers)
+	c.logger(domain).Info(fmt.Sprintf("acme: Checking DNS record propagation using %+v", recursiveNameservers))
 
-	time.Sleep(interval)
+	select {
+	case <-time.After(interval):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 
-	err = wait.For("propagation", timeout, interval, func() (bool, error) {
+	err = wait.ForContext(ctx, "propagation", timeout, interval, func() (bool, error) {
 		stop, errP := c.preCheck.call(domain, info.EffectiveFQDN, info.Value)
 		if !stop || errP != nil {
-			log.Infof("[%s] acme: Waiting for DNS record propagation.", domain)
+			c.logger(domain).Info("acme: Waiting for DNS record propagation.")
 		}
 		return stop, errP
 	})
@@ -140,14 +220,14 @@ func (c *Challenge) Solve(authz acme.Authorization) error {
 	}
 
 	chlng.KeyAuthorization = keyAuth
-	return c.validate(c.core, domain, chlng)
+	return c.validate(ctx, c.core, domain, chlng)
 }
 
 // CleanUp cleans the challenge.
 func (c *Challenge) CleanUp(authz acme.Authorization) error {
-	log.Infof("[%s] acme: Cleaning DNS-01 challenge", challenge.GetTargetedDomain(authz))
+	c.logger(challenge.GetTargetedDomain(authz)).Info("acme: Cleaning " + c.name() + " challenge")
 
-	chlng, err := challenge.FindChallenge(challenge.DNS01, authz)
+	chlng, err := challenge.FindChallenge(c.chlgType, authz)
 	if err != nil {
 		return err
 	}
@@ -157,10 +237,39 @@ func (c *Challenge) CleanUp(authz acme.Authorization) error {
 		return err
 	}
 
-	return c.provider.CleanUp(authz.Identifier.Value, chlng.Token, keyAuth)
+	record := challenge.Record{Domain: authz.Identifier.Value, Token: chlng.Token, KeyAuth: keyAuth}
+
+	if c.chlgType == challenge.DNSAccount01 {
+		record, err = c.accountRecord(record)
+		if err != nil {
+			return err
+		}
+	}
+
+	if provider, ok := c.provider.(challenge.AccountProvider); ok && record.AccountURL != "" {
+		return provider.CleanUpAccount(record.Domain, record.Token, record.KeyAuth, record.AccountURL)
+	}
+
+	return c.provider.CleanUp(record.Domain, record.Token, record.KeyAuth)
+}
+
+// name returns the name of the challenge type for the logs (i.e. "DNS-01").
+// logger returns the logger of the challenge of a domain.
+func (c *Challenge) logger(domain string) *log.FieldLogger {
+	return c.core.Logger().With(log.Domain(domain), log.Challenge(c.chlgType.String()))
+}
+
+func (c *Challenge) name() string {
+	return strings.ToUpper(c.chlgType.String())
 }
 
+// Sequential returns true if the challenges must be solved one by one, and the interval between 2 challenges.
+// The records of a challenge.BatchProvider are presented together, so they are never solved one by one.
 func (c *Challenge) Sequential() (bool, time.Duration) {
+	if _, ok := c.provider.(challenge.BatchProvider); ok {
+		return false, 0
+	}
+
 	if p, ok := c.provider.(sequential); ok {
 		return ok, p.Sequential()
 	}
@@ -181,7 +290,7 @@ func GetRecord(domain, keyAuth string) (fqdn, value string) {
 
 // ChallengeInfo contains the information use to create the TXT record.
 type ChallengeInfo struct {
-	// FQDN is the full-qualified challenge domain (i.e. `_acme-challenge.[domain].`)
+	// FQDN is the full-qualified challenge domain (i.e. `_acme-challenge.[domain].` or `_[account label]._acme-challenge.[domain].`)
 	FQDN string
 
 	// EffectiveFQDN contains the resulting FQDN after the CNAMEs resolutions.
@@ -191,8 +300,15 @@ type ChallengeInfo struct {
 	Value string
 }
 
-// GetChallengeInfo returns information used to create a DNS record which will fulfill the `dns-01` challenge.
+// GetChallengeInfo returns information used to create a DNS record which will fulfill the `dns-01` challenge,
+// or the `dns-account-01` challenge if the domain is an account domain (see AccountDomain).
 func GetChallengeInfo(domain, keyAuth string) ChallengeInfo {
+	accountLabel, name := splitAccountDomain(domain)
+
+	return newChallengeInfo(accountLabel, name, keyAuth)
+}
+
+func newChallengeInfo(accountLabel, domain, keyAuth string) ChallengeInfo {
 	keyAuthShaBytes := sha256.Sum256([]byte(keyAuth))
 	// base64URL encoding without padding
 	value := base64.RawURLEncoding.EncodeToString(keyAuthShaBytes[:sha256.Size])
@@ -201,13 +317,16 @@ func GetChallengeInfo(domain, keyAuth string) ChallengeInfo {
 
 	return ChallengeInfo{
 		Value:         value,
-		FQDN:          getChallengeFQDN(domain, false),
-		EffectiveFQDN: getChallengeFQDN(domain, !ok),
+		FQDN:          getChallengeFQDN(accountLabel, domain, false),
+		EffectiveFQDN: getChallengeFQDN(accountLabel, domain, !ok),
 	}
 }
 
-func getChallengeFQDN(domain string, followCNAME bool) string {
-	fqdn := fmt.Sprintf("_acme-challenge.%s.", domain)
+func getChallengeFQDN(accountLabel, domain string, followCNAME bool) string {
+	fqdn := fmt.Sprintf("%s.%s.", acmeChallengeLabel, domain)
+	if accountLabel != "" {
+		fqdn = accountLabel + "." + fqdn
+	}
 
 	if !followCNAME {
 		return fqdn
@@ -216,7 +335,7 @@ func getChallengeFQDN(domain string, followCNAME bool) string {
 	// recursion counter so it doesn't spin out of control
 	for limit := 0; limit < 50; limit++ {
 		// Keep following CNAMEs
-		r, err := dnsQuery(fqdn, dns.TypeCNAME, recursiveNameservers, true)
+		r, err := dnsQuery(fqdn, dns.TypeCNAME, nameserversFor(fqdn, recursiveNameservers), true)
 
 		if err != nil || r.Rcode != dns.RcodeSuccess {
 			// No more CNAME records to follow, exit
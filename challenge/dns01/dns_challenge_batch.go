@@ -0,0 +1,132 @@
+package dns01
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/LukasDeco/lego/v4/acme"
+	"github.com/LukasDeco/lego/v4/challenge"
+)
+
+// PreSolveBatch submits the TXT records of several authorizations.
+// If the provider implements challenge.BatchProvider, the records are presented with a single call,
+// otherwise they are presented one by one (see PreSolveContext).
+// The returned errors are in the same order as the authorizations (nil when the record has been presented).
+func (c *Challenge) PreSolveBatch(ctx context.Context, authzs []acme.Authorization) []error {
+	errs := make([]error, len(authzs))
+
+	provider, ok := c.provider.(challenge.BatchProvider)
+	if !ok {
+		for i, authz := range authzs {
+			errs[i] = c.PreSolveContext(ctx, authz)
+		}
+
+		return errs
+	}
+
+	var records []challenge.Record
+	var indexes []int
+
+	for i, authz := range authzs {
+		domain := challenge.GetTargetedDomain(authz)
+		c.logger(domain).Info("acme: Preparing to solve " + c.name())
+
+		record, err := c.getRecord(authz)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+
+		records = append(records, record)
+		indexes = append(indexes, i)
+	}
+
+	if len(records) == 0 {
+		return errs
+	}
+
+	err := ctx.Err()
+	if err == nil {
+		err = provider.PresentBatch(records)
+	}
+
+	if err != nil {
+		for _, i := range indexes {
+			errs[i] = fmt.Errorf("[%s] acme: error presenting token: %w", challenge.GetTargetedDomain(authzs[i]), err)
+		}
+	}
+
+	return errs
+}
+
+// CleanUpBatch cleans the challenges of several authorizations.
+// If the provider implements challenge.BatchProvider, the records are cleaned with a single call,
+// otherwise they are cleaned one by one (see CleanUp).
+// The returned errors are in the same order as the authorizations (nil when the record has been cleaned).
+func (c *Challenge) CleanUpBatch(authzs []acme.Authorization) []error {
+	errs := make([]error, len(authzs))
+
+	provider, ok := c.provider.(challenge.BatchProvider)
+	if !ok {
+		for i, authz := range authzs {
+			errs[i] = c.CleanUp(authz)
+		}
+
+		return errs
+	}
+
+	var records []challenge.Record
+	var indexes []int
+
+	for i, authz := range authzs {
+		c.logger(challenge.GetTargetedDomain(authz)).Info("acme: Cleaning " + c.name() + " challenge")
+
+		record, err := c.getRecord(authz)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+
+		records = append(records, record)
+		indexes = append(indexes, i)
+	}
+
+	if len(records) == 0 {
+		return errs
+	}
+
+	err := provider.CleanUpBatch(records)
+	if err != nil {
+		for _, i := range indexes {
+			errs[i] = err
+		}
+	}
+
+	return errs
+}
+
+// getRecord returns the record to present for an authorization.
+func (c *Challenge) getRecord(authz acme.Authorization) (challenge.Record, error) {
+	chlng, err := challenge.FindChallenge(c.chlgType, authz)
+	if err != nil {
+		return challenge.Record{}, err
+	}
+
+	// Generate the Key Authorization for the challenge
+	keyAuth, err := c.core.GetKeyAuthorization(chlng.Token)
+	if err != nil {
+		return challenge.Record{}, err
+	}
+
+	record := challenge.Record{Domain: authz.Identifier.Value, Token: chlng.Token, KeyAuth: keyAuth}
+
+	if c.chlgType == challenge.DNSAccount01 {
+		// The batch provider creates the record of the account (see GetRecordChallengeInfo).
+		record, err = c.accountRecord(record)
+		if err != nil {
+			return challenge.Record{}, fmt.Errorf("[%s] acme: %w", challenge.GetTargetedDomain(authz), err)
+		}
+	}
+
+	return record, nil
+}
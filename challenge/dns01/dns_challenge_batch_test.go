@@ -0,0 +1,156 @@
+package dns01
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/LukasDeco/lego/v4/acme"
+	"github.com/LukasDeco/lego/v4/acme/api"
+	"github.com/LukasDeco/lego/v4/challenge"
+	"github.com/LukasDeco/lego/v4/platform/tester"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchProviderRecorder records the batches of records, like a provider with a batch API does.
+type batchProviderRecorder struct {
+	providerRecorder
+
+	presentedBatches, cleanedBatches [][]challenge.Record
+	err                              error
+}
+
+func (p *batchProviderRecorder) PresentBatch(records []challenge.Record) error {
+	p.presentedBatches = append(p.presentedBatches, records)
+	return p.err
+}
+
+func (p *batchProviderRecorder) CleanUpBatch(records []challenge.Record) error {
+	p.cleanedBatches = append(p.cleanedBatches, records)
+	return p.err
+}
+
+func setupBatchTest(t *testing.T) *api.Core {
+	t.Helper()
+
+	_, apiURL := tester.SetupFakeAPI(t)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 512)
+	require.NoError(t, err)
+
+	core, err := api.New(http.DefaultClient, "lego-test", apiURL+"/dir", "", privateKey)
+	require.NoError(t, err)
+
+	return core
+}
+
+func createBatchAuthorizations() []acme.Authorization {
+	return []acme.Authorization{
+		{
+			Identifier: acme.Identifier{Value: "example.org"},
+			Challenges: []acme.Challenge{{Type: challenge.DNS01.String(), Token: "a"}},
+		},
+		{
+			Identifier: acme.Identifier{Value: "example.com"},
+			Challenges: []acme.Challenge{{Type: challenge.HTTP01.String(), Token: "b"}},
+		},
+		{
+			Identifier: acme.Identifier{Value: "example.net"},
+			Challenges: []acme.Challenge{{Type: challenge.DNS01.String(), Token: "c"}},
+		},
+	}
+}
+
+func TestChallenge_PreSolveBatch(t *testing.T) {
+	t.Setenv("LEGO_DISABLE_CNAME_SUPPORT", "true")
+
+	core := setupBatchTest(t)
+
+	provider := &batchProviderRecorder{}
+
+	chlg := NewChallenge(core, nil, provider)
+
+	authzs := createBatchAuthorizations()
+
+	errs := chlg.PreSolveBatch(context.Background(), authzs)
+	require.Len(t, errs, 3)
+
+	assert.NoError(t, errs[0])
+	assert.EqualError(t, errs[1], "[example.com] acme: unable to find challenge dns-01")
+	assert.NoError(t, errs[2])
+
+	keyAuthA, err := core.GetKeyAuthorization("a")
+	require.NoError(t, err)
+
+	keyAuthC, err := core.GetKeyAuthorization("c")
+	require.NoError(t, err)
+
+	expected := [][]challenge.Record{{
+		{Domain: "example.org", Token: "a", KeyAuth: keyAuthA},
+		{Domain: "example.net", Token: "c", KeyAuth: keyAuthC},
+	}}
+
+	assert.Equal(t, expected, provider.presentedBatches)
+	assert.Empty(t, provider.presented)
+
+	errs = chlg.CleanUpBatch(authzs)
+	require.Len(t, errs, 3)
+
+	assert.NoError(t, errs[0])
+	assert.Error(t, errs[1])
+	assert.NoError(t, errs[2])
+
+	assert.Equal(t, expected, provider.cleanedBatches)
+	assert.Empty(t, provider.cleaned)
+
+	// the records are presented together: no need to solve the challenges one by one.
+	ok, _ := chlg.Sequential()
+	assert.False(t, ok)
+}
+
+func TestChallenge_PreSolveBatch_error(t *testing.T) {
+	core := setupBatchTest(t)
+
+	provider := &batchProviderRecorder{err: errors.New("oops")}
+
+	chlg := NewChallenge(core, nil, provider)
+
+	errs := chlg.PreSolveBatch(context.Background(), createBatchAuthorizations())
+	require.Len(t, errs, 3)
+
+	assert.EqualError(t, errs[0], "[example.org] acme: error presenting token: oops")
+	assert.EqualError(t, errs[1], "[example.com] acme: unable to find challenge dns-01")
+	assert.EqualError(t, errs[2], "[example.net] acme: error presenting token: oops")
+}
+
+func TestChallenge_PreSolveBatch_notBatchProvider(t *testing.T) {
+	t.Setenv("LEGO_DISABLE_CNAME_SUPPORT", "true")
+
+	core := setupBatchTest(t)
+
+	provider := &providerRecorder{}
+
+	chlg := NewChallenge(core, nil, provider)
+
+	authzs := createBatchAuthorizations()
+
+	errs := chlg.PreSolveBatch(context.Background(), authzs)
+	require.Len(t, errs, 3)
+
+	assert.NoError(t, errs[0])
+	assert.Error(t, errs[1])
+	assert.NoError(t, errs[2])
+
+	expected := []string{"_acme-challenge.example.org.", "_acme-challenge.example.net."}
+
+	assert.Equal(t, expected, provider.presented)
+
+	errs = chlg.CleanUpBatch(authzs)
+	require.Len(t, errs, 3)
+
+	assert.Equal(t, expected, provider.cleaned)
+}
@@ -20,9 +20,16 @@ func NewDNSProviderManual() (*DNSProviderManual, error) {
 }
 
 // Present prints instructions for manually creating the TXT record.
-func (*DNSProviderManual) Present(domain, token, keyAuth string) error {
-	info := GetChallengeInfo(domain, keyAuth)
+func (d *DNSProviderManual) Present(domain, token, keyAuth string) error {
+	return d.present(GetChallengeInfo(domain, keyAuth))
+}
+
+// PresentAccount prints instructions for manually creating the TXT record of the dns-account-01 challenge.
+func (d *DNSProviderManual) PresentAccount(domain, token, keyAuth, accountURL string) error {
+	return d.present(GetAccountChallengeInfo(domain, accountURL, keyAuth))
+}
 
+func (*DNSProviderManual) present(info ChallengeInfo) error {
 	authZone, err := FindZoneByFqdn(info.EffectiveFQDN)
 	if err != nil {
 		return err
@@ -38,9 +45,16 @@ func (*DNSProviderManual) Present(domain, token, keyAuth string) error {
 }
 
 // CleanUp prints instructions for manually removing the TXT record.
-func (*DNSProviderManual) CleanUp(domain, token, keyAuth string) error {
-	info := GetChallengeInfo(domain, keyAuth)
+func (d *DNSProviderManual) CleanUp(domain, token, keyAuth string) error {
+	return d.cleanUp(GetChallengeInfo(domain, keyAuth))
+}
+
+// CleanUpAccount prints instructions for manually removing the TXT record of the dns-account-01 challenge.
+func (d *DNSProviderManual) CleanUpAccount(domain, token, keyAuth, accountURL string) error {
+	return d.cleanUp(GetAccountChallengeInfo(domain, accountURL, keyAuth))
+}
 
+func (*DNSProviderManual) cleanUp(info ChallengeInfo) error {
 	authZone, err := FindZoneByFqdn(info.EffectiveFQDN)
 	if err != nil {
 		return err
@@ -77,9 +77,16 @@ func getNameservers(path string, defaults []string) []string {
 	return ParseNameservers(config.Servers)
 }
 
+// ParseNameservers ensures that all the classic nameservers have a port number.
+// The nameservers with a URL syntax (see NewResolver) are kept as is.
 func ParseNameservers(servers []string) []string {
 	var resolvers []string
 	for _, resolver := range servers {
+		if strings.Contains(resolver, "://") {
+			resolvers = append(resolvers, resolver)
+			continue
+		}
+
 		// ensure all servers have a port number
 		if _, _, err := net.SplitHostPort(resolver); err != nil {
 			resolvers = append(resolvers, net.JoinHostPort(resolver, "53"))
@@ -99,7 +106,7 @@ func lookupNameservers(fqdn string) ([]string, error) {
 		return nil, fmt.Errorf("could not determine the zone: %w", err)
 	}
 
-	r, err := dnsQuery(zone, dns.TypeNS, recursiveNameservers, true)
+	r, err := dnsQuery(zone, dns.TypeNS, nameserversFor(zone, recursiveNameservers), true)
 	if err != nil {
 		return nil, err
 	}
@@ -118,8 +125,9 @@ func lookupNameservers(fqdn string) ([]string, error) {
 
 // FindPrimaryNsByFqdn determines the primary nameserver of the zone apex for the given fqdn
 // by recursing up the domain labels until the nameserver returns a SOA record in the answer section.
+// The nameservers of the zone (see AddZoneNameservers) are used if they are defined.
 func FindPrimaryNsByFqdn(fqdn string) (string, error) {
-	return FindPrimaryNsByFqdnCustom(fqdn, recursiveNameservers)
+	return FindPrimaryNsByFqdnCustom(fqdn, nameserversFor(fqdn, recursiveNameservers))
 }
 
 // FindPrimaryNsByFqdnCustom determines the primary nameserver of the zone apex for the given fqdn
@@ -134,12 +142,14 @@ func FindPrimaryNsByFqdnCustom(fqdn string, nameservers []string) (string, error
 
 // FindZoneByFqdn determines the zone apex for the given fqdn
 // by recursing up the domain labels until the nameserver returns a SOA record in the answer section.
+// The nameservers of the zone (see AddZoneNameservers) are used if they are defined.
 func FindZoneByFqdn(fqdn string) (string, error) {
-	return FindZoneByFqdnCustom(fqdn, recursiveNameservers)
+	return FindZoneByFqdnCustom(fqdn, nameserversFor(fqdn, recursiveNameservers))
 }
 
 // FindZoneByFqdnCustom determines the zone apex for the given fqdn
 // by recursing up the domain labels until the nameserver returns a SOA record in the answer section.
+// The nameservers use the syntax of NewResolver (i.e. "host:port", "tls://host", "https://host/dns-query").
 func FindZoneByFqdnCustom(fqdn string, nameservers []string) (string, error) {
 	soa, err := lookupSoaByFqdn(fqdn, nameservers)
 	if err != nil {
@@ -250,16 +260,12 @@ func createDNSMsg(fqdn string, rtype uint16, recursive bool) *dns.Msg {
 }
 
 func sendDNSQuery(m *dns.Msg, ns string) (*dns.Msg, error) {
-	udp := &dns.Client{Net: "udp", Timeout: dnsTimeout}
-	in, _, err := udp.Exchange(m, ns)
-
-	if in != nil && in.Truncated {
-		tcp := &dns.Client{Net: "tcp", Timeout: dnsTimeout}
-		// If the TCP request succeeds, the err will reset to nil
-		in, _, err = tcp.Exchange(m, ns)
+	resolver, err := NewResolver(ns)
+	if err != nil {
+		return nil, err
 	}
 
-	return in, err
+	return resolver.Exchange(m)
 }
 
 func formatDNSError(msg *dns.Msg, err error) string {
@@ -2,7 +2,6 @@ package dns01
 
 import (
 	"fmt"
-	"net"
 	"strings"
 
 	"github.com/miekg/dns"
@@ -54,7 +53,7 @@ func (p preCheck) call(domain, fqdn, value string) (bool, error) {
 // checkDNSPropagation checks if the expected TXT record has been propagated to all authoritative nameservers.
 func (p preCheck) checkDNSPropagation(fqdn, value string) (bool, error) {
 	// Initial attempt to resolve at the recursive NS
-	r, err := dnsQuery(fqdn, dns.TypeTXT, recursiveNameservers, true)
+	r, err := dnsQuery(fqdn, dns.TypeTXT, nameserversFor(fqdn, recursiveNameservers), true)
 	if err != nil {
 		return false, err
 	}
@@ -67,6 +66,11 @@ func (p preCheck) checkDNSPropagation(fqdn, value string) (bool, error) {
 		fqdn = updateDomainWithCName(r, fqdn)
 	}
 
+	if zoneNss := getZoneNameservers(fqdn); len(zoneNss) > 0 {
+		// The nameservers of the zone are used instead of the authoritative nameservers.
+		return checkNameservers(fqdn, value, zoneNss, true)
+	}
+
 	authoritativeNss, err := lookupNameservers(fqdn)
 	if err != nil {
 		return false, err
@@ -76,9 +80,16 @@ func (p preCheck) checkDNSPropagation(fqdn, value string) (bool, error) {
 }
 
 // checkAuthoritativeNss queries each of the given nameservers for the expected TXT record.
+// The nameservers are host names (queried on the port 53),
+// or use the syntax of NewResolver (i.e. "host:port", "tls://host", "https://host/dns-query").
 func checkAuthoritativeNss(fqdn, value string, nameservers []string) (bool, error) {
+	return checkNameservers(fqdn, value, nameservers, false)
+}
+
+// checkNameservers queries each of the given nameservers for the expected TXT record.
+func checkNameservers(fqdn, value string, nameservers []string, recursive bool) (bool, error) {
 	for _, ns := range nameservers {
-		r, err := dnsQuery(fqdn, dns.TypeTXT, []string{net.JoinHostPort(ns, "53")}, false)
+		r, err := dnsQuery(fqdn, dns.TypeTXT, ParseNameservers([]string{ns}), recursive)
 		if err != nil {
 			return false, err
 		}
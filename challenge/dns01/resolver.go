@@ -0,0 +1,271 @@
+package dns01
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	defaultDNSPort = "53"
+	defaultDoTPort = "853"
+
+	dohMediaType = "application/dns-message"
+)
+
+// Resolver sends DNS queries to a nameserver.
+type Resolver interface {
+	Exchange(m *dns.Msg) (*dns.Msg, error)
+}
+
+// NewResolver creates the resolver of a nameserver address:
+//   - "host", "host:port", "udp://host:port": classic DNS over UDP, with a fallback to TCP for the truncated responses.
+//   - "tcp://host:port": classic DNS over TCP.
+//   - "tls://host:port": DNS over TLS (RFC 7858), the default port is 853.
+//   - "https://host/path": DNS over HTTPS (RFC 8484).
+//
+// The default port of the classic DNS is 53.
+func NewResolver(nameserver string) (Resolver, error) {
+	if !strings.Contains(nameserver, "://") {
+		return &classicResolver{addr: withDefaultPort(nameserver, defaultDNSPort)}, nil
+	}
+
+	u, err := url.Parse(nameserver)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nameserver %q: %w", nameserver, err)
+	}
+
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid nameserver %q: missing host", nameserver)
+	}
+
+	switch u.Scheme {
+	case "udp":
+		return &classicResolver{addr: withDefaultPort(u.Host, defaultDNSPort)}, nil
+	case "tcp":
+		return &classicResolver{addr: withDefaultPort(u.Host, defaultDNSPort), net: "tcp"}, nil
+	case "tls":
+		return &dotResolver{addr: withDefaultPort(u.Host, defaultDoTPort), serverName: u.Hostname()}, nil
+	case "https":
+		return &dohResolver{endpoint: u.String()}, nil
+	default:
+		return nil, fmt.Errorf("invalid nameserver %q: unsupported scheme %q", nameserver, u.Scheme)
+	}
+}
+
+func withDefaultPort(host, port string) string {
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		return net.JoinHostPort(strings.Trim(host, "[]"), port)
+	}
+
+	return host
+}
+
+// classicResolver sends the queries over UDP (with a fallback to TCP), or only over TCP.
+type classicResolver struct {
+	addr string
+	net  string
+}
+
+func (r *classicResolver) Exchange(m *dns.Msg) (*dns.Msg, error) {
+	if r.net == "tcp" {
+		tcp := &dns.Client{Net: "tcp", Timeout: dnsTimeout}
+		in, _, err := tcp.Exchange(m, r.addr)
+		return in, err
+	}
+
+	udp := &dns.Client{Net: "udp", Timeout: dnsTimeout}
+	in, _, err := udp.Exchange(m, r.addr)
+
+	if in != nil && in.Truncated {
+		tcp := &dns.Client{Net: "tcp", Timeout: dnsTimeout}
+		// If the TCP request succeeds, the err will reset to nil
+		in, _, err = tcp.Exchange(m, r.addr)
+	}
+
+	return in, err
+}
+
+// dotResolver sends the queries over TLS (DoT).
+type dotResolver struct {
+	addr       string
+	serverName string
+}
+
+func (r *dotResolver) Exchange(m *dns.Msg) (*dns.Msg, error) {
+	client := &dns.Client{
+		Net:       "tcp-tls",
+		Timeout:   dnsTimeout,
+		TLSConfig: &tls.Config{ServerName: r.serverName, MinVersion: tls.VersionTLS12},
+	}
+
+	in, _, err := client.Exchange(m, r.addr)
+
+	return in, err
+}
+
+// dohResolver sends the queries over HTTPS (DoH).
+type dohResolver struct {
+	endpoint string
+	client   *http.Client
+}
+
+func (r *dohResolver) Exchange(m *dns.Msg) (*dns.Msg, error) {
+	// The ID should be 0 to maximize the HTTP cache friendliness (RFC 8484 section 4.1).
+	id := m.Id
+
+	query := m.Copy()
+	query.Id = 0
+
+	raw, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, r.endpoint, bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", dohMediaType)
+	req.Header.Set("Accept", dohMediaType)
+
+	client := r.client
+	if client == nil {
+		client = &http.Client{Timeout: dnsTimeout}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH query to %s failed: %d %s", r.endpoint, resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, dns.MaxMsgSize))
+	if err != nil {
+		return nil, err
+	}
+
+	in := new(dns.Msg)
+	err = in.Unpack(body)
+	if err != nil {
+		return nil, fmt.Errorf("DoH query to %s: invalid response: %w", r.endpoint, err)
+	}
+
+	in.Id = id
+
+	return in, nil
+}
+
+var (
+	zoneNameservers   = map[string][]string{}
+	muZoneNameservers sync.RWMutex
+)
+
+// AddZoneNameservers defines the nameservers used for the domains of a zone (the zone itself and its subdomains).
+// They are used instead of the recursive nameservers,
+// and the propagation of the TXT record is checked through them instead of the authoritative nameservers
+// (e.g. when the authoritative nameservers cannot be reached directly).
+// The root zone (".") matches all the domains without a more specific zone:
+// all the queries, including the propagation checks, go through its nameservers (e.g. when only DNS over HTTPS is allowed).
+// The nameservers use the syntax of NewResolver.
+func AddZoneNameservers(zone string, nameservers []string) ChallengeOption {
+	return func(_ *Challenge) error {
+		for _, ns := range nameservers {
+			if _, err := NewResolver(ns); err != nil {
+				return err
+			}
+		}
+
+		muZoneNameservers.Lock()
+		zoneNameservers[ToFqdn(strings.ToLower(zone))] = ParseNameservers(nameservers)
+		muZoneNameservers.Unlock()
+
+		return nil
+	}
+}
+
+// ClearZoneNameservers removes the nameservers defined by AddZoneNameservers. Primarily used in testing.
+func ClearZoneNameservers() {
+	muZoneNameservers.Lock()
+	zoneNameservers = map[string][]string{}
+	muZoneNameservers.Unlock()
+}
+
+// getZoneNameservers returns the nameservers of the most specific zone containing the fqdn (including the root zone), or nil.
+func getZoneNameservers(fqdn string) []string {
+	muZoneNameservers.RLock()
+	defer muZoneNameservers.RUnlock()
+
+	if len(zoneNameservers) == 0 {
+		return nil
+	}
+
+	fqdn = ToFqdn(strings.ToLower(fqdn))
+
+	for _, index := range dns.Split(fqdn) {
+		if nameservers, ok := zoneNameservers[fqdn[index:]]; ok {
+			return nameservers
+		}
+	}
+
+	// dns.Split never returns the root zone.
+	return zoneNameservers["."]
+}
+
+// nameserversFor returns the nameservers to use for the fqdn:
+// the nameservers of its zone if they are defined, or the given nameservers.
+func nameserversFor(fqdn string, nameservers []string) []string {
+	if zoneNss := getZoneNameservers(fqdn); len(zoneNss) > 0 {
+		return zoneNss
+	}
+
+	return nameservers
+}
+
+// ParseResolvers splits the resolvers into the recursive nameservers and the nameservers by zone.
+// A resolver is a nameserver (see NewResolver), or a zone and a nameserver separated by "=" (i.e. "example.com=tls://1.1.1.1").
+func ParseResolvers(resolvers []string) (recursive []string, zones map[string][]string, err error) {
+	zones = map[string][]string{}
+
+	for _, resolver := range resolvers {
+		zone, nameserver := splitZoneResolver(resolver)
+
+		if _, err := NewResolver(nameserver); err != nil {
+			return nil, nil, err
+		}
+
+		if zone == "" {
+			recursive = append(recursive, nameserver)
+			continue
+		}
+
+		zones[zone] = append(zones[zone], nameserver)
+	}
+
+	return ParseNameservers(recursive), zones, nil
+}
+
+// splitZoneResolver splits "zone=nameserver".
+// The "=" of the URL query strings are ignored: a zone contains neither "/" nor ":".
+func splitZoneResolver(resolver string) (zone, nameserver string) {
+	before, after, found := strings.Cut(resolver, "=")
+	if !found || before == "" || strings.ContainsAny(before, "/:") {
+		return "", resolver
+	}
+
+	return before, after
+}
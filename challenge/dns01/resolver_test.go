@@ -0,0 +1,293 @@
+package dns01
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewResolver(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		nameserver string
+		expected   Resolver
+	}{
+		{
+			desc:       "host",
+			nameserver: "1.1.1.1",
+			expected:   &classicResolver{addr: "1.1.1.1:53"},
+		},
+		{
+			desc:       "host and port",
+			nameserver: "1.1.1.1:5353",
+			expected:   &classicResolver{addr: "1.1.1.1:5353"},
+		},
+		{
+			desc:       "IPv6",
+			nameserver: "[2606:4700:4700::1111]",
+			expected:   &classicResolver{addr: "[2606:4700:4700::1111]:53"},
+		},
+		{
+			desc:       "UDP",
+			nameserver: "udp://1.1.1.1",
+			expected:   &classicResolver{addr: "1.1.1.1:53"},
+		},
+		{
+			desc:       "TCP",
+			nameserver: "tcp://1.1.1.1:5353",
+			expected:   &classicResolver{addr: "1.1.1.1:5353", net: "tcp"},
+		},
+		{
+			desc:       "DoT",
+			nameserver: "tls://one.one.one.one",
+			expected:   &dotResolver{addr: "one.one.one.one:853", serverName: "one.one.one.one"},
+		},
+		{
+			desc:       "DoT with port",
+			nameserver: "tls://one.one.one.one:8853",
+			expected:   &dotResolver{addr: "one.one.one.one:8853", serverName: "one.one.one.one"},
+		},
+		{
+			desc:       "DoH",
+			nameserver: "https://cloudflare-dns.com/dns-query",
+			expected:   &dohResolver{endpoint: "https://cloudflare-dns.com/dns-query"},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			resolver, err := NewResolver(test.nameserver)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, resolver)
+		})
+	}
+}
+
+func TestNewResolver_error(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		nameserver string
+		expected   string
+	}{
+		{
+			desc:       "unsupported scheme",
+			nameserver: "quic://1.1.1.1",
+			expected:   `invalid nameserver "quic://1.1.1.1": unsupported scheme "quic"`,
+		},
+		{
+			desc:       "missing host",
+			nameserver: "https:///dns-query",
+			expected:   `invalid nameserver "https:///dns-query": missing host`,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewResolver(test.nameserver)
+			require.EqualError(t, err, test.expected)
+		})
+	}
+}
+
+func TestParseResolvers(t *testing.T) {
+	recursive, zones, err := ParseResolvers([]string{
+		"1.1.1.1",
+		"https://dns.example.com/dns-query?foo=bar",
+		"example.com=tls://1.1.1.1",
+		"example.com=8.8.8.8",
+		"example.org=https://dns.example.com/dns-query",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"1.1.1.1:53", "https://dns.example.com/dns-query?foo=bar"}, recursive)
+
+	expected := map[string][]string{
+		"example.com": {"tls://1.1.1.1", "8.8.8.8"},
+		"example.org": {"https://dns.example.com/dns-query"},
+	}
+	assert.Equal(t, expected, zones)
+
+	_, _, err = ParseResolvers([]string{"example.com=quic://1.1.1.1"})
+	require.Error(t, err)
+}
+
+func Test_getZoneNameservers(t *testing.T) {
+	t.Cleanup(ClearZoneNameservers)
+
+	require.NoError(t, AddZoneNameservers("example.com", []string{"1.1.1.1"})(nil))
+	require.NoError(t, AddZoneNameservers("sub.example.com.", []string{"tls://8.8.8.8"})(nil))
+
+	assert.Equal(t, []string{"1.1.1.1:53"}, getZoneNameservers("_acme-challenge.example.com."))
+	assert.Equal(t, []string{"1.1.1.1:53"}, getZoneNameservers("EXAMPLE.com"))
+	assert.Equal(t, []string{"tls://8.8.8.8"}, getZoneNameservers("_acme-challenge.a.sub.example.com."))
+	assert.Nil(t, getZoneNameservers("_acme-challenge.example.org."))
+
+	assert.Equal(t, []string{"9.9.9.9:53"}, nameserversFor("example.org.", []string{"9.9.9.9:53"}))
+
+	require.NoError(t, AddZoneNameservers(".", []string{"https://dns.example.com/dns-query"})(nil))
+
+	assert.Equal(t, []string{"https://dns.example.com/dns-query"}, getZoneNameservers("_acme-challenge.example.org."))
+	assert.Equal(t, []string{"1.1.1.1:53"}, getZoneNameservers("_acme-challenge.example.com."))
+}
+
+func Test_dohResolver_Exchange(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost || req.Header.Get("Content-Type") != dohMediaType {
+			http.Error(rw, "invalid request", http.StatusBadRequest)
+			return
+		}
+
+		raw, err := io.ReadAll(req.Body)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		query := new(dns.Msg)
+		if err = query.Unpack(raw); err != nil || query.Id != 0 {
+			http.Error(rw, "invalid query", http.StatusBadRequest)
+			return
+		}
+
+		reply := newTXTReply(query, "value")
+
+		out, err := reply.Pack()
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Header().Set("Content-Type", dohMediaType)
+		_, _ = rw.Write(out)
+	}))
+	t.Cleanup(server.Close)
+
+	resolver := &dohResolver{endpoint: server.URL + "/dns-query", client: server.Client()}
+
+	m := createDNSMsg("_acme-challenge.example.com.", dns.TypeTXT, true)
+
+	in, err := resolver.Exchange(m)
+	require.NoError(t, err)
+
+	assert.Equal(t, m.Id, in.Id)
+	require.Len(t, in.Answer, 1)
+	assert.Equal(t, []string{"value"}, in.Answer[0].(*dns.TXT).Txt)
+}
+
+func TestCheckDNSPropagation_zoneNameservers(t *testing.T) {
+	t.Cleanup(ClearZoneNameservers)
+
+	addr := runTXTServer(t, "udp", "value")
+
+	require.NoError(t, AddZoneNameservers("lego.test", []string{"udp://" + addr})(nil))
+
+	check := newPreCheck()
+
+	ok, err := check.checkDNSPropagation("_acme-challenge.lego.test.", "value")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = check.checkDNSPropagation("_acme-challenge.lego.test.", "other")
+	require.Error(t, err)
+	assert.False(t, ok)
+}
+
+func TestCheckDNSPropagation_rootZoneNameservers(t *testing.T) {
+	t.Cleanup(ClearZoneNameservers)
+
+	// neither the recursive nameservers nor the port 53 are reachable.
+	originalNameservers, originalTimeout := recursiveNameservers, dnsTimeout
+	t.Cleanup(func() {
+		recursiveNameservers, dnsTimeout = originalNameservers, originalTimeout
+	})
+
+	recursiveNameservers = []string{"127.0.0.1:1"}
+	dnsTimeout = 500 * time.Millisecond
+
+	addr := runTXTServer(t, "tcp", "value")
+
+	require.NoError(t, AddZoneNameservers(".", []string{"tcp://" + addr})(nil))
+
+	check := newPreCheck()
+
+	ok, err := check.checkDNSPropagation("_acme-challenge.example.com.", "value")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func Test_classicResolver_Exchange_tcp(t *testing.T) {
+	addr := runTXTServer(t, "tcp", "value")
+
+	resolver, err := NewResolver("tcp://" + addr)
+	require.NoError(t, err)
+
+	in, err := resolver.Exchange(createDNSMsg("_acme-challenge.example.com.", dns.TypeTXT, true))
+	require.NoError(t, err)
+
+	require.Len(t, in.Answer, 1)
+	assert.Equal(t, []string{"value"}, in.Answer[0].(*dns.TXT).Txt)
+}
+
+// runTXTServer runs a DNS server answering value to all the TXT queries.
+func runTXTServer(t *testing.T, network, value string) string {
+	t.Helper()
+
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		_ = w.WriteMsg(newTXTReply(req, value))
+	})
+
+	started := make(chan struct{})
+	server := &dns.Server{Net: network, Handler: handler, NotifyStartedFunc: func() { close(started) }}
+
+	switch network {
+	case "udp":
+		pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		server.PacketConn = pc
+	default:
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		server.Listener = l
+	}
+
+	go func() { _ = server.ActivateAndServe() }()
+	t.Cleanup(func() { _ = server.Shutdown() })
+
+	<-started
+
+	if server.PacketConn != nil {
+		return server.PacketConn.LocalAddr().String()
+	}
+
+	return server.Listener.Addr().String()
+}
+
+func newTXTReply(req *dns.Msg, value string) *dns.Msg {
+	reply := new(dns.Msg)
+	reply.SetReply(req)
+
+	if len(req.Question) == 1 && req.Question[0].Qtype == dns.TypeTXT {
+		reply.Answer = append(reply.Answer, &dns.TXT{
+			Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 60},
+			Txt: []string{value},
+		})
+	}
+
+	return reply
+}
@@ -2,6 +2,7 @@ package http01
 
 import (
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
 )
@@ -54,7 +55,7 @@ func (m *hostMatcher) name() string {
 }
 
 func (m *hostMatcher) matches(r *http.Request, domain string) bool {
-	return strings.HasPrefix(r.Host, domain)
+	return matchDomain(r.Host, domain)
 }
 
 // hostMatcher checks whether the specified (*net/http.Request).Header value starts with a domain name.
@@ -65,7 +66,7 @@ func (m arbitraryMatcher) name() string {
 }
 
 func (m arbitraryMatcher) matches(r *http.Request, domain string) bool {
-	return strings.HasPrefix(r.Header.Get(m.name()), domain)
+	return matchDomain(r.Header.Get(m.name()), domain)
 }
 
 // forwardedMatcher checks whether the Forwarded header contains a "host" element starting with a domain name.
@@ -87,7 +88,23 @@ func (m *forwardedMatcher) matches(r *http.Request, domain string) bool {
 	}
 
 	host := fwds[0]["host"]
-	return strings.HasPrefix(host, domain)
+	return matchDomain(host, domain)
+}
+
+// matchDomain checks whether a host value starts with a domain name.
+// For IP identifiers (RFC 8738), the host value is parsed instead,
+// because IPv6 literals are enclosed in brackets and may be followed by a port.
+func matchDomain(host, domain string) bool {
+	ip := net.ParseIP(domain)
+	if ip == nil {
+		return strings.HasPrefix(host, domain)
+	}
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return ip.Equal(net.ParseIP(strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")))
 }
 
 // parsing requires some form of state machine.
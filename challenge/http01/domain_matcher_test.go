@@ -84,3 +84,32 @@ func TestParseForwardedHeader(t *testing.T) {
 		})
 	}
 }
+
+func Test_matchDomain(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		host   string
+		domain string
+		want   bool
+	}{
+		{desc: "domain", host: "example.com", domain: "example.com", want: true},
+		{desc: "domain with port", host: "example.com:80", domain: "example.com", want: true},
+		{desc: "other domain", host: "example.org", domain: "example.com"},
+		{desc: "IPv4", host: "192.0.2.1", domain: "192.0.2.1", want: true},
+		{desc: "IPv4 with port", host: "192.0.2.1:80", domain: "192.0.2.1", want: true},
+		{desc: "other IPv4", host: "192.0.2.10", domain: "192.0.2.1"},
+		{desc: "IPv6", host: "[2001:db8::1]", domain: "2001:db8::1", want: true},
+		{desc: "IPv6 with port", host: "[2001:db8::1]:80", domain: "2001:db8::1", want: true},
+		{desc: "IPv6 not canonical", host: "[2001:DB8:0::1]", domain: "2001:db8::1", want: true},
+		{desc: "other IPv6", host: "[2001:db8::2]", domain: "2001:db8::1"},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.want, matchDomain(test.host, test.domain))
+		})
+	}
+}
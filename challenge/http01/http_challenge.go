@@ -1,6 +1,7 @@
 package http01
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/LukasDeco/lego/v4/acme"
@@ -9,8 +10,24 @@ import (
 	"github.com/LukasDeco/lego/v4/log"
 )
 
+// ValidateFunc validates a challenge with the ACME server.
 type ValidateFunc func(core *api.Core, domain string, chlng acme.Challenge) error
 
+// ValidateContextFunc validates a challenge with the ACME server.
+// The validation is aborted if the context is canceled.
+type ValidateContextFunc func(ctx context.Context, core *api.Core, domain string, chlng acme.Challenge) error
+
+// withContext adapts a ValidateFunc to a ValidateContextFunc (the context is ignored).
+func (f ValidateFunc) withContext() ValidateContextFunc {
+	if f == nil {
+		return nil
+	}
+
+	return func(_ context.Context, core *api.Core, domain string, chlng acme.Challenge) error {
+		return f(core, domain, chlng)
+	}
+}
+
 // ChallengePath returns the URL path for the `http-01` challenge.
 func ChallengePath(token string) string {
 	return "/.well-known/acme-challenge/" + token
@@ -18,11 +35,16 @@ func ChallengePath(token string) string {
 
 type Challenge struct {
 	core     *api.Core
-	validate ValidateFunc
+	validate ValidateContextFunc
 	provider challenge.Provider
 }
 
 func NewChallenge(core *api.Core, validate ValidateFunc, provider challenge.Provider) *Challenge {
+	return NewChallengeContext(core, validate.withContext(), provider)
+}
+
+// NewChallengeContext creates a solver for the HTTP-01 challenge, with a validation aborted if the context is canceled.
+func NewChallengeContext(core *api.Core, validate ValidateContextFunc, provider challenge.Provider) *Challenge {
 	return &Challenge{
 		core:     core,
 		validate: validate,
@@ -35,8 +57,14 @@ func (c *Challenge) SetProvider(provider challenge.Provider) {
 }
 
 func (c *Challenge) Solve(authz acme.Authorization) error {
+	return c.SolveContext(context.Background(), authz)
+}
+
+// SolveContext manages the provider to validate and solve the challenge.
+// The validation is aborted if the context is canceled.
+func (c *Challenge) SolveContext(ctx context.Context, authz acme.Authorization) error {
 	domain := challenge.GetTargetedDomain(authz)
-	log.Infof("[%s] acme: Trying to solve HTTP-01", domain)
+	c.core.Logger().Info("acme: Trying to solve HTTP-01", log.Domain(domain), log.Challenge(challenge.HTTP01.String()))
 
 	chlng, err := challenge.FindChallenge(challenge.HTTP01, authz)
 	if err != nil {
@@ -49,17 +77,17 @@ func (c *Challenge) Solve(authz acme.Authorization) error {
 		return err
 	}
 
-	err = c.provider.Present(authz.Identifier.Value, chlng.Token, keyAuth)
+	err = challenge.PresentWithContext(ctx, c.provider, authz.Identifier.Value, chlng.Token, keyAuth)
 	if err != nil {
 		return fmt.Errorf("[%s] acme: error presenting token: %w", domain, err)
 	}
 	defer func() {
 		err := c.provider.CleanUp(authz.Identifier.Value, chlng.Token, keyAuth)
 		if err != nil {
-			log.Warnf("[%s] acme: cleaning up failed: %v", domain, err)
+			c.core.Logger().Warn("acme: cleaning up failed", log.Domain(domain), log.Challenge(challenge.HTTP01.String()), log.Err(err))
 		}
 	}()
 
 	chlng.KeyAuthorization = keyAuth
-	return c.validate(c.core, domain, chlng)
+	return c.validate(ctx, c.core, domain, chlng)
 }
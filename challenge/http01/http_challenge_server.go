@@ -87,13 +87,18 @@ func (s *ProviderServer) CleanUp(domain, token, keyAuth string) error {
 // - "Forwarded" will look for a Forwarded header, and inspect it according to https://www.rfc-editor.org/rfc/rfc7239.html
 // - any other value will check the header value with the same name.
 func (s *ProviderServer) SetProxyHeader(headerName string) {
+	s.matcher = newDomainMatcher(headerName)
+}
+
+// newDomainMatcher creates the domainMatcher of a header (see ProviderServer.SetProxyHeader).
+func newDomainMatcher(headerName string) domainMatcher {
 	switch h := textproto.CanonicalMIMEHeaderKey(headerName); h {
 	case "", "Host":
-		s.matcher = &hostMatcher{}
+		return &hostMatcher{}
 	case "Forwarded":
-		s.matcher = &forwardedMatcher{}
+		return &forwardedMatcher{}
 	default:
-		s.matcher = arbitraryMatcher(h)
+		return arbitraryMatcher(h)
 	}
 }
 
@@ -0,0 +1,210 @@
+package http01
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/LukasDeco/lego/v4/log"
+)
+
+// SharedServer implements ChallengeProvider for `http-01` challenge with a long-lived HTTP server.
+// Unlike ProviderServer, it holds a single listener for all the challenges:
+// the challenges of any number of concurrent orders (and Certifiers) are served at the same time.
+//
+// The server is started with Start, or the Handler can be mounted in an existing HTTP server.
+type SharedServer struct {
+	address string
+	network string // must be valid argument to net.Listen
+
+	socketMode fs.FileMode
+
+	matcher domainMatcher
+
+	mu         sync.RWMutex
+	challenges map[string]*sharedChallenge
+
+	muServer sync.Mutex
+	server   *http.Server
+	done     chan struct{}
+}
+
+// sharedChallenge a presented challenge.
+// The same challenge can be presented by several orders at the same time (i.e. the same authorization):
+// it is removed once all the orders have cleaned it.
+type sharedChallenge struct {
+	domain  string
+	keyAuth string
+	refs    int
+}
+
+// NewSharedServer creates a new SharedServer on the selected interface and port.
+// Setting iface and / or port to an empty string will make the server fall back to
+// the "any" interface and port 80 respectively.
+func NewSharedServer(iface, port string) *SharedServer {
+	if port == "" {
+		port = "80"
+	}
+
+	return &SharedServer{
+		network:    "tcp",
+		address:    net.JoinHostPort(iface, port),
+		matcher:    &hostMatcher{},
+		challenges: map[string]*sharedChallenge{},
+	}
+}
+
+// NewUnixSharedServer creates a new SharedServer listening on a Unix socket.
+func NewUnixSharedServer(socketPath string, mode fs.FileMode) *SharedServer {
+	return &SharedServer{
+		network:    "unix",
+		address:    socketPath,
+		socketMode: mode,
+		matcher:    &hostMatcher{},
+		challenges: map[string]*sharedChallenge{},
+	}
+}
+
+func (s *SharedServer) GetAddress() string {
+	return s.address
+}
+
+// SetProxyHeader changes the validation of incoming requests (see ProviderServer.SetProxyHeader).
+// It must be called before the server is started.
+func (s *SharedServer) SetProxyHeader(headerName string) {
+	s.matcher = newDomainMatcher(headerName)
+}
+
+// Start starts the HTTP server.
+// It is not needed when the Handler is mounted in another HTTP server.
+func (s *SharedServer) Start() error {
+	s.muServer.Lock()
+	defer s.muServer.Unlock()
+
+	if s.server != nil {
+		return errors.New("the HTTP server is already started")
+	}
+
+	listener, err := net.Listen(s.network, s.GetAddress())
+	if err != nil {
+		return fmt.Errorf("could not start HTTP server for challenge: %w", err)
+	}
+
+	if s.network == "unix" {
+		if err = os.Chmod(s.address, s.socketMode); err != nil {
+			_ = listener.Close()
+			return fmt.Errorf("chmod %s: %w", s.address, err)
+		}
+	}
+
+	s.server = &http.Server{Handler: s.Handler()}
+	s.done = make(chan struct{})
+
+	go func(server *http.Server, done chan struct{}) {
+		defer close(done)
+
+		err := server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) && !strings.Contains(err.Error(), "use of closed network connection") {
+			log.Println(err)
+		}
+	}(s.server, s.done)
+
+	return nil
+}
+
+// Close stops the HTTP server started by Start.
+func (s *SharedServer) Close() error {
+	s.muServer.Lock()
+	defer s.muServer.Unlock()
+
+	if s.server == nil {
+		return nil
+	}
+
+	err := s.server.Close()
+	<-s.done
+
+	s.server = nil
+
+	return err
+}
+
+// Present makes the token available at `ChallengePath(token)` for web requests.
+// The server must be started (see Start), or its Handler mounted in another HTTP server.
+func (s *SharedServer) Present(domain, token, keyAuth string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if chlg, ok := s.challenges[token]; ok && chlg.domain == domain && chlg.keyAuth == keyAuth {
+		chlg.refs++
+		return nil
+	}
+
+	s.challenges[token] = &sharedChallenge{domain: domain, keyAuth: keyAuth, refs: 1}
+
+	return nil
+}
+
+// CleanUp removes the token from `ChallengePath(token)`.
+func (s *SharedServer) CleanUp(domain, token, keyAuth string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chlg, ok := s.challenges[token]
+	if !ok || chlg.domain != domain || chlg.keyAuth != keyAuth {
+		return nil
+	}
+
+	chlg.refs--
+	if chlg.refs <= 0 {
+		delete(s.challenges, token)
+	}
+
+	return nil
+}
+
+// Handler returns the handler serving the presented challenges.
+// It handles the requests to `ChallengePath(token)`, and replies 404 to the other requests.
+func (s *SharedServer) Handler() http.Handler {
+	return http.HandlerFunc(s.serveHTTP)
+}
+
+func (s *SharedServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	prefix := ChallengePath("")
+	token := strings.TrimPrefix(r.URL.Path, prefix)
+	if !strings.HasPrefix(r.URL.Path, prefix) || token == "" || strings.Contains(token, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.mu.RLock()
+	chlg, ok := s.challenges[token]
+	var domain, keyAuth string
+	if ok {
+		domain, keyAuth = chlg.domain, chlg.keyAuth
+	}
+	s.mu.RUnlock()
+
+	// The incoming request will be validated to prevent DNS rebind attacks.
+	// We only respond with the keyAuth, when we're receiving a GET requests with
+	// the "Host" header matching the domain (the latter is configurable though SetProxyHeader).
+	if !ok || r.Method != http.MethodGet || !s.matcher.matches(r, domain) {
+		log.Warnf("Received request for domain %s with method %s but the domain did not match any challenge. Please ensure you are passing the %s header properly.", r.Host, r.Method, s.matcher.name())
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	_, err := w.Write([]byte(keyAuth))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Infof("[%s] Served key authentication", domain)
+}
@@ -0,0 +1,181 @@
+package http01
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/LukasDeco/lego/v4/acme"
+	"github.com/LukasDeco/lego/v4/acme/api"
+	"github.com/LukasDeco/lego/v4/challenge"
+	"github.com/LukasDeco/lego/v4/platform/tester"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharedServer_concurrentChallenges(t *testing.T) {
+	server := NewSharedServer("", "23460")
+	require.NoError(t, server.Start())
+	t.Cleanup(func() { _ = server.Close() })
+
+	validate := func(_ *api.Core, domain string, chlng acme.Challenge) error {
+		uri := "http://localhost" + server.GetAddress() + ChallengePath(chlng.Token)
+
+		req, err := http.NewRequest(http.MethodGet, uri, http.NoBody)
+		if err != nil {
+			return err
+		}
+
+		req.Host = domain
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+
+		if string(body) != chlng.KeyAuthorization {
+			return fmt.Errorf("got %q, want %q", string(body), chlng.KeyAuthorization)
+		}
+
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+
+	for i := 0; i < len(errs); i++ {
+		privateKey, err := rsa.GenerateKey(rand.Reader, 512)
+		require.NoError(t, err, "Could not generate test key")
+
+		// one ACME server and account by order: the key authorizations are different.
+		_, apiURL := tester.SetupFakeAPI(t)
+
+		core, err := api.New(http.DefaultClient, "lego-test", apiURL+"/dir", "", privateKey)
+		require.NoError(t, err)
+
+		solver := NewChallenge(core, validate, server)
+
+		authz := acme.Authorization{
+			Identifier: acme.Identifier{Value: fmt.Sprintf("%d.example.com", i)},
+			Challenges: []acme.Challenge{
+				{Type: challenge.HTTP01.String(), Token: fmt.Sprintf("token%d", i)},
+			},
+		}
+
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			errs[i] = solver.Solve(authz)
+		}(i)
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+
+	// all the challenges have been cleaned.
+	assert.Empty(t, server.challenges)
+}
+
+func TestSharedServer_Handler(t *testing.T) {
+	server := NewSharedServer("", "")
+
+	require.NoError(t, server.Present("example.com", "token", "keyAuth"))
+	// the same challenge presented by a second order.
+	require.NoError(t, server.Present("example.com", "token", "keyAuth"))
+
+	testCases := []struct {
+		desc         string
+		method       string
+		host         string
+		path         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			desc:         "challenge",
+			method:       http.MethodGet,
+			host:         "example.com",
+			path:         ChallengePath("token"),
+			expectedCode: http.StatusOK,
+			expectedBody: "keyAuth",
+		},
+		{
+			desc:         "domain mismatch",
+			method:       http.MethodGet,
+			host:         "example.org",
+			path:         ChallengePath("token"),
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			desc:         "unknown token",
+			method:       http.MethodGet,
+			host:         "example.com",
+			path:         ChallengePath("other"),
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			desc:         "method",
+			method:       http.MethodPost,
+			host:         "example.com",
+			path:         ChallengePath("token"),
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			desc:         "other path",
+			method:       http.MethodGet,
+			host:         "example.com",
+			path:         "/token",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(test.method, "http://"+test.host+test.path, http.NoBody)
+			rec := httptest.NewRecorder()
+
+			server.Handler().ServeHTTP(rec, req)
+
+			assert.Equal(t, test.expectedCode, rec.Code)
+
+			if test.expectedBody != "" {
+				assert.Equal(t, test.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestSharedServer_CleanUp(t *testing.T) {
+	server := NewSharedServer("", "")
+
+	require.NoError(t, server.Present("example.com", "token", "keyAuth"))
+	require.NoError(t, server.Present("example.com", "token", "keyAuth"))
+
+	require.NoError(t, server.CleanUp("example.com", "token", "keyAuth"))
+
+	// still used by the second order.
+	assert.Contains(t, server.challenges, "token")
+
+	require.NoError(t, server.CleanUp("example.com", "token", "keyAuth"))
+
+	assert.Empty(t, server.challenges)
+}
@@ -119,6 +119,39 @@ func TestChallenge(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestChallengeContext(t *testing.T) {
+	_, apiURL := tester.SetupFakeAPI(t)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 512)
+	require.NoError(t, err, "Could not generate test key")
+
+	core, err := api.New(http.DefaultClient, "lego-test", apiURL+"/dir", "", privateKey)
+	require.NoError(t, err)
+
+	type ctxKey struct{}
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+
+	validate := func(ctx context.Context, _ *api.Core, _ string, _ acme.Challenge) error {
+		assert.Equal(t, "value", ctx.Value(ctxKey{}))
+		return nil
+	}
+
+	solver := NewChallengeContext(core, validate, NewProviderServer("", "23461"))
+
+	authz := acme.Authorization{
+		Identifier: acme.Identifier{
+			Value: "localhost:23461",
+		},
+		Challenges: []acme.Challenge{
+			{Type: challenge.HTTP01.String(), Token: "http-ctx"},
+		},
+	}
+
+	err = solver.SolveContext(ctx, authz)
+	require.NoError(t, err)
+}
+
 func TestChallengeUnix(t *testing.T) {
 	if runtime.GOOS != "linux" {
 		t.Skip("only for UNIX systems")
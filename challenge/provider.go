@@ -1,6 +1,9 @@
 package challenge
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // Provider enables implementing a custom challenge
 // provider. Present presents the solution to a challenge available to
@@ -26,3 +29,67 @@ type ProviderTimeout interface {
 	Provider
 	Timeout() (timeout, interval time.Duration)
 }
+
+// ProviderContext allows for implementing a
+// Provider where the presentation of the solution can be canceled.
+// If an implementor of a Provider provides a PresentContext method,
+// it will be used instead of Present when a challenge is solved with a context.
+//
+// CleanUp is always called without a context:
+// the cleaning must be done even if the context has been canceled.
+type ProviderContext interface {
+	Provider
+	PresentContext(ctx context.Context, domain, token, keyAuth string) error
+}
+
+// AccountProvider allows for implementing a
+// Provider which receives the URL of the account for the dns-account-01 challenge:
+// the TXT record is scoped to the account (see dns01.GetAccountChallengeInfo).
+// If an implementor of a Provider provides PresentAccount and CleanUpAccount methods,
+// they will be used instead of Present and CleanUp for the dns-account-01 challenge.
+// The other providers receive an account domain instead of the domain (see dns01.AccountDomain).
+type AccountProvider interface {
+	Provider
+	PresentAccount(domain, token, keyAuth, accountURL string) error
+	CleanUpAccount(domain, token, keyAuth, accountURL string) error
+}
+
+// Record a challenge record to present (or clean) with a BatchProvider.
+// The fields are the arguments of Provider.Present,
+// and the URL of the account for the dns-account-01 challenge (see AccountProvider).
+type Record struct {
+	Domain  string
+	Token   string
+	KeyAuth string
+
+	// AccountURL the URL of the account, only for the dns-account-01 challenge.
+	AccountURL string
+}
+
+// BatchProvider allows for implementing a
+// Provider where several challenge records can be presented
+// (and cleaned) at once, e.g. with a single change batch of the DNS API.
+// If an implementor of a Provider provides PresentBatch and CleanUpBatch methods,
+// they will be used instead of Present and CleanUp when the challenges of several authorizations are solved together.
+//
+// The records can belong to different zones:
+// the implementors group them by zone, and send one change per zone.
+type BatchProvider interface {
+	Provider
+	PresentBatch(records []Record) error
+	CleanUpBatch(records []Record) error
+}
+
+// PresentWithContext presents the solution to a challenge,
+// using PresentContext if the provider implements ProviderContext.
+func PresentWithContext(ctx context.Context, provider Provider, domain, token, keyAuth string) error {
+	if p, ok := provider.(ProviderContext); ok {
+		return p.PresentContext(ctx, domain, token, keyAuth)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return provider.Present(domain, token, keyAuth)
+}
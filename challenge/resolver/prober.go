@@ -1,7 +1,10 @@
 package resolver
 
 import (
+	"context"
 	"fmt"
+	"runtime/debug"
+	"sync"
 	"time"
 
 	"github.com/LukasDeco/lego/v4/acme"
@@ -14,25 +17,48 @@ type solver interface {
 	Solve(authorization acme.Authorization) error
 }
 
+// Interface for challenge solvers which can be canceled through a context.
+type solverContext interface {
+	SolveContext(ctx context.Context, authorization acme.Authorization) error
+}
+
 // Interface for challenges like dns, where we can set a record in advance for ALL challenges.
 // This saves quite a bit of time vs creating the records and solving them serially.
 type preSolver interface {
 	PreSolve(authorization acme.Authorization) error
 }
 
+// Interface for preSolvers which can be canceled through a context.
+type preSolverContext interface {
+	PreSolveContext(ctx context.Context, authorization acme.Authorization) error
+}
+
+// Interface for preSolvers which can present the challenges of several authorizations at once.
+// The returned errors are in the same order as the authorizations.
+type batchPreSolver interface {
+	PreSolveBatch(ctx context.Context, authorizations []acme.Authorization) []error
+}
+
 // Interface for challenges like dns, where we can solve all the challenges before to delete them.
 type cleanup interface {
 	CleanUp(authorization acme.Authorization) error
 }
 
+// Interface for cleanups which can clean the challenges of several authorizations at once.
+// The returned errors are in the same order as the authorizations.
+type batchCleanup interface {
+	CleanUpBatch(authorizations []acme.Authorization) []error
+}
+
 type sequential interface {
 	Sequential() (bool, time.Duration)
 }
 
-// an authz with the solver we have chosen and the index of the challenge associated with it.
+// an authz with the solver we have chosen and the type of the challenge associated with it.
 type selectedAuthSolver struct {
-	authz  acme.Authorization
-	solver solver
+	authz    acme.Authorization
+	solver   solver
+	chlgType challenge.Type
 }
 
 type Prober struct {
@@ -46,8 +72,16 @@ func NewProber(solverManager *SolverManager) *Prober {
 }
 
 // Solve Looks through the challenge combinations to find a solvable match.
-// Then solves the challenges in series and returns.
+// Then solves the challenges concurrently (within the limits defined by the SolverManager) and returns.
 func (p *Prober) Solve(authorizations []acme.Authorization) error {
+	return p.SolveContext(context.Background(), authorizations)
+}
+
+// SolveContext Looks through the challenge combinations to find a solvable match.
+// Then solves the challenges concurrently (within the limits defined by the SolverManager) and returns.
+// The challenges not yet solved are aborted if the context is canceled,
+// but the cleanup of the presented challenges is always done, even if a solver panics.
+func (p *Prober) SolveContext(ctx context.Context, authorizations []acme.Authorization) error {
 	failures := make(obtainError)
 
 	var authSolvers []*selectedAuthSolver
@@ -59,12 +93,12 @@ func (p *Prober) Solve(authorizations []acme.Authorization) error {
 		domain := challenge.GetTargetedDomain(authz)
 		if authz.Status == acme.StatusValid {
 			// Boulder might recycle recent validated authz (see issue #267)
-			log.Infof("[%s] acme: authorization already valid; skipping challenge", domain)
+			p.logger().Info("acme: authorization already valid; skipping challenge", log.Domain(domain))
 			continue
 		}
 
-		if solvr := p.solverManager.chooseSolver(authz); solvr != nil {
-			authSolver := &selectedAuthSolver{authz: authz, solver: solvr}
+		if chlgType, solvr := p.solverManager.chooseSolver(authz); solvr != nil {
+			authSolver := &selectedAuthSolver{authz: authz, solver: solvr, chlgType: chlgType}
 
 			switch s := solvr.(type) {
 			case sequential:
@@ -81,9 +115,9 @@ func (p *Prober) Solve(authorizations []acme.Authorization) error {
 		}
 	}
 
-	parallelSolve(authSolvers, failures)
+	p.parallelSolve(ctx, newLimiter(p.solverManager), authSolvers, failures)
 
-	sequentialSolve(authSolversSequential, failures)
+	p.sequentialSolve(ctx, authSolversSequential, failures)
 
 	// Be careful not to return an empty failures map,
 	// for even an empty obtainError is a non-nil error value
@@ -93,81 +127,315 @@ func (p *Prober) Solve(authorizations []acme.Authorization) error {
 	return nil
 }
 
-func sequentialSolve(authSolvers []*selectedAuthSolver, failures obtainError) {
+func (p *Prober) sequentialSolve(ctx context.Context, authSolvers []*selectedAuthSolver, failures obtainError) {
 	for i, authSolver := range authSolvers {
 		// Submit the challenge
 		domain := challenge.GetTargetedDomain(authSolver.authz)
 
-		if solvr, ok := authSolver.solver.(preSolver); ok {
-			err := solvr.PreSolve(authSolver.authz)
+		if err := ctx.Err(); err != nil {
+			failures[domain] = err
+			continue
+		}
+
+		if _, ok := authSolver.solver.(preSolver); ok {
+			err := protect(func() error { return preSolve(ctx, authSolver.solver, authSolver.authz) })
 			if err != nil {
 				failures[domain] = err
-				cleanUp(authSolver.solver, authSolver.authz)
+				p.cleanUp(authSolver)
 				continue
 			}
 		}
 
 		// Solve challenge
-		err := authSolver.solver.Solve(authSolver.authz)
+		err := protect(func() error { return solve(ctx, authSolver.solver, authSolver.authz) })
 		if err != nil {
 			failures[domain] = err
-			cleanUp(authSolver.solver, authSolver.authz)
+			p.cleanUp(authSolver)
 			continue
 		}
 
 		// Clean challenge
-		cleanUp(authSolver.solver, authSolver.authz)
+		p.cleanUp(authSolver)
 
 		if len(authSolvers)-1 > i {
 			solvr := authSolver.solver.(sequential)
 			_, interval := solvr.Sequential()
-			log.Infof("sequence: wait for %s", interval)
-			time.Sleep(interval)
+			p.logger().Info("sequence: wait for "+interval.String(), log.Challenge(string(authSolver.chlgType)))
+
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+			}
 		}
 	}
 }
 
-func parallelSolve(authSolvers []*selectedAuthSolver, failures obtainError) {
+func (p *Prober) parallelSolve(ctx context.Context, lim *limiter, authSolvers []*selectedAuthSolver, failures obtainError) {
+	// Clean all created TXT records
+	defer p.cleanUpAll(authSolvers)
+
 	// For all valid preSolvers, first submit the challenges so they have max time to propagate
+	preSolveAll(ctx, authSolvers, failures)
+
+	var toSolve []*selectedAuthSolver
 	for _, authSolver := range authSolvers {
-		authz := authSolver.authz
-		if solvr, ok := authSolver.solver.(preSolver); ok {
-			err := solvr.PreSolve(authz)
+		if failures[challenge.GetTargetedDomain(authSolver.authz)] != nil {
+			// already failed in previous loop
+			continue
+		}
+
+		toSolve = append(toSolve, authSolver)
+	}
+
+	// Finally solve all challenges for real
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, authSolver := range toSolve {
+		wg.Add(1)
+
+		go func(authSolver *selectedAuthSolver) {
+			defer wg.Done()
+
+			err := solveWithLimit(ctx, lim, authSolver)
+			if err != nil {
+				mu.Lock()
+				failures[challenge.GetTargetedDomain(authSolver.authz)] = err
+				mu.Unlock()
+			}
+		}(authSolver)
+	}
+
+	wg.Wait()
+}
+
+func solveWithLimit(ctx context.Context, lim *limiter, authSolver *selectedAuthSolver) error {
+	release, err := lim.acquire(ctx, authSolver.chlgType)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return protect(func() error { return solve(ctx, authSolver.solver, authSolver.authz) })
+}
+
+// preSolveAll presents the challenges of the preSolvers,
+// with a single call by solver when the solver supports it.
+func preSolveAll(ctx context.Context, authSolvers []*selectedAuthSolver, failures obtainError) {
+	for _, group := range groupBySolver(authSolvers) {
+		if solvr, ok := group.solver.(batchPreSolver); ok {
+			var errs []error
+			err := protect(func() error {
+				errs = solvr.PreSolveBatch(ctx, group.authorizations())
+				return nil
+			})
+
+			group.setErrors(failures, errs, err)
+
+			continue
+		}
+
+		if _, ok := group.solver.(preSolver); !ok {
+			continue
+		}
+
+		for _, authSolver := range group.authSolvers {
+			authz := authSolver.authz
+
+			err := protect(func() error { return preSolve(ctx, group.solver, authz) })
 			if err != nil {
 				failures[challenge.GetTargetedDomain(authz)] = err
 			}
 		}
 	}
+}
 
-	defer func() {
-		// Clean all created TXT records
-		for _, authSolver := range authSolvers {
-			cleanUp(authSolver.solver, authSolver.authz)
+// cleanUpAll cleans the challenges of all the solvers,
+// with a single call by solver when the solver supports it.
+func (p *Prober) cleanUpAll(authSolvers []*selectedAuthSolver) {
+	for _, group := range groupBySolver(authSolvers) {
+		solvr, ok := group.solver.(batchCleanup)
+		if !ok {
+			for _, authSolver := range group.authSolvers {
+				p.cleanUp(authSolver)
+			}
+
+			continue
 		}
-	}()
 
-	// Finally solve all challenges for real
+		var errs []error
+		err := protect(func() error {
+			errs = solvr.CleanUpBatch(group.authorizations())
+			return nil
+		})
+
+		failures := make(obtainError)
+		group.setErrors(failures, errs, err)
+
+		for domain, errC := range failures {
+			p.logger().Warn("acme: cleaning up failed", log.Domain(domain), log.Challenge(string(group.authSolvers[0].chlgType)), log.Err(errC))
+		}
+	}
+}
+
+// solverGroup the authorizations handled by the same solver.
+type solverGroup struct {
+	solver      solver
+	authSolvers []*selectedAuthSolver
+}
+
+// groupBySolver groups the authorizations by solver, in the order of the authorizations.
+func groupBySolver(authSolvers []*selectedAuthSolver) []*solverGroup {
+	var groups []*solverGroup
+	indexes := map[solver]int{}
+
 	for _, authSolver := range authSolvers {
-		authz := authSolver.authz
-		domain := challenge.GetTargetedDomain(authz)
-		if failures[domain] != nil {
-			// already failed in previous loop
-			continue
+		i, ok := indexes[authSolver.solver]
+		if !ok {
+			i = len(groups)
+			indexes[authSolver.solver] = i
+			groups = append(groups, &solverGroup{solver: authSolver.solver})
 		}
 
-		err := authSolver.solver.Solve(authz)
-		if err != nil {
+		groups[i].authSolvers = append(groups[i].authSolvers, authSolver)
+	}
+
+	return groups
+}
+
+func (g *solverGroup) authorizations() []acme.Authorization {
+	authzs := make([]acme.Authorization, 0, len(g.authSolvers))
+	for _, authSolver := range g.authSolvers {
+		authzs = append(authzs, authSolver.authz)
+	}
+
+	return authzs
+}
+
+// setErrors sets the errors of a batch call:
+// err (i.e. a panic) applies to all the authorizations, errs are in the order of the authorizations.
+func (g *solverGroup) setErrors(failures obtainError, errs []error, err error) {
+	for i, authSolver := range g.authSolvers {
+		domain := challenge.GetTargetedDomain(authSolver.authz)
+
+		switch {
+		case err != nil:
 			failures[domain] = err
+		case i < len(errs) && errs[i] != nil:
+			failures[domain] = errs[i]
 		}
 	}
 }
 
-func cleanUp(solvr solver, authz acme.Authorization) {
-	if solvr, ok := solvr.(cleanup); ok {
-		domain := challenge.GetTargetedDomain(authz)
-		err := solvr.CleanUp(authz)
+func preSolve(ctx context.Context, solvr solver, authz acme.Authorization) error {
+	if s, ok := solvr.(preSolverContext); ok {
+		return s.PreSolveContext(ctx, authz)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if s, ok := solvr.(preSolver); ok {
+		return s.PreSolve(authz)
+	}
+
+	return nil
+}
+
+func solve(ctx context.Context, solvr solver, authz acme.Authorization) error {
+	if s, ok := solvr.(solverContext); ok {
+		return s.SolveContext(ctx, authz)
+	}
+
+	return solvr.Solve(authz)
+}
+
+func (p *Prober) cleanUp(authSolver *selectedAuthSolver) {
+	if solvr, ok := authSolver.solver.(cleanup); ok {
+		domain := challenge.GetTargetedDomain(authSolver.authz)
+		err := protect(func() error { return solvr.CleanUp(authSolver.authz) })
 		if err != nil {
-			log.Warnf("[%s] acme: cleaning up failed: %v ", domain, err)
+			p.logger().Warn("acme: cleaning up failed", log.Domain(domain), log.Challenge(string(authSolver.chlgType)), log.Err(err))
+		}
+	}
+}
+
+func (p *Prober) logger() *log.FieldLogger {
+	return p.solverManager.core.Logger()
+}
+
+// protect calls f and converts a panic into an error,
+// so the other challenges are still solved and all the presented challenges are cleaned up.
+func protect(f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("acme: solver panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+
+	return f()
+}
+
+// limiter limits the number of authorizations solved at the same time, globally and per challenge type.
+type limiter struct {
+	global  chan struct{}
+	solvers map[challenge.Type]chan struct{}
+}
+
+func newLimiter(manager *SolverManager) *limiter {
+	lim := &limiter{solvers: map[challenge.Type]chan struct{}{}}
+
+	if manager.concurrency > 0 {
+		lim.global = make(chan struct{}, manager.concurrency)
+	}
+
+	for chlgType := range manager.solvers {
+		if limit := manager.getSolverConcurrency(chlgType); limit > 0 {
+			lim.solvers[chlgType] = make(chan struct{}, limit)
 		}
 	}
+
+	return lim
+}
+
+// acquire waits for a slot of the challenge type and a global slot, and returns the function to release them.
+// The slot of the challenge type is acquired first to not hold a global slot while waiting.
+func (l *limiter) acquire(ctx context.Context, chlgType challenge.Type) (func(), error) {
+	solverSlots := l.solvers[chlgType]
+
+	err := acquireSlot(ctx, solverSlots)
+	if err != nil {
+		return nil, err
+	}
+
+	err = acquireSlot(ctx, l.global)
+	if err != nil {
+		releaseSlot(solverSlots)
+		return nil, err
+	}
+
+	return func() {
+		releaseSlot(l.global)
+		releaseSlot(solverSlots)
+	}, nil
+}
+
+func acquireSlot(ctx context.Context, slots chan struct{}) error {
+	if slots == nil {
+		return ctx.Err()
+	}
+
+	select {
+	case slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func releaseSlot(slots chan struct{}) {
+	if slots != nil {
+		<-slots
+	}
 }
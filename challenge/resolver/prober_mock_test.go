@@ -1,6 +1,8 @@
 package resolver
 
 import (
+	"context"
+	"sync"
 	"time"
 
 	"github.com/LukasDeco/lego/v4/acme"
@@ -42,3 +44,107 @@ func createStubAuthorizationHTTP01(domain, status string) acme.Authorization {
 		},
 	}
 }
+
+// concurrentSolverMock records the maximum number of authorizations solved at the same time.
+type concurrentSolverMock struct {
+	mu       sync.Mutex
+	inFlight int
+	max      int
+	cleaned  []string
+	panics   map[string]bool
+}
+
+func (s *concurrentSolverMock) PreSolve(_ acme.Authorization) error {
+	return nil
+}
+
+func (s *concurrentSolverMock) Solve(authorization acme.Authorization) error {
+	s.mu.Lock()
+	s.inFlight++
+	if s.inFlight > s.max {
+		s.max = s.inFlight
+	}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.inFlight--
+		s.mu.Unlock()
+	}()
+
+	if s.panics[authorization.Identifier.Value] {
+		panic("solve panic " + authorization.Identifier.Value)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	return nil
+}
+
+func (s *concurrentSolverMock) CleanUp(authorization acme.Authorization) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cleaned = append(s.cleaned, authorization.Identifier.Value)
+
+	return nil
+}
+
+func createStubAuthorizationDNS01(domain, status string) acme.Authorization {
+	return acme.Authorization{
+		Status:  status,
+		Expires: time.Now(),
+		Identifier: acme.Identifier{
+			Type:  "dns",
+			Value: domain,
+		},
+		Challenges: []acme.Challenge{
+			{
+				Type: challenge.DNS01.String(),
+			},
+		},
+	}
+}
+
+// batchSolverMock presents and cleans all the authorizations at once.
+type batchSolverMock struct {
+	preSolveBatches [][]string
+	cleanUpBatches  [][]string
+	preSolve        map[string]error
+}
+
+func (s *batchSolverMock) PreSolve(_ acme.Authorization) error {
+	panic("PreSolve must not be called")
+}
+
+func (s *batchSolverMock) PreSolveBatch(_ context.Context, authorizations []acme.Authorization) []error {
+	var domains []string
+	var errs []error
+	for _, authz := range authorizations {
+		domains = append(domains, authz.Identifier.Value)
+		errs = append(errs, s.preSolve[authz.Identifier.Value])
+	}
+
+	s.preSolveBatches = append(s.preSolveBatches, domains)
+
+	return errs
+}
+
+func (s *batchSolverMock) Solve(_ acme.Authorization) error {
+	return nil
+}
+
+func (s *batchSolverMock) CleanUp(_ acme.Authorization) error {
+	panic("CleanUp must not be called")
+}
+
+func (s *batchSolverMock) CleanUpBatch(authorizations []acme.Authorization) []error {
+	var domains []string
+	for _, authz := range authorizations {
+		domains = append(domains, authz.Identifier.Value)
+	}
+
+	s.cleanUpBatches = append(s.cleanUpBatches, domains)
+
+	return make([]error, len(authorizations))
+}
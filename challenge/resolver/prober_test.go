@@ -1,11 +1,14 @@
 package resolver
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/LukasDeco/lego/v4/acme"
 	"github.com/LukasDeco/lego/v4/challenge"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -116,3 +119,144 @@ func TestProber_Solve(t *testing.T) {
 		})
 	}
 }
+
+func TestProber_SolveContext_canceled(t *testing.T) {
+	prober := &Prober{
+		solverManager: &SolverManager{solvers: map[challenge.Type]solver{
+			challenge.HTTP01: &preSolverMock{
+				preSolve: map[string]error{},
+				solve:    map[string]error{},
+				cleanUp:  map[string]error{},
+			},
+		}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	authz := []acme.Authorization{
+		createStubAuthorizationHTTP01("acme.wtf", acme.StatusProcessing),
+		createStubAuthorizationHTTP01("lego.wtf", acme.StatusProcessing),
+	}
+
+	err := prober.SolveContext(ctx, authz)
+	require.EqualError(t, err, `error: one or more domains had a problem:
+[acme.wtf] context canceled
+[lego.wtf] context canceled
+`)
+}
+
+func TestProber_SolveContext_concurrency(t *testing.T) {
+	testCases := []struct {
+		desc              string
+		concurrency       int
+		solverConcurrency int
+		expectedMax       int
+	}{
+		{
+			desc:        "global limit",
+			concurrency: 3,
+			expectedMax: 3,
+		},
+		{
+			desc:              "solver limit",
+			concurrency:       10,
+			solverConcurrency: 2,
+			expectedMax:       2,
+		},
+		{
+			desc:              "global limit lower than the solver limit",
+			concurrency:       1,
+			solverConcurrency: 4,
+			expectedMax:       1,
+		},
+		{
+			desc:        "no limit",
+			expectedMax: 8,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			mock := &concurrentSolverMock{}
+
+			manager := &SolverManager{
+				solvers:           map[challenge.Type]solver{challenge.DNS01: mock},
+				solverConcurrency: map[challenge.Type]int{},
+			}
+			manager.SetConcurrency(test.concurrency)
+
+			if test.solverConcurrency > 0 {
+				manager.SetSolverConcurrency(challenge.DNS01, test.solverConcurrency)
+			}
+
+			prober := &Prober{solverManager: manager}
+
+			var authz []acme.Authorization
+			for i := 0; i < 8; i++ {
+				authz = append(authz, createStubAuthorizationDNS01(fmt.Sprintf("%d.example.com", i), acme.StatusProcessing))
+			}
+
+			err := prober.SolveContext(context.Background(), authz)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expectedMax, mock.max)
+			assert.Len(t, mock.cleaned, 8)
+		})
+	}
+}
+
+func TestProber_SolveContext_panic(t *testing.T) {
+	mock := &concurrentSolverMock{
+		panics: map[string]bool{"lego.wtf": true},
+	}
+
+	prober := &Prober{
+		solverManager: &SolverManager{solvers: map[challenge.Type]solver{challenge.DNS01: mock}},
+	}
+
+	authz := []acme.Authorization{
+		createStubAuthorizationDNS01("acme.wtf", acme.StatusProcessing),
+		createStubAuthorizationDNS01("lego.wtf", acme.StatusProcessing),
+		createStubAuthorizationDNS01("mydomain.wtf", acme.StatusProcessing),
+	}
+
+	err := prober.SolveContext(context.Background(), authz)
+	require.Error(t, err)
+
+	var obtainErr obtainError
+	require.ErrorAs(t, err, &obtainErr)
+	require.Len(t, obtainErr, 1)
+	assert.ErrorContains(t, obtainErr["lego.wtf"], "acme: solver panic: solve panic lego.wtf")
+
+	assert.ElementsMatch(t, []string{"acme.wtf", "lego.wtf", "mydomain.wtf"}, mock.cleaned)
+}
+
+func TestProber_SolveContext_batch(t *testing.T) {
+	mock := &batchSolverMock{
+		preSolve: map[string]error{"lego.wtf": errors.New("preSolve error lego.wtf")},
+	}
+
+	prober := &Prober{
+		solverManager: &SolverManager{solvers: map[challenge.Type]solver{challenge.DNS01: mock}},
+	}
+
+	authz := []acme.Authorization{
+		createStubAuthorizationDNS01("acme.wtf", acme.StatusProcessing),
+		createStubAuthorizationDNS01("lego.wtf", acme.StatusProcessing),
+		createStubAuthorizationDNS01("mydomain.wtf", acme.StatusProcessing),
+	}
+
+	err := prober.SolveContext(context.Background(), authz)
+	require.EqualError(t, err, `error: one or more domains had a problem:
+[lego.wtf] preSolve error lego.wtf
+`)
+
+	expected := [][]string{{"acme.wtf", "lego.wtf", "mydomain.wtf"}}
+
+	assert.Equal(t, expected, mock.preSolveBatches)
+	assert.Equal(t, expected, mock.cleanUpBatches)
+}
@@ -1,10 +1,10 @@
 package resolver
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sort"
-	"strconv"
 	"time"
 
 	"github.com/LukasDeco/lego/v4/acme"
@@ -14,7 +14,7 @@ import (
 	"github.com/LukasDeco/lego/v4/challenge/http01"
 	"github.com/LukasDeco/lego/v4/challenge/tlsalpn01"
 	"github.com/LukasDeco/lego/v4/log"
-	"github.com/cenkalti/backoff/v4"
+	"github.com/LukasDeco/lego/v4/platform/wait"
 )
 
 type byType []acme.Challenge
@@ -23,60 +23,143 @@ func (a byType) Len() int           { return len(a) }
 func (a byType) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a byType) Less(i, j int) bool { return a[i].Type > a[j].Type }
 
+// defaultValidationPolling the default polling strategy used while the server validates a challenge.
+var defaultValidationPolling = wait.Strategy{
+	Timeout:         10 * time.Minute,
+	InitialInterval: 5 * time.Second,
+	MaxInterval:     50 * time.Second,
+	Multiplier:      1.5,
+}
+
+// defaultConcurrency the default maximum number of authorizations solved at the same time.
+const defaultConcurrency = 10
+
 type SolverManager struct {
-	core    *api.Core
-	solvers map[challenge.Type]solver
+	core       *api.Core
+	solvers    map[challenge.Type]solver
+	validation wait.Strategy
+
+	// concurrency the maximum number of authorizations solved at the same time (0: no limit).
+	concurrency int
+	// solverConcurrency the maximum number of authorizations solved at the same time by a solver (0: no limit).
+	solverConcurrency map[challenge.Type]int
 }
 
 func NewSolversManager(core *api.Core) *SolverManager {
 	return &SolverManager{
-		solvers: map[challenge.Type]solver{},
-		core:    core,
+		solvers:           map[challenge.Type]solver{},
+		core:              core,
+		validation:        defaultValidationPolling,
+		concurrency:       defaultConcurrency,
+		solverConcurrency: map[challenge.Type]int{},
+	}
+}
+
+// SetConcurrency sets the maximum number of authorizations solved (propagation checks and validations) at the same time,
+// for all the challenge types (0: no limit).
+func (c *SolverManager) SetConcurrency(limit int) {
+	c.concurrency = limit
+}
+
+// SetSolverConcurrency sets the maximum number of authorizations solved at the same time
+// by the solver (i.e. the provider) of a challenge type (0: only the global limit applies).
+//
+// By default, the challenges which are presented during the Solve step (HTTP-01, TLS-ALPN-01) are solved one at a time,
+// because their providers usually bind a port.
+// The challenges presented in advance (DNS-01, DNS-ACCOUNT-01) are only limited by the global limit.
+func (c *SolverManager) SetSolverConcurrency(chlgType challenge.Type, limit int) {
+	if c.solverConcurrency == nil {
+		c.solverConcurrency = map[challenge.Type]int{}
 	}
+
+	c.solverConcurrency[chlgType] = limit
+}
+
+// getSolverConcurrency returns the concurrency limit of the solver of a challenge type.
+func (c *SolverManager) getSolverConcurrency(chlgType challenge.Type) int {
+	if limit, ok := c.solverConcurrency[chlgType]; ok {
+		return limit
+	}
+
+	if _, ok := c.solvers[chlgType].(preSolver); ok {
+		return 0
+	}
+
+	return 1
+}
+
+// SetValidationPolling sets the polling strategy used while the server validates the challenges.
+// The zero fields of the strategy are replaced by the default values.
+func (c *SolverManager) SetValidationPolling(strategy wait.Strategy) {
+	c.validation = strategy.WithDefaults(defaultValidationPolling)
 }
 
 // SetHTTP01Provider specifies a custom provider p that can solve the given HTTP-01 challenge.
 func (c *SolverManager) SetHTTP01Provider(p challenge.Provider) error {
-	c.solvers[challenge.HTTP01] = http01.NewChallenge(c.core, validate, p)
+	c.setProviderLogger(p, challenge.HTTP01)
+	c.solvers[challenge.HTTP01] = http01.NewChallengeContext(c.core, c.validate, p)
 	return nil
 }
 
 // SetTLSALPN01Provider specifies a custom provider p that can solve the given TLS-ALPN-01 challenge.
 func (c *SolverManager) SetTLSALPN01Provider(p challenge.Provider) error {
-	c.solvers[challenge.TLSALPN01] = tlsalpn01.NewChallenge(c.core, validate, p)
+	c.setProviderLogger(p, challenge.TLSALPN01)
+	c.solvers[challenge.TLSALPN01] = tlsalpn01.NewChallengeContext(c.core, c.validate, p)
 	return nil
 }
 
 // SetDNS01Provider specifies a custom provider p that can solve the given DNS-01 challenge.
 func (c *SolverManager) SetDNS01Provider(p challenge.Provider, opts ...dns01.ChallengeOption) error {
-	c.solvers[challenge.DNS01] = dns01.NewChallenge(c.core, validate, p, opts...)
+	c.setProviderLogger(p, challenge.DNS01)
+	c.solvers[challenge.DNS01] = dns01.NewChallengeContext(c.core, c.validate, p, opts...)
+	return nil
+}
+
+// SetDNSAccount01Provider specifies a custom provider p that can solve the given DNS-ACCOUNT-01 challenge.
+// The provider can implement challenge.AccountProvider, see dns01.NewAccountChallenge.
+// The provider must not be the one of SetDNS01Provider: the logger of the provider is specific to the challenge type.
+func (c *SolverManager) SetDNSAccount01Provider(p challenge.Provider, opts ...dns01.ChallengeOption) error {
+	c.setProviderLogger(p, challenge.DNSAccount01)
+	c.solvers[challenge.DNSAccount01] = dns01.NewAccountChallenge(c.core, c.validate, p, opts...)
 	return nil
 }
 
+// setProviderLogger gives the logger of the core to the providers accepting a logger (see log.LoggerSetter).
+func (c *SolverManager) setProviderLogger(p challenge.Provider, chlgType challenge.Type) {
+	if setter, ok := p.(log.LoggerSetter); ok {
+		setter.SetLogger(c.core.Logger().With(log.Challenge(chlgType.String())))
+	}
+}
+
 // Remove removes a challenge type from the available solvers.
 func (c *SolverManager) Remove(chlgType challenge.Type) {
 	delete(c.solvers, chlgType)
 }
 
-// Checks all challenges from the server in order and returns the first matching solver.
-func (c *SolverManager) chooseSolver(authz acme.Authorization) solver {
+// validate validates a challenge with the polling strategy of the manager.
+func (c *SolverManager) validate(ctx context.Context, core *api.Core, domain string, chlg acme.Challenge) error {
+	return validate(ctx, core, c.validation, domain, chlg)
+}
+
+// Checks all challenges from the server in order and returns the first matching solver, and its challenge type.
+func (c *SolverManager) chooseSolver(authz acme.Authorization) (challenge.Type, solver) {
 	// Allow to have a deterministic challenge order
 	sort.Sort(byType(authz.Challenges))
 
 	domain := challenge.GetTargetedDomain(authz)
 	for _, chlg := range authz.Challenges {
 		if solvr, ok := c.solvers[challenge.Type(chlg.Type)]; ok {
-			log.Infof("[%s] acme: use %s solver", domain, chlg.Type)
-			return solvr
+			c.core.Logger().Info(fmt.Sprintf("acme: use %s solver", chlg.Type), log.Domain(domain), log.Challenge(chlg.Type))
+			return challenge.Type(chlg.Type), solvr
 		}
-		log.Infof("[%s] acme: Could not find solver for: %s", domain, chlg.Type)
+		c.core.Logger().Info("acme: Could not find solver for: "+chlg.Type, log.Domain(domain), log.Challenge(chlg.Type))
 	}
 
-	return nil
+	return "", nil
 }
 
-func validate(core *api.Core, domain string, chlg acme.Challenge) error {
-	chlng, err := core.Challenges.New(chlg.URL)
+func validate(ctx context.Context, core *api.Core, strategy wait.Strategy, domain string, chlg acme.Challenge) error {
+	chlng, err := core.Challenges.NewWithContext(ctx, chlg.URL)
 	if err != nil {
 		return fmt.Errorf("failed to initiate challenge: %w", err)
 	}
@@ -87,47 +170,43 @@ func validate(core *api.Core, domain string, chlg acme.Challenge) error {
 	}
 
 	if valid {
-		log.Infof("[%s] The server validated our request", domain)
+		core.Logger().Info("The server validated our request", log.Domain(domain), log.Challenge(chlg.Type))
 		return nil
 	}
 
-	ra, err := strconv.Atoi(chlng.RetryAfter)
-	if err != nil {
-		// The ACME server MUST return a Retry-After.
-		// If it doesn't, we'll just poll hard.
-		// Boulder does not implement the ability to retry challenges or the Retry-After header.
-		// https://github.com/letsencrypt/boulder/blob/master/docs/acme-divergences.md#section-82
-		ra = 5
-	}
-	initialInterval := time.Duration(ra) * time.Second
-
-	bo := backoff.NewExponentialBackOff()
-	bo.InitialInterval = initialInterval
-	bo.MaxInterval = 10 * initialInterval
-	bo.MaxElapsedTime = 100 * initialInterval
+	// The ACME server MUST return a Retry-After.
+	// If it doesn't, we'll just poll with the backoff of the strategy.
+	// Boulder does not implement the ability to retry challenges or the Retry-After header.
+	// https://github.com/letsencrypt/boulder/blob/master/docs/acme-divergences.md#section-82
+	// An invalid or missing Retry-After is ignored.
+	fallback, _ := wait.ParseRetryAfter(chlng.RetryAfter)
 
 	// After the path is sent, the ACME server will access our server.
 	// Repeatedly check the server for an updated status on our request.
-	operation := func() error {
-		authz, err := core.Authorizations.Get(chlng.AuthorizationURL)
+	return strategy.Poll(ctx, "validation", func() (bool, time.Duration, error) {
+		authz, err := core.Authorizations.GetWithContext(ctx, chlng.AuthorizationURL)
 		if err != nil {
-			return backoff.Permanent(err)
+			return false, 0, err
 		}
 
-		valid, err := checkAuthorizationStatus(authz)
+		valid, err := checkAuthorizationStatus(authz.Authorization)
 		if err != nil {
-			return backoff.Permanent(err)
+			return false, 0, wait.Permanent(err)
 		}
 
 		if valid {
-			log.Infof("[%s] The server validated our request", domain)
-			return nil
+			core.Logger().Info("The server validated our request", log.Domain(domain), log.Challenge(chlg.Type))
+			return true, 0, nil
 		}
 
-		return errors.New("the server didn't respond to our request")
-	}
+		delay, _ := wait.ParseRetryAfter(authz.RetryAfter)
+		if delay <= 0 {
+			// the Retry-After of the challenge is only used once.
+			delay, fallback = fallback, 0
+		}
 
-	return backoff.Retry(operation, bo)
+		return false, delay, nil
+	})
 }
 
 func checkChallengeStatus(chlng acme.ExtendedChallenge) (bool, error) {
@@ -1,6 +1,8 @@
 package resolver
 
 import (
+	"bytes"
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"fmt"
@@ -8,10 +10,14 @@ import (
 	"net/http"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/LukasDeco/lego/v4/acme"
 	"github.com/LukasDeco/lego/v4/acme/api"
+	"github.com/LukasDeco/lego/v4/challenge"
+	"github.com/LukasDeco/lego/v4/log"
 	"github.com/LukasDeco/lego/v4/platform/tester"
+	"github.com/LukasDeco/lego/v4/platform/wait"
 	"github.com/go-jose/go-jose/v3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -31,6 +37,89 @@ func TestByType(t *testing.T) {
 	assert.Equal(t, expected, challenges)
 }
 
+func TestSolverManager_chooseSolver_dnsAccount01(t *testing.T) {
+	_, apiURL := tester.SetupFakeAPI(t)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	core, err := api.New(http.DefaultClient, "lego-test", apiURL+"/dir", "", privateKey)
+	require.NoError(t, err)
+
+	manager := NewSolversManager(core)
+
+	require.NoError(t, manager.SetDNS01Provider(nil))
+
+	require.NoError(t, manager.SetDNSAccount01Provider(&loggerProvider{}))
+
+	authz := acme.Authorization{
+		Identifier: acme.Identifier{Value: "example.com"},
+		Challenges: []acme.Challenge{{Type: "dns-01"}, {Type: "dns-account-01"}},
+	}
+
+	// the account-scoped challenge is preferred when the CA offers it.
+	chlgType, solvr := manager.chooseSolver(authz)
+	assert.Equal(t, challenge.DNSAccount01, chlgType)
+	assert.Same(t, manager.solvers[challenge.DNSAccount01], solvr)
+
+	authz.Challenges = []acme.Challenge{{Type: "dns-01"}}
+
+	chlgType, solvr = manager.chooseSolver(authz)
+	assert.Equal(t, challenge.DNS01, chlgType)
+	assert.Same(t, manager.solvers[challenge.DNS01], solvr)
+}
+
+// loggerProvider a provider accepting a logger.
+type loggerProvider struct {
+	logger *log.FieldLogger
+}
+
+func (p *loggerProvider) Present(_, _, _ string) error { return nil }
+func (p *loggerProvider) CleanUp(_, _, _ string) error { return nil }
+
+func (p *loggerProvider) SetLogger(logger *log.FieldLogger) { p.logger = logger }
+
+func TestSolverManager_setProviderLogger(t *testing.T) {
+	_, apiURL := tester.SetupFakeAPI(t)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	core, err := api.New(http.DefaultClient, "lego-test", apiURL+"/dir", "", privateKey)
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	core.SetLogger(log.New(log.NewJSONHandler(buf, log.LevelInfo)))
+
+	manager := NewSolversManager(core)
+
+	provider := &loggerProvider{}
+	require.NoError(t, manager.SetDNS01Provider(provider))
+
+	require.NotNil(t, provider.logger)
+
+	provider.logger.Info("test")
+
+	assert.Contains(t, buf.String(), `"msg":"test","challenge":"dns-01"}`)
+
+	accountProvider := &loggerProvider{}
+	require.NoError(t, manager.SetDNSAccount01Provider(accountProvider))
+
+	accountProvider.logger.Info("account")
+	provider.logger.Info("test")
+
+	assert.Contains(t, buf.String(), `"msg":"account","challenge":"dns-account-01"}`)
+	assert.NotContains(t, buf.String(), `"msg":"test","challenge":"dns-account-01"}`)
+}
+
+// small values keep tests fast.
+var testValidationPolling = wait.Strategy{
+	Timeout:         5 * time.Second,
+	InitialInterval: 10 * time.Millisecond,
+	MaxInterval:     50 * time.Millisecond,
+	Multiplier:      2,
+}
+
 func TestValidate(t *testing.T) {
 	mux, apiURL := tester.SetupFakeAPI(t)
 
@@ -137,7 +226,7 @@ func TestValidate(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			statuses = test.statuses
 
-			err := validate(core, "example.com", acme.Challenge{Type: "http-01", Token: "token", URL: apiURL + "/chlg"})
+			err := validate(context.Background(), core, testValidationPolling, "example.com", acme.Challenge{Type: "http-01", Token: "token", URL: apiURL + "/chlg"})
 			if test.want == "" {
 				require.NoError(t, err)
 			} else {
@@ -148,6 +237,53 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestValidate_retryAfterAndTimeout(t *testing.T) {
+	mux, apiURL := tester.SetupFakeAPI(t)
+
+	privateKey, _ := rsa.GenerateKey(rand.Reader, 512)
+
+	mux.HandleFunc("/chlg", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Link", "<"+apiURL+`/my-authz>; rel="up"`)
+
+		err := tester.WriteJSONResponse(w, &acme.Challenge{Type: "http-01", Status: acme.StatusPending, URL: "http://example.com/", Token: "token"})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+
+	var calls []time.Time
+
+	mux.HandleFunc("/my-authz", func(w http.ResponseWriter, _ *http.Request) {
+		calls = append(calls, time.Now())
+
+		w.Header().Set("Retry-After", "1")
+
+		err := tester.WriteJSONResponse(w, acme.Authorization{Status: acme.StatusPending})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+
+	core, err := api.New(http.DefaultClient, "lego-test", apiURL+"/dir", "", privateKey)
+	require.NoError(t, err)
+
+	strategy := testValidationPolling
+	strategy.Timeout = 1500 * time.Millisecond
+
+	err = validate(context.Background(), core, strategy, "example.com", acme.Challenge{Type: "http-01", Token: "token", URL: apiURL + "/chlg"})
+	require.Error(t, err)
+
+	var timeoutErr *wait.TimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	assert.Equal(t, "validation", timeoutErr.Phase)
+
+	// the Retry-After (1s) is honored instead of the backoff of the strategy (10ms-50ms).
+	require.GreaterOrEqual(t, len(calls), 2)
+	assert.GreaterOrEqual(t, calls[1].Sub(calls[0]), time.Second)
+}
+
 // validateNoBody reads the http.Request POST body, parses the JWS and validates it to read the body.
 // If there is an error doing this,
 // or if the JWS body is not the empty JSON payload "{}" or a POST-as-GET payload "" an error is returned.
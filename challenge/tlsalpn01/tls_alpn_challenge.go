@@ -1,33 +1,58 @@
 package tlsalpn01
 
 import (
+	"context"
 	"crypto/rsa"
 	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"fmt"
+	"net"
+	"strings"
 
 	"github.com/LukasDeco/lego/v4/acme"
 	"github.com/LukasDeco/lego/v4/acme/api"
 	"github.com/LukasDeco/lego/v4/certcrypto"
 	"github.com/LukasDeco/lego/v4/challenge"
 	"github.com/LukasDeco/lego/v4/log"
+	"github.com/miekg/dns"
 )
 
 // idPeAcmeIdentifierV1 is the SMI Security for PKIX Certification Extension OID referencing the ACME extension.
 // Reference: https://www.rfc-editor.org/rfc/rfc8737.html#section-6.1
 var idPeAcmeIdentifierV1 = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 31}
 
+// ValidateFunc validates a challenge with the ACME server.
 type ValidateFunc func(core *api.Core, domain string, chlng acme.Challenge) error
 
+// ValidateContextFunc validates a challenge with the ACME server.
+// The validation is aborted if the context is canceled.
+type ValidateContextFunc func(ctx context.Context, core *api.Core, domain string, chlng acme.Challenge) error
+
+// withContext adapts a ValidateFunc to a ValidateContextFunc (the context is ignored).
+func (f ValidateFunc) withContext() ValidateContextFunc {
+	if f == nil {
+		return nil
+	}
+
+	return func(_ context.Context, core *api.Core, domain string, chlng acme.Challenge) error {
+		return f(core, domain, chlng)
+	}
+}
+
 type Challenge struct {
 	core     *api.Core
-	validate ValidateFunc
+	validate ValidateContextFunc
 	provider challenge.Provider
 }
 
 func NewChallenge(core *api.Core, validate ValidateFunc, provider challenge.Provider) *Challenge {
+	return NewChallengeContext(core, validate.withContext(), provider)
+}
+
+// NewChallengeContext creates a solver for the TLS-ALPN-01 challenge, with a validation aborted if the context is canceled.
+func NewChallengeContext(core *api.Core, validate ValidateContextFunc, provider challenge.Provider) *Challenge {
 	return &Challenge{
 		core:     core,
 		validate: validate,
@@ -41,8 +66,14 @@ func (c *Challenge) SetProvider(provider challenge.Provider) {
 
 // Solve manages the provider to validate and solve the challenge.
 func (c *Challenge) Solve(authz acme.Authorization) error {
+	return c.SolveContext(context.Background(), authz)
+}
+
+// SolveContext manages the provider to validate and solve the challenge.
+// The validation is aborted if the context is canceled.
+func (c *Challenge) SolveContext(ctx context.Context, authz acme.Authorization) error {
 	domain := authz.Identifier.Value
-	log.Infof("[%s] acme: Trying to solve TLS-ALPN-01", challenge.GetTargetedDomain(authz))
+	c.core.Logger().Info("acme: Trying to solve TLS-ALPN-01", log.Domain(challenge.GetTargetedDomain(authz)), log.Challenge(challenge.TLSALPN01.String()))
 
 	chlng, err := challenge.FindChallenge(challenge.TLSALPN01, authz)
 	if err != nil {
@@ -55,23 +86,41 @@ func (c *Challenge) Solve(authz acme.Authorization) error {
 		return err
 	}
 
-	err = c.provider.Present(domain, chlng.Token, keyAuth)
+	err = challenge.PresentWithContext(ctx, c.provider, domain, chlng.Token, keyAuth)
 	if err != nil {
 		return fmt.Errorf("[%s] acme: error presenting token: %w", challenge.GetTargetedDomain(authz), err)
 	}
 	defer func() {
 		err := c.provider.CleanUp(domain, chlng.Token, keyAuth)
 		if err != nil {
-			log.Warnf("[%s] acme: cleaning up failed: %v", challenge.GetTargetedDomain(authz), err)
+			c.core.Logger().Warn("acme: cleaning up failed", log.Domain(challenge.GetTargetedDomain(authz)), log.Challenge(challenge.TLSALPN01.String()), log.Err(err))
 		}
 	}()
 
 	chlng.KeyAuthorization = keyAuth
-	return c.validate(c.core, domain, chlng)
+	return c.validate(ctx, c.core, domain, chlng)
+}
+
+// ServerName returns the TLS SNI value used by the ACME server to validate the identifier.
+// For IP identifiers, it's the reverse DNS name of the address (`in-addr.arpa` or `ip6.arpa`),
+// for DNS identifiers, it's the domain itself.
+// Reference: https://www.rfc-editor.org/rfc/rfc8738.html#section-6
+func ServerName(domain string) string {
+	if net.ParseIP(domain) == nil {
+		return domain
+	}
+
+	name, err := dns.ReverseAddr(domain)
+	if err != nil {
+		return domain
+	}
+
+	return strings.TrimSuffix(name, ".")
 }
 
 // ChallengeBlocks returns PEM blocks (certPEMBlock, keyPEMBlock) with the acmeValidation-v1 extension
 // and domain name for the `tls-alpn-01` challenge.
+// If the domain is an IP address, the certificate contains it as an iPAddress SAN (RFC 8738).
 func ChallengeBlocks(domain, keyAuth string) ([]byte, []byte, error) {
 	// Compute the SHA-256 digest of the key authorization.
 	zBytes := sha256.Sum256([]byte(keyAuth))
@@ -0,0 +1,155 @@
+package tlsalpn01
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// CertificateProvider implements ChallengeProvider for `TLS-ALPN-01` challenge without its own listener.
+// The challenge certificates are served by an existing TLS server (i.e. a net/http server on the port 443)
+// through the hooks of its tls.Config: GetCertificate or GetConfigForClient.
+//
+// The challenge certificate is only returned when the client negotiates the `acme-tls/1` protocol,
+// the other connections fall through to the certificates of the server.
+type CertificateProvider struct {
+	mu    sync.RWMutex
+	certs map[string]*hookCertificate
+}
+
+// hookCertificate a presented challenge certificate.
+// The same challenge can be presented by several orders at the same time (i.e. the same authorization):
+// it is removed once all the orders have cleaned it.
+type hookCertificate struct {
+	keyAuth string
+	cert    *tls.Certificate
+	refs    int
+}
+
+// NewCertificateProvider creates a new CertificateProvider.
+func NewCertificateProvider() *CertificateProvider {
+	return &CertificateProvider{certs: map[string]*hookCertificate{}}
+}
+
+// Present generates the challenge certificate of the domain,
+// and makes it available to the hooks.
+func (p *CertificateProvider) Present(domain, token, keyAuth string) error {
+	cert, err := ChallengeCert(domain, keyAuth)
+	if err != nil {
+		return err
+	}
+
+	name := normalizeServerName(ServerName(domain))
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if existing, ok := p.certs[name]; ok && existing.keyAuth == keyAuth {
+		existing.refs++
+		return nil
+	}
+
+	p.certs[name] = &hookCertificate{keyAuth: keyAuth, cert: cert, refs: 1}
+
+	return nil
+}
+
+// CleanUp removes the challenge certificate of the domain.
+func (p *CertificateProvider) CleanUp(domain, token, keyAuth string) error {
+	name := normalizeServerName(ServerName(domain))
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	existing, ok := p.certs[name]
+	if !ok || existing.keyAuth != keyAuth {
+		return nil
+	}
+
+	existing.refs--
+	if existing.refs <= 0 {
+		delete(p.certs, name)
+	}
+
+	return nil
+}
+
+// GetCertificate returns a function for tls.Config.GetCertificate.
+// It returns the challenge certificate when the client negotiates the `acme-tls/1` protocol,
+// otherwise it calls next (when next is nil, the certificates of the tls.Config are used).
+//
+// The `acme-tls/1` protocol (ACMETLS1Protocol) must be in the tls.Config.NextProtos,
+// otherwise the protocol negotiation fails: GetConfigForClient does not have this requirement.
+func (p *CertificateProvider) GetCertificate(next func(*tls.ClientHelloInfo) (*tls.Certificate, error)) func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		if !isACMETLS1(hello) {
+			if next == nil {
+				return nil, nil
+			}
+
+			return next(hello)
+		}
+
+		return p.getChallengeCertificate(hello)
+	}
+}
+
+// GetConfigForClient returns a function for tls.Config.GetConfigForClient.
+// When the client negotiates the `acme-tls/1` protocol,
+// it returns a configuration dedicated to the challenge (only the challenge certificate and the `acme-tls/1` protocol),
+// otherwise it calls next (when next is nil, the tls.Config is used as is).
+func (p *CertificateProvider) GetConfigForClient(next func(*tls.ClientHelloInfo) (*tls.Config, error)) func(*tls.ClientHelloInfo) (*tls.Config, error) {
+	return func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		if !isACMETLS1(hello) {
+			if next == nil {
+				return nil, nil
+			}
+
+			return next(hello)
+		}
+
+		cert, err := p.getChallengeCertificate(hello)
+		if err != nil {
+			return nil, err
+		}
+
+		// We must set that the `acme-tls/1` application level protocol is supported
+		// so that the protocol negotiation can succeed. Reference:
+		// https://www.rfc-editor.org/rfc/rfc8737.html#section-6.2
+		return &tls.Config{
+			Certificates: []tls.Certificate{*cert},
+			NextProtos:   []string{ACMETLS1Protocol},
+		}, nil
+	}
+}
+
+func (p *CertificateProvider) getChallengeCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	name := normalizeServerName(hello.ServerName)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	existing, ok := p.certs[name]
+	if !ok {
+		// The certificates of the server must not be used for the `acme-tls/1` protocol.
+		return nil, fmt.Errorf("no TLS-ALPN-01 challenge for %q", hello.ServerName)
+	}
+
+	return existing.cert, nil
+}
+
+// isACMETLS1 checks if the client negotiates the `acme-tls/1` protocol.
+func isACMETLS1(hello *tls.ClientHelloInfo) bool {
+	for _, proto := range hello.SupportedProtos {
+		if proto == ACMETLS1Protocol {
+			return true
+		}
+	}
+
+	return false
+}
+
+func normalizeServerName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(name), ".")
+}
@@ -0,0 +1,142 @@
+package tlsalpn01
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/LukasDeco/lego/v4/acme"
+	"github.com/LukasDeco/lego/v4/acme/api"
+	"github.com/LukasDeco/lego/v4/challenge"
+	"github.com/LukasDeco/lego/v4/platform/tester"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertificateProvider(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		configure func(config *tls.Config, provider *CertificateProvider)
+	}{
+		{
+			desc: "GetCertificate",
+			configure: func(config *tls.Config, provider *CertificateProvider) {
+				config.NextProtos = []string{"http/1.1", ACMETLS1Protocol}
+				config.GetCertificate = provider.GetCertificate(nil)
+			},
+		},
+		{
+			desc: "GetConfigForClient",
+			configure: func(config *tls.Config, provider *CertificateProvider) {
+				config.GetConfigForClient = provider.GetConfigForClient(nil)
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, apiURL := tester.SetupFakeAPI(t)
+
+			provider := NewCertificateProvider()
+
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+				_, _ = rw.Write([]byte("hello"))
+			}))
+			server.TLS = &tls.Config{}
+			test.configure(server.TLS, provider)
+			server.StartTLS()
+			t.Cleanup(server.Close)
+
+			domain := "lego.test"
+
+			validate := func(_ *api.Core, _ string, chlng acme.Challenge) error {
+				conn, err := tls.Dial("tcp", server.Listener.Addr().String(), &tls.Config{
+					ServerName:         domain,
+					NextProtos:         []string{ACMETLS1Protocol},
+					InsecureSkipVerify: true,
+				})
+				if err != nil {
+					return err
+				}
+				defer func() { _ = conn.Close() }()
+
+				connState := conn.ConnectionState()
+				assert.Equal(t, ACMETLS1Protocol, connState.NegotiatedProtocol)
+				require.Len(t, connState.PeerCertificates, 1)
+
+				expected, err := ChallengeCert(domain, chlng.KeyAuthorization)
+				require.NoError(t, err)
+
+				assert.Equal(t, []string{domain}, connState.PeerCertificates[0].DNSNames)
+				assert.Equal(t, expected.Leaf.Extensions, connState.PeerCertificates[0].Extensions)
+
+				return nil
+			}
+
+			privateKey, err := rsa.GenerateKey(rand.Reader, 512)
+			require.NoError(t, err, "Could not generate test key")
+
+			core, err := api.New(http.DefaultClient, "lego-test", apiURL+"/dir", "", privateKey)
+			require.NoError(t, err)
+
+			solver := NewChallenge(core, validate, provider)
+
+			authz := acme.Authorization{
+				Identifier: acme.Identifier{Value: domain},
+				Challenges: []acme.Challenge{
+					{Type: challenge.TLSALPN01.String(), Token: "tlsalpn1"},
+				},
+			}
+
+			err = solver.Solve(authz)
+			require.NoError(t, err)
+
+			assert.Empty(t, provider.certs)
+
+			// the other connections use the certificates of the server.
+			resp, err := server.Client().Get(server.URL)
+			require.NoError(t, err)
+			defer func() { _ = resp.Body.Close() }()
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+			// without a challenge, the acme-tls/1 connections are rejected.
+			_, err = tls.Dial("tcp", server.Listener.Addr().String(), &tls.Config{
+				ServerName:         domain,
+				NextProtos:         []string{ACMETLS1Protocol},
+				InsecureSkipVerify: true,
+			})
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestCertificateProvider_GetCertificate_next(t *testing.T) {
+	provider := NewCertificateProvider()
+
+	fallback := &tls.Certificate{}
+
+	getCertificate := provider.GetCertificate(func(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return fallback, nil
+	})
+
+	require.NoError(t, provider.Present("example.com", "", "keyAuth"))
+
+	cert, err := getCertificate(&tls.ClientHelloInfo{ServerName: "example.com", SupportedProtos: []string{"h2", "http/1.1"}})
+	require.NoError(t, err)
+	assert.Same(t, fallback, cert)
+
+	cert, err = getCertificate(&tls.ClientHelloInfo{ServerName: "EXAMPLE.com", SupportedProtos: []string{ACMETLS1Protocol}})
+	require.NoError(t, err)
+	assert.NotSame(t, fallback, cert)
+	assert.Equal(t, []string{"example.com"}, cert.Leaf.DNSNames)
+
+	_, err = getCertificate(&tls.ClientHelloInfo{ServerName: "example.org", SupportedProtos: []string{ACMETLS1Protocol}})
+	require.EqualError(t, err, `no TLS-ALPN-01 challenge for "example.org"`)
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/sha256"
 	"crypto/subtle"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/asn1"
 	"net/http"
 	"testing"
@@ -116,3 +117,35 @@ func TestChallengeInvalidPort(t *testing.T) {
 	assert.Contains(t, err.Error(), "invalid port")
 	assert.Contains(t, err.Error(), "123456")
 }
+
+func TestChallengeCert_ipAddress(t *testing.T) {
+	cert, err := ChallengeCert("192.0.2.1", "keyAuth")
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+
+	assert.Empty(t, leaf.DNSNames)
+	require.Len(t, leaf.IPAddresses, 1)
+	assert.Equal(t, "192.0.2.1", leaf.IPAddresses[0].String())
+}
+
+func TestServerName(t *testing.T) {
+	testCases := []struct {
+		domain   string
+		expected string
+	}{
+		{domain: "example.com", expected: "example.com"},
+		{domain: "192.0.2.1", expected: "1.2.0.192.in-addr.arpa"},
+		{domain: "2001:db8::1", expected: "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa"},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.domain, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, ServerName(test.domain))
+		})
+	}
+}
@@ -6,9 +6,10 @@ import (
 	"encoding/json"
 	"encoding/pem"
 	"errors"
+	"fmt"
 	"net/url"
-	"os"
-	"path/filepath"
+	"path"
+	"sort"
 	"strings"
 
 	"github.com/LukasDeco/lego/v4/certcrypto"
@@ -25,6 +26,7 @@ const (
 )
 
 // AccountsStorage A storage for account data.
+// The paths are the keys inside the Storage ("storage" option, by default the directory defined by the "path" option).
 //
 // rootPath:
 //
@@ -58,6 +60,7 @@ const (
 //	     │      └── root accounts directory
 //	     └── "path" option
 type AccountsStorage struct {
+	storage         Storage
 	userID          string
 	rootPath        string
 	rootUserPath    string
@@ -76,37 +79,73 @@ func NewAccountsStorage(ctx *cli.Context) *AccountsStorage {
 		log.Fatal(err)
 	}
 
-	rootPath := filepath.Join(ctx.String("path"), baseAccountsRootFolderName)
-	serverPath := strings.NewReplacer(":", "_", "/", string(os.PathSeparator)).Replace(serverURL.Host)
-	accountsPath := filepath.Join(rootPath, serverPath)
-	rootUserPath := filepath.Join(accountsPath, email)
+	storage, err := NewStorage(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rootPath := baseAccountsRootFolderName
+	serverPath := strings.ReplaceAll(serverURL.Host, ":", "_")
+	accountsPath := path.Join(rootPath, serverPath)
+	rootUserPath := path.Join(accountsPath, email)
 
 	return &AccountsStorage{
+		storage:         storage,
 		userID:          email,
 		rootPath:        rootPath,
 		rootUserPath:    rootUserPath,
-		keysPath:        filepath.Join(rootUserPath, baseKeysFolderName),
-		accountFilePath: filepath.Join(rootUserPath, accountFileName),
+		keysPath:        path.Join(rootUserPath, baseKeysFolderName),
+		accountFilePath: path.Join(rootUserPath, accountFileName),
 		ctx:             ctx,
 	}
 }
 
 func (s *AccountsStorage) ExistsAccountFilePath() bool {
-	accountFile := filepath.Join(s.rootUserPath, accountFileName)
-	if _, err := os.Stat(accountFile); os.IsNotExist(err) {
-		return false
-	} else if err != nil {
+	exists, err := s.storage.Exists(s.accountFilePath)
+	if err != nil {
 		log.Fatal(err)
 	}
-	return true
+	return exists
 }
 
 func (s *AccountsStorage) GetRootPath() string {
-	return s.rootPath
+	return s.storage.Location(s.rootPath)
 }
 
 func (s *AccountsStorage) GetRootUserPath() string {
-	return s.rootUserPath
+	return s.storage.Location(s.rootUserPath)
+}
+
+// ListAccountFiles returns the paths of the account files of all the users and servers,
+// e.g. "accounts/acme-v02.api.letsencrypt.org/hubert@hubert.com/account.json".
+func (s *AccountsStorage) ListAccountFiles() ([]string, error) {
+	keys, err := s.storage.List(s.rootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	pattern := path.Join(s.rootPath, "*", "*", "*.json")
+
+	var files []string
+	for _, key := range keys {
+		if match, _ := path.Match(pattern, key); match {
+			files = append(files, key)
+		}
+	}
+
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// ReadAccountFile reads an account file returned by ListAccountFiles.
+func (s *AccountsStorage) ReadAccountFile(filePath string) ([]byte, error) {
+	return s.storage.ReadFile(filePath)
+}
+
+// GetLocation returns the location of a path inside the storage.
+func (s *AccountsStorage) GetLocation(filePath string) string {
+	return s.storage.Location(filePath)
 }
 
 func (s *AccountsStorage) GetUserID() string {
@@ -119,11 +158,11 @@ func (s *AccountsStorage) Save(account *Account) error {
 		return err
 	}
 
-	return os.WriteFile(s.accountFilePath, jsonBytes, filePerm)
+	return s.storage.WriteFile(s.accountFilePath, jsonBytes)
 }
 
 func (s *AccountsStorage) LoadAccount(privateKey crypto.PrivateKey) *Account {
-	fileBytes, err := os.ReadFile(s.accountFilePath)
+	fileBytes, err := s.storage.ReadFile(s.accountFilePath)
 	if err != nil {
 		log.Fatalf("Could not load file for account %s: %v", s.userID, err)
 	}
@@ -153,49 +192,74 @@ func (s *AccountsStorage) LoadAccount(privateKey crypto.PrivateKey) *Account {
 }
 
 func (s *AccountsStorage) GetPrivateKey(keyType certcrypto.KeyType) crypto.PrivateKey {
-	accKeyPath := filepath.Join(s.keysPath, s.userID+".key")
+	accKeyPath := s.getPrivateKeyPath()
 
-	if _, err := os.Stat(accKeyPath); os.IsNotExist(err) {
+	exists, err := s.storage.Exists(accKeyPath)
+	if err != nil {
+		log.Fatalf("Could not check the private key of the account %s: %v", s.userID, err)
+	}
+
+	if !exists {
 		log.Printf("No key found for account %s. Generating a %s key.", s.userID, keyType)
-		s.createKeysFolder()
 
-		privateKey, err := generatePrivateKey(accKeyPath, keyType)
+		privateKey, err := generatePrivateKey(s.storage, accKeyPath, keyType)
 		if err != nil {
 			log.Fatalf("Could not generate RSA private account key for account %s: %v", s.userID, err)
 		}
 
-		log.Printf("Saved key to %s", accKeyPath)
+		log.Printf("Saved key to %s", s.storage.Location(accKeyPath))
 		return privateKey
 	}
 
-	privateKey, err := loadPrivateKey(accKeyPath)
+	privateKey, err := loadPrivateKey(s.storage, accKeyPath)
 	if err != nil {
-		log.Fatalf("Could not load RSA private key from file %s: %v", accKeyPath, err)
+		log.Fatalf("Could not load RSA private key from file %s: %v", s.storage.Location(accKeyPath), err)
 	}
 
 	return privateKey
 }
 
-func (s *AccountsStorage) createKeysFolder() {
-	if err := createNonExistingFolder(s.keysPath); err != nil {
-		log.Fatalf("Could not check/create directory for account %s: %v", s.userID, err)
+// RolloverPrivateKey generates a new account key and replaces the current one.
+// The new key is first written next to the current key, then rollover is called (i.e. the key change on the ACME server),
+// and only if it succeeds, the new key file is renamed over the current one.
+// The current key is left untouched if rollover fails.
+func (s *AccountsStorage) RolloverPrivateKey(keyType certcrypto.KeyType, rollover func(crypto.PrivateKey) error) (crypto.PrivateKey, error) {
+	accKeyPath := s.getPrivateKeyPath()
+	newKeyPath := accKeyPath + ".new"
+
+	privateKey, err := generatePrivateKey(s.storage, newKeyPath, keyType)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate the new private key: %w", err)
 	}
-}
 
-func generatePrivateKey(file string, keyType certcrypto.KeyType) (crypto.PrivateKey, error) {
-	privateKey, err := certcrypto.GeneratePrivateKey(keyType)
+	err = rollover(privateKey)
 	if err != nil {
+		_ = s.storage.Remove(newKeyPath)
 		return nil, err
 	}
 
-	certOut, err := os.Create(file)
+	err = s.storage.Rename(newKeyPath, accKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("the key change succeeded but the new private key could not be moved from %s to %s: %w",
+			s.storage.Location(newKeyPath), s.storage.Location(accKeyPath), err)
+	}
+
+	return privateKey, nil
+}
+
+func (s *AccountsStorage) getPrivateKeyPath() string {
+	return path.Join(s.keysPath, s.userID+".key")
+}
+
+func generatePrivateKey(storage Storage, key string, keyType certcrypto.KeyType) (crypto.PrivateKey, error) {
+	privateKey, err := certcrypto.GeneratePrivateKey(keyType)
 	if err != nil {
 		return nil, err
 	}
-	defer certOut.Close()
 
-	pemKey := certcrypto.PEMBlock(privateKey)
-	err = pem.Encode(certOut, pemKey)
+	pemKey := certcrypto.PEMBlock(privateKey, nil)
+
+	err = storage.WriteFile(key, pem.EncodeToMemory(pemKey))
 	if err != nil {
 		return nil, err
 	}
@@ -203,19 +267,24 @@ func generatePrivateKey(file string, keyType certcrypto.KeyType) (crypto.Private
 	return privateKey, nil
 }
 
-func loadPrivateKey(file string) (crypto.PrivateKey, error) {
-	keyBytes, err := os.ReadFile(file)
+func loadPrivateKey(storage Storage, key string) (crypto.PrivateKey, error) {
+	keyBytes, err := storage.ReadFile(key)
 	if err != nil {
 		return nil, err
 	}
 
 	keyBlock, _ := pem.Decode(keyBytes)
+	if keyBlock == nil {
+		return nil, errors.New("no PEM data found")
+	}
 
 	switch keyBlock.Type {
 	case "RSA PRIVATE KEY":
 		return x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
 	case "EC PRIVATE KEY":
 		return x509.ParseECPrivateKey(keyBlock.Bytes)
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
 	}
 
 	return nil, errors.New("unknown private key type")
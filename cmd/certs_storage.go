@@ -1,15 +1,12 @@
 package cmd
 
 import (
-	"bytes"
-	"crypto"
-	"crypto/rand"
 	"crypto/x509"
 	"encoding/json"
-	"encoding/pem"
 	"fmt"
-	"os"
-	"path/filepath"
+	"net"
+	"path"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -19,7 +16,6 @@ import (
 	"github.com/LukasDeco/lego/v4/log"
 	"github.com/urfave/cli/v2"
 	"golang.org/x/net/idna"
-	"software.sslmate.com/src/go-pkcs12"
 )
 
 const (
@@ -28,6 +24,7 @@ const (
 )
 
 // CertificatesStorage a certificates' storage.
+// The paths are the keys inside the Storage ("storage" option, by default the directory defined by the "path" option).
 //
 // rootPath:
 //
@@ -41,115 +38,173 @@ const (
 //	     │      └── archived certificates directory
 //	     └── "path" option
 type CertificatesStorage struct {
+	storage     Storage
 	rootPath    string
 	archivePath string
-	pem         bool
-	pfx         bool
-	pfxPassword string
+	outputs     []outputWriter
 	filename    string // Deprecated
 }
 
 // NewCertificatesStorage create a new certificates storage.
 func NewCertificatesStorage(ctx *cli.Context) *CertificatesStorage {
+	storage, err := NewStorage(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	outputs, err := newOutputWriters(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	return &CertificatesStorage{
-		rootPath:    filepath.Join(ctx.String("path"), baseCertificatesFolderName),
-		archivePath: filepath.Join(ctx.String("path"), baseArchivesFolderName),
-		pem:         ctx.Bool("pem"),
-		pfx:         ctx.Bool("pfx"),
-		pfxPassword: ctx.String("pfx.pass"),
+		storage:     storage,
+		rootPath:    baseCertificatesFolderName,
+		archivePath: baseArchivesFolderName,
+		outputs:     outputs,
 		filename:    ctx.String("filename"),
 	}
 }
 
-func (s *CertificatesStorage) CreateRootFolder() {
-	err := createNonExistingFolder(s.rootPath)
-	if err != nil {
-		log.Fatalf("Could not check/create path: %v", err)
-	}
+func (s *CertificatesStorage) GetRootPath() string {
+	return s.storage.Location(s.rootPath)
 }
 
-func (s *CertificatesStorage) CreateArchiveFolder() {
-	err := createNonExistingFolder(s.archivePath)
+// ListCertificates returns the names of the certificate files (".crt", except the issuer certificates)
+// at the root of the certificates directory, e.g. "example.com.crt".
+func (s *CertificatesStorage) ListCertificates() ([]string, error) {
+	keys, err := s.storage.List(s.rootPath)
 	if err != nil {
-		log.Fatalf("Could not check/create path: %v", err)
+		return nil, err
+	}
+
+	var names []string
+	for _, key := range keys {
+		if path.Dir(key) != s.rootPath || !strings.HasSuffix(key, ".crt") || strings.HasSuffix(key, ".issuer.crt") {
+			continue
+		}
+
+		names = append(names, path.Base(key))
 	}
+
+	sort.Strings(names)
+
+	return names, nil
 }
 
-func (s *CertificatesStorage) GetRootPath() string {
-	return s.rootPath
+// ReadNamedFile reads a file from the certificates directory by its name (e.g. "example.com.crt").
+func (s *CertificatesStorage) ReadNamedFile(name string) ([]byte, error) {
+	return s.storage.ReadFile(path.Join(s.rootPath, name))
+}
+
+// GetNamedFileLocation returns the location of a file from the certificates directory by its name.
+func (s *CertificatesStorage) GetNamedFileLocation(name string) string {
+	return s.storage.Location(path.Join(s.rootPath, name))
+}
+
+// certificateMeta the metadata of a certificate (the .json file).
+type certificateMeta struct {
+	certificate.Resource
+
+	// Account the email of the account which has obtained the certificate.
+	Account string `json:"account,omitempty"`
 }
 
-func (s *CertificatesStorage) SaveResource(certRes *certificate.Resource) {
+// SaveResource writes the certificate, its files (private key, outputs, CSR),
+// and its metadata, including the email of the account which has obtained the certificate.
+func (s *CertificatesStorage) SaveResource(certRes *certificate.Resource, account string) error {
 	domain := certRes.Domain
 
 	// We store the certificate, private key and metadata in different files
 	// as web servers would not be able to work with a combined file.
 	err := s.WriteFile(domain, ".crt", certRes.Certificate)
 	if err != nil {
-		log.Fatalf("Unable to save Certificate for domain %s\n\t%v", domain, err)
+		return fmt.Errorf("unable to save Certificate for domain %s: %w", domain, err)
 	}
 
 	if certRes.IssuerCertificate != nil {
 		err = s.WriteFile(domain, ".issuer.crt", certRes.IssuerCertificate)
 		if err != nil {
-			log.Fatalf("Unable to save IssuerCertificate for domain %s\n\t%v", domain, err)
+			return fmt.Errorf("unable to save IssuerCertificate for domain %s: %w", domain, err)
 		}
 	}
 
-	// if we were given a CSR, we don't know the private key
-	if certRes.PrivateKey != nil {
-		err = s.WriteCertificateFiles(domain, certRes)
+	err = s.WriteCertificateFiles(domain, certRes)
+	if err != nil {
+		return fmt.Errorf("unable to save certificate files for domain %s: %w", domain, err)
+	}
+
+	// the CSR is needed to renew the certificate without the CSR file (i.e. by the daemon).
+	if certRes.CSR != nil {
+		err = s.WriteFile(domain, ".csr", certRes.CSR)
 		if err != nil {
-			log.Fatalf("Unable to save PrivateKey for domain %s\n\t%v", domain, err)
+			return fmt.Errorf("unable to save CSR for domain %s: %w", domain, err)
 		}
-	} else if s.pem || s.pfx {
-		// we don't have the private key; can't write the .pem or .pfx file
-		log.Fatalf("Unable to save PEM or PFX without private key for domain %s. Are you using a CSR?", domain)
 	}
 
-	jsonBytes, err := json.MarshalIndent(certRes, "", "\t")
+	jsonBytes, err := json.MarshalIndent(certificateMeta{Resource: *certRes, Account: account}, "", "\t")
 	if err != nil {
-		log.Fatalf("Unable to marshal CertResource for domain %s\n\t%v", domain, err)
+		return fmt.Errorf("unable to marshal CertResource for domain %s: %w", domain, err)
 	}
 
 	err = s.WriteFile(domain, ".json", jsonBytes)
 	if err != nil {
-		log.Fatalf("Unable to save CertResource for domain %s\n\t%v", domain, err)
+		return fmt.Errorf("unable to save CertResource for domain %s: %w", domain, err)
 	}
+
+	return nil
 }
 
-func (s *CertificatesStorage) ReadResource(domain string) certificate.Resource {
+func (s *CertificatesStorage) ReadResource(domain string) (certificate.Resource, error) {
+	var resource certificate.Resource
+
 	raw, err := s.ReadFile(domain, ".json")
 	if err != nil {
-		log.Fatalf("Error while loading the meta data for domain %s\n\t%v", domain, err)
+		return resource, fmt.Errorf("error while loading the meta data for domain %s: %w", domain, err)
 	}
 
-	var resource certificate.Resource
 	if err = json.Unmarshal(raw, &resource); err != nil {
-		log.Fatalf("Error while marshaling the meta data for domain %s\n\t%v", domain, err)
+		return resource, fmt.Errorf("error while marshaling the meta data for domain %s: %w", domain, err)
 	}
 
-	return resource
+	return resource, nil
 }
 
-func (s *CertificatesStorage) ExistsFile(domain, extension string) bool {
-	filePath := s.GetFileName(domain, extension)
-
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return false
-	} else if err != nil {
-		log.Fatal(err)
+func (s *CertificatesStorage) ExistsFile(domain, extension string) (bool, error) {
+	key, err := s.getKey(domain, extension)
+	if err != nil {
+		return false, err
 	}
-	return true
+
+	return s.storage.Exists(key)
 }
 
 func (s *CertificatesStorage) ReadFile(domain, extension string) ([]byte, error) {
-	return os.ReadFile(s.GetFileName(domain, extension))
+	key, err := s.getKey(domain, extension)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.storage.ReadFile(key)
+}
+
+// GetFileName returns the location of a file inside the storage (a file path for the filesystem storages).
+func (s *CertificatesStorage) GetFileName(domain, extension string) (string, error) {
+	key, err := s.getKey(domain, extension)
+	if err != nil {
+		return "", err
+	}
+
+	return s.storage.Location(key), nil
 }
 
-func (s *CertificatesStorage) GetFileName(domain, extension string) string {
-	filename := sanitizedDomain(domain) + extension
-	return filepath.Join(s.rootPath, filename)
+func (s *CertificatesStorage) getKey(domain, extension string) (string, error) {
+	name, err := sanitizedDomain(domain)
+	if err != nil {
+		return "", err
+	}
+
+	return path.Join(s.rootPath, name+extension), nil
 }
 
 func (s *CertificatesStorage) ReadCertificate(domain, extension string) ([]*x509.Certificate, error) {
@@ -167,101 +222,70 @@ func (s *CertificatesStorage) WriteFile(domain, extension string, data []byte) e
 	if s.filename != "" {
 		baseFileName = s.filename
 	} else {
-		baseFileName = sanitizedDomain(domain)
-	}
+		var err error
 
-	filePath := filepath.Join(s.rootPath, baseFileName+extension)
+		baseFileName, err = sanitizedDomain(domain)
+		if err != nil {
+			return err
+		}
+	}
 
-	return os.WriteFile(filePath, data, filePerm)
+	return s.storage.WriteFile(path.Join(s.rootPath, baseFileName+extension), data)
 }
 
+// WriteCertificateFiles writes the private key, and the files of the outputs ("output" option).
 func (s *CertificatesStorage) WriteCertificateFiles(domain string, certRes *certificate.Resource) error {
-	err := s.WriteFile(domain, ".key", certRes.PrivateKey)
-	if err != nil {
-		return fmt.Errorf("unable to save key file: %w", err)
+	// if we were given a CSR, we don't know the private key
+	if certRes.PrivateKey != nil {
+		err := s.WriteFile(domain, ".key", certRes.PrivateKey)
+		if err != nil {
+			return fmt.Errorf("unable to save key file: %w", err)
+		}
 	}
 
-	if s.pem {
-		err = s.WriteFile(domain, ".pem", bytes.Join([][]byte{certRes.Certificate, certRes.PrivateKey}, nil))
+	for _, output := range s.outputs {
+		if output.NeedsPrivateKey() && certRes.PrivateKey == nil {
+			// we don't have the private key; can't write the file
+			return fmt.Errorf("unable to save %s file without private key: are you using a CSR?", output.Name())
+		}
+
+		data, err := output.Encode(certRes)
 		if err != nil {
-			return fmt.Errorf("unable to save PEM file: %w", err)
+			return fmt.Errorf("unable to encode %s file: %w", output.Name(), err)
 		}
-	}
 
-	if s.pfx {
-		err = s.WritePFXFile(domain, certRes)
+		err = s.WriteFile(domain, output.Extension(), data)
 		if err != nil {
-			return fmt.Errorf("unable to save PFX file: %w", err)
+			return fmt.Errorf("unable to save %s file: %w", output.Name(), err)
 		}
 	}
 
 	return nil
 }
 
-func (s *CertificatesStorage) WritePFXFile(domain string, certRes *certificate.Resource) error {
-	certPemBlock, _ := pem.Decode(certRes.Certificate)
-	if certPemBlock == nil {
-		return fmt.Errorf("unable to parse Certificate for domain %s", domain)
-	}
-
-	cert, err := x509.ParseCertificate(certPemBlock.Bytes)
+func (s *CertificatesStorage) MoveToArchive(domain string) error {
+	keys, err := s.storage.List(s.rootPath)
 	if err != nil {
-		return fmt.Errorf("unable to load Certificate for domain %s: %w", domain, err)
-	}
-
-	issuerCertPemBlock, _ := pem.Decode(certRes.IssuerCertificate)
-	if issuerCertPemBlock == nil {
-		return fmt.Errorf("unable to parse Issuer Certificate for domain %s", domain)
+		return err
 	}
 
-	issuerCert, err := x509.ParseCertificate(issuerCertPemBlock.Bytes)
+	name, err := sanitizedDomain(domain)
 	if err != nil {
-		return fmt.Errorf("unable to load Issuer Certificate for domain %s: %w", domain, err)
-	}
-
-	keyPemBlock, _ := pem.Decode(certRes.PrivateKey)
-	if keyPemBlock == nil {
-		return fmt.Errorf("unable to parse PrivateKey for domain %s", domain)
+		return err
 	}
 
-	var privateKey crypto.Signer
-	var keyErr error
+	pattern := path.Join(s.rootPath, name+".*")
 
-	switch keyPemBlock.Type {
-	case "RSA PRIVATE KEY":
-		privateKey, keyErr = x509.ParsePKCS1PrivateKey(keyPemBlock.Bytes)
-		if keyErr != nil {
-			return fmt.Errorf("unable to load RSA PrivateKey for domain %s: %w", domain, keyErr)
+	for _, oldKey := range keys {
+		if match, _ := path.Match(pattern, oldKey); !match {
+			continue
 		}
-	case "EC PRIVATE KEY":
-		privateKey, keyErr = x509.ParseECPrivateKey(keyPemBlock.Bytes)
-		if keyErr != nil {
-			return fmt.Errorf("unable to load EC PrivateKey for domain %s: %w", domain, keyErr)
-		}
-	default:
-		return fmt.Errorf("unsupported PrivateKey type '%s' for domain %s", keyPemBlock.Type, domain)
-	}
-
-	pfxBytes, err := pkcs12.Encode(rand.Reader, privateKey, cert, []*x509.Certificate{issuerCert}, s.pfxPassword)
-	if err != nil {
-		return fmt.Errorf("unable to encode PFX data for domain %s: %w", domain, err)
-	}
 
-	return s.WriteFile(domain, ".pfx", pfxBytes)
-}
-
-func (s *CertificatesStorage) MoveToArchive(domain string) error {
-	matches, err := filepath.Glob(filepath.Join(s.rootPath, sanitizedDomain(domain)+".*"))
-	if err != nil {
-		return err
-	}
-
-	for _, oldFile := range matches {
 		date := strconv.FormatInt(time.Now().Unix(), 10)
-		filename := date + "." + filepath.Base(oldFile)
-		newFile := filepath.Join(s.archivePath, filename)
+		filename := date + "." + path.Base(oldKey)
+		newKey := path.Join(s.archivePath, filename)
 
-		err = os.Rename(oldFile, newFile)
+		err = s.storage.Rename(oldKey, newKey)
 		if err != nil {
 			return err
 		}
@@ -271,10 +295,16 @@ func (s *CertificatesStorage) MoveToArchive(domain string) error {
 }
 
 // sanitizedDomain Make sure no funny chars are in the cert names (like wildcards ;)).
-func sanitizedDomain(domain string) string {
+func sanitizedDomain(domain string) (string, error) {
+	if ip := net.ParseIP(domain); ip != nil {
+		// IPv6 colons are not allowed in file names on some platforms.
+		return strings.ReplaceAll(ip.String(), ":", "-"), nil
+	}
+
 	safe, err := idna.ToASCII(strings.ReplaceAll(domain, "*", "_"))
 	if err != nil {
-		log.Fatal(err)
+		return "", fmt.Errorf("invalid domain %q: %w", domain, err)
 	}
-	return safe
+
+	return safe, nil
 }
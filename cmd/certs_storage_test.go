@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/LukasDeco/lego/v4/certcrypto"
+	"github.com/LukasDeco/lego/v4/certificate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertificatesStorage_MoveToArchive(t *testing.T) {
+	storage := NewFileStorage(t.TempDir())
+
+	certsStorage := &CertificatesStorage{
+		storage:     storage,
+		rootPath:    baseCertificatesFolderName,
+		archivePath: baseArchivesFolderName,
+	}
+
+	for _, name := range []string{"example.com.crt", "example.com.issuer.crt", "example.com.key", "example.com.json", "example.org.crt"} {
+		err := storage.WriteFile("certificates/"+name, []byte(name))
+		require.NoError(t, err)
+	}
+
+	names, err := certsStorage.ListCertificates()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.com.crt", "example.org.crt"}, names)
+
+	err = certsStorage.MoveToArchive("example.com")
+	require.NoError(t, err)
+
+	names, err = certsStorage.ListCertificates()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.org.crt"}, names)
+
+	archives, err := storage.List("archives")
+	require.NoError(t, err)
+	assert.Len(t, archives, 4)
+
+	assert.False(t, mustExistFile(t, certsStorage, "example.com", ".key"))
+	assert.True(t, mustExistFile(t, certsStorage, "example.org", ".crt"))
+}
+
+func TestCertificatesStorage_WriteCertificateFiles(t *testing.T) {
+	key, err := certcrypto.GeneratePrivateKey(certcrypto.EC256)
+	require.NoError(t, err)
+
+	chain := createTestChain(t, "example.com", key)
+
+	writers, err := createOutputWriters([]string{outputFullChain, outputDER, outputJKS, outputKubernetes}, outputOptions{jksPassword: defaultJKSPassword})
+	require.NoError(t, err)
+
+	certsStorage := &CertificatesStorage{
+		storage:     NewFileStorage(t.TempDir()),
+		rootPath:    baseCertificatesFolderName,
+		archivePath: baseArchivesFolderName,
+		outputs:     writers,
+	}
+
+	err = certsStorage.WriteCertificateFiles("example.com", chain.resource("example.com", certcrypto.PEMEncode(key), false))
+	require.NoError(t, err)
+
+	for _, ext := range []string{".key", ".fullchain.pem", ".der", ".jks", ".k8s.yaml"} {
+		assert.True(t, mustExistFile(t, certsStorage, "example.com", ext), ext)
+	}
+
+	// without the private key (CSR): only the outputs without the private key are written.
+	certsStorage.outputs = writers[:2]
+
+	err = certsStorage.WriteCertificateFiles("example.org", chain.resource("example.org", nil, false))
+	require.NoError(t, err)
+
+	assert.False(t, mustExistFile(t, certsStorage, "example.org", ".key"))
+	assert.True(t, mustExistFile(t, certsStorage, "example.org", ".der"))
+
+	certsStorage.outputs = writers
+
+	err = certsStorage.WriteCertificateFiles("example.org", chain.resource("example.org", nil, false))
+	require.EqualError(t, err, "unable to save jks file without private key: are you using a CSR?")
+}
+
+func TestCertificatesStorage_SaveResource(t *testing.T) {
+	certsStorage := &CertificatesStorage{
+		storage:     NewFileStorage(t.TempDir()),
+		rootPath:    baseCertificatesFolderName,
+		archivePath: baseArchivesFolderName,
+	}
+
+	err := certsStorage.SaveResource(&certificate.Resource{
+		Domain:      "example.com",
+		CertURL:     "https://ca.example.com/cert/1",
+		Certificate: []byte("cert"),
+	}, "a@example.com")
+	require.NoError(t, err)
+
+	raw, err := certsStorage.ReadFile("example.com", ".json")
+	require.NoError(t, err)
+
+	var meta certificateMeta
+	require.NoError(t, json.Unmarshal(raw, &meta))
+
+	assert.Equal(t, "example.com", meta.Domain)
+	assert.Equal(t, "https://ca.example.com/cert/1", meta.CertURL)
+	assert.Equal(t, "a@example.com", meta.Account)
+
+	// the metadata is still a certificate resource.
+	resource, err := certsStorage.ReadResource("example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "https://ca.example.com/cert/1", resource.CertURL)
+}
+
+func TestCertificatesStorage_SaveResource_error(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "root")
+
+	// The certificates directory cannot be created: the root of the storage is a file.
+	require.NoError(t, os.WriteFile(root, nil, 0o600))
+
+	certsStorage := &CertificatesStorage{
+		storage:     NewFileStorage(root),
+		rootPath:    baseCertificatesFolderName,
+		archivePath: baseArchivesFolderName,
+	}
+
+	err := certsStorage.SaveResource(&certificate.Resource{Domain: "example.com", Certificate: []byte("cert")}, "a@example.com")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to save Certificate for domain example.com")
+}
+
+func mustExistFile(t *testing.T, certsStorage *CertificatesStorage, domain, extension string) bool {
+	t.Helper()
+
+	exists, err := certsStorage.ExistsFile(domain, extension)
+	require.NoError(t, err)
+
+	return exists
+}
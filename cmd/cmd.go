@@ -10,5 +10,8 @@ func CreateCommands() []*cli.Command {
 		createRenew(),
 		createDNSHelp(),
 		createList(),
+		createAccount(),
+		createDaemon(),
+		createOCSP(),
 	}
 }
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"github.com/LukasDeco/lego/v4/log"
+	"github.com/urfave/cli/v2"
+)
+
+func createAccount() *cli.Command {
+	return &cli.Command{
+		Name:  "account",
+		Usage: "Manage the ACME account",
+		Subcommands: []*cli.Command{
+			{
+				Name: "rollover",
+				Usage: "Replace the account key with a new one (RFC 8555 section 7.3.5)." +
+					" The new key is stored only after the CA has confirmed the change.",
+				Action: rollover,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "new-key-type",
+						Usage: "Key type of the new account key. Supported: rsa2048, rsa3072, rsa4096, rsa8192, ec256, ec384, ec521, ed25519. Defaults to the --key-type value.",
+					},
+				},
+			},
+		},
+	}
+}
+
+func rollover(ctx *cli.Context) error {
+	accountsStorage := NewAccountsStorage(ctx)
+
+	if !accountsStorage.ExistsAccountFilePath() {
+		log.Fatalf("Account %s does not exist. Use 'run' to register a new account.\n", accountsStorage.GetUserID())
+	}
+
+	acc, client := setup(ctx, accountsStorage)
+
+	if acc.Registration == nil {
+		log.Fatalf("Account %s is not registered. Use 'run' to register a new account.\n", acc.Email)
+	}
+
+	keyType := getKeyType(ctx)
+	if ctx.IsSet("new-key-type") {
+		keyType = parseKeyType(ctx.String("new-key-type"))
+	}
+
+	_, err := accountsStorage.RolloverPrivateKey(keyType, client.Registration.RolloverKey)
+	if err != nil {
+		log.Fatalf("Could not rollover the key of the account %s: %v", acc.Email, err)
+	}
+
+	log.Printf("The key of the account %s has been replaced.", acc.Email)
+
+	return nil
+}
@@ -1,18 +1,30 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/LukasDeco/lego/v4/log"
 	"github.com/urfave/cli/v2"
 )
 
 func Before(ctx *cli.Context) error {
+	err := setupLogger(ctx)
+	if err != nil {
+		return err
+	}
+
 	if ctx.String("path") == "" {
 		log.Fatal("Could not determine current working directory. Please pass --path.")
 	}
 
-	err := createNonExistingFolder(ctx.String("path"))
-	if err != nil {
-		log.Fatalf("Could not check/create path: %v", err)
+	if ctx.String("storage") == "" {
+		err := createNonExistingFolder(ctx.String("path"))
+		if err != nil {
+			log.Fatalf("Could not check/create path: %v", err)
+		}
+	} else if _, err := NewStorage(ctx); err != nil {
+		log.Fatalf("Could not create the storage: %v", err)
 	}
 
 	if ctx.String("server") == "" {
@@ -21,3 +33,22 @@ func Before(ctx *cli.Context) error {
 
 	return nil
 }
+
+// setupLogger replaces the default logger according to the log-format and log-level options.
+func setupLogger(ctx *cli.Context) error {
+	level, err := log.ParseLevel(ctx.String("log-level"))
+	if err != nil {
+		return err
+	}
+
+	switch ctx.String("log-format") {
+	case "text":
+		log.SetDefault(log.New(log.NewStdHandler(level)))
+	case "json":
+		log.SetDefault(log.New(log.NewJSONHandler(os.Stderr, level)))
+	default:
+		return fmt.Errorf("unknown log format: %q", ctx.String("log-format"))
+	}
+
+	return nil
+}
@@ -0,0 +1,504 @@
+package cmd
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/LukasDeco/lego/v4/acme/api"
+	"github.com/LukasDeco/lego/v4/certcrypto"
+	"github.com/LukasDeco/lego/v4/certificate"
+	"github.com/LukasDeco/lego/v4/log"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/urfave/cli/v2"
+)
+
+func createDaemon() *cli.Command {
+	return &cli.Command{
+		Name:   "daemon",
+		Usage:  "Run in the foreground and renew the certificates of the storage when needed. Send SIGHUP to reload the certificates.",
+		Action: daemon,
+		Flags: append(createRenewFlags(),
+			&cli.DurationFlag{
+				Name:  "interval",
+				Value: 12 * time.Hour,
+				Usage: "The maximum duration between two checks of a certificate (renewal time, renewalInfo endpoint).",
+			},
+			&cli.DurationFlag{
+				Name:  "jitter",
+				Value: time.Hour,
+				Usage: "The maximum random delay added to the renewal time computed from the '--days' option.",
+			},
+			&cli.DurationFlag{
+				Name:  "retry.initial-interval",
+				Value: 5 * time.Minute,
+				Usage: "The delay before the first retry of a failed renewal. The delay grows exponentially between the retries.",
+			},
+			&cli.DurationFlag{
+				Name:  "retry.max-interval",
+				Value: 6 * time.Hour,
+				Usage: "The maximum delay between two retries of a failed renewal.",
+			},
+		),
+	}
+}
+
+func daemon(ctx *cli.Context) error {
+	account, client := setup(ctx, NewAccountsStorage(ctx))
+	setupChallenges(ctx, client)
+
+	if account.Registration == nil {
+		log.Fatalf("Account %s is not registered. Use 'run' to register a new account.\n", account.Email)
+	}
+
+	certsStorage := NewCertificatesStorage(ctx)
+
+	bundle := !ctx.Bool("no-bundle")
+
+	scheduler := newRenewalScheduler(ctx)
+
+	scheduler.load = func() ([]*scheduledCertificate, error) {
+		return loadScheduledCertificates(certsStorage)
+	}
+
+	scheduler.renew = func(c *scheduledCertificate) error {
+		meta := map[string]string{renewEnvAccountEmail: account.Email}
+
+		if c.csr != nil {
+			return renewForCSR(ctx, client, certsStorage, bundle, meta, c.renewRequest())
+		}
+
+		return renewForDomains(ctx, client, certsStorage, bundle, meta, c.renewRequest())
+	}
+
+	if !ctx.Bool("crl-disable") {
+		scheduler.isRevoked = func(c *scheduledCertificate) bool {
+			return isRevoked(ctx, certsStorage, c.domain)
+		}
+	}
+
+	if !ctx.Bool("ari-disable") {
+		scheduler.renewalInfo = func(cert *x509.Certificate) (*certificate.RenewalInfoResponse, error) {
+			return client.Certificate.GetRenewalInfo(certificate.RenewalInfoRequest{Cert: cert})
+		}
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	return scheduler.run(signals)
+}
+
+// scheduledCertificate a certificate managed by the daemon.
+type scheduledCertificate struct {
+	// domain the main domain, identifies the certificate files.
+	domain string
+
+	// domains the domains to renew (renewal with the private key stored by lego).
+	domains []string
+
+	// csr the CSR to renew (renewal of a certificate obtained with a CSR).
+	csr *x509.CertificateRequest
+
+	cert *x509.Certificate
+
+	// next the time of the next action: a renewal if renew is true, otherwise a new check.
+	next  time.Time
+	renew bool
+
+	// revoked the certificate has been revoked: it's renewed now.
+	revoked bool
+
+	// retries the backoff of the failed renewals.
+	retries *backoff.ExponentialBackOff
+}
+
+// renewRequest returns the request of the renewal: the scheduler has already decided that the certificate must be renewed.
+func (c *scheduledCertificate) renewRequest() renewRequest {
+	return renewRequest{domains: c.domains, csr: c.csr, scheduled: true, revoked: c.revoked}
+}
+
+// renewalScheduler schedules the renewals of the certificates:
+//   - the renewal time comes from the renewalInfo endpoint (ARI) if available,
+//     otherwise from the "days" option with a random jitter.
+//   - a revoked certificate (CRL) is renewed immediately.
+//   - a certificate is checked again at least every "interval" (the ARI window can change).
+//   - the failed renewals are retried with an exponential backoff.
+type renewalScheduler struct {
+	days                 int
+	interval             time.Duration
+	jitter               time.Duration
+	retryInitialInterval time.Duration
+	retryMaxInterval     time.Duration
+
+	// load reads the certificates from the storage.
+	load func() ([]*scheduledCertificate, error)
+
+	// renew renews a certificate.
+	renew func(c *scheduledCertificate) error
+
+	// isRevoked checks the revocation status of a certificate, nil if the check is disabled.
+	isRevoked func(c *scheduledCertificate) bool
+
+	// renewalInfo calls the renewalInfo endpoint, nil if ARI is disabled.
+	renewalInfo func(cert *x509.Certificate) (*certificate.RenewalInfoResponse, error)
+
+	now func() time.Time
+	rnd *rand.Rand
+
+	certificates []*scheduledCertificate
+
+	// reloadAt the time of the next attempt of a failed reload, zero if the last reload succeeded.
+	reloadAt time.Time
+
+	// reloadRetries the backoff of the failed reloads.
+	reloadRetries *backoff.ExponentialBackOff
+}
+
+func newRenewalScheduler(ctx *cli.Context) *renewalScheduler {
+	return &renewalScheduler{
+		days:                 ctx.Int("days"),
+		interval:             ctx.Duration("interval"),
+		jitter:               ctx.Duration("jitter"),
+		retryInitialInterval: ctx.Duration("retry.initial-interval"),
+		retryMaxInterval:     ctx.Duration("retry.max-interval"),
+		now:                  time.Now,
+		rnd:                  rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// run processes the certificates until a SIGINT or SIGTERM signal is received.
+// A SIGHUP signal reloads the certificates from the storage.
+// A failed load of the certificates is retried with the backoff of the failed renewals.
+func (s *renewalScheduler) run(signals <-chan os.Signal) error {
+	s.tryReload()
+
+	for {
+		var timer *time.Timer
+		var timeout <-chan time.Time
+
+		c := s.nextCertificate()
+
+		switch {
+		case !s.reloadAt.IsZero() && (c == nil || s.reloadAt.Before(c.next)):
+			c = nil
+			timer = time.NewTimer(s.reloadAt.Sub(s.now()))
+			timeout = timer.C
+
+		case c != nil:
+			timer = time.NewTimer(c.next.Sub(s.now()))
+			timeout = timer.C
+
+		default:
+			log.Println("daemon: no certificates found, waiting for SIGHUP.")
+		}
+
+		select {
+		case <-timeout:
+			if c == nil {
+				s.tryReload()
+				continue
+			}
+
+			s.process(c)
+
+		case sig := <-signals:
+			if timer != nil {
+				timer.Stop()
+			}
+
+			if sig != syscall.SIGHUP {
+				log.Printf("daemon: %s received, stopping.", sig)
+				return nil
+			}
+
+			log.Println("daemon: SIGHUP received, reloading the certificates.")
+
+			s.tryReload()
+		}
+	}
+}
+
+// nextCertificate returns the certificate with the earliest action.
+func (s *renewalScheduler) nextCertificate() *scheduledCertificate {
+	var next *scheduledCertificate
+
+	for _, c := range s.certificates {
+		if next == nil || c.next.Before(next.next) {
+			next = c
+		}
+	}
+
+	return next
+}
+
+// reload reads the certificates from the storage.
+// The certificates that have not changed keep their schedule (and their retries).
+func (s *renewalScheduler) reload() error {
+	certificates, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	previous := make(map[string]*scheduledCertificate, len(s.certificates))
+	for _, c := range s.certificates {
+		previous[c.domain] = c
+	}
+
+	for i, c := range certificates {
+		if p, ok := previous[c.domain]; ok && p.cert.SerialNumber.Cmp(c.cert.SerialNumber) == 0 {
+			certificates[i] = p
+			continue
+		}
+
+		s.schedule(c)
+	}
+
+	s.certificates = certificates
+
+	return nil
+}
+
+// process executes the action of a certificate (renewal or check) and schedules the next one.
+func (s *renewalScheduler) process(c *scheduledCertificate) {
+	if !c.renew {
+		s.schedule(c)
+		return
+	}
+
+	err := s.renew(c)
+
+	var hookErr *hookError
+	if errors.As(err, &hookErr) {
+		log.Warnf("[%s] daemon: the certificate has been renewed but a hook failed: %v", c.domain, err)
+		err = nil
+	}
+
+	if err != nil {
+		if c.retries == nil {
+			c.retries = s.newRetries()
+		}
+
+		delay := c.retries.NextBackOff()
+
+		log.Warnf("[%s] daemon: renewal failed, next attempt in %s: %v", c.domain, delay.Round(time.Second), err)
+
+		c.next = s.now().Add(delay)
+
+		return
+	}
+
+	c.retries = nil
+
+	// The renewed certificate replaces this one during the reload.
+	// Until then (e.g. if the reload fails), only a check is scheduled to avoid renewing twice.
+	c.next = s.now().Add(s.interval)
+	c.renew = false
+
+	s.tryReload()
+}
+
+// tryReload reloads the certificates.
+// A failed reload keeps the current certificates, and is retried with a backoff.
+func (s *renewalScheduler) tryReload() {
+	err := s.reload()
+	if err == nil {
+		s.reloadAt = time.Time{}
+		s.reloadRetries = nil
+
+		return
+	}
+
+	if s.reloadRetries == nil {
+		s.reloadRetries = s.newRetries()
+	}
+
+	delay := s.reloadRetries.NextBackOff()
+
+	log.Warnf("daemon: unable to load the certificates, next attempt in %s: %v", delay.Round(time.Second), err)
+
+	s.reloadAt = s.now().Add(delay)
+}
+
+// schedule computes the next action of a certificate.
+func (s *renewalScheduler) schedule(c *scheduledCertificate) {
+	now := s.now()
+
+	if s.isRevoked != nil && s.isRevoked(c) {
+		log.Infof("[%s] daemon: renewal scheduled now (revoked certificate)", c.domain)
+
+		c.next = now
+		c.renew = true
+		c.revoked = true
+
+		return
+	}
+
+	if s.renewalInfo != nil {
+		info, err := s.renewalInfo(c.cert)
+		if err == nil {
+			s.scheduleARI(c, info, now)
+			return
+		}
+
+		if !errors.Is(err, api.ErrNoARI) {
+			log.Warnf("[%s] daemon: calling renewal info endpoint: %v", c.domain, err)
+		}
+	}
+
+	renewAt := c.cert.NotAfter.Add(-time.Duration(s.days) * 24 * time.Hour)
+	if s.jitter > 0 {
+		renewAt = renewAt.Add(time.Duration(s.rnd.Int63n(int64(s.jitter))))
+	}
+
+	if renewAt.Before(now) {
+		renewAt = now
+	}
+
+	if renewAt.After(now.Add(s.interval)) {
+		c.next = now.Add(s.interval)
+		c.renew = false
+
+		return
+	}
+
+	log.Infof("[%s] daemon: renewal scheduled at %s", c.domain, renewAt.Format(time.RFC3339))
+
+	c.next = renewAt
+	c.renew = true
+}
+
+func (s *renewalScheduler) scheduleARI(c *scheduledCertificate, info *certificate.RenewalInfoResponse, now time.Time) {
+	renewAt := info.ShouldRenewAt(now, s.interval)
+	if renewAt != nil {
+		log.Infof("[%s] daemon: renewal scheduled at %s (renewalInfo endpoint)", c.domain, renewAt.Format(time.RFC3339))
+
+		c.next = *renewAt
+		c.renew = true
+
+		return
+	}
+
+	// The server can ask to check again sooner.
+	delay := s.interval
+	if info.RetryAfter > 0 && info.RetryAfter < delay {
+		delay = info.RetryAfter
+	}
+
+	c.next = now.Add(delay)
+	c.renew = false
+}
+
+func (s *renewalScheduler) newRetries() *backoff.ExponentialBackOff {
+	bo := backoff.NewExponentialBackOff()
+	bo.InitialInterval = s.retryInitialInterval
+	bo.MaxInterval = s.retryMaxInterval
+	bo.MaxElapsedTime = 0 // retry forever
+	bo.Reset()
+
+	return bo
+}
+
+// errCertificateIgnored the certificate cannot be renewed by the daemon (e.g. neither a private key nor a CSR).
+var errCertificateIgnored = errors.New("certificate ignored")
+
+// loadScheduledCertificates reads all the certificates of the storage.
+// The certificates that cannot be renewed (no private key and no CSR) are ignored,
+// a storage error fails the whole load (the daemon retries it).
+func loadScheduledCertificates(certsStorage *CertificatesStorage) ([]*scheduledCertificate, error) {
+	names, err := certsStorage.ListCertificates()
+	if err != nil {
+		return nil, err
+	}
+
+	var certificates []*scheduledCertificate
+
+	for _, name := range names {
+		domain := strings.TrimSuffix(name, ".crt")
+
+		c, err := loadScheduledCertificate(certsStorage, domain)
+		if errors.Is(err, errCertificateIgnored) {
+			log.Warnf("[%s] daemon: %v", domain, err)
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("[%s] %w", domain, err)
+		}
+
+		certificates = append(certificates, c)
+	}
+
+	sort.Slice(certificates, func(i, j int) bool { return certificates[i].domain < certificates[j].domain })
+
+	return certificates, nil
+}
+
+func loadScheduledCertificate(certsStorage *CertificatesStorage, domain string) (*scheduledCertificate, error) {
+	raw, err := certsStorage.ReadFile(domain, ".crt")
+	if err != nil {
+		return nil, err
+	}
+
+	certificates, err := certcrypto.ParsePEMBundle(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errCertificateIgnored, err)
+	}
+
+	cert := certificates[0]
+	if cert.IsCA {
+		return nil, fmt.Errorf("%w: certificate bundle starts with a CA certificate", errCertificateIgnored)
+	}
+
+	// The file names are derived from the sanitized domain (e.g. "_.example.com" for "*.example.com"),
+	// the metadata contains the original domain.
+	raw, err = certsStorage.ReadFile(domain, ".json")
+	switch {
+	case err == nil:
+		var resource certificate.Resource
+		if json.Unmarshal(raw, &resource) == nil && resource.Domain != "" {
+			domain = resource.Domain
+		}
+
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, err
+	}
+
+	c := &scheduledCertificate{domain: domain, cert: cert}
+
+	hasKey, err := certsStorage.ExistsFile(domain, ".key")
+	if err != nil {
+		return nil, err
+	}
+
+	if hasKey {
+		c.domains = merge([]string{domain}, certcrypto.ExtractDomains(cert))
+		return c, nil
+	}
+
+	raw, err = certsStorage.ReadFile(domain, ".csr")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: neither a private key nor a CSR is available", errCertificateIgnored)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	c.csr, err = certcrypto.PemDecodeTox509CSR(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid CSR: %v", errCertificateIgnored, err)
+	}
+
+	return c, nil
+}
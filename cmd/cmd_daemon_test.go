@@ -0,0 +1,448 @@
+package cmd
+
+import (
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"math/rand"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/LukasDeco/lego/v4/acme"
+	"github.com/LukasDeco/lego/v4/acme/api"
+	"github.com/LukasDeco/lego/v4/certcrypto"
+	"github.com/LukasDeco/lego/v4/certificate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestScheduler(now time.Time) *renewalScheduler {
+	return &renewalScheduler{
+		days:                 30,
+		interval:             12 * time.Hour,
+		retryInitialInterval: time.Minute,
+		retryMaxInterval:     time.Hour,
+		now:                  func() time.Time { return now },
+		rnd:                  rand.New(rand.NewSource(1)),
+	}
+}
+
+func newScheduledCertificate(domain string, serial int64, notAfter time.Time) *scheduledCertificate {
+	return &scheduledCertificate{
+		domain:  domain,
+		domains: []string{domain},
+		cert:    &x509.Certificate{SerialNumber: big.NewInt(serial), NotAfter: notAfter},
+	}
+}
+
+func Test_renewalScheduler_schedule(t *testing.T) {
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		desc          string
+		notAfter      time.Time
+		renewalInfo   func(cert *x509.Certificate) (*certificate.RenewalInfoResponse, error)
+		expectedNext  time.Time
+		expectedRenew bool
+	}{
+		{
+			desc:          "days: renewal needed",
+			notAfter:      now.Add(10 * 24 * time.Hour),
+			expectedNext:  now,
+			expectedRenew: true,
+		},
+		{
+			desc:          "days: renewal within the interval",
+			notAfter:      now.Add(30*24*time.Hour + 6*time.Hour),
+			expectedNext:  now.Add(6 * time.Hour),
+			expectedRenew: true,
+		},
+		{
+			desc:         "days: renewal not needed",
+			notAfter:     now.Add(60 * 24 * time.Hour),
+			expectedNext: now.Add(12 * time.Hour),
+		},
+		{
+			desc:     "ARI not supported",
+			notAfter: now.Add(10 * 24 * time.Hour),
+			renewalInfo: func(_ *x509.Certificate) (*certificate.RenewalInfoResponse, error) {
+				return nil, api.ErrNoARI
+			},
+			expectedNext:  now,
+			expectedRenew: true,
+		},
+		{
+			desc:     "ARI error",
+			notAfter: now.Add(60 * 24 * time.Hour),
+			renewalInfo: func(_ *x509.Certificate) (*certificate.RenewalInfoResponse, error) {
+				return nil, errors.New("oops")
+			},
+			expectedNext: now.Add(12 * time.Hour),
+		},
+		{
+			desc:     "ARI: window in the past",
+			notAfter: now.Add(60 * 24 * time.Hour),
+			renewalInfo: func(_ *x509.Certificate) (*certificate.RenewalInfoResponse, error) {
+				return &certificate.RenewalInfoResponse{
+					RenewalInfo: acme.RenewalInfo{SuggestedWindow: acme.Window{Start: now.Add(-2 * time.Hour), End: now.Add(-time.Hour)}},
+				}, nil
+			},
+			expectedNext:  now,
+			expectedRenew: true,
+		},
+		{
+			desc:     "ARI: window within the interval",
+			notAfter: now.Add(60 * 24 * time.Hour),
+			renewalInfo: func(_ *x509.Certificate) (*certificate.RenewalInfoResponse, error) {
+				return &certificate.RenewalInfoResponse{
+					RenewalInfo: acme.RenewalInfo{SuggestedWindow: acme.Window{Start: now.Add(2 * time.Hour), End: now.Add(2 * time.Hour)}},
+				}, nil
+			},
+			expectedNext:  now.Add(2 * time.Hour),
+			expectedRenew: true,
+		},
+		{
+			desc:     "ARI: window after the interval",
+			notAfter: now.Add(10 * 24 * time.Hour),
+			renewalInfo: func(_ *x509.Certificate) (*certificate.RenewalInfoResponse, error) {
+				return &certificate.RenewalInfoResponse{
+					RenewalInfo: acme.RenewalInfo{SuggestedWindow: acme.Window{Start: now.Add(48 * time.Hour), End: now.Add(72 * time.Hour)}},
+				}, nil
+			},
+			expectedNext: now.Add(12 * time.Hour),
+		},
+		{
+			desc:     "ARI: window after the interval with Retry-After",
+			notAfter: now.Add(10 * 24 * time.Hour),
+			renewalInfo: func(_ *x509.Certificate) (*certificate.RenewalInfoResponse, error) {
+				return &certificate.RenewalInfoResponse{
+					RenewalInfo: acme.RenewalInfo{SuggestedWindow: acme.Window{Start: now.Add(48 * time.Hour), End: now.Add(72 * time.Hour)}},
+					RetryAfter:  3 * time.Hour,
+				}, nil
+			},
+			expectedNext: now.Add(3 * time.Hour),
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			scheduler := newTestScheduler(now)
+			scheduler.renewalInfo = test.renewalInfo
+
+			c := newScheduledCertificate("example.com", 1, test.notAfter)
+
+			scheduler.schedule(c)
+
+			assert.Equal(t, test.expectedNext, c.next)
+			assert.Equal(t, test.expectedRenew, c.renew)
+		})
+	}
+}
+
+func Test_renewalScheduler_schedule_jitter(t *testing.T) {
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	scheduler := newTestScheduler(now)
+	scheduler.jitter = time.Hour
+
+	c := newScheduledCertificate("example.com", 1, now.Add(30*24*time.Hour+6*time.Hour))
+
+	scheduler.schedule(c)
+
+	assert.True(t, c.renew)
+	assert.False(t, c.next.Before(now.Add(6*time.Hour)))
+	assert.True(t, c.next.Before(now.Add(7*time.Hour)))
+}
+
+func Test_renewalScheduler_revoked(t *testing.T) {
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	scheduler := newTestScheduler(now)
+
+	// the certificate doesn't need a renewal, except for its revocation.
+	scheduler.load = func() ([]*scheduledCertificate, error) {
+		return []*scheduledCertificate{
+			newScheduledCertificate("example.com", 1, now.Add(60*24*time.Hour)),
+			newScheduledCertificate("example.org", 2, now.Add(60*24*time.Hour)),
+		}, nil
+	}
+
+	scheduler.isRevoked = func(c *scheduledCertificate) bool {
+		return c.domain == "example.com"
+	}
+
+	scheduler.renewalInfo = func(_ *x509.Certificate) (*certificate.RenewalInfoResponse, error) {
+		return nil, api.ErrNoARI
+	}
+
+	require.NoError(t, scheduler.reload())
+	require.Len(t, scheduler.certificates, 2)
+
+	revoked, valid := scheduler.certificates[0], scheduler.certificates[1]
+
+	assert.True(t, revoked.renew)
+	assert.True(t, revoked.revoked)
+	assert.Equal(t, now, revoked.next)
+
+	assert.False(t, valid.renew)
+	assert.False(t, valid.revoked)
+
+	// the revocation is passed to the renewal.
+	var requests []renewRequest
+	scheduler.renew = func(c *scheduledCertificate) error {
+		requests = append(requests, c.renewRequest())
+		return errors.New("oops")
+	}
+
+	scheduler.process(revoked)
+
+	assert.Equal(t, []renewRequest{{domains: []string{"example.com"}, scheduled: true, revoked: true}}, requests)
+
+	// the failed renewal of a revoked certificate is retried.
+	assert.True(t, revoked.renew)
+	assert.True(t, revoked.revoked)
+	assert.NotNil(t, revoked.retries)
+}
+
+func Test_renewalScheduler_process(t *testing.T) {
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	scheduler := newTestScheduler(now)
+
+	current := newScheduledCertificate("example.com", 1, now.Add(10*24*time.Hour))
+	scheduler.load = func() ([]*scheduledCertificate, error) {
+		return []*scheduledCertificate{newScheduledCertificate(current.domain, current.cert.SerialNumber.Int64(), current.cert.NotAfter)}, nil
+	}
+
+	require.NoError(t, scheduler.reload())
+	require.Len(t, scheduler.certificates, 1)
+
+	c := scheduler.certificates[0]
+	assert.True(t, c.renew)
+
+	// first failure
+	scheduler.renew = func(_ *scheduledCertificate) error { return errors.New("oops") }
+
+	scheduler.process(c)
+
+	assert.True(t, c.renew)
+	assert.NotNil(t, c.retries)
+	assert.True(t, c.next.After(now))
+	assert.False(t, c.next.After(now.Add(2*time.Minute)))
+
+	// the retries are kept during a reload when the certificate has not changed.
+	require.NoError(t, scheduler.reload())
+	assert.Same(t, c, scheduler.certificates[0])
+
+	// success
+	var renewed []string
+	scheduler.renew = func(c *scheduledCertificate) error {
+		renewed = append(renewed, c.domain)
+		current = newScheduledCertificate("example.com", 2, now.Add(90*24*time.Hour))
+		return nil
+	}
+
+	scheduler.process(c)
+
+	assert.Equal(t, []string{"example.com"}, renewed)
+	require.Len(t, scheduler.certificates, 1)
+
+	c = scheduler.certificates[0]
+	assert.Equal(t, int64(2), c.cert.SerialNumber.Int64())
+	assert.False(t, c.renew)
+	assert.Nil(t, c.retries)
+	assert.Equal(t, now.Add(12*time.Hour), c.next)
+}
+
+func Test_renewalScheduler_process_hookError(t *testing.T) {
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	scheduler := newTestScheduler(now)
+	scheduler.load = func() ([]*scheduledCertificate, error) {
+		return []*scheduledCertificate{newScheduledCertificate("example.com", 2, now.Add(90*24*time.Hour))}, nil
+	}
+	scheduler.renew = func(_ *scheduledCertificate) error {
+		return &hookError{err: errors.New("exit status 1")}
+	}
+
+	c := newScheduledCertificate("example.com", 1, now)
+	c.renew = true
+	scheduler.certificates = []*scheduledCertificate{c}
+
+	scheduler.process(c)
+
+	// not retried: the certificate has been renewed.
+	require.Len(t, scheduler.certificates, 1)
+	assert.Equal(t, int64(2), scheduler.certificates[0].cert.SerialNumber.Int64())
+	assert.False(t, scheduler.certificates[0].renew)
+}
+
+func Test_renewalScheduler_tryReload(t *testing.T) {
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	scheduler := newTestScheduler(now)
+
+	current := newScheduledCertificate("example.com", 1, now.Add(60*24*time.Hour))
+	scheduler.certificates = []*scheduledCertificate{current}
+
+	scheduler.load = func() ([]*scheduledCertificate, error) {
+		return nil, errors.New("oops")
+	}
+
+	scheduler.tryReload()
+
+	// the current certificates are kept, the reload is retried.
+	assert.Equal(t, []*scheduledCertificate{current}, scheduler.certificates)
+	assert.NotNil(t, scheduler.reloadRetries)
+	assert.True(t, scheduler.reloadAt.After(now))
+	assert.False(t, scheduler.reloadAt.After(now.Add(2*time.Minute)))
+
+	scheduler.load = func() ([]*scheduledCertificate, error) {
+		return []*scheduledCertificate{newScheduledCertificate("example.com", 2, now.Add(90*24*time.Hour))}, nil
+	}
+
+	scheduler.tryReload()
+
+	require.Len(t, scheduler.certificates, 1)
+	assert.Equal(t, int64(2), scheduler.certificates[0].cert.SerialNumber.Int64())
+	assert.Nil(t, scheduler.reloadRetries)
+	assert.True(t, scheduler.reloadAt.IsZero())
+}
+
+func Test_renewalScheduler_run_loadError(t *testing.T) {
+	scheduler := newTestScheduler(time.Now())
+	scheduler.now = time.Now
+	scheduler.retryInitialInterval = 10 * time.Millisecond
+
+	loads := make(chan error, 10)
+
+	// only accessed by the daemon goroutine.
+	var calls int
+
+	scheduler.load = func() ([]*scheduledCertificate, error) {
+		calls++
+
+		var err error
+		if calls == 1 {
+			err = errors.New("oops")
+		}
+
+		loads <- err
+
+		return nil, err
+	}
+
+	signals := make(chan os.Signal, 1)
+
+	done := make(chan error)
+	go func() { done <- scheduler.run(signals) }()
+
+	// the failed initial load doesn't stop the daemon, it's retried.
+	require.EqualError(t, <-loads, "oops")
+	require.NoError(t, <-loads)
+
+	signals <- syscall.SIGTERM
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the daemon did not stop")
+	}
+}
+
+// existsErrorStorage a storage where Exists always fails.
+type existsErrorStorage struct {
+	Storage
+}
+
+func (existsErrorStorage) Exists(_ string) (bool, error) {
+	return false, errors.New("oops")
+}
+
+func Test_loadScheduledCertificates(t *testing.T) {
+	key, err := certcrypto.GeneratePrivateKey(certcrypto.EC256)
+	require.NoError(t, err)
+
+	chain := createTestChain(t, "example.com", key)
+
+	certsStorage := &CertificatesStorage{
+		storage:     NewFileStorage(t.TempDir()),
+		rootPath:    baseCertificatesFolderName,
+		archivePath: baseArchivesFolderName,
+	}
+
+	for _, domain := range []string{"example.com", "example.org"} {
+		err = certsStorage.WriteFile(domain, ".crt", chain.resource(domain, nil, true).Certificate)
+		require.NoError(t, err)
+	}
+
+	err = certsStorage.WriteFile("example.com", ".key", certcrypto.PEMEncode(key))
+	require.NoError(t, err)
+
+	// example.org is ignored: neither a private key nor a CSR.
+	certificates, err := loadScheduledCertificates(certsStorage)
+	require.NoError(t, err)
+
+	require.Len(t, certificates, 1)
+	assert.Equal(t, "example.com", certificates[0].domain)
+	assert.Equal(t, []string{"example.com"}, certificates[0].domains)
+
+	// a storage error fails the load.
+	certsStorage.storage = existsErrorStorage{Storage: certsStorage.storage}
+
+	_, err = loadScheduledCertificates(certsStorage)
+	require.EqualError(t, err, "[example.com] oops")
+}
+
+func Test_renewalScheduler_run(t *testing.T) {
+	scheduler := newTestScheduler(time.Now())
+	scheduler.now = time.Now
+
+	// only accessed by the daemon goroutine.
+	serial := int64(1)
+
+	loads := make(chan int64, 10)
+	scheduler.load = func() ([]*scheduledCertificate, error) {
+		loads <- serial
+
+		notAfter := time.Now().Add(90 * 24 * time.Hour)
+		if serial == 1 {
+			notAfter = time.Now().Add(10 * 24 * time.Hour)
+		}
+
+		return []*scheduledCertificate{newScheduledCertificate("example.com", serial, notAfter)}, nil
+	}
+
+	signals := make(chan os.Signal, 1)
+
+	scheduler.renew = func(_ *scheduledCertificate) error {
+		serial++
+		return nil
+	}
+
+	done := make(chan error)
+	go func() { done <- scheduler.run(signals) }()
+
+	// initial load, then reload after the renewal.
+	assert.Equal(t, int64(1), <-loads)
+	assert.Equal(t, int64(2), <-loads)
+
+	signals <- syscall.SIGHUP
+
+	assert.Equal(t, int64(2), <-loads)
+
+	signals <- syscall.SIGTERM
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the daemon did not stop")
+	}
+}
@@ -1,15 +1,30 @@
 package cmd
 
 import (
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
+	"io"
+	"math"
 	"net/url"
 	"os"
-	"path/filepath"
+	"path"
+	"strconv"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/LukasDeco/lego/v4/certcrypto"
 	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+)
+
+// The output formats of the list command.
+const (
+	listFormatText  = "text"
+	listFormatTable = "table"
+	listFormatJSON  = "json"
+	listFormatYAML  = "yaml"
 )
 
 func createList() *cli.Command {
@@ -28,111 +43,404 @@ func createList() *cli.Command {
 				Aliases: []string{"n"},
 				Usage:   "Display certificate common names only.",
 			},
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "The output format: text, table, json, or yaml.",
+				Value: listFormatText,
+			},
+			&cli.StringSliceFlag{
+				Name:  "domain",
+				Usage: "Only display the certificates for the domain (wildcards are matched). Supports multiple values.",
+			},
+			&cli.IntFlag{
+				Name:  "days",
+				Usage: "Only display the certificates expiring in the given number of days or less (expired certificates included).",
+			},
 		},
 	}
 }
 
+// listOutput the output of the list command in the json and yaml formats.
+type listOutput struct {
+	Certificates []certificateInfo `json:"certificates" yaml:"certificates"`
+	Accounts     []accountInfo     `json:"accounts,omitempty" yaml:"accounts,omitempty"`
+}
+
+// certificateInfo the description of a stored certificate.
+type certificateInfo struct {
+	Name          string                 `json:"name" yaml:"name"`
+	Domains       []string               `json:"domains" yaml:"domains"`
+	IPAddresses   []string               `json:"ipAddresses,omitempty" yaml:"ipAddresses,omitempty"`
+	SerialNumber  string                 `json:"serialNumber" yaml:"serialNumber"`
+	Issuer        string                 `json:"issuer" yaml:"issuer"`
+	KeyType       string                 `json:"keyType" yaml:"keyType"`
+	NotBefore     time.Time              `json:"notBefore" yaml:"notBefore"`
+	NotAfter      time.Time              `json:"notAfter" yaml:"notAfter"`
+	DaysRemaining int                    `json:"daysRemaining" yaml:"daysRemaining"`
+	MustStaple    bool                   `json:"mustStaple" yaml:"mustStaple"`
+	Chain         []chainCertificateInfo `json:"chain,omitempty" yaml:"chain,omitempty"`
+	CertURL       string                 `json:"certUrl,omitempty" yaml:"certUrl,omitempty"`
+	Account       string                 `json:"account,omitempty" yaml:"account,omitempty"`
+	Path          string                 `json:"path" yaml:"path"`
+}
+
+// chainCertificateInfo the description of a stored issuer certificate.
+type chainCertificateInfo struct {
+	Subject  string    `json:"subject" yaml:"subject"`
+	Issuer   string    `json:"issuer" yaml:"issuer"`
+	NotAfter time.Time `json:"notAfter" yaml:"notAfter"`
+}
+
+// accountInfo the description of a stored account.
+type accountInfo struct {
+	Email  string `json:"email" yaml:"email"`
+	Server string `json:"server" yaml:"server"`
+	URI    string `json:"uri,omitempty" yaml:"uri,omitempty"`
+	Path   string `json:"path" yaml:"path"`
+}
+
+// certificateFilter the criteria of the displayed certificates.
+type certificateFilter struct {
+	domains []string
+	// days the maximum number of days before the expiration, ignored if negative.
+	days int
+}
+
 func list(ctx *cli.Context) error {
-	if ctx.Bool("accounts") && !ctx.Bool("names") {
-		if err := listAccount(ctx); err != nil {
-			return err
-		}
+	format := ctx.String("format")
+
+	switch format {
+	case listFormatText, listFormatTable, listFormatJSON, listFormatYAML:
+	default:
+		return fmt.Errorf("unsupported format: %q", format)
 	}
 
-	return listCertificates(ctx)
-}
+	filter := certificateFilter{domains: ctx.StringSlice("domain"), days: -1}
+	if ctx.IsSet("days") {
+		filter.days = ctx.Int("days")
+	}
 
-func listCertificates(ctx *cli.Context) error {
-	certsStorage := NewCertificatesStorage(ctx)
+	// The accounts are needed to find the accounts of the certificates.
+	var accounts []accountInfo
+	if ctx.Bool("accounts") || format != listFormatText {
+		var err error
+		accounts, err = collectAccounts(ctx)
+		if err != nil {
+			return err
+		}
+	}
 
-	matches, err := filepath.Glob(filepath.Join(certsStorage.GetRootPath(), "*.crt"))
+	certs, err := collectCertificates(NewCertificatesStorage(ctx), accounts, filter, time.Now())
 	if err != nil {
 		return err
 	}
 
-	names := ctx.Bool("names")
-
-	if len(matches) == 0 {
-		if !names {
-			fmt.Println("No certificates found.")
+	if ctx.Bool("names") {
+		for _, cert := range certs {
+			fmt.Println(cert.Name)
 		}
+
 		return nil
 	}
 
-	if !names {
-		fmt.Println("Found the following certs:")
+	output := listOutput{Certificates: certs}
+	if ctx.Bool("accounts") {
+		output.Accounts = accounts
+	}
+
+	return writeList(os.Stdout, format, output)
+}
+
+// collectCertificates returns the descriptions of the stored certificates matching the filter.
+func collectCertificates(certsStorage *CertificatesStorage, accounts []accountInfo, filter certificateFilter, now time.Time) ([]certificateInfo, error) {
+	matches, err := certsStorage.ListCertificates()
+	if err != nil {
+		return nil, err
 	}
 
+	certs := []certificateInfo{}
+
 	for _, filename := range matches {
-		if strings.HasSuffix(filename, ".issuer.crt") {
+		domain := strings.TrimSuffix(filename, ".crt")
+
+		bundle, err := certsStorage.ReadCertificate(domain, ".crt")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+
+		if !filter.match(bundle[0], now) {
 			continue
 		}
 
-		data, err := os.ReadFile(filename)
+		info := newCertificateInfo(bundle[0], now)
+		info.Path = certsStorage.GetNamedFileLocation(filename)
+
+		hasIssuer, err := certsStorage.ExistsFile(domain, ".issuer.crt")
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+
+		chain := bundle[1:]
+		if len(chain) == 0 && hasIssuer {
+			chain, err = certsStorage.ReadCertificate(domain, ".issuer.crt")
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", filename, err)
+			}
 		}
 
-		pCert, err := certcrypto.ParsePEMCertificate(data)
+		for _, cert := range chain {
+			info.Chain = append(info.Chain, chainCertificateInfo{
+				Subject:  cert.Subject.String(),
+				Issuer:   cert.Issuer.String(),
+				NotAfter: cert.NotAfter,
+			})
+		}
+
+		hasResource, err := certsStorage.ExistsFile(domain, ".json")
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("%s: %w", filename, err)
 		}
 
-		if names {
-			fmt.Println(pCert.Subject.CommonName)
-		} else {
-			fmt.Println("  Certificate Name:", pCert.Subject.CommonName)
-			fmt.Println("    Domains:", strings.Join(pCert.DNSNames, ", "))
-			fmt.Println("    Expiry Date:", pCert.NotAfter)
-			fmt.Println("    Certificate Path:", filename)
-			fmt.Println()
+		if hasResource {
+			var resource certificateMeta
+
+			raw, err := certsStorage.ReadFile(domain, ".json")
+			if err == nil {
+				err = json.Unmarshal(raw, &resource)
+			}
+
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", filename, err)
+			}
+
+			info.CertURL = resource.CertURL
+			info.Account = resource.Account
+			if info.Account == "" {
+				info.Account = findAccount(accounts, resource.CertURL)
+			}
 		}
+
+		certs = append(certs, info)
 	}
 
-	return nil
+	return certs, nil
 }
 
-func listAccount(ctx *cli.Context) error {
+func newCertificateInfo(cert *x509.Certificate, now time.Time) certificateInfo {
+	info := certificateInfo{
+		Name:          cert.Subject.CommonName,
+		Domains:       cert.DNSNames,
+		SerialNumber:  fmt.Sprintf("%x", cert.SerialNumber),
+		Issuer:        cert.Issuer.String(),
+		NotBefore:     cert.NotBefore,
+		NotAfter:      cert.NotAfter,
+		DaysRemaining: int(math.Floor(cert.NotAfter.Sub(now).Hours() / 24)),
+		MustStaple:    certcrypto.IsMustStaple(cert),
+	}
+
+	for _, ip := range cert.IPAddresses {
+		info.IPAddresses = append(info.IPAddresses, ip.String())
+	}
+
+	keyType, err := certcrypto.GetKeyType(cert.PublicKey)
+	if err == nil {
+		info.KeyType = formatKeyType(keyType)
+	} else {
+		info.KeyType = strings.ToLower(cert.PublicKeyAlgorithm.String())
+	}
+
+	return info
+}
+
+// match returns true if the certificate matches the filter.
+func (f certificateFilter) match(cert *x509.Certificate, now time.Time) bool {
+	if f.days >= 0 && cert.NotAfter.After(now.Add(time.Duration(f.days)*24*time.Hour)) {
+		return false
+	}
+
+	if len(f.domains) == 0 {
+		return true
+	}
+
+	for _, domain := range f.domains {
+		if cert.VerifyHostname(domain) == nil || containsDomain(certcrypto.ExtractDomains(cert), domain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// collectAccounts returns the descriptions of the stored accounts.
+func collectAccounts(ctx *cli.Context) ([]accountInfo, error) {
 	// fake email, needed by NewAccountsStorage
 	if err := ctx.Set("email", "unknown"); err != nil {
-		return err
+		return nil, err
 	}
 
 	accountsStorage := NewAccountsStorage(ctx)
 
-	matches, err := filepath.Glob(filepath.Join(accountsStorage.GetRootPath(), "*", "*", "*.json"))
+	matches, err := accountsStorage.ListAccountFiles()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if len(matches) == 0 {
-		fmt.Println("No accounts found.")
-		return nil
-	}
+	accounts := []accountInfo{}
 
-	fmt.Println("Found the following accounts:")
 	for _, filename := range matches {
-		data, err := os.ReadFile(filename)
+		data, err := accountsStorage.ReadAccountFile(filename)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		var account Account
 		err = json.Unmarshal(data, &account)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		uri, err := url.Parse(account.Registration.URI)
-		if err != nil {
-			return err
+		info := accountInfo{
+			Email: account.Email,
+			Path:  accountsStorage.GetLocation(path.Dir(filename)),
+		}
+
+		if account.Registration != nil {
+			uri, err := url.Parse(account.Registration.URI)
+			if err != nil {
+				return nil, err
+			}
+
+			info.Server = uri.Host
+			info.URI = account.Registration.URI
+		}
+
+		accounts = append(accounts, info)
+	}
+
+	return accounts, nil
+}
+
+// findAccount returns the email of the account of a certificate saved without its account (i.e. by a previous version):
+// the account is found only if it is the only account of the CA server of the certificate.
+func findAccount(accounts []accountInfo, certURL string) string {
+	uri, err := url.Parse(certURL)
+	if err != nil || uri.Host == "" {
+		return ""
+	}
+
+	var email string
+
+	for _, account := range accounts {
+		if account.Server != uri.Host {
+			continue
+		}
+
+		if email != "" {
+			return ""
 		}
 
-		fmt.Println("  Email:", account.Email)
-		fmt.Println("  Server:", uri.Host)
-		fmt.Println("  Path:", filepath.Dir(filename))
-		fmt.Println()
+		email = account.Email
+	}
+
+	return email
+}
+
+func writeList(w io.Writer, format string, output listOutput) error {
+	switch format {
+	case listFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(output)
+
+	case listFormatYAML:
+		return yaml.NewEncoder(w).Encode(output)
+
+	case listFormatTable:
+		return writeListTable(w, output)
+
+	default:
+		writeListText(w, output)
+		return nil
+	}
+}
+
+func writeListTable(w io.Writer, output listOutput) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	if output.Accounts != nil {
+		_, _ = fmt.Fprintln(tw, "EMAIL\tSERVER\tPATH")
+
+		for _, account := range output.Accounts {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", account.Email, account.Server, account.Path)
+		}
+
+		_, _ = fmt.Fprintln(tw)
+	}
+
+	_, _ = fmt.Fprintln(tw, "NAME\tDOMAINS\tKEY TYPE\tNOT AFTER\tDAYS\tMUST STAPLE\tACCOUNT\tPATH")
+
+	for _, cert := range output.Certificates {
+		domains := strings.Join(append(append([]string{}, cert.Domains...), cert.IPAddresses...), ",")
+
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			cert.Name, domains, cert.KeyType, cert.NotAfter.Format(time.RFC3339), cert.DaysRemaining,
+			strconv.FormatBool(cert.MustStaple), cert.Account, cert.Path)
+	}
+
+	return tw.Flush()
+}
+
+func writeListText(w io.Writer, output listOutput) {
+	if output.Accounts != nil {
+		if len(output.Accounts) == 0 {
+			_, _ = fmt.Fprintln(w, "No accounts found.")
+		} else {
+			_, _ = fmt.Fprintln(w, "Found the following accounts:")
+
+			for _, account := range output.Accounts {
+				_, _ = fmt.Fprintln(w, "  Email:", account.Email)
+				_, _ = fmt.Fprintln(w, "  Server:", account.Server)
+				_, _ = fmt.Fprintln(w, "  Path:", account.Path)
+				_, _ = fmt.Fprintln(w)
+			}
+		}
+	}
+
+	if len(output.Certificates) == 0 {
+		_, _ = fmt.Fprintln(w, "No certificates found.")
+		return
+	}
+
+	_, _ = fmt.Fprintln(w, "Found the following certs:")
+
+	for _, cert := range output.Certificates {
+		_, _ = fmt.Fprintln(w, "  Certificate Name:", cert.Name)
+		_, _ = fmt.Fprintln(w, "    Domains:", strings.Join(cert.Domains, ", "))
+		_, _ = fmt.Fprintln(w, "    Expiry Date:", cert.NotAfter)
+		_, _ = fmt.Fprintln(w, "    Certificate Path:", cert.Path)
+		_, _ = fmt.Fprintln(w)
+	}
+}
+
+// formatKeyType returns the value of the key-type option of a KeyType.
+func formatKeyType(keyType certcrypto.KeyType) string {
+	switch keyType {
+	case certcrypto.EC256, certcrypto.EC384, certcrypto.EC521:
+		return "ec" + strings.TrimPrefix(string(keyType), "P")
+	case certcrypto.ED25519:
+		return "ed25519"
+	default:
+		return "rsa" + string(keyType)
+	}
+}
+
+func containsDomain(domains []string, domain string) bool {
+	for _, d := range domains {
+		if strings.EqualFold(d, domain) {
+			return true
+		}
 	}
 
-	return nil
+	return false
 }
@@ -0,0 +1,314 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/LukasDeco/lego/v4/certcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func Test_collectCertificates(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	certsStorage := setupListStorage(t, now)
+
+	accounts := []accountInfo{
+		{Email: "a@example.com", Server: "ca.example.com"},
+		{Email: "b@example.org", Server: "other.example.org"},
+		{Email: "c@example.org", Server: "other.example.org"},
+	}
+
+	certs, err := collectCertificates(certsStorage, accounts, certificateFilter{days: -1}, now)
+	require.NoError(t, err)
+
+	require.Len(t, certs, 3)
+
+	assert.Equal(t, "192.0.2.1", certs[0].Name)
+	assert.Empty(t, certs[0].Domains)
+	assert.Equal(t, []string{"192.0.2.1"}, certs[0].IPAddresses)
+	assert.Empty(t, certs[0].Account)
+
+	assert.Empty(t, certs[1].Name)
+	assert.Equal(t, []string{"*.example.org"}, certs[1].Domains)
+	assert.Equal(t, 1, certs[1].DaysRemaining)
+	assert.Equal(t, "rsa2048", certs[1].KeyType)
+	assert.False(t, certs[1].MustStaple)
+	assert.Equal(t, "c@example.org", certs[1].Account, "saved account")
+
+	example := certs[2]
+	assert.Equal(t, "example.com", example.Name)
+	assert.Equal(t, []string{"example.com", "www.example.com"}, example.Domains)
+	assert.Equal(t, "2a", example.SerialNumber)
+	assert.Equal(t, "CN=Test CA", example.Issuer)
+	assert.Equal(t, "ec256", example.KeyType)
+	assert.Equal(t, now.Add(-24*time.Hour), example.NotBefore)
+	assert.Equal(t, now.Add(60*24*time.Hour), example.NotAfter)
+	assert.Equal(t, 60, example.DaysRemaining)
+	assert.True(t, example.MustStaple)
+	assert.Equal(t, []chainCertificateInfo{{Subject: "CN=Test CA", Issuer: "CN=Test CA", NotAfter: now.Add(365 * 24 * time.Hour)}}, example.Chain)
+	assert.Equal(t, "https://ca.example.com/cert/2a", example.CertURL)
+	assert.Equal(t, "a@example.com", example.Account)
+	assert.Equal(t, certsStorage.GetNamedFileLocation("example.com.crt"), example.Path)
+}
+
+func Test_collectCertificates_filter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	certsStorage := setupListStorage(t, now)
+
+	testCases := []struct {
+		desc     string
+		filter   certificateFilter
+		expected []string
+	}{
+		{
+			desc:     "no filter",
+			filter:   certificateFilter{days: -1},
+			expected: []string{"192.0.2.1", "", "example.com"},
+		},
+		{
+			desc:     "domain",
+			filter:   certificateFilter{domains: []string{"www.example.com"}, days: -1},
+			expected: []string{"example.com"},
+		},
+		{
+			desc:     "wildcard",
+			filter:   certificateFilter{domains: []string{"foo.example.org"}, days: -1},
+			expected: []string{""},
+		},
+		{
+			desc:     "wildcard name",
+			filter:   certificateFilter{domains: []string{"*.example.org"}, days: -1},
+			expected: []string{""},
+		},
+		{
+			desc:     "IP address",
+			filter:   certificateFilter{domains: []string{"192.0.2.1"}, days: -1},
+			expected: []string{"192.0.2.1"},
+		},
+		{
+			desc:     "multiple domains",
+			filter:   certificateFilter{domains: []string{"example.com", "192.0.2.1"}, days: -1},
+			expected: []string{"192.0.2.1", "example.com"},
+		},
+		{
+			desc:     "unknown domain",
+			filter:   certificateFilter{domains: []string{"example.net"}, days: -1},
+			expected: []string{},
+		},
+		{
+			desc:     "days",
+			filter:   certificateFilter{days: 30},
+			expected: []string{"192.0.2.1", ""},
+		},
+		{
+			desc:     "expired",
+			filter:   certificateFilter{days: 0},
+			expected: []string{"192.0.2.1"},
+		},
+		{
+			desc:     "domain and days",
+			filter:   certificateFilter{domains: []string{"example.com"}, days: 30},
+			expected: []string{},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			certs, err := collectCertificates(certsStorage, nil, test.filter, now)
+			require.NoError(t, err)
+
+			names := []string{}
+			for _, cert := range certs {
+				names = append(names, cert.Name)
+			}
+
+			assert.Equal(t, test.expected, names)
+		})
+	}
+}
+
+func Test_writeList(t *testing.T) {
+	notAfter := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	output := listOutput{
+		Certificates: []certificateInfo{{
+			Name:          "example.com",
+			Domains:       []string{"example.com", "www.example.com"},
+			SerialNumber:  "2a",
+			KeyType:       "ec256",
+			NotAfter:      notAfter,
+			DaysRemaining: 59,
+			Account:       "a@example.com",
+			Path:          "/tmp/certificates/example.com.crt",
+		}},
+		Accounts: []accountInfo{{Email: "a@example.com", Server: "ca.example.com", Path: "/tmp/accounts/ca.example.com/a@example.com"}},
+	}
+
+	t.Run("json", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+
+		err := writeList(buf, listFormatJSON, output)
+		require.NoError(t, err)
+
+		var actual listOutput
+		err = json.Unmarshal(buf.Bytes(), &actual)
+		require.NoError(t, err)
+
+		assert.Equal(t, output, actual)
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+
+		err := writeList(buf, listFormatYAML, output)
+		require.NoError(t, err)
+
+		var actual listOutput
+		err = yaml.Unmarshal(buf.Bytes(), &actual)
+		require.NoError(t, err)
+
+		assert.Equal(t, output, actual)
+	})
+
+	t.Run("table", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+
+		err := writeList(buf, listFormatTable, output)
+		require.NoError(t, err)
+
+		expected := `EMAIL          SERVER          PATH
+a@example.com  ca.example.com  /tmp/accounts/ca.example.com/a@example.com
+
+NAME         DOMAINS                      KEY TYPE  NOT AFTER             DAYS  MUST STAPLE  ACCOUNT        PATH
+example.com  example.com,www.example.com  ec256     2025-03-01T00:00:00Z  59    false        a@example.com  /tmp/certificates/example.com.crt
+`
+
+		assert.Equal(t, expected, buf.String())
+	})
+
+	t.Run("text", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+
+		err := writeList(buf, listFormatText, listOutput{Certificates: []certificateInfo{}, Accounts: []accountInfo{}})
+		require.NoError(t, err)
+
+		assert.Equal(t, "No accounts found.\nNo certificates found.\n", buf.String())
+	})
+}
+
+func Test_findAccount(t *testing.T) {
+	accounts := []accountInfo{
+		{Email: "a@example.com", Server: "ca.example.com"},
+		{Email: "b@example.org", Server: "other.example.org"},
+		{Email: "c@example.org", Server: "other.example.org"},
+	}
+
+	assert.Equal(t, "a@example.com", findAccount(accounts, "https://ca.example.com/cert/1"))
+	assert.Empty(t, findAccount(accounts, "https://other.example.org/cert/1"))
+	assert.Empty(t, findAccount(accounts, "https://unknown.example.net/cert/1"))
+	assert.Empty(t, findAccount(accounts, ""))
+}
+
+// setupListStorage creates a storage containing:
+// - example.com: EC key, must-staple, valid for 60 days, with a chain and a resource file.
+// - _.example.org: RSA key, without common name, valid for 1 day, with an issuer file and a resource file.
+// - 192.0.2.1: expired, without resource file.
+func setupListStorage(t *testing.T, now time.Time) *CertificatesStorage {
+	t.Helper()
+
+	certsStorage := &CertificatesStorage{
+		storage:     NewFileStorage(t.TempDir()),
+		rootPath:    baseCertificatesFolderName,
+		archivePath: baseArchivesFolderName,
+	}
+
+	caKey, err := certcrypto.GeneratePrivateKey(certcrypto.EC256)
+	require.NoError(t, err)
+
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             now.Add(-24 * time.Hour),
+		NotAfter:              now.Add(365 * 24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.(crypto.Signer).Public(), caKey)
+	require.NoError(t, err)
+
+	caPEM := certcrypto.PEMEncode(certcrypto.DERCertificateBytes(caDER))
+
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	createCert := func(keyType certcrypto.KeyType, template *x509.Certificate) []byte {
+		key, errK := certcrypto.GeneratePrivateKey(keyType)
+		require.NoError(t, errK)
+
+		der, errC := x509.CreateCertificate(rand.Reader, template, caCert, key.(crypto.Signer).Public(), caKey)
+		require.NoError(t, errC)
+
+		return certcrypto.PEMEncode(certcrypto.DERCertificateBytes(der))
+	}
+
+	mustStaple, err := asn1.Marshal([]int{5})
+	require.NoError(t, err)
+
+	exampleCom := createCert(certcrypto.EC256, &x509.Certificate{
+		SerialNumber:    big.NewInt(42),
+		Subject:         pkix.Name{CommonName: "example.com"},
+		DNSNames:        []string{"example.com", "www.example.com"},
+		NotBefore:       now.Add(-24 * time.Hour),
+		NotAfter:        now.Add(60 * 24 * time.Hour),
+		ExtraExtensions: []pkix.Extension{{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 24}, Value: mustStaple}},
+	})
+
+	exampleOrg := createCert(certcrypto.RSA2048, &x509.Certificate{
+		SerialNumber: big.NewInt(43),
+		DNSNames:     []string{"*.example.org"},
+		NotBefore:    now.Add(-24 * time.Hour),
+		NotAfter:     now.Add(36 * time.Hour),
+	})
+
+	ip := createCert(certcrypto.EC256, &x509.Certificate{
+		SerialNumber: big.NewInt(44),
+		Subject:      pkix.Name{CommonName: "192.0.2.1"},
+		IPAddresses:  []net.IP{net.ParseIP("192.0.2.1")},
+		NotBefore:    now.Add(-48 * time.Hour),
+		NotAfter:     now.Add(-24 * time.Hour),
+	})
+
+	files := map[string][]byte{
+		"example.com.crt":          append(exampleCom, caPEM...),
+		"example.com.json":         []byte(`{"domain":"example.com","certUrl":"https://ca.example.com/cert/2a"}`),
+		"_.example.org.crt":        exampleOrg,
+		"_.example.org.issuer.crt": caPEM,
+		"_.example.org.json":       []byte(`{"domain":"*.example.org","certUrl":"https://other.example.org/cert/2b","account":"c@example.org"}`),
+		"192.0.2.1.crt":            ip,
+	}
+
+	for name, data := range files {
+		err = certsStorage.storage.WriteFile(baseCertificatesFolderName+"/"+name, data)
+		require.NoError(t, err)
+	}
+
+	return certsStorage
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/LukasDeco/lego/v4/certcrypto"
+	"github.com/LukasDeco/lego/v4/lego"
+	"github.com/LukasDeco/lego/v4/log"
+	"github.com/LukasDeco/lego/v4/stapling"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/crypto/ocsp"
+)
+
+func createOCSP() *cli.Command {
+	return &cli.Command{
+		Name:   "ocsp",
+		Usage:  "Fetch and store the OCSP responses of the certificates, to staple them to the TLS handshakes.",
+		Action: staple,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "Fetch new OCSP responses even if the stored responses are not halfway through their validity period.",
+			},
+		},
+	}
+}
+
+func staple(ctx *cli.Context) error {
+	certsStorage := NewCertificatesStorage(ctx)
+
+	domains := getDomains(ctx)
+	if len(domains) == 0 {
+		names, err := certsStorage.ListCertificates()
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			domains = append(domains, strings.TrimSuffix(name, ".crt"))
+		}
+	}
+
+	if len(domains) == 0 {
+		fmt.Println("No certificates found.")
+		return nil
+	}
+
+	httpClient := lego.NewConfig(nil).HTTPClient
+	if ctx.IsSet("http-timeout") {
+		httpClient.Timeout = time.Duration(ctx.Int("http-timeout")) * time.Second
+	}
+
+	stapler := stapling.New(stapling.Options{
+		HTTPClient: httpClient,
+		Cache:      ocspCache{certsStorage: certsStorage},
+	})
+
+	var failed int
+
+	for _, domain := range domains {
+		s, err := fetchStaple(ctx.Context, stapler, certsStorage, domain, ctx.Bool("force"))
+		if err != nil {
+			log.Printf("Error while fetching the OCSP response of the certificate %s\n\t%v", domain, err)
+
+			fmt.Printf("%s: error: %v\n", domain, err)
+			failed++
+
+			continue
+		}
+
+		origin := "updated"
+		if s.Cached {
+			origin = "cached"
+		}
+
+		fmt.Printf("%s: %s, next update: %s (%s)\n", domain, formatOCSPStatus(s.Response), formatNextUpdate(s.Response), origin)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d OCSP responses could not be fetched", failed, len(domains))
+	}
+
+	return nil
+}
+
+// fetchStaple returns the OCSP response of a stored certificate: the stored response if it's fresh, or a new one.
+func fetchStaple(ctx context.Context, stapler *stapling.Stapler, certsStorage *CertificatesStorage, domain string, force bool) (*stapling.Staple, error) {
+	bundle, err := readCertificateBundle(certsStorage, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	if force {
+		return stapler.Refresh(ctx, domain, bundle)
+	}
+
+	return stapler.Staple(ctx, domain, bundle)
+}
+
+// readCertificateBundle reads the certificate, followed by the issuer certificate if the certificate file doesn't contain the chain.
+func readCertificateBundle(certsStorage *CertificatesStorage, domain string) ([]byte, error) {
+	bundle, err := certsStorage.ReadFile(domain, ".crt")
+	if err != nil {
+		return nil, err
+	}
+
+	certificates, err := certcrypto.ParsePEMBundle(bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(certificates) > 1 {
+		return bundle, nil
+	}
+
+	hasIssuer, err := certsStorage.ExistsFile(domain, ".issuer.crt")
+	if err != nil {
+		return nil, err
+	}
+
+	if !hasIssuer {
+		return bundle, nil
+	}
+
+	issuer, err := certsStorage.ReadFile(domain, ".issuer.crt")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(bundle, issuer...), nil
+}
+
+func formatOCSPStatus(resp *ocsp.Response) string {
+	switch resp.Status {
+	case ocsp.Good:
+		return "good"
+	case ocsp.Revoked:
+		return fmt.Sprintf("revoked (at %s)", resp.RevokedAt.Format(time.RFC3339))
+	default:
+		return "unknown"
+	}
+}
+
+func formatNextUpdate(resp *ocsp.Response) string {
+	if resp.NextUpdate.IsZero() {
+		return "none"
+	}
+
+	return resp.NextUpdate.Format(time.RFC3339)
+}
+
+// ocspCache stores the OCSP responses beside the certificates (".ocsp" files).
+type ocspCache struct {
+	certsStorage *CertificatesStorage
+}
+
+// Get reads the OCSP response of the certificate.
+func (c ocspCache) Get(_ context.Context, name string) ([]byte, error) {
+	data, err := c.certsStorage.ReadFile(name, ".ocsp")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, stapling.ErrCacheMiss
+	}
+
+	return data, err
+}
+
+// Put writes the OCSP response of the certificate.
+// The "filename" option is ignored: the file is always named after the certificate.
+func (c ocspCache) Put(_ context.Context, name string, data []byte) error {
+	key, err := c.certsStorage.getKey(name, ".ocsp")
+	if err != nil {
+		return err
+	}
+
+	return c.certsStorage.storage.WriteFile(key, data)
+}
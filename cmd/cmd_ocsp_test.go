@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/LukasDeco/lego/v4/certcrypto"
+	"github.com/LukasDeco/lego/v4/stapling"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ocspCache(t *testing.T) {
+	certsStorage := &CertificatesStorage{
+		storage:     NewFileStorage(t.TempDir()),
+		rootPath:    baseCertificatesFolderName,
+		archivePath: baseArchivesFolderName,
+		filename:    "ignored",
+	}
+
+	cache := ocspCache{certsStorage: certsStorage}
+
+	ctx := context.Background()
+
+	_, err := cache.Get(ctx, "*.example.com")
+	require.ErrorIs(t, err, stapling.ErrCacheMiss)
+
+	err = cache.Put(ctx, "*.example.com", []byte("staple"))
+	require.NoError(t, err)
+
+	data, err := certsStorage.ReadNamedFile("_.example.com.ocsp")
+	require.NoError(t, err)
+	assert.Equal(t, "staple", string(data))
+
+	data, err = cache.Get(ctx, "*.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "staple", string(data))
+}
+
+func Test_readCertificateBundle(t *testing.T) {
+	certsStorage := setupListStorage(t, time.Now())
+
+	testCases := []struct {
+		desc     string
+		domain   string
+		expected int
+	}{
+		{
+			desc:     "certificate with chain",
+			domain:   "example.com",
+			expected: 2,
+		},
+		{
+			desc:     "certificate with issuer file",
+			domain:   "*.example.org",
+			expected: 2,
+		},
+		{
+			desc:     "certificate only",
+			domain:   "192.0.2.1",
+			expected: 1,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			bundle, err := readCertificateBundle(certsStorage, test.domain)
+			require.NoError(t, err)
+
+			certificates, err := certcrypto.ParsePEMBundle(bundle)
+			require.NoError(t, err)
+
+			assert.Len(t, certificates, test.expected)
+		})
+	}
+}
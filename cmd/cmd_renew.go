@@ -3,10 +3,13 @@ package cmd
 import (
 	"crypto"
 	"crypto/x509"
+	"errors"
+	"fmt"
 	"math/rand"
 	"os"
 	"time"
 
+	"github.com/LukasDeco/lego/v4/acme/api"
 	"github.com/LukasDeco/lego/v4/certcrypto"
 	"github.com/LukasDeco/lego/v4/certificate"
 	"github.com/LukasDeco/lego/v4/lego"
@@ -24,6 +27,22 @@ const (
 	renewEnvCertPFXPath  = "LEGO_CERT_PFX_PATH"
 )
 
+// renewRequest a certificate to renew.
+type renewRequest struct {
+	// domains the domains of the certificate (renewForDomains), the first one identifies the certificate files.
+	domains []string
+
+	// csr the CSR of the certificate (renewForCSR).
+	csr *x509.CertificateRequest
+
+	// scheduled the caller has already decided that the certificate must be renewed now (i.e. the daemon):
+	// the renewal checks ("days" option and renewalInfo endpoint, CRLs) and the delays before the renewal are skipped.
+	scheduled bool
+
+	// revoked the caller has already found that the certificate is revoked (i.e. the daemon).
+	revoked bool
+}
+
 func createRenew() *cli.Command {
 	return &cli.Command{
 		Name:   "renew",
@@ -31,57 +50,97 @@ func createRenew() *cli.Command {
 		Action: renew,
 		Before: func(ctx *cli.Context) error {
 			// we require either domains or csr, but not both
-			hasDomains := len(ctx.StringSlice("domains")) > 0
+			hasDomains := len(ctx.StringSlice("domains")) > 0 || len(ctx.StringSlice("ip")) > 0
 			hasCsr := len(ctx.String("csr")) > 0
 			if hasDomains && hasCsr {
-				log.Fatal("Please specify either --domains/-d or --csr/-c, but not both")
+				log.Fatal("Please specify either --domains/-d (or --ip) or --csr/-c, but not both")
 			}
 			if !hasDomains && !hasCsr {
-				log.Fatal("Please specify --domains/-d (or --csr/-c if you already have a CSR)")
+				log.Fatal("Please specify --domains/-d or --ip (or --csr/-c if you already have a CSR)")
 			}
 			return nil
 		},
-		Flags: []cli.Flag{
-			&cli.IntFlag{
-				Name:  "days",
-				Value: 30,
-				Usage: "The number of days left on a certificate to renew it.",
-			},
-			&cli.BoolFlag{
-				Name:  "reuse-key",
-				Usage: "Used to indicate you want to reuse your current private key for the new certificate.",
-			},
-			&cli.BoolFlag{
-				Name:  "no-bundle",
-				Usage: "Do not create a certificate bundle by adding the issuers certificate to the new certificate.",
-			},
-			&cli.BoolFlag{
-				Name: "must-staple",
-				Usage: "Include the OCSP must staple TLS extension in the CSR and generated certificate." +
-					" Only works if the CSR is generated by lego.",
-			},
-			&cli.StringFlag{
-				Name:  "renew-hook",
-				Usage: "Define a hook. The hook is executed only when the certificates are effectively renewed.",
-			},
-			&cli.StringFlag{
-				Name: "preferred-chain",
-				Usage: "If the CA offers multiple certificate chains, prefer the chain with an issuer matching this Subject Common Name." +
-					" If no match, the default offered chain will be used.",
+		Flags: append(createRenewFlags(),
+			&cli.TimestampFlag{
+				Name:   "not-before",
+				Usage:  "Set the notBefore field in the certificate (RFC 3339 format). Not all CAs support it.",
+				Layout: time.RFC3339,
 			},
-			&cli.StringFlag{
-				Name:  "always-deactivate-authorizations",
-				Usage: "Force the authorizations to be relinquished even if the certificate request was successful.",
+			&cli.TimestampFlag{
+				Name:   "not-after",
+				Usage:  "Set the notAfter field in the certificate (RFC 3339 format). Not all CAs support it.",
+				Layout: time.RFC3339,
 			},
 			&cli.BoolFlag{
 				Name: "no-random-sleep",
 				Usage: "Do not add a random sleep before the renewal." +
 					" We do not recommend using this flag if you are doing your renewals in an automated way.",
 			},
-		},
+			&cli.DurationFlag{
+				Name:  "ari-wait-to-renew-duration",
+				Usage: "The maximum duration you're willing to sleep for a renewal time returned by the renewalInfo endpoint.",
+			},
+			&cli.BoolFlag{
+				Name: "crl-disable",
+				Usage: "Do not check the revocation status of the certificate with the CRLs of its issuer." +
+					" By default, a revoked certificate is renewed immediately.",
+			},
+			&cli.StringSliceFlag{
+				Name: "crl.partitioned",
+				Usage: "The URL of a list of partitioned CRLs (JSON array of CRL URLs, as published in the CCADB)," +
+					" used to check the revocation status of the certificates without CRL distribution point. Supports multiple values.",
+			},
+		),
 	}
 }
 
+// createRenewFlags the flags shared by the renew and daemon commands.
+func createRenewFlags() []cli.Flag {
+	return append([]cli.Flag{
+		&cli.IntFlag{
+			Name:  "days",
+			Value: 30,
+			Usage: "The number of days left on a certificate to renew it.",
+		},
+		&cli.BoolFlag{
+			Name:  "reuse-key",
+			Usage: "Used to indicate you want to reuse your current private key for the new certificate.",
+		},
+		&cli.BoolFlag{
+			Name:  "no-bundle",
+			Usage: "Do not create a certificate bundle by adding the issuers certificate to the new certificate.",
+		},
+		&cli.BoolFlag{
+			Name: "must-staple",
+			Usage: "Include the OCSP must staple TLS extension in the CSR and generated certificate." +
+				" Only works if the CSR is generated by lego.",
+		},
+		&cli.StringFlag{
+			Name:  "renew-hook",
+			Usage: "Define a hook. The hook is executed only when the certificates are effectively renewed. Same as --deploy-hook.",
+		},
+		&cli.StringFlag{
+			Name: "preferred-chain",
+			Usage: "If the CA offers multiple certificate chains, prefer the chain with an issuer matching this Subject Common Name." +
+				" If no match, the default offered chain will be used.",
+		},
+		&cli.StringFlag{
+			Name:  "always-deactivate-authorizations",
+			Usage: "Force the authorizations to be relinquished even if the certificate request was successful.",
+		},
+		&cli.StringFlag{
+			Name: "profile",
+			Usage: "If the CA offers multiple certificate profiles (draft-aaron-acme-profiles), choose this one." +
+				" The profile must be advertised by the CA.",
+		},
+		&cli.BoolFlag{
+			Name: "ari-disable",
+			Usage: "Do not use the renewalInfo endpoint (draft-ietf-acme-ari) to check if a certificate should be renewed." +
+				" The renewal is then only based on the '--days' option.",
+		},
+	}, createHookFlags()...)
+}
+
 func renew(ctx *cli.Context) error {
 	account, client := setup(ctx, NewAccountsStorage(ctx))
 	setupChallenges(ctx, client)
@@ -98,15 +157,20 @@ func renew(ctx *cli.Context) error {
 
 	// CSR
 	if ctx.IsSet("csr") {
-		return renewForCSR(ctx, client, certsStorage, bundle, meta)
+		csr, err := readCSRFile(ctx.String("csr"))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		return renewForCSR(ctx, client, certsStorage, bundle, meta, renewRequest{csr: csr})
 	}
 
 	// Domains
-	return renewForDomains(ctx, client, certsStorage, bundle, meta)
+	return renewForDomains(ctx, client, certsStorage, bundle, meta, renewRequest{domains: getDomains(ctx)})
 }
 
-func renewForDomains(ctx *cli.Context, client *lego.Client, certsStorage *CertificatesStorage, bundle bool, meta map[string]string) error {
-	domains := ctx.StringSlice("domains")
+func renewForDomains(ctx *cli.Context, client *lego.Client, certsStorage *CertificatesStorage, bundle bool, meta map[string]string, req renewRequest) error {
+	domains := req.domains
 	domain := domains[0]
 
 	// load the cert resource from files.
@@ -114,13 +178,26 @@ func renewForDomains(ctx *cli.Context, client *lego.Client, certsStorage *Certif
 	// as web servers would not be able to work with a combined file.
 	certificates, err := certsStorage.ReadCertificate(domain, ".crt")
 	if err != nil {
-		log.Fatalf("Error while loading the certificate for domain %s\n\t%v", domain, err)
+		return fmt.Errorf("error while loading the certificate for domain %s: %w", domain, err)
 	}
 
 	cert := certificates[0]
 
-	if !needRenewal(cert, domain, ctx.Int("days")) {
-		return nil
+	revoked := req.revoked || (!req.scheduled && isRevoked(ctx, certsStorage, domain))
+	if revoked {
+		// A revoked certificate is renewed now, regardless of its expiration date.
+		req.scheduled = true
+	}
+
+	var ariRenewalTime *time.Time
+	if !req.scheduled {
+		if !ctx.Bool("ari-disable") {
+			ariRenewalTime = getARIRenewalTime(ctx, cert, domain, client)
+		}
+
+		if ariRenewalTime == nil && !needRenewal(cert, domain, ctx.Int("days")) {
+			return nil
+		}
 	}
 
 	// This is just meant to be informal for the user.
@@ -130,10 +207,13 @@ func renewForDomains(ctx *cli.Context, client *lego.Client, certsStorage *Certif
 	certDomains := certcrypto.ExtractDomains(cert)
 
 	var privateKey crypto.PrivateKey
-	if ctx.Bool("reuse-key") {
+	if ctx.Bool("reuse-key") && revoked {
+		// The key may have been compromised: a new one is generated.
+		log.Warnf("[%s] The certificate has been revoked: the private key is not reused (\"reuse-key\" option).", domain)
+	} else if ctx.Bool("reuse-key") {
 		keyBytes, errR := certsStorage.ReadFile(domain, ".key")
 		if errR != nil {
-			log.Fatalf("Error while loading the private key for domain %s\n\t%v", domain, errR)
+			return fmt.Errorf("error while loading the private key for domain %s: %w", domain, errR)
 		}
 
 		privateKey, errR = certcrypto.ParsePEMPrivateKey(keyBytes)
@@ -144,7 +224,10 @@ func renewForDomains(ctx *cli.Context, client *lego.Client, certsStorage *Certif
 
 	// https://github.com/go-acme/lego/issues/1656
 	// https://github.com/certbot/certbot/blob/284023a1b7672be2bd4018dd7623b3b92197d4b0/certbot/certbot/_internal/renewal.py#L435-L440
-	if !isatty.IsTerminal(os.Stdout.Fd()) && !ctx.Bool("no-random-sleep") {
+	// The renewal time suggested by the renewalInfo endpoint is already randomized inside the suggested window.
+	if ariRenewalTime != nil {
+		sleepUntil(domain, *ariRenewalTime)
+	} else if !req.scheduled && !isatty.IsTerminal(os.Stdout.Fd()) && !ctx.Bool("no-random-sleep") {
 		// https://github.com/certbot/certbot/blob/284023a1b7672be2bd4018dd7623b3b92197d4b0/certbot/certbot/_internal/renewal.py#L472
 		const jitter = 8 * time.Minute
 		rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
@@ -161,28 +244,32 @@ func renewForDomains(ctx *cli.Context, client *lego.Client, certsStorage *Certif
 		MustStaple:                     ctx.Bool("must-staple"),
 		PreferredChain:                 ctx.String("preferred-chain"),
 		AlwaysDeactivateAuthorizations: ctx.Bool("always-deactivate-authorizations"),
-	}
-	certRes, err := client.Certificate.Obtain(request)
-	if err != nil {
-		log.Fatal(err)
+		Profile:                        ctx.String("profile"),
+		NotBefore:                      getTime(ctx, "not-before"),
+		NotAfter:                       getTime(ctx, "not-after"),
 	}
 
-	certsStorage.SaveResource(certRes)
+	if !ctx.Bool("ari-disable") {
+		request.ReplacesCertID = getARICertID(cert, domain)
+	}
 
 	meta[renewEnvCertDomain] = domain
-	meta[renewEnvCertPath] = certsStorage.GetFileName(domain, ".crt")
-	meta[renewEnvCertKeyPath] = certsStorage.GetFileName(domain, ".key")
-	meta[renewEnvCertPEMPath] = certsStorage.GetFileName(domain, ".pem")
-	meta[renewEnvCertPFXPath] = certsStorage.GetFileName(domain, ".pfx")
+	payload := &hookPayload{Account: meta[renewEnvAccountEmail], Domain: domain, Domains: request.Domains}
 
-	return launchHook(ctx.String("renew-hook"), meta)
+	hks := newHooks(ctx, "renew-hook")
+
+	return hks.wrap(meta, payload, func() error {
+		certRes, err := client.Certificate.Obtain(request)
+		if err != nil {
+			return err
+		}
+
+		return deployCertificate(hks, certsStorage, certRes, meta, payload)
+	})
 }
 
-func renewForCSR(ctx *cli.Context, client *lego.Client, certsStorage *CertificatesStorage, bundle bool, meta map[string]string) error {
-	csr, err := readCSRFile(ctx.String("csr"))
-	if err != nil {
-		log.Fatal(err)
-	}
+func renewForCSR(ctx *cli.Context, client *lego.Client, certsStorage *CertificatesStorage, bundle bool, meta map[string]string, req renewRequest) error {
+	csr := req.csr
 
 	domain := csr.Subject.CommonName
 
@@ -191,36 +278,105 @@ func renewForCSR(ctx *cli.Context, client *lego.Client, certsStorage *Certificat
 	// as web servers would not be able to work with a combined file.
 	certificates, err := certsStorage.ReadCertificate(domain, ".crt")
 	if err != nil {
-		log.Fatalf("Error while loading the certificate for domain %s\n\t%v", domain, err)
+		return fmt.Errorf("error while loading the certificate for domain %s: %w", domain, err)
 	}
 
 	cert := certificates[0]
 
-	if !needRenewal(cert, domain, ctx.Int("days")) {
-		return nil
+	revoked := req.revoked || (!req.scheduled && isRevoked(ctx, certsStorage, domain))
+	if revoked {
+		// A revoked certificate is renewed now, regardless of its expiration date.
+		req.scheduled = true
+	}
+
+	var ariRenewalTime *time.Time
+	if !req.scheduled {
+		if !ctx.Bool("ari-disable") {
+			ariRenewalTime = getARIRenewalTime(ctx, cert, domain, client)
+		}
+
+		if ariRenewalTime == nil && !needRenewal(cert, domain, ctx.Int("days")) {
+			return nil
+		}
+	}
+
+	if ariRenewalTime != nil {
+		sleepUntil(domain, *ariRenewalTime)
 	}
 
 	// This is just meant to be informal for the user.
 	timeLeft := cert.NotAfter.Sub(time.Now().UTC())
 	log.Infof("[%s] acme: Trying renewal with %d hours remaining", domain, int(timeLeft.Hours()))
 
-	certRes, err := client.Certificate.ObtainForCSR(certificate.ObtainForCSRRequest{
+	if revoked {
+		// The key of the CSR can't be replaced by lego.
+		log.Warnf("[%s] The certificate has been revoked: the renewal reuses the key of the CSR, provide a CSR with a new key if the key may have been compromised.", domain)
+	}
+
+	request := certificate.ObtainForCSRRequest{
 		CSR:                            csr,
 		Bundle:                         bundle,
 		PreferredChain:                 ctx.String("preferred-chain"),
 		AlwaysDeactivateAuthorizations: ctx.Bool("always-deactivate-authorizations"),
+		Profile:                        ctx.String("profile"),
+		NotBefore:                      getTime(ctx, "not-before"),
+		NotAfter:                       getTime(ctx, "not-after"),
+	}
+
+	if !ctx.Bool("ari-disable") {
+		request.ReplacesCertID = getARICertID(cert, domain)
+	}
+
+	meta[renewEnvCertDomain] = domain
+	payload := &hookPayload{Account: meta[renewEnvAccountEmail], Domain: domain, Domains: certcrypto.ExtractDomainsCSR(csr)}
+
+	hks := newHooks(ctx, "renew-hook")
+
+	return hks.wrap(meta, payload, func() error {
+		certRes, err := client.Certificate.ObtainForCSR(request)
+		if err != nil {
+			return err
+		}
+
+		return deployCertificate(hks, certsStorage, certRes, meta, payload)
 	})
+}
+
+// deployCertificate saves the certificate, and executes the deploy hooks.
+// A failure to save the certificate is returned as is: the operation has failed (e.g. the daemon retries the renewal).
+func deployCertificate(hks *hooks, certsStorage *CertificatesStorage, certRes *certificate.Resource, meta map[string]string, payload *hookPayload) error {
+	err := certsStorage.SaveResource(certRes, payload.Account)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	certsStorage.SaveResource(certRes)
+	domain := certRes.Domain
 
 	meta[renewEnvCertDomain] = domain
-	meta[renewEnvCertPath] = certsStorage.GetFileName(domain, ".crt")
-	meta[renewEnvCertKeyPath] = certsStorage.GetFileName(domain, ".key")
 
-	return launchHook(ctx.String("renew-hook"), meta)
+	for key, ext := range map[string]string{
+		renewEnvCertPath:    ".crt",
+		renewEnvCertKeyPath: ".key",
+		renewEnvCertPEMPath: ".pem",
+		renewEnvCertPFXPath: ".pfx",
+	} {
+		meta[key], err = certsStorage.GetFileName(domain, ext)
+		if err != nil {
+			return &hookError{err: fmt.Errorf("unable to describe the certificate: %w", err)}
+		}
+	}
+
+	cert, err := certcrypto.ParsePEMCertificate(certRes.Certificate)
+	if err != nil {
+		return &hookError{err: fmt.Errorf("unable to describe the certificate: %w", err)}
+	}
+
+	err = payload.setCertificate(certsStorage, domain, cert, certRes.CertURL)
+	if err != nil {
+		return &hookError{err: fmt.Errorf("unable to describe the certificate: %w", err)}
+	}
+
+	return hks.runDeploy(meta, payload)
 }
 
 func needRenewal(x509Cert *x509.Certificate, domain string, days int) bool {
@@ -240,6 +396,58 @@ func needRenewal(x509Cert *x509.Certificate, domain string, days int) bool {
 	return true
 }
 
+// getARIRenewalTime checks if the certificate needs to be renewed using the renewalInfo endpoint.
+// Returns nil if the CA doesn't support ARI or if the renewal is not needed yet.
+func getARIRenewalTime(ctx *cli.Context, cert *x509.Certificate, domain string, client *lego.Client) *time.Time {
+	if cert.IsCA {
+		log.Fatalf("[%s] Certificate bundle starts with a CA certificate", domain)
+	}
+
+	renewalInfo, err := client.Certificate.GetRenewalInfo(certificate.RenewalInfoRequest{Cert: cert})
+	if err != nil {
+		if !errors.Is(err, api.ErrNoARI) {
+			log.Warnf("[%s] acme: calling renewal info endpoint: %v", domain, err)
+		}
+		return nil
+	}
+
+	renewalTime := renewalInfo.ShouldRenewAt(time.Now().UTC(), ctx.Duration("ari-wait-to-renew-duration"))
+	if renewalTime == nil {
+		log.Infof("[%s] acme: renewalInfo endpoint indicates that renewal is not needed", domain)
+		return nil
+	}
+
+	log.Infof("[%s] acme: renewalInfo endpoint indicates that renewal is needed", domain)
+
+	if renewalInfo.ExplanationURL != "" {
+		log.Infof("[%s] acme: renewalInfo endpoint provided an explanation: %s", domain, renewalInfo.ExplanationURL)
+	}
+
+	return renewalTime
+}
+
+// getARICertID returns the ARI certificate ID of the certificate to replace,
+// or an empty string if it cannot be computed (the order is then created without the "replaces" field).
+func getARICertID(cert *x509.Certificate, domain string) string {
+	certID, err := certificate.MakeARICertID(cert)
+	if err != nil {
+		log.Warnf("[%s] acme: unable to compute the ARI certificate ID: %v", domain, err)
+		return ""
+	}
+
+	return certID
+}
+
+func sleepUntil(domain string, renewalTime time.Time) {
+	now := time.Now().UTC()
+	if !renewalTime.After(now) {
+		return
+	}
+
+	log.Infof("[%s] acme: sleeping %s until renewal time %s", domain, renewalTime.Sub(now), renewalTime)
+	time.Sleep(renewalTime.Sub(now))
+}
+
 func merge(prevDomains, nextDomains []string) []string {
 	for _, next := range nextDomains {
 		var found bool
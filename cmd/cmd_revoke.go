@@ -1,7 +1,17 @@
 package cmd
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
 	"github.com/LukasDeco/lego/v4/acme"
+	"github.com/LukasDeco/lego/v4/certcrypto"
+	"github.com/LukasDeco/lego/v4/lego"
 	"github.com/LukasDeco/lego/v4/log"
 	"github.com/urfave/cli/v2"
 )
@@ -27,50 +37,330 @@ func createRevoke() *cli.Command {
 					" 9 (privilegeWithdrawn), or 10 (aACompromise).",
 				Value: acme.CRLReasonUnspecified,
 			},
+			&cli.StringSliceFlag{
+				Name:  "cert",
+				Usage: "Path to a certificate file (PEM) to revoke, instead of the stored certificates. Supports multiple values.",
+			},
+			&cli.StringSliceFlag{
+				Name: "key",
+				Usage: "Path to the private key of a certificate given with '--cert' (in the same order)." +
+					" The revocation request is signed with this key instead of the account key. Supports multiple values.",
+			},
+			&cli.StringFlag{
+				Name: "list",
+				Usage: "Path to a file listing the certificates to revoke, one per line: the path of the certificate," +
+					" optionally followed by the path of its private key.",
+			},
+			&cli.StringSliceFlag{
+				Name:  "glob",
+				Usage: "Revoke the stored certificates with a name matching the pattern (e.g. '*.example.com'). Supports multiple values.",
+			},
+			&cli.BoolFlag{
+				Name:  "cert-key",
+				Usage: "Sign the revocation requests of the stored certificates with their private key instead of the account key.",
+			},
 		},
 	}
 }
 
-func revoke(ctx *cli.Context) error {
-	acc, client := setup(ctx, NewAccountsStorage(ctx))
+// revocationTarget a certificate to revoke.
+type revocationTarget struct {
+	// domain the domain of a stored certificate.
+	domain string
 
-	if acc.Registration == nil {
-		log.Fatalf("Account %s is not registered. Use 'run' to register a new account.\n", acc.Email)
+	// certPath the path of a certificate file.
+	certPath string
+	// keyPath the path of the private key file of the certificate.
+	keyPath string
+
+	// withKey signs the request with the private key of the certificate instead of the account key.
+	withKey bool
+}
+
+func (t revocationTarget) String() string {
+	if t.domain != "" {
+		return t.domain
 	}
 
+	return t.certPath
+}
+
+func revoke(ctx *cli.Context) error {
 	certsStorage := NewCertificatesStorage(ctx)
-	certsStorage.CreateRootFolder()
 
-	for _, domain := range ctx.StringSlice("domains") {
-		log.Printf("Trying to revoke certificate for domain %s", domain)
+	targets, err := getRevocationTargets(ctx, certsStorage)
+	if err != nil {
+		return err
+	}
 
-		certBytes, err := certsStorage.ReadFile(domain, ".crt")
+	if len(targets) == 0 {
+		return errors.New("no certificate to revoke: use '--domains', '--glob', '--cert', or '--list'")
+	}
+
+	client := newRevocationClient(ctx, targets)
+
+	reason := ctx.Uint("reason")
+
+	var failed int
+
+	results := make([]string, 0, len(targets))
+
+	for _, target := range targets {
+		log.Printf("Trying to revoke certificate %s", target)
+
+		err = revokeCertificate(client, certsStorage, target, &reason)
 		if err != nil {
-			log.Fatalf("Error while revoking the certificate for domain %s\n\t%v", domain, err)
+			log.Printf("Error while revoking the certificate %s\n\t%v", target, err)
+
+			results = append(results, fmt.Sprintf("%s: error: %v", target, err))
+			failed++
+
+			continue
 		}
 
-		reason := ctx.Uint("reason")
+		log.Println("Certificate was revoked.")
+
+		if target.domain == "" || ctx.Bool("keep") {
+			results = append(results, fmt.Sprintf("%s: revoked", target))
+			continue
+		}
 
-		err = client.Certificate.RevokeWithReason(certBytes, &reason)
+		err = certsStorage.MoveToArchive(target.domain)
 		if err != nil {
-			log.Fatalf("Error while revoking the certificate for domain %s\n\t%v", domain, err)
+			log.Printf("Error while archiving the certificate %s\n\t%v", target, err)
+
+			results = append(results, fmt.Sprintf("%s: revoked, not archived: %v", target, err))
+			failed++
+
+			continue
 		}
 
-		log.Println("Certificate was revoked.")
+		log.Println("Certificate was archived for domain:", target.domain)
 
-		if ctx.Bool("keep") {
-			return nil
+		results = append(results, fmt.Sprintf("%s: revoked and archived", target))
+	}
+
+	if len(targets) > 1 {
+		fmt.Println("Revocation results:")
+
+		for _, result := range results {
+			fmt.Println("  " + result)
 		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d certificates could not be revoked", failed, len(targets))
+	}
+
+	return nil
+}
+
+// newRevocationClient creates the client of the revocations.
+// When all the requests are signed with the keys of the certificates, no account is needed.
+func newRevocationClient(ctx *cli.Context, targets []revocationTarget) *lego.Client {
+	for _, target := range targets {
+		if target.withKey {
+			continue
+		}
+
+		acc, client := setup(ctx, NewAccountsStorage(ctx))
+
+		if acc.Registration == nil {
+			log.Fatalf("Account %s is not registered. Use 'run' to register a new account.\n", acc.Email)
+		}
+
+		return client
+	}
 
-		certsStorage.CreateArchiveFolder()
+	keyType := getKeyType(ctx)
+
+	// The account key is only used to get the nonces.
+	privateKey, err := certcrypto.GeneratePrivateKey(keyType)
+	if err != nil {
+		log.Fatalf("Could not generate a private key: %v", err)
+	}
+
+	return newClient(ctx, &Account{Email: ctx.String("email"), key: privateKey}, keyType)
+}
+
+// getRevocationTargets returns the certificates to revoke, without duplicates:
+// the stored certificates (--domains, --glob), followed by the certificate files (--cert, --list).
+func getRevocationTargets(ctx *cli.Context, certsStorage *CertificatesStorage) ([]revocationTarget, error) {
+	domains, err := matchStoredCertificates(certsStorage, getDomains(ctx), ctx.StringSlice("glob"))
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []revocationTarget
+
+	for _, domain := range domains {
+		targets = append(targets, revocationTarget{domain: domain, withKey: ctx.Bool("cert-key")})
+	}
+
+	certPaths := ctx.StringSlice("cert")
+	keyPaths := ctx.StringSlice("key")
+
+	if len(keyPaths) > 0 && len(keyPaths) != len(certPaths) {
+		return nil, fmt.Errorf("the number of keys (%d) doesn't match the number of certificates (%d)", len(keyPaths), len(certPaths))
+	}
+
+	for i, certPath := range certPaths {
+		target := revocationTarget{certPath: certPath}
+
+		if len(keyPaths) > 0 {
+			target.keyPath = keyPaths[i]
+			target.withKey = true
+		}
 
-		err = certsStorage.MoveToArchive(domain)
+		targets = append(targets, target)
+	}
+
+	if ctx.IsSet("list") {
+		file, err := os.Open(ctx.String("list"))
+		if err != nil {
+			return nil, err
+		}
+
+		defer func() { _ = file.Close() }()
+
+		listed, err := parseRevocationList(file)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("%s: %w", ctx.String("list"), err)
 		}
 
-		log.Println("Certificate was archived for domain:", domain)
+		targets = append(targets, listed...)
 	}
 
-	return nil
+	return dedupRevocationTargets(targets), nil
+}
+
+// matchStoredCertificates returns the domains of the stored certificates:
+// the domains, followed by the names of the stored certificates matching the patterns.
+func matchStoredCertificates(certsStorage *CertificatesStorage, domains, patterns []string) ([]string, error) {
+	if len(patterns) == 0 {
+		return domains, nil
+	}
+
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	names, err := certsStorage.ListCertificates()
+	if err != nil {
+		return nil, err
+	}
+
+	matched := append([]string{}, domains...)
+
+	for _, name := range names {
+		domain := strings.TrimSuffix(name, ".crt")
+
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, domain); ok {
+				matched = append(matched, domain)
+				break
+			}
+		}
+	}
+
+	return matched, nil
+}
+
+// parseRevocationList parses a list of certificates:
+// one certificate per line, the path of the certificate optionally followed by the path of its private key.
+// The empty lines and the lines starting with '#' are ignored.
+func parseRevocationList(r io.Reader) ([]revocationTarget, error) {
+	var targets []revocationTarget
+
+	scanner := bufio.NewScanner(r)
+
+	for i := 1; scanner.Scan(); i++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+
+		switch len(fields) {
+		case 1:
+			targets = append(targets, revocationTarget{certPath: fields[0]})
+		case 2:
+			targets = append(targets, revocationTarget{certPath: fields[0], keyPath: fields[1], withKey: true})
+		default:
+			return nil, fmt.Errorf("line %d: expected a certificate path optionally followed by a key path, got %q", i, line)
+		}
+	}
+
+	return targets, scanner.Err()
+}
+
+func dedupRevocationTargets(targets []revocationTarget) []revocationTarget {
+	seen := map[revocationTarget]struct{}{}
+
+	var result []revocationTarget
+
+	for _, target := range targets {
+		if _, ok := seen[target]; ok {
+			continue
+		}
+
+		seen[target] = struct{}{}
+		result = append(result, target)
+	}
+
+	return result
+}
+
+// revokeCertificate revokes a certificate, with the account key or the private key of the certificate.
+func revokeCertificate(client *lego.Client, certsStorage *CertificatesStorage, target revocationTarget, reason *uint) error {
+	certBytes, keyBytes, err := readRevocationTarget(certsStorage, target)
+	if err != nil {
+		return err
+	}
+
+	if !target.withKey {
+		return client.Certificate.RevokeWithReason(certBytes, reason)
+	}
+
+	privateKey, err := certcrypto.ParsePEMPrivateKey(keyBytes)
+	if err != nil {
+		return fmt.Errorf("invalid private key: %w", err)
+	}
+
+	return client.Certificate.RevokeWithKey(certBytes, privateKey, reason)
+}
+
+// readRevocationTarget reads the certificate, and the private key if needed.
+func readRevocationTarget(certsStorage *CertificatesStorage, target revocationTarget) (certBytes, keyBytes []byte, err error) {
+	read := os.ReadFile
+	certName, keyName := target.certPath, target.keyPath
+
+	if target.domain != "" {
+		read = certsStorage.ReadNamedFile
+		name, errS := sanitizedDomain(target.domain)
+		if errS != nil {
+			return nil, nil, errS
+		}
+
+		certName, keyName = name+".crt", name+".key"
+	}
+
+	certBytes, err = read(certName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !target.withKey {
+		return certBytes, nil, nil
+	}
+
+	keyBytes, err = read(keyName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return certBytes, keyBytes, nil
 }
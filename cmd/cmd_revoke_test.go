@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseRevocationList(t *testing.T) {
+	content := `
+# compromised keys
+/etc/ssl/a.crt /etc/ssl/a.key
+
+/etc/ssl/b.crt
+   /etc/ssl/c.crt	/etc/ssl/c.key
+`
+
+	targets, err := parseRevocationList(strings.NewReader(content))
+	require.NoError(t, err)
+
+	expected := []revocationTarget{
+		{certPath: "/etc/ssl/a.crt", keyPath: "/etc/ssl/a.key", withKey: true},
+		{certPath: "/etc/ssl/b.crt"},
+		{certPath: "/etc/ssl/c.crt", keyPath: "/etc/ssl/c.key", withKey: true},
+	}
+
+	assert.Equal(t, expected, targets)
+}
+
+func Test_parseRevocationList_error(t *testing.T) {
+	_, err := parseRevocationList(strings.NewReader("/etc/ssl/a.crt\n/etc/ssl/b.crt /etc/ssl/b.key extra\n"))
+	require.EqualError(t, err, `line 2: expected a certificate path optionally followed by a key path, got "/etc/ssl/b.crt /etc/ssl/b.key extra"`)
+}
+
+func Test_matchStoredCertificates(t *testing.T) {
+	storage := NewFileStorage(t.TempDir())
+
+	certsStorage := &CertificatesStorage{
+		storage:     storage,
+		rootPath:    baseCertificatesFolderName,
+		archivePath: baseArchivesFolderName,
+	}
+
+	for _, name := range []string{"example.com.crt", "www.example.com.crt", "_.example.com.crt", "example.com.issuer.crt", "example.org.crt"} {
+		err := storage.WriteFile("certificates/"+name, []byte(name))
+		require.NoError(t, err)
+	}
+
+	testCases := []struct {
+		desc     string
+		domains  []string
+		patterns []string
+		expected []string
+	}{
+		{
+			desc:     "domains only",
+			domains:  []string{"example.net"},
+			expected: []string{"example.net"},
+		},
+		{
+			desc:     "subdomains",
+			patterns: []string{"*.example.com"},
+			expected: []string{"_.example.com", "www.example.com"},
+		},
+		{
+			desc:     "domains and patterns",
+			domains:  []string{"example.net"},
+			patterns: []string{"example.*", "*.example.com"},
+			expected: []string{"example.net", "_.example.com", "example.com", "example.org", "www.example.com"},
+		},
+		{
+			desc:     "no match",
+			patterns: []string{"*.example.net"},
+			expected: []string{},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			domains, err := matchStoredCertificates(certsStorage, test.domains, test.patterns)
+			require.NoError(t, err)
+
+			if len(test.expected) == 0 {
+				assert.Empty(t, domains)
+			} else {
+				assert.Equal(t, test.expected, domains)
+			}
+		})
+	}
+}
+
+func Test_matchStoredCertificates_invalidPattern(t *testing.T) {
+	_, err := matchStoredCertificates(&CertificatesStorage{}, nil, []string{"[a-"})
+	require.EqualError(t, err, `invalid pattern "[a-": syntax error in pattern`)
+}
+
+func Test_dedupRevocationTargets(t *testing.T) {
+	targets := []revocationTarget{
+		{domain: "example.com"},
+		{certPath: "a.crt"},
+		{domain: "example.com"},
+		{certPath: "a.crt", keyPath: "a.key", withKey: true},
+		{certPath: "a.crt"},
+	}
+
+	expected := []revocationTarget{
+		{domain: "example.com"},
+		{certPath: "a.crt"},
+		{certPath: "a.crt", keyPath: "a.key", withKey: true},
+	}
+
+	assert.Equal(t, expected, dedupRevocationTargets(targets))
+}
+
+func Test_readRevocationTarget(t *testing.T) {
+	storage := NewFileStorage(t.TempDir())
+
+	certsStorage := &CertificatesStorage{
+		storage:     storage,
+		rootPath:    baseCertificatesFolderName,
+		archivePath: baseArchivesFolderName,
+	}
+
+	for _, name := range []string{"_.example.com.crt", "_.example.com.key"} {
+		err := storage.WriteFile("certificates/"+name, []byte(name))
+		require.NoError(t, err)
+	}
+
+	dir := t.TempDir()
+
+	for _, name := range []string{"a.crt", "a.key"} {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o600)
+		require.NoError(t, err)
+	}
+
+	testCases := []struct {
+		desc         string
+		target       revocationTarget
+		expectedCert string
+		expectedKey  string
+	}{
+		{
+			desc:         "stored certificate",
+			target:       revocationTarget{domain: "*.example.com"},
+			expectedCert: "_.example.com.crt",
+		},
+		{
+			desc:         "stored certificate with key",
+			target:       revocationTarget{domain: "_.example.com", withKey: true},
+			expectedCert: "_.example.com.crt",
+			expectedKey:  "_.example.com.key",
+		},
+		{
+			desc:         "file",
+			target:       revocationTarget{certPath: filepath.Join(dir, "a.crt")},
+			expectedCert: "a.crt",
+		},
+		{
+			desc:         "file with key",
+			target:       revocationTarget{certPath: filepath.Join(dir, "a.crt"), keyPath: filepath.Join(dir, "a.key"), withKey: true},
+			expectedCert: "a.crt",
+			expectedKey:  "a.key",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			certBytes, keyBytes, err := readRevocationTarget(certsStorage, test.target)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expectedCert, string(certBytes))
+			assert.Equal(t, test.expectedKey, string(keyBytes))
+		})
+	}
+}
+
+func Test_readRevocationTarget_missingKey(t *testing.T) {
+	dir := t.TempDir()
+
+	err := os.WriteFile(filepath.Join(dir, "a.crt"), []byte("a.crt"), 0o600)
+	require.NoError(t, err)
+
+	_, _, err = readRevocationTarget(nil, revocationTarget{certPath: filepath.Join(dir, "a.crt"), keyPath: filepath.Join(dir, "a.key"), withKey: true})
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -2,9 +2,11 @@ package cmd
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/LukasDeco/lego/v4/certificate"
 	"github.com/LukasDeco/lego/v4/lego"
@@ -19,18 +21,18 @@ func createRun() *cli.Command {
 		Usage: "Register an account, then create and install a certificate",
 		Before: func(ctx *cli.Context) error {
 			// we require either domains or csr, but not both
-			hasDomains := len(ctx.StringSlice("domains")) > 0
+			hasDomains := len(ctx.StringSlice("domains")) > 0 || len(ctx.StringSlice("ip")) > 0
 			hasCsr := len(ctx.String("csr")) > 0
 			if hasDomains && hasCsr {
-				log.Fatal("Please specify either --domains/-d or --csr/-c, but not both")
+				log.Fatal("Please specify either --domains/-d (or --ip) or --csr/-c, but not both")
 			}
 			if !hasDomains && !hasCsr {
-				log.Fatal("Please specify --domains/-d (or --csr/-c if you already have a CSR)")
+				log.Fatal("Please specify --domains/-d or --ip (or --csr/-c if you already have a CSR)")
 			}
 			return nil
 		},
 		Action: run,
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			&cli.BoolFlag{
 				Name:  "no-bundle",
 				Usage: "Do not create a certificate bundle by adding the issuers certificate to the new certificate.",
@@ -53,7 +55,22 @@ func createRun() *cli.Command {
 				Name:  "always-deactivate-authorizations",
 				Usage: "Force the authorizations to be relinquished even if the certificate request was successful.",
 			},
-		},
+			&cli.StringFlag{
+				Name: "profile",
+				Usage: "If the CA offers multiple certificate profiles (draft-aaron-acme-profiles), choose this one." +
+					" The profile must be advertised by the CA.",
+			},
+			&cli.TimestampFlag{
+				Name:   "not-before",
+				Usage:  "Set the notBefore field in the certificate (RFC 3339 format). Not all CAs support it.",
+				Layout: time.RFC3339,
+			},
+			&cli.TimestampFlag{
+				Name:   "not-after",
+				Usage:  "Set the notAfter field in the certificate (RFC 3339 format). Not all CAs support it.",
+				Layout: time.RFC3339,
+			},
+		}, createHookFlags()...),
 	}
 }
 
@@ -89,25 +106,35 @@ func run(ctx *cli.Context) error {
 	}
 
 	certsStorage := NewCertificatesStorage(ctx)
-	certsStorage.CreateRootFolder()
 
-	cert, err := obtainCertificate(ctx, client)
-	if err != nil {
-		// Make sure to return a non-zero exit code if ObtainSANCertificate returned at least one error.
-		// Due to us not returning partial certificate we can just exit here instead of at the end.
-		log.Fatalf("Could not obtain certificates:\n\t%v", err)
+	meta := map[string]string{renewEnvAccountEmail: account.Email}
+	payload := &hookPayload{Account: account.Email}
+
+	if domains := getDomains(ctx); len(domains) > 0 {
+		meta[renewEnvCertDomain] = domains[0]
+		payload.Domain = domains[0]
+		payload.Domains = domains
 	}
 
-	certsStorage.SaveResource(cert)
+	hks := newHooks(ctx, "run-hook")
 
-	meta := map[string]string{
-		renewEnvAccountEmail: account.Email,
-		renewEnvCertDomain:   cert.Domain,
-		renewEnvCertPath:     certsStorage.GetFileName(cert.Domain, ".crt"),
-		renewEnvCertKeyPath:  certsStorage.GetFileName(cert.Domain, ".key"),
+	err := hks.wrap(meta, payload, func() error {
+		cert, err := obtainCertificate(ctx, client)
+		if err != nil {
+			return err
+		}
+
+		return deployCertificate(hks, certsStorage, cert, meta, payload)
+	})
+
+	var hookErr *hookError
+	if err != nil && !errors.As(err, &hookErr) {
+		// Make sure to return a non-zero exit code if ObtainSANCertificate returned at least one error.
+		// Due to us not returning partial certificate we can just exit here instead of at the end.
+		log.Fatalf("Could not obtain certificates:\n\t%v", err)
 	}
 
-	return launchHook(ctx.String("run-hook"), meta)
+	return err
 }
 
 func handleTOS(ctx *cli.Context, client *lego.Client) bool {
@@ -165,7 +192,7 @@ func register(ctx *cli.Context, client *lego.Client) (*registration.Resource, er
 func obtainCertificate(ctx *cli.Context, client *lego.Client) (*certificate.Resource, error) {
 	bundle := !ctx.Bool("no-bundle")
 
-	domains := ctx.StringSlice("domains")
+	domains := getDomains(ctx)
 	if len(domains) > 0 {
 		// obtain a certificate, generating a new private key
 		request := certificate.ObtainRequest{
@@ -174,6 +201,9 @@ func obtainCertificate(ctx *cli.Context, client *lego.Client) (*certificate.Reso
 			MustStaple:                     ctx.Bool("must-staple"),
 			PreferredChain:                 ctx.String("preferred-chain"),
 			AlwaysDeactivateAuthorizations: ctx.Bool("always-deactivate-authorizations"),
+			Profile:                        ctx.String("profile"),
+			NotBefore:                      getTime(ctx, "not-before"),
+			NotAfter:                       getTime(ctx, "not-after"),
 		}
 		return client.Certificate.Obtain(request)
 	}
@@ -190,5 +220,8 @@ func obtainCertificate(ctx *cli.Context, client *lego.Client) (*certificate.Reso
 		Bundle:                         bundle,
 		PreferredChain:                 ctx.String("preferred-chain"),
 		AlwaysDeactivateAuthorizations: ctx.Bool("always-deactivate-authorizations"),
+		Profile:                        ctx.String("profile"),
+		NotBefore:                      getTime(ctx, "not-before"),
+		NotAfter:                       getTime(ctx, "not-after"),
 	})
 }
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"path"
+	"time"
+
+	"github.com/LukasDeco/lego/v4/certificate"
+	"github.com/LukasDeco/lego/v4/lego"
+	"github.com/LukasDeco/lego/v4/log"
+	"github.com/urfave/cli/v2"
+)
+
+const baseCRLsFolderName = "crls"
+
+// crlCache stores the CRLs in the storage ("crls" directory), by the SHA-256 hash of their URL.
+type crlCache struct {
+	storage Storage
+}
+
+// Get reads the CRL.
+func (c crlCache) Get(_ context.Context, url string) ([]byte, error) {
+	data, err := c.storage.ReadFile(c.getKey(url))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, certificate.ErrCRLCacheMiss
+	}
+
+	return data, err
+}
+
+// Put writes the CRL.
+func (c crlCache) Put(_ context.Context, url string, data []byte) error {
+	return c.storage.WriteFile(c.getKey(url), data)
+}
+
+func (c crlCache) getKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+
+	return path.Join(baseCRLsFolderName, hex.EncodeToString(sum[:])+".crl")
+}
+
+func newCRLChecker(ctx *cli.Context, certsStorage *CertificatesStorage) *certificate.CRLChecker {
+	httpClient := lego.NewConfig(nil).HTTPClient
+	if ctx.IsSet("http-timeout") {
+		httpClient.Timeout = time.Duration(ctx.Int("http-timeout")) * time.Second
+	}
+
+	return certificate.NewCRLChecker(certificate.CRLCheckerOptions{
+		HTTPClient:      httpClient,
+		Cache:           crlCache{storage: certsStorage.storage},
+		PartitionedCRLs: ctx.StringSlice("crl.partitioned"),
+	})
+}
+
+// isRevoked checks the revocation status of the stored certificate with the CRLs of its issuer.
+// The errors are only logged: the renewal is then based on the other checks.
+func isRevoked(ctx *cli.Context, certsStorage *CertificatesStorage, domain string) bool {
+	if ctx.Bool("crl-disable") {
+		return false
+	}
+
+	bundle, err := readCertificateBundle(certsStorage, domain)
+	if err != nil {
+		log.Warnf("[%s] unable to read the certificate to check its revocation status: %v", domain, err)
+		return false
+	}
+
+	status, err := newCRLChecker(ctx, certsStorage).Check(ctx.Context, bundle)
+	if err != nil {
+		if !errors.Is(err, certificate.ErrNoCRL) {
+			log.Warnf("[%s] unable to check the revocation status of the certificate: %v", domain, err)
+		}
+
+		return false
+	}
+
+	if !status.Revoked {
+		return false
+	}
+
+	log.Warnf("[%s] The certificate has been revoked at %s (reason: %d, CRL: %s): forcing the renewal.",
+		domain, status.RevokedAt.Format(time.RFC3339), status.Reason, status.CRL)
+
+	return true
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/LukasDeco/lego/v4/certificate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_crlCache(t *testing.T) {
+	storage := NewFileStorage(t.TempDir())
+
+	cache := crlCache{storage: storage}
+
+	ctx := context.Background()
+
+	_, err := cache.Get(ctx, "http://crl.example.com/1.crl")
+	require.ErrorIs(t, err, certificate.ErrCRLCacheMiss)
+
+	err = cache.Put(ctx, "http://crl.example.com/1.crl", []byte("crl"))
+	require.NoError(t, err)
+
+	data, err := cache.Get(ctx, "http://crl.example.com/1.crl")
+	require.NoError(t, err)
+	assert.Equal(t, "crl", string(data))
+
+	keys, err := storage.List(baseCRLsFolderName)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"crls/b4d28de18831af9fa5c9e6a11041180490214a23237fc4ae32c9b1edb93967bd.crl"}, keys)
+}
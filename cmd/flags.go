@@ -13,6 +13,10 @@ func CreateFlags(defaultPath string) []cli.Flag {
 			Aliases: []string{"d"},
 			Usage:   "Add a domain to the process. Can be specified multiple times.",
 		},
+		&cli.StringSliceFlag{
+			Name:  "ip",
+			Usage: "Add an IP address to the process (RFC 8738). Can be specified multiple times.",
+		},
 		&cli.StringFlag{
 			Name:    "server",
 			Aliases: []string{"s"},
@@ -50,7 +54,7 @@ func CreateFlags(defaultPath string) []cli.Flag {
 			Name:    "key-type",
 			Aliases: []string{"k"},
 			Value:   "ec256",
-			Usage:   "Key type to use for private keys. Supported: rsa2048, rsa3072, rsa4096, rsa8192, ec256, ec384.",
+			Usage:   "Key type to use for private keys. Supported: rsa2048, rsa3072, rsa4096, rsa8192, ec256, ec384, ec521, ed25519.",
 		},
 		&cli.StringFlag{
 			Name:  "filename",
@@ -62,6 +66,15 @@ func CreateFlags(defaultPath string) []cli.Flag {
 			Usage:   "Directory to use for storing the data.",
 			Value:   defaultPath,
 		},
+		&cli.StringFlag{
+			Name:    "storage",
+			EnvVars: []string{"LEGO_STORAGE"},
+			Usage: "Storage backend URI, overrides --path." +
+				" Supported: 'file:///path/to/dir' (a directory, like --path)," +
+				" 'kv:///path/to/dir?lock-timeout=30s&stale-lock=5m' (a shared directory with file locking)," +
+				" 's3://bucket/prefix?endpoint=http://localhost:9000&region=us-east-1&path-style=true' (an S3-compatible object storage," +
+				" the credentials are read from the AWS environment variables or shared configuration).",
+		},
 		&cli.BoolFlag{
 			Name:  "http",
 			Usage: "Use the HTTP-01 challenge to solve challenges. Can be mixed with other types of challenges.",
@@ -98,6 +111,11 @@ func CreateFlags(defaultPath string) []cli.Flag {
 			Name:  "dns",
 			Usage: "Solve a DNS-01 challenge using the specified provider. Can be mixed with other types of challenges. Run 'lego dnshelp' for help on usage.",
 		},
+		&cli.BoolFlag{
+			Name: "dns.account",
+			Usage: "Also use the DNS provider to solve the DNS-ACCOUNT-01 challenge (draft-ietf-acme-dns-account-label) when the CA offers it." +
+				" The TXT record is scoped to the account, several accounts can validate the same domain at the same time.",
+		},
 		&cli.BoolFlag{
 			Name:  "dns.disable-cp",
 			Usage: "By setting this flag to true, disables the need to await propagation of the TXT record to all authoritative name servers.",
@@ -106,7 +124,10 @@ func CreateFlags(defaultPath string) []cli.Flag {
 			Name: "dns.resolvers",
 			Usage: "Set the resolvers to use for performing (recursive) CNAME resolving and apex domain determination." +
 				" For DNS-01 challenge verification, the authoritative DNS server is queried directly." +
-				" Supported: host:port." +
+				" Supported: host:port, udp://host:port, tcp://host:port, tls://host:port (DNS over TLS), https://host/path (DNS over HTTPS)." +
+				" A resolver can be restricted to a zone with zone=resolver (e.g. example.com=https://dns.example.com/dns-query):" +
+				" it is used for the domains of the zone, and instead of the authoritative DNS servers." +
+				" The root zone (.=resolver) matches all the domains, e.g. when the port 53 cannot be reached." +
 				" The default is to use the system resolvers, or Google's DNS resolvers if the system's cannot be determined.",
 		},
 		&cli.IntFlag{
@@ -118,24 +139,65 @@ func CreateFlags(defaultPath string) []cli.Flag {
 			Usage: "Set the DNS timeout value to a specific value in seconds. Used only when performing authoritative name server queries.",
 			Value: 10,
 		},
+		&cli.StringSliceFlag{
+			Name: "output",
+			Usage: "Generate additional files for the certificate (can be repeated): pem (.pem, certificate and key), fullchain (.fullchain.pem)," +
+				" chain (.chain.pem, issuer certificates), der (.der), pfx (.pfx, PKCS#12), jks (.jks, Java KeyStore), k8s (.k8s.yaml, Kubernetes TLS Secret).",
+		},
 		&cli.BoolFlag{
 			Name:  "pem",
-			Usage: "Generate an additional .pem (base64) file by concatenating the .key and .crt files together.",
+			Usage: "Generate an additional .pem (base64) file by concatenating the .key and .crt files together. Same as '--output pem'.",
 		},
 		&cli.BoolFlag{
-			Name:  "pfx",
-			Usage: "Generate an additional .pfx (PKCS#12) file by concatenating the .key and .crt and issuer .crt files together.",
+			Name: "pfx",
+			Usage: "Generate an additional .pfx (PKCS#12) file by concatenating the .key and .crt and issuer .crt files together (full chain)." +
+				" Same as '--output pfx'.",
 		},
 		&cli.StringFlag{
 			Name:  "pfx.pass",
 			Usage: "The password used to encrypt the .pfx (PCKS#12) file.",
 			Value: pkcs12.DefaultPassword,
 		},
+		&cli.StringFlag{
+			Name: "pfx.format",
+			Usage: "The encryption of the .pfx (PKCS#12) file: legacy (RC2 and 3DES, SHA-1 MAC), legacy-des (3DES, SHA-1 MAC)," +
+				" or modern (AES-256 and SHA-256). Use modern for the recent versions of Windows, Java, and OpenSSL.",
+			Value: pfxFormatLegacy,
+		},
+		&cli.StringFlag{
+			Name:  "jks.pass",
+			Usage: "The password used to protect the .jks (Java KeyStore) file and its private key.",
+			Value: defaultJKSPassword,
+		},
+		&cli.StringFlag{
+			Name:  "k8s.namespace",
+			Usage: "The namespace of the Kubernetes Secret in the .k8s.yaml file.",
+		},
 		&cli.IntFlag{
 			Name:  "cert.timeout",
 			Usage: "Set the certificate timeout value to a specific value in seconds. Only used when obtaining certificates.",
 			Value: 30,
 		},
+		&cli.IntFlag{
+			Name:  "validation.timeout",
+			Usage: "Set the timeout value of the challenge validation by the CA to a specific value in seconds. Only used when obtaining certificates.",
+			Value: 600,
+		},
+		&cli.IntFlag{
+			Name:  "validation.concurrency",
+			Usage: "Set the maximum number of authorizations solved at the same time (0: no limit). Only used when obtaining certificates.",
+			Value: 10,
+		},
+		&cli.StringFlag{
+			Name:  "log-format",
+			Usage: "The format of the logs: text or json (one JSON object per line).",
+			Value: "text",
+		},
+		&cli.StringFlag{
+			Name:  "log-level",
+			Usage: "The minimum level of the logs: debug, info, warn or error.",
+			Value: "info",
+		},
 		&cli.StringFlag{
 			Name:  "user-agent",
 			Usage: "Add to the user-agent sent to the CA to identify an application embedding lego-cli",
@@ -1,27 +1,211 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
+	"crypto/x509"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"strings"
 	"time"
+
+	"github.com/LukasDeco/lego/v4/certcrypto"
+	"github.com/LukasDeco/lego/v4/log"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	hookEnvType  = "LEGO_HOOK_TYPE"
+	hookEnvError = "LEGO_HOOK_ERROR"
+)
+
+// The types of hooks.
+const (
+	hookTypePre    = "pre"
+	hookTypePost   = "post"
+	hookTypeDeploy = "deploy"
 )
 
-func launchHook(hook string, meta map[string]string) error {
+const defaultHookTimeout = 2 * time.Minute
+
+// createHookFlags the flags of the hooks shared by the run, renew and daemon commands.
+func createHookFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "pre-hook",
+			Usage: "Define a hook executed before obtaining a certificate (only when a renewal is needed). If the hook fails, the certificate is not obtained.",
+		},
+		&cli.StringFlag{
+			Name:  "post-hook",
+			Usage: "Define a hook executed after an attempt to obtain a certificate, even if it failed (LEGO_HOOK_ERROR).",
+		},
+		&cli.StringFlag{
+			Name:  "deploy-hook",
+			Usage: "Define a hook executed when the certificates are effectively created or renewed.",
+		},
+		&cli.DurationFlag{
+			Name:  "hook.timeout",
+			Usage: "The maximum duration of a hook.",
+			Value: defaultHookTimeout,
+		},
+		&cli.BoolFlag{
+			Name:  "hook.json",
+			Usage: "Send a JSON description of the certificate (domains, paths, serial number, expiration, issuer, account) to the hooks on stdin.",
+		},
+	}
+}
+
+// hooks the hooks of a command.
+type hooks struct {
+	pre  string
+	post string
+	// legacy the hook historically executed after a success (run-hook, renew-hook).
+	legacy string
+	// deploy the deploy hooks.
+	deploy []string
+
+	timeout time.Duration
+	json    bool
+}
+
+// newHooks creates the hooks of a command.
+// legacyFlag is the name of the flag of the hook historically executed after a success (run-hook, renew-hook).
+func newHooks(ctx *cli.Context, legacyFlag string) *hooks {
+	h := &hooks{
+		pre:     ctx.String("pre-hook"),
+		post:    ctx.String("post-hook"),
+		legacy:  ctx.String(legacyFlag),
+		timeout: ctx.Duration("hook.timeout"),
+		json:    ctx.Bool("hook.json"),
+	}
+
+	if hook := ctx.String("deploy-hook"); hook != "" {
+		h.deploy = append(h.deploy, hook)
+	}
+
+	if h.timeout <= 0 {
+		h.timeout = defaultHookTimeout
+	}
+
+	return h
+}
+
+// runPre executes the pre-hook.
+func (h *hooks) runPre(meta map[string]string, payload *hookPayload) error {
+	err := h.launch(hookTypePre, h.pre, meta, payload)
+	if err != nil {
+		return fmt.Errorf("pre-hook: %w", err)
+	}
+
+	return nil
+}
+
+// runPost executes the post-hook.
+// opErr is the error of the operation, if any.
+func (h *hooks) runPost(meta map[string]string, payload *hookPayload, opErr error) error {
+	if h.post == "" {
+		return nil
+	}
+
+	postMeta := copyMeta(meta)
+	postPayload := *payload
+
+	if opErr != nil {
+		postMeta[hookEnvError] = opErr.Error()
+		postPayload.Error = opErr.Error()
+	}
+
+	err := h.launch(hookTypePost, h.post, postMeta, &postPayload)
+	if err != nil {
+		return &hookError{err: fmt.Errorf("post-hook: %w", err)}
+	}
+
+	return nil
+}
+
+// runDeploy executes the legacy hook, and the deploy hooks.
+func (h *hooks) runDeploy(meta map[string]string, payload *hookPayload) error {
+	// The legacy hook is split on spaces only, as in the previous versions:
+	// the backslashes are kept (e.g. Windows paths like C:\hooks\deploy.bat).
+	err := h.launchArgs(hookTypeDeploy, strings.Fields(h.legacy), meta, payload)
+	if err != nil {
+		return &hookError{err: fmt.Errorf("deploy hook: %w", err)}
+	}
+
+	for _, hook := range h.deploy {
+		err := h.launch(hookTypeDeploy, hook, meta, payload)
+		if err != nil {
+			return &hookError{err: fmt.Errorf("deploy hook: %w", err)}
+		}
+	}
+
+	return nil
+}
+
+// wrap executes the pre-hook, the operation, and the post-hook.
+// The post-hook is executed even if the operation fails, but not if the pre-hook fails.
+// The error of the operation takes precedence over the error of the post-hook.
+func (h *hooks) wrap(meta map[string]string, payload *hookPayload, operation func() error) error {
+	err := h.runPre(meta, payload)
+	if err != nil {
+		return err
+	}
+
+	err = operation()
+
+	postErr := h.runPost(meta, payload, err)
+	if err != nil {
+		if postErr != nil {
+			log.Warnf("%v", postErr)
+		}
+
+		return err
+	}
+
+	return postErr
+}
+
+// launch executes a hook, split according to the quoting rules of the POSIX shell (see splitHookCommand).
+func (h *hooks) launch(hookType, hook string, meta map[string]string, payload *hookPayload) error {
 	if hook == "" {
 		return nil
 	}
 
-	ctxCmd, cancel := context.WithTimeout(context.Background(), 120*time.Second)
-	defer cancel()
+	parts, err := splitHookCommand(hook)
+	if err != nil {
+		return err
+	}
 
-	parts := strings.Fields(hook)
+	return h.launchArgs(hookType, parts, meta, payload)
+}
+
+// launchArgs executes a hook, with the metadata as environment variables, and the payload on stdin if enabled.
+func (h *hooks) launchArgs(hookType string, parts []string, meta map[string]string, payload *hookPayload) error {
+	if len(parts) == 0 {
+		return nil
+	}
+
+	ctxCmd, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
 
 	cmdCtx := exec.CommandContext(ctxCmd, parts[0], parts[1:]...)
 	cmdCtx.Env = append(os.Environ(), metaToEnv(meta)...)
+	cmdCtx.Env = append(cmdCtx.Env, hookEnvType+"="+hookType)
+
+	if h.json {
+		p := *payload
+		p.Hook = hookType
+
+		raw, errM := json.Marshal(p)
+		if errM != nil {
+			return errM
+		}
+
+		cmdCtx.Stdin = bytes.NewReader(raw)
+	}
 
 	output, err := cmdCtx.CombinedOutput()
 
@@ -30,12 +214,203 @@ func launchHook(hook string, meta map[string]string) error {
 	}
 
 	if errors.Is(ctxCmd.Err(), context.DeadlineExceeded) {
-		return errors.New("hook timed out")
+		return fmt.Errorf("hook timed out after %s", h.timeout)
 	}
 
 	return err
 }
 
+// hookError an error from a hook.
+// The operation before the hook (i.e. the renewal) has succeeded.
+type hookError struct {
+	err error
+}
+
+func (e *hookError) Error() string {
+	return e.err.Error()
+}
+
+func (e *hookError) Unwrap() error {
+	return e.err
+}
+
+// hookPayload the JSON description sent to the hooks on stdin.
+type hookPayload struct {
+	Hook    string   `json:"hook"`
+	Account string   `json:"account,omitempty"`
+	Domain  string   `json:"domain,omitempty"`
+	Domains []string `json:"domains,omitempty"`
+
+	Paths *hookPaths `json:"paths,omitempty"`
+
+	SerialNumber string     `json:"serialNumber,omitempty"`
+	NotBefore    *time.Time `json:"notBefore,omitempty"`
+	NotAfter     *time.Time `json:"notAfter,omitempty"`
+	Issuer       string     `json:"issuer,omitempty"`
+	CertURL      string     `json:"certUrl,omitempty"`
+
+	// Error the error of the operation (post-hook).
+	Error string `json:"error,omitempty"`
+}
+
+// hookPaths the locations of the files of a certificate.
+type hookPaths struct {
+	Certificate string `json:"certificate,omitempty"`
+	PrivateKey  string `json:"privateKey,omitempty"`
+	Issuer      string `json:"issuer,omitempty"`
+	PEM         string `json:"pem,omitempty"`
+	PFX         string `json:"pfx,omitempty"`
+
+	// Outputs the files of the outputs ("output" option), by name.
+	Outputs map[string]string `json:"outputs,omitempty"`
+}
+
+// setCertificate sets the description of the certificate, and the paths of the existing files.
+func (p *hookPayload) setCertificate(certsStorage *CertificatesStorage, domain string, cert *x509.Certificate, certURL string) error {
+	p.Domain = domain
+	p.Domains = certcrypto.ExtractDomains(cert)
+	p.SerialNumber = fmt.Sprintf("%x", cert.SerialNumber)
+	p.NotBefore = &cert.NotBefore
+	p.NotAfter = &cert.NotAfter
+	p.Issuer = cert.Issuer.String()
+	p.CertURL = certURL
+
+	certPath, err := certsStorage.GetFileName(domain, ".crt")
+	if err != nil {
+		return err
+	}
+
+	p.Paths = &hookPaths{Certificate: certPath}
+
+	for ext, path := range map[string]*string{
+		".key":        &p.Paths.PrivateKey,
+		".issuer.crt": &p.Paths.Issuer,
+		".pem":        &p.Paths.PEM,
+		".pfx":        &p.Paths.PFX,
+	} {
+		if !existsFile(certsStorage, domain, ext) {
+			continue
+		}
+
+		*path, err = certsStorage.GetFileName(domain, ext)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, output := range certsStorage.outputs {
+		if !existsFile(certsStorage, domain, output.Extension()) {
+			continue
+		}
+
+		if p.Paths.Outputs == nil {
+			p.Paths.Outputs = map[string]string{}
+		}
+
+		p.Paths.Outputs[output.Name()], err = certsStorage.GetFileName(domain, output.Extension())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// existsFile checks if a file of the certificate exists.
+// A storage error only prevents the path from being described to the hooks: the file is considered missing.
+func existsFile(certsStorage *CertificatesStorage, domain, extension string) bool {
+	exists, err := certsStorage.ExistsFile(domain, extension)
+	if err != nil {
+		log.Warnf("[%s] unable to check the %s file: %v", domain, extension, err)
+		return false
+	}
+
+	return exists
+}
+
+// splitHookCommand splits a hook command into its arguments, following the quoting rules of the POSIX shell:
+// the arguments are separated by spaces, the single quotes preserve the literal value of all the characters,
+// the double quotes preserve the literal value of all the characters except the backslash escapes of `\`, `"`, `$` and "`",
+// and a backslash outside the quotes preserves the literal value of the next character.
+// The other shell features (variables, globs, redirections, ...) are not supported.
+func splitHookCommand(command string) ([]string, error) {
+	var args []string
+
+	var current strings.Builder
+	inArg := false
+
+	runes := []rune(command)
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch {
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+
+		case r == '\\':
+			if i+1 >= len(runes) {
+				return nil, fmt.Errorf("invalid hook %q: trailing backslash", command)
+			}
+
+			i++
+			current.WriteRune(runes[i])
+			inArg = true
+
+		case r == '\'':
+			end := indexRune(runes, i+1, '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid hook %q: unterminated single quote", command)
+			}
+
+			current.WriteString(string(runes[i+1 : end]))
+			i = end
+			inArg = true
+
+		case r == '"':
+			i++
+
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("\\\"$`", runes[i+1]) {
+					i++
+				}
+
+				current.WriteRune(runes[i])
+			}
+
+			if i >= len(runes) {
+				return nil, fmt.Errorf("invalid hook %q: unterminated double quote", command)
+			}
+
+			inArg = true
+
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
+
+func indexRune(runes []rune, from int, r rune) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+
+	return -1
+}
+
 func metaToEnv(meta map[string]string) []string {
 	var envs []string
 
@@ -45,3 +420,12 @@ func metaToEnv(meta map[string]string) []string {
 
 	return envs
 }
+
+func copyMeta(meta map[string]string) map[string]string {
+	c := make(map[string]string, len(meta))
+	for k, v := range meta {
+		c[k] = v
+	}
+
+	return c
+}
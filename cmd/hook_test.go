@@ -0,0 +1,326 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_splitHookCommand(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		command  string
+		expected []string
+	}{
+		{
+			desc:     "empty",
+			command:  "",
+			expected: nil,
+		},
+		{
+			desc:     "spaces only",
+			command:  "   ",
+			expected: nil,
+		},
+		{
+			desc:     "simple",
+			command:  "/usr/bin/hook.sh --reload nginx",
+			expected: []string{"/usr/bin/hook.sh", "--reload", "nginx"},
+		},
+		{
+			desc:     "multiple spaces",
+			command:  "  hook.sh \t a  b ",
+			expected: []string{"hook.sh", "a", "b"},
+		},
+		{
+			desc:     "single quotes",
+			command:  `hook.sh 'a b' 'c "d" \e'`,
+			expected: []string{"hook.sh", "a b", `c "d" \e`},
+		},
+		{
+			desc:     "double quotes",
+			command:  `hook.sh "a b" "c 'd'"`,
+			expected: []string{"hook.sh", "a b", "c 'd'"},
+		},
+		{
+			desc:     "double quotes escapes",
+			command:  `hook.sh "a \"b\" \\ \$c \d"`,
+			expected: []string{"hook.sh", `a "b" \ $c \d`},
+		},
+		{
+			desc:     "backslash",
+			command:  `/path/with\ space/hook.sh a\'b`,
+			expected: []string{"/path/with space/hook.sh", "a'b"},
+		},
+		{
+			desc:     "concatenation",
+			command:  `hook.sh a"b c"'d e'f`,
+			expected: []string{"hook.sh", "ab cd ef"},
+		},
+		{
+			desc:     "empty quotes",
+			command:  `hook.sh "" ''`,
+			expected: []string{"hook.sh", "", ""},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			args, err := splitHookCommand(test.command)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, args)
+		})
+	}
+}
+
+func Test_splitHookCommand_errors(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		command  string
+		expected string
+	}{
+		{
+			desc:     "unterminated single quote",
+			command:  `hook.sh 'a b`,
+			expected: `invalid hook "hook.sh 'a b": unterminated single quote`,
+		},
+		{
+			desc:     "unterminated double quote",
+			command:  `hook.sh "a b`,
+			expected: `invalid hook "hook.sh \"a b": unterminated double quote`,
+		},
+		{
+			desc:     "trailing backslash",
+			command:  `hook.sh a\`,
+			expected: `invalid hook "hook.sh a\\": trailing backslash`,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := splitHookCommand(test.command)
+			require.EqualError(t, err, test.expected)
+		})
+	}
+}
+
+func Test_hooks_launch(t *testing.T) {
+	skipWithoutShell(t)
+
+	dir := t.TempDir()
+	output := filepath.Join(dir, "output")
+
+	script := writeHookScript(t, dir, `echo "$LEGO_HOOK_TYPE $LEGO_CERT_DOMAIN $1" > "`+output+`"`)
+
+	h := &hooks{timeout: time.Minute}
+
+	err := h.launch(hookTypeDeploy, script+` "a b"`, map[string]string{renewEnvCertDomain: "example.com"}, &hookPayload{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "deploy example.com a b\n", readFile(t, output))
+}
+
+func Test_hooks_runDeploy_legacy(t *testing.T) {
+	skipWithoutShell(t)
+
+	// The backslashes of the legacy hooks are kept, like in a Windows path (C:\hooks\deploy.bat).
+	dir := filepath.Join(t.TempDir(), `C:\hooks`)
+	require.NoError(t, os.Mkdir(dir, 0o700))
+
+	output := filepath.Join(t.TempDir(), "output")
+
+	script := writeHookScript(t, dir, `printf '%s %s %s\n' "$LEGO_HOOK_TYPE" "$1" "$2" > "`+output+`"`)
+
+	h := &hooks{timeout: time.Minute, legacy: script + ` a\b "c`}
+
+	err := h.runDeploy(map[string]string{}, &hookPayload{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "deploy a\\b \"c\n", readFile(t, output))
+}
+
+func Test_hooks_launch_json(t *testing.T) {
+	skipWithoutShell(t)
+
+	dir := t.TempDir()
+	output := filepath.Join(dir, "output")
+
+	script := writeHookScript(t, dir, `cat > "`+output+`"`)
+
+	h := &hooks{timeout: time.Minute, json: true}
+
+	notAfter := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	payload := &hookPayload{
+		Account:      "test@example.com",
+		Domain:       "example.com",
+		Domains:      []string{"example.com", "www.example.com"},
+		Paths:        &hookPaths{Certificate: "/certs/example.com.crt"},
+		SerialNumber: "2a",
+		NotAfter:     &notAfter,
+		Issuer:       "CN=Test",
+	}
+
+	err := h.launch(hookTypeDeploy, script, map[string]string{}, payload)
+	require.NoError(t, err)
+
+	var actual hookPayload
+	err = json.Unmarshal([]byte(readFile(t, output)), &actual)
+	require.NoError(t, err)
+
+	expected := *payload
+	expected.Hook = hookTypeDeploy
+
+	assert.Equal(t, expected, actual)
+
+	// The payload is not modified.
+	assert.Empty(t, payload.Hook)
+}
+
+func Test_hooks_launch_timeout(t *testing.T) {
+	skipWithoutShell(t)
+
+	script := writeHookScript(t, t.TempDir(), "exec sleep 10")
+
+	h := &hooks{timeout: 100 * time.Millisecond}
+
+	err := h.launch(hookTypeDeploy, script, map[string]string{}, &hookPayload{})
+	require.EqualError(t, err, "hook timed out after 100ms")
+}
+
+func Test_hooks_wrap(t *testing.T) {
+	skipWithoutShell(t)
+
+	dir := t.TempDir()
+	output := filepath.Join(dir, "output")
+
+	script := writeHookScript(t, dir, `echo "$LEGO_HOOK_TYPE:$LEGO_HOOK_ERROR" >> "`+output+`"`)
+	failing := writeHookScript(t, dir, "exit 1")
+
+	testCases := []struct {
+		desc      string
+		hooks     *hooks
+		operation error
+		expected  string
+		hookErr   bool
+		output    string
+	}{
+		{
+			desc:   "success",
+			hooks:  &hooks{pre: script, post: script, deploy: []string{script}},
+			output: "pre:\ndeploy:\npost:\n",
+		},
+		{
+			desc:      "operation error",
+			hooks:     &hooks{pre: script, post: script, deploy: []string{script}},
+			operation: errors.New("oops"),
+			expected:  "oops",
+			output:    "pre:\npost:oops\n",
+		},
+		{
+			desc:     "pre-hook error",
+			hooks:    &hooks{pre: failing, post: script, deploy: []string{script}},
+			expected: "pre-hook: exit status 1",
+			output:   "",
+		},
+		{
+			desc:     "post-hook error",
+			hooks:    &hooks{pre: script, post: failing, deploy: []string{script}},
+			expected: "post-hook: exit status 1",
+			hookErr:  true,
+			output:   "pre:\ndeploy:\n",
+		},
+		{
+			desc:      "operation and post-hook errors",
+			hooks:     &hooks{pre: script, post: failing},
+			operation: errors.New("oops"),
+			expected:  "oops",
+			output:    "pre:\n",
+		},
+		{
+			desc:     "deploy hook error",
+			hooks:    &hooks{deploy: []string{failing, script}, post: script},
+			expected: "deploy hook: exit status 1",
+			hookErr:  true,
+			output:   "post:deploy hook: exit status 1\n",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			_ = os.Remove(output)
+
+			test.hooks.timeout = time.Minute
+
+			meta := map[string]string{}
+
+			err := test.hooks.wrap(meta, &hookPayload{}, func() error {
+				if test.operation != nil {
+					return test.operation
+				}
+
+				return test.hooks.runDeploy(meta, &hookPayload{})
+			})
+
+			if test.expected == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, test.expected)
+
+				var hookErr *hookError
+				assert.Equal(t, test.hookErr, errors.As(err, &hookErr))
+			}
+
+			content, _ := os.ReadFile(output)
+			assert.Equal(t, test.output, string(content))
+
+			assert.Empty(t, meta[hookEnvError])
+		})
+	}
+}
+
+func skipWithoutShell(t *testing.T) {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the hook scripts require a POSIX shell")
+	}
+}
+
+func writeHookScript(t *testing.T, dir, content string) string {
+	t.Helper()
+
+	f, err := os.CreateTemp(dir, "hook-*.sh")
+	require.NoError(t, err)
+
+	_, err = f.WriteString("#!/bin/sh\n" + content + "\n")
+	require.NoError(t, err)
+
+	require.NoError(t, f.Close())
+	require.NoError(t, os.Chmod(f.Name(), 0o700))
+
+	return f.Name()
+}
+
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(name)
+	require.NoError(t, err)
+
+	return string(data)
+}
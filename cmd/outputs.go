@@ -0,0 +1,405 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/LukasDeco/lego/v4/certcrypto"
+	"github.com/LukasDeco/lego/v4/certificate"
+	"github.com/pavlo-v-chernykh/keystore-go/v4"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// The names of the outputs ("output" option).
+const (
+	outputPEM        = "pem"
+	outputFullChain  = "fullchain"
+	outputChain      = "chain"
+	outputDER        = "der"
+	outputPFX        = "pfx"
+	outputJKS        = "jks"
+	outputKubernetes = "k8s"
+)
+
+// The encodings of the .pfx files ("pfx.format" option).
+const (
+	pfxFormatLegacy    = "legacy"
+	pfxFormatLegacyDES = "legacy-des"
+	pfxFormatModern    = "modern"
+)
+
+// defaultJKSPassword the default password of the Java keystores.
+const defaultJKSPassword = "changeit"
+
+// outputWriter encodes an additional file of a certificate, written beside the .crt file.
+type outputWriter interface {
+	// Name returns the name of the output (a value of the "output" option).
+	Name() string
+
+	// Extension returns the extension of the file (e.g. ".pem").
+	Extension() string
+
+	// NeedsPrivateKey returns true if the file contains the private key:
+	// the file cannot be written for the certificates obtained with a CSR.
+	NeedsPrivateKey() bool
+
+	// Encode returns the content of the file.
+	Encode(certRes *certificate.Resource) ([]byte, error)
+}
+
+// newOutputWriters creates the writers of the outputs selected by the "output" option,
+// and by the legacy "pem" and "pfx" options.
+func newOutputWriters(ctx *cli.Context) ([]outputWriter, error) {
+	names := ctx.StringSlice("output")
+
+	if ctx.Bool("pem") {
+		names = append(names, outputPEM)
+	}
+
+	if ctx.Bool("pfx") {
+		names = append(names, outputPFX)
+	}
+
+	options := outputOptions{
+		pfxPassword:         ctx.String("pfx.pass"),
+		pfxFormat:           ctx.String("pfx.format"),
+		jksPassword:         ctx.String("jks.pass"),
+		kubernetesNamespace: ctx.String("k8s.namespace"),
+	}
+
+	return createOutputWriters(names, options)
+}
+
+// outputOptions the options of the output writers.
+type outputOptions struct {
+	pfxPassword         string
+	pfxFormat           string
+	jksPassword         string
+	kubernetesNamespace string
+}
+
+// createOutputWriters creates the writers of the outputs, without duplicates.
+func createOutputWriters(names []string, options outputOptions) ([]outputWriter, error) {
+	var writers []outputWriter
+
+	seen := map[string]struct{}{}
+
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		if _, ok := seen[name]; ok {
+			continue
+		}
+
+		seen[name] = struct{}{}
+
+		var writer outputWriter
+
+		switch name {
+		case outputPEM:
+			writer = pemOutput{}
+		case outputFullChain:
+			writer = fullChainOutput{}
+		case outputChain:
+			writer = chainOutput{}
+		case outputDER:
+			writer = derOutput{}
+		case outputPFX:
+			encoder, err := getPFXEncoder(options.pfxFormat)
+			if err != nil {
+				return nil, err
+			}
+
+			writer = pfxOutput{encoder: encoder, password: options.pfxPassword}
+		case outputJKS:
+			writer = jksOutput{password: options.jksPassword}
+		case outputKubernetes:
+			writer = kubernetesOutput{namespace: options.kubernetesNamespace}
+		default:
+			return nil, fmt.Errorf("unsupported output %q: use %s", name,
+				strings.Join([]string{outputPEM, outputFullChain, outputChain, outputDER, outputPFX, outputJKS, outputKubernetes}, ", "))
+		}
+
+		writers = append(writers, writer)
+	}
+
+	return writers, nil
+}
+
+// pemOutput the certificate (and its chain if bundled) followed by the private key, in a .pem file (e.g. for HAProxy).
+type pemOutput struct{}
+
+func (pemOutput) Name() string          { return outputPEM }
+func (pemOutput) Extension() string     { return ".pem" }
+func (pemOutput) NeedsPrivateKey() bool { return true }
+
+func (pemOutput) Encode(certRes *certificate.Resource) ([]byte, error) {
+	return bytes.Join([][]byte{certRes.Certificate, certRes.PrivateKey}, nil), nil
+}
+
+// fullChainOutput the certificate followed by its issuer certificates, in a .fullchain.pem file (e.g. for nginx).
+type fullChainOutput struct{}
+
+func (fullChainOutput) Name() string          { return outputFullChain }
+func (fullChainOutput) Extension() string     { return ".fullchain.pem" }
+func (fullChainOutput) NeedsPrivateKey() bool { return false }
+
+func (fullChainOutput) Encode(certRes *certificate.Resource) ([]byte, error) {
+	cert, chain, err := parseCertificateChain(certRes)
+	if err != nil {
+		return nil, err
+	}
+
+	return encodePEMCertificates(append([]*x509.Certificate{cert}, chain...)), nil
+}
+
+// chainOutput the issuer certificates only, in a .chain.pem file (e.g. for Apache SSLCertificateChainFile).
+type chainOutput struct{}
+
+func (chainOutput) Name() string          { return outputChain }
+func (chainOutput) Extension() string     { return ".chain.pem" }
+func (chainOutput) NeedsPrivateKey() bool { return false }
+
+func (chainOutput) Encode(certRes *certificate.Resource) ([]byte, error) {
+	_, chain, err := parseCertificateChain(certRes)
+	if err != nil {
+		return nil, err
+	}
+
+	return encodePEMCertificates(chain), nil
+}
+
+// derOutput the certificate only, DER encoded, in a .der file.
+type derOutput struct{}
+
+func (derOutput) Name() string          { return outputDER }
+func (derOutput) Extension() string     { return ".der" }
+func (derOutput) NeedsPrivateKey() bool { return false }
+
+func (derOutput) Encode(certRes *certificate.Resource) ([]byte, error) {
+	cert, err := certcrypto.ParsePEMCertificate(certRes.Certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	return cert.Raw, nil
+}
+
+// pfxOutput the private key, the certificate, and the full chain in a .pfx (PKCS#12) file,
+// encrypted according to the "pfx.format" option.
+type pfxOutput struct {
+	encoder  *pkcs12.Encoder
+	password string
+}
+
+func (pfxOutput) Name() string          { return outputPFX }
+func (pfxOutput) Extension() string     { return ".pfx" }
+func (pfxOutput) NeedsPrivateKey() bool { return true }
+
+func (o pfxOutput) Encode(certRes *certificate.Resource) ([]byte, error) {
+	cert, chain, err := parseCertificateChain(certRes)
+	if err != nil {
+		return nil, err
+	}
+
+	// PKCS#1 and PKCS#8 RSA keys, SEC1 and PKCS#8 EC keys, and PKCS#8 Ed25519 keys.
+	privateKey, err := certcrypto.ParsePEMPrivateKey(certRes.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load PrivateKey: %w", err)
+	}
+
+	pfxBytes, err := o.encoder.Encode(privateKey, cert, chain, o.password)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode PFX data: %w", err)
+	}
+
+	return pfxBytes, nil
+}
+
+// jksOutput a Java KeyStore (.jks file) containing:
+//   - the private key with the certificate and its chain (alias: the domain).
+//   - the issuer certificates as trusted certificates (aliases: "ca-1", "ca-2", ...),
+//     so the file can also be used as a truststore.
+//
+// The keystore and the private key are protected by the same password.
+type jksOutput struct {
+	password string
+}
+
+func (jksOutput) Name() string          { return outputJKS }
+func (jksOutput) Extension() string     { return ".jks" }
+func (jksOutput) NeedsPrivateKey() bool { return true }
+
+func (o jksOutput) Encode(certRes *certificate.Resource) ([]byte, error) {
+	cert, chain, err := parseCertificateChain(certRes)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := certcrypto.ParsePEMPrivateKey(certRes.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load PrivateKey: %w", err)
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	password := []byte(o.password)
+
+	now := time.Now()
+
+	ks := keystore.New(keystore.WithOrderedAliases())
+
+	certificates := []keystore.Certificate{{Type: "X509", Content: cert.Raw}}
+	for _, issuer := range chain {
+		certificates = append(certificates, keystore.Certificate{Type: "X509", Content: issuer.Raw})
+	}
+
+	err = ks.SetPrivateKeyEntry(certRes.Domain, keystore.PrivateKeyEntry{
+		CreationTime:     now,
+		PrivateKey:       keyDER,
+		CertificateChain: certificates,
+	}, password)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, issuer := range chain {
+		err = ks.SetTrustedCertificateEntry(fmt.Sprintf("ca-%d", i+1), keystore.TrustedCertificateEntry{
+			CreationTime: now,
+			Certificate:  keystore.Certificate{Type: "X509", Content: issuer.Raw},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	buf := &bytes.Buffer{}
+
+	err = ks.Store(buf, password)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// kubernetesOutput a Kubernetes Secret manifest (type kubernetes.io/tls) in a .k8s.yaml file,
+// containing the full chain (tls.crt), the private key (tls.key), and the issuer certificates (ca.crt).
+type kubernetesOutput struct {
+	namespace string
+}
+
+// kubernetesSecret a Kubernetes Secret manifest.
+type kubernetesSecret struct {
+	APIVersion string                   `yaml:"apiVersion"`
+	Kind       string                   `yaml:"kind"`
+	Metadata   kubernetesSecretMetadata `yaml:"metadata"`
+	Type       string                   `yaml:"type"`
+	Data       map[string]string        `yaml:"data"`
+}
+
+type kubernetesSecretMetadata struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace,omitempty"`
+}
+
+func (kubernetesOutput) Name() string          { return outputKubernetes }
+func (kubernetesOutput) Extension() string     { return ".k8s.yaml" }
+func (kubernetesOutput) NeedsPrivateKey() bool { return true }
+
+func (o kubernetesOutput) Encode(certRes *certificate.Resource) ([]byte, error) {
+	cert, chain, err := parseCertificateChain(certRes)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := kubernetesSecretName(certRes.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := kubernetesSecret{
+		APIVersion: "v1",
+		Kind:       "Secret",
+		Metadata: kubernetesSecretMetadata{
+			Name:      name,
+			Namespace: o.namespace,
+		},
+		Type: "kubernetes.io/tls",
+		Data: map[string]string{
+			"tls.crt": base64.StdEncoding.EncodeToString(encodePEMCertificates(append([]*x509.Certificate{cert}, chain...))),
+			"tls.key": base64.StdEncoding.EncodeToString(certRes.PrivateKey),
+			"ca.crt":  base64.StdEncoding.EncodeToString(encodePEMCertificates(chain)),
+		},
+	}
+
+	return yaml.Marshal(secret)
+}
+
+// kubernetesSecretName returns a valid Secret name (RFC 1123 subdomain) for the domain:
+// e.g. "example.com-tls", "wildcard.example.com-tls".
+func kubernetesSecretName(domain string) (string, error) {
+	name, err := sanitizedDomain(domain)
+	if err != nil {
+		return "", err
+	}
+
+	name = strings.ReplaceAll(strings.ToLower(name), "_", "wildcard")
+
+	return name + "-tls", nil
+}
+
+// parseCertificateChain returns the certificate and its issuer certificates:
+// from the certificate file if it's a bundle, or from the issuer certificate file.
+func parseCertificateChain(certRes *certificate.Resource) (*x509.Certificate, []*x509.Certificate, error) {
+	certificates, err := certcrypto.ParsePEMBundle(certRes.Certificate)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to load Certificate: %w", err)
+	}
+
+	cert, chain := certificates[0], certificates[1:]
+
+	if len(chain) == 0 {
+		chain, err = certcrypto.ParsePEMBundle(certRes.IssuerCertificate)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to load Issuer Certificate: %w", err)
+		}
+	}
+
+	return cert, chain, nil
+}
+
+func encodePEMCertificates(certificates []*x509.Certificate) []byte {
+	var data []byte
+	for _, cert := range certificates {
+		data = append(data, certcrypto.PEMEncode(certcrypto.DERCertificateBytes(cert.Raw))...)
+	}
+
+	return data
+}
+
+// getPFXEncoder returns the encoder of a .pfx format (defaults to the legacy format).
+func getPFXEncoder(format string) (*pkcs12.Encoder, error) {
+	switch strings.ToLower(format) {
+	case "", pfxFormatLegacy:
+		// RC2-40 for the certificates, 3DES for the key, and a SHA-1 MAC: the format of the previous versions.
+		return pkcs12.LegacyRC2, nil
+	case pfxFormatLegacyDES:
+		// 3DES for the certificates and the key, and a SHA-1 MAC.
+		return pkcs12.LegacyDES, nil
+	case pfxFormatModern:
+		// AES-256-CBC with PBKDF2 (HMAC-SHA-256) for the certificates and the key, and a SHA-256 MAC.
+		return pkcs12.Modern2023, nil
+	default:
+		return nil, fmt.Errorf("unsupported PFX format %q: use %s, %s, or %s", format, pfxFormatLegacy, pfxFormatLegacyDES, pfxFormatModern)
+	}
+}
@@ -0,0 +1,301 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"testing"
+
+	"github.com/LukasDeco/lego/v4/certcrypto"
+	"github.com/LukasDeco/lego/v4/certificate"
+	"github.com/LukasDeco/lego/v4/platform/tester"
+	"github.com/pavlo-v-chernykh/keystore-go/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// testChain a certificate signed by an intermediate CA, signed by a root CA.
+type testChain struct {
+	leafDER      []byte
+	intermediate *x509.Certificate
+	root         *x509.Certificate
+}
+
+// pem returns the PEM encoded issuer certificates.
+func (c testChain) pem() []byte {
+	return append(certcrypto.PEMEncode(certcrypto.DERCertificateBytes(c.intermediate.Raw)),
+		certcrypto.PEMEncode(certcrypto.DERCertificateBytes(c.root.Raw))...)
+}
+
+// resource returns the resource of the certificate, with the chain in the certificate file if bundle is true.
+func (c testChain) resource(domain string, keyPEM []byte, bundle bool) *certificate.Resource {
+	certRes := &certificate.Resource{
+		Domain:            domain,
+		Certificate:       certcrypto.PEMEncode(certcrypto.DERCertificateBytes(c.leafDER)),
+		IssuerCertificate: c.pem(),
+		PrivateKey:        keyPEM,
+	}
+
+	if bundle {
+		certRes.Certificate = append(certRes.Certificate, c.pem()...)
+	}
+
+	return certRes
+}
+
+func createTestChain(t *testing.T, domain string, key crypto.PrivateKey) testChain {
+	t.Helper()
+
+	root := tester.NewCA(t, "Root CA")
+	intermediate := root.NewIntermediate(t, "Intermediate CA")
+
+	leaf, _ := intermediate.IssueForKey(t, &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		DNSNames:     []string{domain},
+	}, key.(crypto.Signer).Public())
+
+	return testChain{leafDER: leaf.Raw, intermediate: intermediate.Cert, root: root.Cert}
+}
+
+func Test_createOutputWriters(t *testing.T) {
+	writers, err := createOutputWriters([]string{"fullchain", " JKS ", "pem", "fullchain"}, outputOptions{jksPassword: "secret"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []outputWriter{fullChainOutput{}, jksOutput{password: "secret"}, pemOutput{}}, writers)
+
+	writers, err = createOutputWriters(nil, outputOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, writers)
+
+	_, err = createOutputWriters([]string{"p12"}, outputOptions{})
+	require.EqualError(t, err, `unsupported output "p12": use pem, fullchain, chain, der, pfx, jks, k8s`)
+
+	_, err = createOutputWriters([]string{"pfx"}, outputOptions{pfxFormat: "aes"})
+	require.EqualError(t, err, `unsupported PFX format "aes": use legacy, legacy-des, or modern`)
+}
+
+func Test_outputWriter_certificates(t *testing.T) {
+	key, err := certcrypto.GeneratePrivateKey(certcrypto.EC256)
+	require.NoError(t, err)
+
+	chain := createTestChain(t, "example.com", key)
+
+	leafPEM := certcrypto.PEMEncode(certcrypto.DERCertificateBytes(chain.leafDER))
+	keyPEM := certcrypto.PEMEncode(key)
+
+	for _, bundle := range []bool{true, false} {
+		certRes := chain.resource("example.com", keyPEM, bundle)
+
+		data, err := pemOutput{}.Encode(certRes)
+		require.NoError(t, err)
+		assert.Equal(t, append(append([]byte{}, certRes.Certificate...), keyPEM...), data)
+
+		data, err = fullChainOutput{}.Encode(certRes)
+		require.NoError(t, err)
+		assert.Equal(t, append(append([]byte{}, leafPEM...), chain.pem()...), data)
+
+		data, err = chainOutput{}.Encode(certRes)
+		require.NoError(t, err)
+		assert.Equal(t, chain.pem(), data)
+
+		data, err = derOutput{}.Encode(certRes)
+		require.NoError(t, err)
+		assert.Equal(t, chain.leafDER, data)
+	}
+}
+
+func Test_pfxOutput(t *testing.T) {
+	rsaKey, err := certcrypto.GeneratePrivateKey(certcrypto.RSA2048)
+	require.NoError(t, err)
+
+	ecKey, err := certcrypto.GeneratePrivateKey(certcrypto.EC256)
+	require.NoError(t, err)
+
+	ed25519Key, err := certcrypto.GeneratePrivateKey(certcrypto.ED25519)
+	require.NoError(t, err)
+
+	ecPKCS8, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		desc   string
+		format string
+		key    crypto.PrivateKey
+		keyPEM []byte
+		bundle bool
+	}{
+		{
+			desc:   "legacy, PKCS#1 RSA key",
+			format: pfxFormatLegacy,
+			key:    rsaKey,
+			keyPEM: certcrypto.PEMEncode(rsaKey),
+			bundle: true,
+		},
+		{
+			desc:   "legacy-des, SEC1 EC key",
+			format: pfxFormatLegacyDES,
+			key:    ecKey,
+			keyPEM: certcrypto.PEMEncode(ecKey),
+			bundle: true,
+		},
+		{
+			desc:   "modern, PKCS#8 RSA key",
+			format: pfxFormatModern,
+			key:    rsaKey,
+			keyPEM: certcrypto.PEMEncodeWithPKCSType(rsaKey, &certcrypto.PKCS8),
+			bundle: true,
+		},
+		{
+			desc:   "modern, PKCS#8 EC key, without bundle",
+			format: pfxFormatModern,
+			key:    ecKey,
+			keyPEM: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: ecPKCS8}),
+		},
+		{
+			desc:   "default format, Ed25519 key",
+			key:    ed25519Key,
+			keyPEM: certcrypto.PEMEncode(ed25519Key),
+			bundle: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			chain := createTestChain(t, "example.com", test.key)
+
+			writers, err := createOutputWriters([]string{outputPFX}, outputOptions{pfxPassword: "secret", pfxFormat: test.format})
+			require.NoError(t, err)
+			require.Len(t, writers, 1)
+
+			pfxData, err := writers[0].Encode(chain.resource("example.com", test.keyPEM, test.bundle))
+			require.NoError(t, err)
+
+			privateKey, cert, caCerts, err := pkcs12.DecodeChain(pfxData, "secret")
+			require.NoError(t, err)
+
+			assert.Equal(t, test.key, privateKey)
+			assert.Equal(t, chain.leafDER, cert.Raw)
+
+			require.Len(t, caCerts, 2)
+			assert.Equal(t, chain.intermediate.Raw, caCerts[0].Raw)
+			assert.Equal(t, chain.root.Raw, caCerts[1].Raw)
+		})
+	}
+}
+
+func Test_getPFXEncoder(t *testing.T) {
+	encoder, err := getPFXEncoder("")
+	require.NoError(t, err)
+	assert.Same(t, pkcs12.LegacyRC2, encoder)
+
+	encoder, err = getPFXEncoder("Modern")
+	require.NoError(t, err)
+	assert.Same(t, pkcs12.Modern2023, encoder)
+
+	_, err = getPFXEncoder("aes")
+	require.EqualError(t, err, `unsupported PFX format "aes": use legacy, legacy-des, or modern`)
+}
+
+func Test_jksOutput(t *testing.T) {
+	key, err := certcrypto.GeneratePrivateKey(certcrypto.RSA2048)
+	require.NoError(t, err)
+
+	chain := createTestChain(t, "example.com", key)
+
+	data, err := jksOutput{password: "secret"}.Encode(chain.resource("example.com", certcrypto.PEMEncode(key), false))
+	require.NoError(t, err)
+
+	ks := keystore.New(keystore.WithOrderedAliases())
+
+	err = ks.Load(bytes.NewReader(data), []byte("secret"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"ca-1", "ca-2", "example.com"}, ks.Aliases())
+
+	entry, err := ks.GetPrivateKeyEntry("example.com", []byte("secret"))
+	require.NoError(t, err)
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(entry.PrivateKey)
+	require.NoError(t, err)
+	assert.Equal(t, key, privateKey)
+
+	require.Len(t, entry.CertificateChain, 3)
+	assert.Equal(t, chain.leafDER, entry.CertificateChain[0].Content)
+	assert.Equal(t, chain.intermediate.Raw, entry.CertificateChain[1].Content)
+	assert.Equal(t, chain.root.Raw, entry.CertificateChain[2].Content)
+
+	trusted, err := ks.GetTrustedCertificateEntry("ca-2")
+	require.NoError(t, err)
+	assert.Equal(t, chain.root.Raw, trusted.Certificate.Content)
+
+	err = keystore.New().Load(bytes.NewReader(data), []byte("changeit"))
+	require.Error(t, err)
+}
+
+func Test_kubernetesOutput(t *testing.T) {
+	key, err := certcrypto.GeneratePrivateKey(certcrypto.EC256)
+	require.NoError(t, err)
+
+	chain := createTestChain(t, "*.example.com", key)
+
+	keyPEM := certcrypto.PEMEncode(key)
+
+	data, err := kubernetesOutput{namespace: "web"}.Encode(chain.resource("*.example.com", keyPEM, true))
+	require.NoError(t, err)
+
+	var secret kubernetesSecret
+	err = yaml.Unmarshal(data, &secret)
+	require.NoError(t, err)
+
+	assert.Equal(t, "v1", secret.APIVersion)
+	assert.Equal(t, "Secret", secret.Kind)
+	assert.Equal(t, "kubernetes.io/tls", secret.Type)
+	assert.Equal(t, kubernetesSecretMetadata{Name: "wildcard.example.com-tls", Namespace: "web"}, secret.Metadata)
+
+	decode := func(name string) []byte {
+		value, errD := base64.StdEncoding.DecodeString(secret.Data[name])
+		require.NoError(t, errD)
+
+		return value
+	}
+
+	assert.Equal(t, append(certcrypto.PEMEncode(certcrypto.DERCertificateBytes(chain.leafDER)), chain.pem()...), decode("tls.crt"))
+	assert.Equal(t, keyPEM, decode("tls.key"))
+	assert.Equal(t, chain.pem(), decode("ca.crt"))
+
+	data, err = kubernetesOutput{}.Encode(chain.resource("*.example.com", keyPEM, true))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "namespace")
+}
+
+func Test_kubernetesSecretName(t *testing.T) {
+	testCases := []struct {
+		domain   string
+		expected string
+	}{
+		{domain: "example.com", expected: "example.com-tls"},
+		{domain: "Example.COM", expected: "example.com-tls"},
+		{domain: "*.example.com", expected: "wildcard.example.com-tls"},
+		{domain: "2001:db8::1", expected: "2001-db8--1-tls"},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.domain, func(t *testing.T) {
+			t.Parallel()
+
+			name, err := kubernetesSecretName(test.domain)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, name)
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
+	"net"
 	"os"
 	"strings"
 	"time"
@@ -11,6 +12,7 @@ import (
 	"github.com/LukasDeco/lego/v4/certcrypto"
 	"github.com/LukasDeco/lego/v4/lego"
 	"github.com/LukasDeco/lego/v4/log"
+	"github.com/LukasDeco/lego/v4/platform/wait"
 	"github.com/LukasDeco/lego/v4/registration"
 	"github.com/urfave/cli/v2"
 )
@@ -40,6 +42,9 @@ func newClient(ctx *cli.Context, acc registration.User, keyType certcrypto.KeyTy
 	config.Certificate = lego.CertificateConfig{
 		KeyType: keyType,
 		Timeout: time.Duration(ctx.Int("cert.timeout")) * time.Second,
+		ValidationPolling: wait.Strategy{
+			Timeout: time.Duration(ctx.Int("validation.timeout")) * time.Second,
+		},
 	}
 	config.UserAgent = getUserAgent(ctx)
 
@@ -52,6 +57,8 @@ func newClient(ctx *cli.Context, acc registration.User, keyType certcrypto.KeyTy
 		log.Fatalf("Could not create client: %v", err)
 	}
 
+	client.Challenge.SetConcurrency(ctx.Int("validation.concurrency"))
+
 	if client.GetExternalAccountRequired() && !ctx.IsSet("eab") {
 		log.Fatal("Server requires External Account Binding. Use --eab with --kid and --hmac.")
 	}
@@ -61,7 +68,11 @@ func newClient(ctx *cli.Context, acc registration.User, keyType certcrypto.KeyTy
 
 // getKeyType the type from which private keys should be generated.
 func getKeyType(ctx *cli.Context) certcrypto.KeyType {
-	keyType := ctx.String("key-type")
+	return parseKeyType(ctx.String("key-type"))
+}
+
+// parseKeyType converts a CLI key type value to a certcrypto.KeyType.
+func parseKeyType(keyType string) certcrypto.KeyType {
 	switch strings.ToUpper(keyType) {
 	case "RSA2048":
 		return certcrypto.RSA2048
@@ -75,6 +86,10 @@ func getKeyType(ctx *cli.Context) certcrypto.KeyType {
 		return certcrypto.EC256
 	case "EC384":
 		return certcrypto.EC384
+	case "EC521":
+		return certcrypto.EC521
+	case "ED25519":
+		return certcrypto.ED25519
 	}
 
 	log.Fatalf("Unsupported KeyType: %s", keyType)
@@ -89,6 +104,32 @@ func getEmail(ctx *cli.Context) string {
 	return email
 }
 
+// getDomains returns the domains (--domains) followed by the IP addresses (--ip) to process.
+func getDomains(ctx *cli.Context) []string {
+	domains := ctx.StringSlice("domains")
+
+	for _, value := range ctx.StringSlice("ip") {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			log.Fatalf("Invalid IP address: %q", value)
+		}
+
+		domains = append(domains, ip.String())
+	}
+
+	return domains
+}
+
+// getTime returns the value of a timestamp flag, or the zero time if the flag is not set.
+func getTime(ctx *cli.Context, name string) time.Time {
+	value := ctx.Timestamp(name)
+	if value == nil {
+		return time.Time{}
+	}
+
+	return *value
+}
+
 func getUserAgent(ctx *cli.Context) string {
 	return strings.TrimSpace(fmt.Sprintf("%s lego-cli/%s", ctx.String("user-agent"), ctx.App.Version))
 }
@@ -111,16 +111,39 @@ func setupDNS(ctx *cli.Context, client *lego.Client) {
 		log.Fatal(err)
 	}
 
-	servers := ctx.StringSlice("dns.resolvers")
-	err = client.Challenge.SetDNS01Provider(provider,
+	servers, zones, err := dns01.ParseResolvers(ctx.StringSlice("dns.resolvers"))
+	if err != nil {
+		log.Fatalf("Invalid DNS resolvers: %v", err)
+	}
+
+	opts := []dns01.ChallengeOption{
 		dns01.CondOption(len(servers) > 0,
-			dns01.AddRecursiveNameservers(dns01.ParseNameservers(ctx.StringSlice("dns.resolvers")))),
+			dns01.AddRecursiveNameservers(servers)),
 		dns01.CondOption(ctx.Bool("dns.disable-cp"),
 			dns01.DisableCompletePropagationRequirement()),
 		dns01.CondOption(ctx.IsSet("dns-timeout"),
 			dns01.AddDNSTimeout(time.Duration(ctx.Int("dns-timeout"))*time.Second)),
-	)
+	}
+
+	for zone, nameservers := range zones {
+		opts = append(opts, dns01.AddZoneNameservers(zone, nameservers))
+	}
+
+	err = client.Challenge.SetDNS01Provider(provider, opts...)
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	if ctx.Bool("dns.account") {
+		// A distinct instance: the logger of the provider is specific to the challenge type.
+		accountProvider, err := dns.NewDNSChallengeProviderByName(ctx.String("dns"))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		err = client.Challenge.SetDNSAccount01Provider(accountProvider, opts...)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 }
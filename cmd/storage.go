@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Storage a key/value storage for the data of the CLI (accounts, certificates, archives).
+//
+// The keys are slash-separated paths relative to the root of the storage,
+// e.g. "certificates/example.com.crt" or "accounts/acme-v02.api.letsencrypt.org/hubert@hubert.com/account.json".
+//
+// The errors related to a missing key must match fs.ErrNotExist.
+type Storage interface {
+	// ReadFile returns the content of a key.
+	ReadFile(key string) ([]byte, error)
+
+	// WriteFile creates or replaces the content of a key.
+	WriteFile(key string, data []byte) error
+
+	// Exists reports whether a key exists.
+	Exists(key string) (bool, error)
+
+	// Remove deletes a key.
+	Remove(key string) error
+
+	// Rename moves the content of a key to another key.
+	Rename(oldKey, newKey string) error
+
+	// List returns, recursively, the keys inside a "directory" (a key prefix followed by a slash).
+	List(dir string) ([]string, error)
+
+	// Location returns a human-readable location of a key (a file path, a URL, etc.).
+	Location(key string) string
+}
+
+// Storage URI schemes.
+const (
+	storageSchemeFile = "file"
+	storageSchemeKV   = "kv"
+	storageSchemeS3   = "s3"
+)
+
+// NewStorage creates the storage selected by the "storage" option,
+// by default the directory defined by the "path" option.
+func NewStorage(ctx *cli.Context) (Storage, error) {
+	return parseStorage(ctx.String("storage"), ctx.String("path"))
+}
+
+// parseStorage creates a storage from a URI:
+//   - "file:///path/to/dir" (or a simple path): a directory on the local filesystem.
+//   - "kv:///path/to/dir?lock-timeout=30s&stale-lock=5m": a directory where the accesses are serialized by a lock file.
+//   - "s3://bucket/prefix?endpoint=http://localhost:9000&region=us-east-1&path-style=true": an S3-compatible object storage.
+func parseStorage(rawURI, defaultPath string) (Storage, error) {
+	if rawURI == "" {
+		return NewFileStorage(defaultPath), nil
+	}
+
+	uri, err := url.Parse(rawURI)
+	if err != nil {
+		return nil, fmt.Errorf("storage: invalid URI %q: %w", rawURI, err)
+	}
+
+	switch uri.Scheme {
+	case "":
+		return NewFileStorage(rawURI), nil
+
+	case storageSchemeFile:
+		return NewFileStorage(storagePath(uri)), nil
+
+	case storageSchemeKV:
+		query := uri.Query()
+
+		lockTimeout, err := parseStorageDuration(query, "lock-timeout", defaultLockTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		staleLock, err := parseStorageDuration(query, "stale-lock", defaultStaleLock)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewKVStorage(storagePath(uri), lockTimeout, staleLock), nil
+
+	case storageSchemeS3:
+		if uri.Host == "" {
+			return nil, fmt.Errorf("storage: missing bucket in %q", rawURI)
+		}
+
+		query := uri.Query()
+
+		pathStyle := false
+		if value := query.Get("path-style"); value != "" {
+			pathStyle, err = strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("storage: invalid path-style value %q: %w", value, err)
+			}
+		}
+
+		return NewS3Storage(S3Config{
+			Bucket:    uri.Host,
+			Prefix:    uri.Path,
+			Endpoint:  query.Get("endpoint"),
+			Region:    query.Get("region"),
+			PathStyle: pathStyle,
+		})
+
+	default:
+		return nil, fmt.Errorf("storage: unsupported scheme %q", uri.Scheme)
+	}
+}
+
+// storagePath returns the filesystem path of a "file" or "kv" URI.
+// Both absolute ("file:///var/lib/lego") and relative ("file://.lego") forms are supported.
+func storagePath(uri *url.URL) string {
+	return filepath.FromSlash(uri.Host + uri.Path)
+}
+
+func parseStorageDuration(query url.Values, name string, defaultValue time.Duration) (time.Duration, error) {
+	value := query.Get(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("storage: invalid %s value %q: %w", name, value, err)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FileStorage a storage backed by a directory on the local filesystem.
+// Each key is a file, the slashes of the keys are directories.
+type FileStorage struct {
+	rootPath string
+}
+
+// NewFileStorage creates a new FileStorage.
+func NewFileStorage(rootPath string) *FileStorage {
+	return &FileStorage{rootPath: rootPath}
+}
+
+func (s *FileStorage) ReadFile(key string) ([]byte, error) {
+	return os.ReadFile(s.Location(key))
+}
+
+func (s *FileStorage) WriteFile(key string, data []byte) error {
+	filePath := s.Location(key)
+
+	err := createNonExistingFolder(filepath.Dir(filePath))
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filePath, data, filePerm)
+}
+
+func (s *FileStorage) Exists(key string) (bool, error) {
+	_, err := os.Stat(s.Location(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *FileStorage) Remove(key string) error {
+	return os.Remove(s.Location(key))
+}
+
+func (s *FileStorage) Rename(oldKey, newKey string) error {
+	newPath := s.Location(newKey)
+
+	err := createNonExistingFolder(filepath.Dir(newPath))
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(s.Location(oldKey), newPath)
+}
+
+func (s *FileStorage) List(dir string) ([]string, error) {
+	var keys []string
+
+	err := filepath.WalkDir(s.Location(dir), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.rootPath, path)
+		if err != nil {
+			return err
+		}
+
+		keys = append(keys, filepath.ToSlash(rel))
+
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	return keys, err
+}
+
+func (s *FileStorage) Location(key string) string {
+	return filepath.Join(s.rootPath, filepath.FromSlash(key))
+}
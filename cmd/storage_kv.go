@@ -0,0 +1,279 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	kvLockFileName = ".lego.lock"
+
+	defaultLockTimeout = 30 * time.Second
+	defaultStaleLock   = 5 * time.Minute
+
+	lockRetryInterval = 50 * time.Millisecond
+)
+
+// KVStorage a key/value storage backed by a directory,
+// intended to be shared between several instances of lego (e.g. on a network filesystem).
+//
+// Every operation is serialized by a lock file at the root of the directory,
+// and the writes are atomic (temporary file then rename).
+type KVStorage struct {
+	files *FileStorage
+
+	lockPath    string
+	lockTimeout time.Duration
+	staleLock   time.Duration
+}
+
+// NewKVStorage creates a new KVStorage.
+// lockTimeout is the maximum time to wait for the lock,
+// staleLock is the age after which a lock is considered abandoned (i.e. by a crashed process) and is removed.
+func NewKVStorage(rootPath string, lockTimeout, staleLock time.Duration) *KVStorage {
+	return &KVStorage{
+		files:       NewFileStorage(rootPath),
+		lockPath:    filepath.Join(rootPath, kvLockFileName),
+		lockTimeout: lockTimeout,
+		staleLock:   staleLock,
+	}
+}
+
+func (s *KVStorage) ReadFile(key string) ([]byte, error) {
+	unlock, err := s.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return s.files.ReadFile(key)
+}
+
+func (s *KVStorage) WriteFile(key string, data []byte) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	filePath := s.files.Location(key)
+
+	err = createNonExistingFolder(filepath.Dir(filePath))
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	err = tmp.Chmod(filePerm)
+	if err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filePath)
+}
+
+func (s *KVStorage) Exists(key string) (bool, error) {
+	unlock, err := s.lock()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	return s.files.Exists(key)
+}
+
+func (s *KVStorage) Remove(key string) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return s.files.Remove(key)
+}
+
+func (s *KVStorage) Rename(oldKey, newKey string) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return s.files.Rename(oldKey, newKey)
+}
+
+func (s *KVStorage) List(dir string) ([]string, error) {
+	unlock, err := s.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	keys, err := s.files.List(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []string
+	for _, key := range keys {
+		// the lock file, and the stale lock files being removed.
+		if strings.HasPrefix(key, kvLockFileName) {
+			continue
+		}
+
+		filtered = append(filtered, key)
+	}
+
+	return filtered, nil
+}
+
+func (s *KVStorage) Location(key string) string {
+	return s.files.Location(key)
+}
+
+// lock acquires the lock file, and returns the function to release it.
+// The lock file contains a token identifying its owner (PID and random ID):
+// the lock is only released by its owner.
+func (s *KVStorage) lock() (func(), error) {
+	err := createNonExistingFolder(filepath.Dir(s.lockPath))
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := newLockToken()
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(s.lockTimeout)
+
+	for {
+		acquired, err := s.tryLock(token)
+		if err != nil {
+			return nil, err
+		}
+
+		if acquired {
+			return func() { s.unlock(token) }, nil
+		}
+
+		s.breakStaleLock(token)
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("storage: could not acquire the lock %s in %s", s.lockPath, s.lockTimeout)
+		}
+
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// tryLock creates the lock file with the token.
+func (s *KVStorage) tryLock(token string) (bool, error) {
+	file, err := os.OpenFile(s.lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, filePerm)
+	if errors.Is(err, fs.ErrExist) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("storage: could not create the lock file %s: %w", s.lockPath, err)
+	}
+
+	_, err = file.WriteString(token)
+	if errC := file.Close(); err == nil {
+		err = errC
+	}
+
+	if err != nil {
+		_ = os.Remove(s.lockPath)
+		return false, fmt.Errorf("storage: could not write the lock file %s: %w", s.lockPath, err)
+	}
+
+	// Another process can have broken the lock in the meantime (see breakStaleLock).
+	return s.ownsLock(token), nil
+}
+
+// unlock removes the lock file if it's still owned by the token:
+// the lock can have been broken, and acquired by another process.
+func (s *KVStorage) unlock(token string) {
+	if s.ownsLock(token) {
+		_ = os.Remove(s.lockPath)
+	}
+}
+
+func (s *KVStorage) ownsLock(token string) bool {
+	data, err := os.ReadFile(s.lockPath)
+
+	return err == nil && string(data) == token
+}
+
+// breakStaleLock removes the lock file if it's older than staleLock (i.e. its owner has crashed).
+// The lock file is moved before being removed, and restored if it's not the stale lock anymore:
+// when several processes break the same stale lock, the new lock of one of them is not removed by the others.
+func (s *KVStorage) breakStaleLock(token string) {
+	info, err := os.Stat(s.lockPath)
+	if err != nil || time.Since(info.ModTime()) <= s.staleLock {
+		return
+	}
+
+	stale, err := os.ReadFile(s.lockPath)
+	if err != nil {
+		return
+	}
+
+	// The owner of the lock is probably dead.
+	moved := s.lockPath + "." + token + ".stale"
+
+	err = os.Rename(s.lockPath, moved)
+	if err != nil {
+		return
+	}
+
+	defer func() { _ = os.Remove(moved) }()
+
+	data, err := os.ReadFile(moved)
+	if err == nil && bytes.Equal(data, stale) {
+		return
+	}
+
+	// The lock has been replaced in the meantime: it's restored, unless another lock has been created since.
+	_ = os.Link(moved, s.lockPath)
+}
+
+// newLockToken returns a token identifying the owner of the lock.
+func newLockToken() (string, error) {
+	id := make([]byte, 8)
+
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", fmt.Errorf("storage: could not create the lock token: %w", err)
+	}
+
+	return strconv.Itoa(os.Getpid()) + "-" + hex.EncodeToString(id), nil
+}
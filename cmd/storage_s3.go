@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const defaultS3Region = "us-east-1"
+
+// S3Config the configuration of an S3Storage.
+type S3Config struct {
+	// Bucket the name of the bucket.
+	Bucket string
+	// Prefix the prefix of all the keys (optional).
+	Prefix string
+	// Endpoint the URL of an S3-compatible server (e.g. MinIO), the AWS endpoint is used if empty.
+	Endpoint string
+	// Region the region of the bucket, defaults to the AWS configuration (AWS_REGION) or to us-east-1.
+	Region string
+	// PathStyle uses path-style addressing (http://endpoint/bucket/key) instead of virtual-hosted-style.
+	PathStyle bool
+	// Credentials defaults to the AWS credentials chain (environment variables, shared configuration, etc.).
+	Credentials *credentials.Credentials
+}
+
+// S3Storage a storage backed by an S3-compatible object storage.
+type S3Storage struct {
+	client *s3.S3
+	bucket string
+	prefix string
+}
+
+// NewS3Storage creates a new S3Storage.
+func NewS3Storage(config S3Config) (*S3Storage, error) {
+	awsConfig := aws.NewConfig()
+
+	if config.Endpoint != "" {
+		awsConfig = awsConfig.WithEndpoint(config.Endpoint)
+	}
+
+	if config.Region != "" {
+		awsConfig = awsConfig.WithRegion(config.Region)
+	}
+
+	if config.PathStyle {
+		awsConfig = awsConfig.WithS3ForcePathStyle(true)
+	}
+
+	if config.Credentials != nil {
+		awsConfig = awsConfig.WithCredentials(config.Credentials)
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *awsConfig,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("storage: could not create the S3 session: %w", err)
+	}
+
+	if aws.StringValue(sess.Config.Region) == "" {
+		sess.Config.Region = aws.String(defaultS3Region)
+	}
+
+	return &S3Storage{
+		client: s3.New(sess),
+		bucket: config.Bucket,
+		prefix: strings.Trim(config.Prefix, "/"),
+	}, nil
+}
+
+func (s *S3Storage) ReadFile(key string) ([]byte, error) {
+	output, err := s.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+	if err != nil {
+		return nil, s.wrapError(key, err)
+	}
+
+	defer func() { _ = output.Body.Close() }()
+
+	return io.ReadAll(output.Body)
+}
+
+func (s *S3Storage) WriteFile(key string, data []byte) error {
+	_, err := s.client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+		Body:   bytes.NewReader(data),
+	})
+
+	return s.wrapError(key, err)
+}
+
+func (s *S3Storage) Exists(key string) (bool, error) {
+	_, err := s.client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+
+	err = s.wrapError(key, err)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *S3Storage) Remove(key string) error {
+	_, err := s.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+
+	return s.wrapError(key, err)
+}
+
+// Rename copies the object then deletes the original: the operation is not atomic.
+func (s *S3Storage) Rename(oldKey, newKey string) error {
+	_, err := s.client.CopyObject(&s3.CopyObjectInput{
+		Bucket:     aws.String(s.bucket),
+		Key:        aws.String(s.objectKey(newKey)),
+		CopySource: aws.String(url.PathEscape(s.bucket + "/" + s.objectKey(oldKey))),
+	})
+	if err != nil {
+		return s.wrapError(oldKey, err)
+	}
+
+	return s.Remove(oldKey)
+}
+
+func (s *S3Storage) List(dir string) ([]string, error) {
+	// The root (without a prefix) is listed with an empty prefix.
+	prefix := strings.TrimSuffix(s.objectKey(dir), "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	}
+
+	var keys []string
+
+	err := s.client.ListObjectsV2Pages(input, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		for _, object := range page.Contents {
+			keys = append(keys, strings.TrimPrefix(aws.StringValue(object.Key), s.prefix+"/"))
+		}
+
+		return true
+	})
+	if err != nil {
+		return nil, s.wrapError(dir, err)
+	}
+
+	return keys, nil
+}
+
+func (s *S3Storage) Location(key string) string {
+	return fmt.Sprintf("s3://%s/%s", s.bucket, s.objectKey(key))
+}
+
+func (s *S3Storage) objectKey(key string) string {
+	key = strings.Trim(key, "/")
+
+	if s.prefix == "" {
+		return key
+	}
+
+	return s.prefix + "/" + key
+}
+
+// wrapError converts the "not found" responses to fs.ErrNotExist.
+func (s *S3Storage) wrapError(key string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var reqErr awserr.RequestFailure
+	if errors.As(err, &reqErr) && reqErr.StatusCode() == http.StatusNotFound {
+		return fmt.Errorf("%s: %w", s.Location(key), fs.ErrNotExist)
+	}
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == s3.ErrCodeNoSuchKey {
+		return fmt.Errorf("%s: %w", s.Location(key), fs.ErrNotExist)
+	}
+
+	return fmt.Errorf("%s: %w", s.Location(key), err)
+}
@@ -0,0 +1,510 @@
+package cmd
+
+import (
+	"encoding/xml"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseStorage(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		uri      string
+		expected Storage
+	}{
+		{
+			desc:     "default",
+			uri:      "",
+			expected: NewFileStorage("/default"),
+		},
+		{
+			desc:     "path",
+			uri:      "/var/lib/lego",
+			expected: NewFileStorage("/var/lib/lego"),
+		},
+		{
+			desc:     "file",
+			uri:      "file:///var/lib/lego",
+			expected: NewFileStorage("/var/lib/lego"),
+		},
+		{
+			desc:     "file relative",
+			uri:      "file://.lego/data",
+			expected: NewFileStorage(".lego/data"),
+		},
+		{
+			desc:     "kv",
+			uri:      "kv:///var/lib/lego",
+			expected: NewKVStorage("/var/lib/lego", defaultLockTimeout, defaultStaleLock),
+		},
+		{
+			desc:     "kv with options",
+			uri:      "kv:///var/lib/lego?lock-timeout=1m&stale-lock=1h",
+			expected: NewKVStorage("/var/lib/lego", time.Minute, time.Hour),
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			storage, err := parseStorage(test.uri, "/default")
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, storage)
+		})
+	}
+}
+
+func Test_parseStorage_s3(t *testing.T) {
+	storage, err := parseStorage("s3://my-bucket/lego/data?endpoint=http://localhost:9000&region=eu-west-1&path-style=true", "/default")
+	require.NoError(t, err)
+
+	require.IsType(t, &S3Storage{}, storage)
+
+	s3Storage := storage.(*S3Storage)
+	assert.Equal(t, "my-bucket", s3Storage.bucket)
+	assert.Equal(t, "lego/data", s3Storage.prefix)
+	assert.Equal(t, "eu-west-1", *s3Storage.client.Config.Region)
+	assert.Equal(t, "http://localhost:9000", *s3Storage.client.Config.Endpoint)
+	assert.True(t, *s3Storage.client.Config.S3ForcePathStyle)
+	assert.Equal(t, "s3://my-bucket/lego/data/certificates/example.com.crt", storage.Location("certificates/example.com.crt"))
+}
+
+func Test_parseStorage_errors(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		uri      string
+		expected string
+	}{
+		{
+			desc:     "unsupported scheme",
+			uri:      "ftp://example.com/lego",
+			expected: `storage: unsupported scheme "ftp"`,
+		},
+		{
+			desc:     "missing bucket",
+			uri:      "s3:///lego",
+			expected: `storage: missing bucket in "s3:///lego"`,
+		},
+		{
+			desc:     "invalid path-style",
+			uri:      "s3://bucket?path-style=foo",
+			expected: `storage: invalid path-style value "foo": strconv.ParseBool: parsing "foo": invalid syntax`,
+		},
+		{
+			desc:     "invalid lock timeout",
+			uri:      "kv:///var/lib/lego?lock-timeout=foo",
+			expected: `storage: invalid lock-timeout value "foo": time: invalid duration "foo"`,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := parseStorage(test.uri, "/default")
+			require.EqualError(t, err, test.expected)
+		})
+	}
+}
+
+func TestFileStorage(t *testing.T) {
+	rootPath := t.TempDir()
+
+	storage := NewFileStorage(rootPath)
+
+	testStorage(t, storage)
+
+	assert.Equal(t, filepath.Join(rootPath, "certificates", "example.com.crt"), storage.Location("certificates/example.com.crt"))
+
+	info, err := os.Stat(filepath.Join(rootPath, "certificates", "example.com.crt"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(filePerm), info.Mode().Perm())
+}
+
+func TestKVStorage(t *testing.T) {
+	rootPath := t.TempDir()
+
+	storage := NewKVStorage(rootPath, time.Second, time.Minute)
+
+	testStorage(t, storage)
+
+	// the lock is released after each operation.
+	assert.NoFileExists(t, filepath.Join(rootPath, kvLockFileName))
+
+	// no temporary files left.
+	entries, err := os.ReadDir(filepath.Join(rootPath, "certificates"))
+	require.NoError(t, err)
+
+	for _, entry := range entries {
+		assert.False(t, strings.HasSuffix(entry.Name(), ".tmp"), entry.Name())
+	}
+}
+
+func TestKVStorage_lockTimeout(t *testing.T) {
+	rootPath := t.TempDir()
+
+	err := os.WriteFile(filepath.Join(rootPath, kvLockFileName), []byte("123"), filePerm)
+	require.NoError(t, err)
+
+	storage := NewKVStorage(rootPath, 200*time.Millisecond, time.Minute)
+
+	err = storage.WriteFile("certificates/example.com.crt", []byte("cert"))
+	require.ErrorContains(t, err, "could not acquire the lock")
+
+	assert.NoFileExists(t, filepath.Join(rootPath, "certificates", "example.com.crt"))
+}
+
+func TestKVStorage_staleLock(t *testing.T) {
+	rootPath := t.TempDir()
+
+	lockPath := filepath.Join(rootPath, kvLockFileName)
+
+	err := os.WriteFile(lockPath, []byte("123"), filePerm)
+	require.NoError(t, err)
+
+	old := time.Now().Add(-time.Hour)
+	err = os.Chtimes(lockPath, old, old)
+	require.NoError(t, err)
+
+	storage := NewKVStorage(rootPath, 200*time.Millisecond, time.Minute)
+
+	err = storage.WriteFile("certificates/example.com.crt", []byte("cert"))
+	require.NoError(t, err)
+
+	assert.NoFileExists(t, lockPath)
+}
+
+func TestKVStorage_unlock(t *testing.T) {
+	rootPath := t.TempDir()
+
+	lockPath := filepath.Join(rootPath, kvLockFileName)
+
+	storage := NewKVStorage(rootPath, 200*time.Millisecond, time.Minute)
+
+	unlock, err := storage.lock()
+	require.NoError(t, err)
+
+	// the lock has been broken, and acquired by another process.
+	err = os.WriteFile(lockPath, []byte("123-other"), filePerm)
+	require.NoError(t, err)
+
+	unlock()
+
+	data, err := os.ReadFile(lockPath)
+	require.NoError(t, err)
+	assert.Equal(t, "123-other", string(data))
+}
+
+func TestKVStorage_breakStaleLock(t *testing.T) {
+	rootPath := t.TempDir()
+
+	lockPath := filepath.Join(rootPath, kvLockFileName)
+
+	err := os.WriteFile(lockPath, []byte("123-stale"), filePerm)
+	require.NoError(t, err)
+
+	old := time.Now().Add(-time.Hour)
+	err = os.Chtimes(lockPath, old, old)
+	require.NoError(t, err)
+
+	storage := NewKVStorage(rootPath, 200*time.Millisecond, time.Minute)
+
+	storage.breakStaleLock("456-breaker")
+
+	assert.NoFileExists(t, lockPath)
+
+	// a fresh lock is not broken.
+	err = os.WriteFile(lockPath, []byte("789-fresh"), filePerm)
+	require.NoError(t, err)
+
+	storage.breakStaleLock("456-breaker")
+
+	data, err := os.ReadFile(lockPath)
+	require.NoError(t, err)
+	assert.Equal(t, "789-fresh", string(data))
+
+	// no moved lock files left.
+	entries, err := os.ReadDir(rootPath)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestKVStorage_concurrent(t *testing.T) {
+	rootPath := t.TempDir()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			// each goroutine acts like a separate lego instance.
+			storage := NewKVStorage(rootPath, 10*time.Second, time.Minute)
+
+			err := storage.WriteFile("certificates/example.com.crt", []byte("cert"))
+			assert.NoError(t, err)
+
+			data, err := storage.ReadFile("certificates/example.com.crt")
+			assert.NoError(t, err)
+			assert.Equal(t, "cert", string(data))
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestS3Storage(t *testing.T) {
+	server := newFakeS3Server("my-bucket")
+	t.Cleanup(server.Close)
+
+	storage, err := NewS3Storage(S3Config{
+		Bucket:      "my-bucket",
+		Prefix:      "/lego/",
+		Endpoint:    server.URL,
+		Region:      "us-east-1",
+		PathStyle:   true,
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	require.NoError(t, err)
+
+	testStorage(t, storage)
+
+	assert.Equal(t, "s3://my-bucket/lego/certificates/example.com.crt", storage.Location("certificates/example.com.crt"))
+
+	// the keys are stored under the prefix.
+	_, ok := server.objects["lego/certificates/example.com.crt"]
+	assert.True(t, ok)
+}
+
+func TestS3Storage_noPrefix(t *testing.T) {
+	server := newFakeS3Server("my-bucket")
+	t.Cleanup(server.Close)
+
+	storage, err := NewS3Storage(S3Config{
+		Bucket:      "my-bucket",
+		Endpoint:    server.URL,
+		Region:      "us-east-1",
+		PathStyle:   true,
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	require.NoError(t, err)
+
+	testStorage(t, storage)
+
+	assert.Equal(t, "s3://my-bucket/certificates/example.com.crt", storage.Location("certificates/example.com.crt"))
+}
+
+// testStorage the behavior shared by all the storages.
+func testStorage(t *testing.T, storage Storage) {
+	t.Helper()
+
+	_, err := storage.ReadFile("certificates/example.com.crt")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	exists, err := storage.Exists("certificates/example.com.crt")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	keys, err := storage.List("certificates")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	err = storage.WriteFile("certificates/example.com.crt", []byte("cert"))
+	require.NoError(t, err)
+
+	err = storage.WriteFile("certificates/example.com.key", []byte("key"))
+	require.NoError(t, err)
+
+	err = storage.WriteFile("accounts/localhost_14000/foo@example.com/account.json", []byte("{}"))
+	require.NoError(t, err)
+
+	exists, err = storage.Exists("certificates/example.com.crt")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	data, err := storage.ReadFile("certificates/example.com.crt")
+	require.NoError(t, err)
+	assert.Equal(t, "cert", string(data))
+
+	// overwrite
+	err = storage.WriteFile("certificates/example.com.crt", []byte("new cert"))
+	require.NoError(t, err)
+
+	data, err = storage.ReadFile("certificates/example.com.crt")
+	require.NoError(t, err)
+	assert.Equal(t, "new cert", string(data))
+
+	keys, err = storage.List("certificates")
+	require.NoError(t, err)
+	sort.Strings(keys)
+	assert.Equal(t, []string{"certificates/example.com.crt", "certificates/example.com.key"}, keys)
+
+	keys, err = storage.List("accounts")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"accounts/localhost_14000/foo@example.com/account.json"}, keys)
+
+	// the root.
+	keys, err = storage.List("")
+	require.NoError(t, err)
+	sort.Strings(keys)
+	assert.Equal(t, []string{
+		"accounts/localhost_14000/foo@example.com/account.json",
+		"certificates/example.com.crt",
+		"certificates/example.com.key",
+	}, keys)
+
+	err = storage.Rename("certificates/example.com.key", "archives/123.example.com.key")
+	require.NoError(t, err)
+
+	exists, err = storage.Exists("certificates/example.com.key")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	data, err = storage.ReadFile("archives/123.example.com.key")
+	require.NoError(t, err)
+	assert.Equal(t, "key", string(data))
+
+	err = storage.Remove("archives/123.example.com.key")
+	require.NoError(t, err)
+
+	keys, err = storage.List("archives")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+type fakeS3Server struct {
+	*httptest.Server
+
+	bucket  string
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+// newFakeS3Server creates a minimal S3-compatible server (path-style addressing),
+// like a local MinIO, supporting the operations used by S3Storage.
+func newFakeS3Server(bucket string) *fakeS3Server {
+	server := &fakeS3Server{
+		bucket:  bucket,
+		objects: map[string][]byte{},
+	}
+
+	server.Server = httptest.NewServer(server)
+
+	return server
+}
+
+func (f *fakeS3Server) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, "/"), "/")
+	if bucket != f.bucket {
+		writeS3Error(rw, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	switch {
+	case req.Method == http.MethodGet && key == "" && req.URL.Query().Get("list-type") == "2":
+		f.list(rw, req.URL.Query().Get("prefix"))
+
+	case req.Method == http.MethodPut && req.Header.Get("X-Amz-Copy-Source") != "":
+		source, err := url.PathUnescape(req.Header.Get("X-Amz-Copy-Source"))
+		if err != nil {
+			writeS3Error(rw, http.StatusBadRequest, "InvalidArgument")
+			return
+		}
+
+		data, ok := f.objects[strings.TrimPrefix(strings.TrimPrefix(source, "/"), f.bucket+"/")]
+		if !ok {
+			writeS3Error(rw, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+
+		f.objects[key] = data
+
+		_, _ = rw.Write([]byte(`<CopyObjectResult><ETag>"etag"</ETag></CopyObjectResult>`))
+
+	case req.Method == http.MethodPut:
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			writeS3Error(rw, http.StatusInternalServerError, "InternalError")
+			return
+		}
+
+		f.objects[key] = data
+
+	case req.Method == http.MethodGet, req.Method == http.MethodHead:
+		data, ok := f.objects[key]
+		if !ok {
+			writeS3Error(rw, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+
+		if req.Method == http.MethodGet {
+			_, _ = rw.Write(data)
+		}
+
+	case req.Method == http.MethodDelete:
+		delete(f.objects, key)
+		rw.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeS3Error(rw, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+func (f *fakeS3Server) list(rw http.ResponseWriter, prefix string) {
+	type content struct {
+		Key string `xml:"Key"`
+	}
+
+	type listBucketResult struct {
+		XMLName     xml.Name  `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+		Name        string    `xml:"Name"`
+		Prefix      string    `xml:"Prefix"`
+		KeyCount    int       `xml:"KeyCount"`
+		IsTruncated bool      `xml:"IsTruncated"`
+		Contents    []content `xml:"Contents"`
+	}
+
+	result := listBucketResult{Name: f.bucket, Prefix: prefix}
+
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) {
+			result.Contents = append(result.Contents, content{Key: key})
+		}
+	}
+
+	sort.Slice(result.Contents, func(i, j int) bool { return result.Contents[i].Key < result.Contents[j].Key })
+
+	result.KeyCount = len(result.Contents)
+
+	rw.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(rw).Encode(result)
+}
+
+func writeS3Error(rw http.ResponseWriter, status int, code string) {
+	rw.Header().Set("Content-Type", "application/xml")
+	rw.WriteHeader(status)
+	_, _ = rw.Write([]byte("<Error><Code>" + code + "</Code><Message>" + code + "</Message></Error>"))
+}
@@ -50,10 +50,19 @@ func NewClient(config *Config) (*Client, error) {
 		return nil, err
 	}
 
+	if config.Logger != nil {
+		core.SetLogger(config.Logger)
+	}
+
 	solversManager := resolver.NewSolversManager(core)
+	solversManager.SetValidationPolling(config.Certificate.ValidationPolling)
 
 	prober := resolver.NewProber(solversManager)
-	certifier := certificate.NewCertifier(core, prober, certificate.CertifierOptions{KeyType: config.Certificate.KeyType, Timeout: config.Certificate.Timeout})
+	certifier := certificate.NewCertifier(core, prober, certificate.CertifierOptions{
+		KeyType:             config.Certificate.KeyType,
+		Timeout:             config.Certificate.Timeout,
+		FinalizationPolling: config.Certificate.FinalizationPolling,
+	})
 
 	return &Client{
 		Certificate:  certifier,
@@ -12,6 +12,8 @@ import (
 	"time"
 
 	"github.com/LukasDeco/lego/v4/certcrypto"
+	"github.com/LukasDeco/lego/v4/log"
+	"github.com/LukasDeco/lego/v4/platform/wait"
 	"github.com/LukasDeco/lego/v4/registration"
 )
 
@@ -46,6 +48,10 @@ type Config struct {
 	UserAgent   string
 	HTTPClient  *http.Client
 	Certificate CertificateConfig
+
+	// Logger the logger of the client, and of the challenge providers accepting a logger (see log.LoggerSetter).
+	// Defaults to log.Default().
+	Logger *log.FieldLogger
 }
 
 func NewConfig(user registration.User) *Config {
@@ -63,6 +69,15 @@ func NewConfig(user registration.User) *Config {
 type CertificateConfig struct {
 	KeyType certcrypto.KeyType
 	Timeout time.Duration
+
+	// ValidationPolling the polling strategy used while the CA validates the challenges.
+	// The zero fields are replaced by the default values.
+	ValidationPolling wait.Strategy
+
+	// FinalizationPolling the polling strategy used while the CA issues the certificate.
+	// Timeout is used when FinalizationPolling.Timeout is not set.
+	// The zero fields are replaced by the default values.
+	FinalizationPolling wait.Strategy
 }
 
 // createDefaultHTTPClient Creates an HTTP client with a reasonable timeout value
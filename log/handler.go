@@ -0,0 +1,146 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stdHandler writes the entries to Logger, in the historical format of lego:
+// `[LEVEL] [domain] message key=value ...`.
+type stdHandler struct {
+	level  Level
+	fields []Field
+}
+
+// NewStdHandler creates a handler writing the entries of at least the level to Logger.
+func NewStdHandler(level Level) Handler {
+	return &stdHandler{level: level}
+}
+
+func (h *stdHandler) Enabled(level Level) bool {
+	return level >= h.level
+}
+
+func (h *stdHandler) Handle(record Record) error {
+	var domain string
+	var others []string
+
+	for _, field := range concatFields(h.fields, record.Fields) {
+		if field.Key == KeyDomain && domain == "" {
+			domain = fmt.Sprint(field.Value)
+			continue
+		}
+
+		others = append(others, field.Key+"="+formatTextValue(field.Value))
+	}
+
+	b := &strings.Builder{}
+	b.WriteString("[" + record.Level.String() + "] ")
+
+	if domain != "" {
+		b.WriteString("[" + domain + "] ")
+	}
+
+	b.WriteString(record.Message)
+
+	for _, other := range others {
+		b.WriteString(" " + other)
+	}
+
+	Logger.Print(b.String())
+
+	return nil
+}
+
+func (h *stdHandler) WithFields(fields []Field) Handler {
+	return &stdHandler{level: h.level, fields: concatFields(h.fields, fields)}
+}
+
+func formatTextValue(value interface{}) string {
+	s := fmt.Sprint(value)
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
+	}
+
+	return s
+}
+
+// jsonHandler writes the entries as JSON objects, one per line.
+type jsonHandler struct {
+	level  Level
+	fields []Field
+
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONHandler creates a handler writing the entries of at least the level to w, as JSON objects (one per line).
+// The objects contain the time, level and msg keys, followed by the fields.
+func NewJSONHandler(w io.Writer, level Level) Handler {
+	return &jsonHandler{level: level, mu: &sync.Mutex{}, w: w}
+}
+
+func (h *jsonHandler) Enabled(level Level) bool {
+	return level >= h.level
+}
+
+func (h *jsonHandler) Handle(record Record) error {
+	buf := &bytes.Buffer{}
+
+	buf.WriteString(`{"time":`)
+	writeJSONValue(buf, record.Time.Format(time.RFC3339Nano))
+	buf.WriteString(`,"level":`)
+	writeJSONValue(buf, record.Level.String())
+	buf.WriteString(`,"msg":`)
+	writeJSONValue(buf, record.Message)
+
+	for _, field := range concatFields(h.fields, record.Fields) {
+		buf.WriteByte(',')
+		writeJSONValue(buf, field.Key)
+		buf.WriteByte(':')
+		writeJSONValue(buf, field.Value)
+	}
+
+	buf.WriteString("}\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	_, err := h.w.Write(buf.Bytes())
+
+	return err
+}
+
+func (h *jsonHandler) WithFields(fields []Field) Handler {
+	return &jsonHandler{level: h.level, fields: concatFields(h.fields, fields), mu: h.mu, w: h.w}
+}
+
+func writeJSONValue(buf *bytes.Buffer, value interface{}) {
+	switch v := value.(type) {
+	case error:
+		value = v.Error()
+	case fmt.Stringer:
+		value = v.String()
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		raw, _ = json.Marshal(fmt.Sprint(value))
+	}
+
+	buf.Write(raw)
+}
+
+// concatFields returns a new slice containing the fields of a and b.
+func concatFields(a, b []Field) []Field {
+	fields := make([]Field, 0, len(a)+len(b))
+	fields = append(fields, a...)
+
+	return append(fields, b...)
+}
@@ -1,11 +1,14 @@
 package log
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"strings"
 )
 
 // Logger is an optional custom logger.
+// It is used by the default handler (see NewStdHandler).
 var Logger StdLogger = log.New(os.Stderr, "", log.LstdFlags)
 
 // StdLogger interface for Standard Logger.
@@ -21,39 +24,84 @@ type StdLogger interface {
 // Fatal writes a log entry.
 // It uses Logger if not nil, otherwise it uses the default log.Logger.
 func Fatal(args ...interface{}) {
-	Logger.Fatal(args...)
+	if isStd() {
+		Logger.Fatal(args...)
+		return
+	}
+
+	Default().Error(fmt.Sprint(args...))
+	os.Exit(1)
 }
 
 // Fatalf writes a log entry.
 // It uses Logger if not nil, otherwise it uses the default log.Logger.
 func Fatalf(format string, args ...interface{}) {
-	Logger.Fatalf(format, args...)
+	if isStd() {
+		Logger.Fatalf(format, args...)
+		return
+	}
+
+	Default().Error(fmt.Sprintf(format, args...))
+	os.Exit(1)
 }
 
-// Print writes a log entry.
+// Print writes a log entry at the info level: it is discarded if the info level is disabled.
 // It uses Logger if not nil, otherwise it uses the default log.Logger.
 func Print(args ...interface{}) {
-	Logger.Print(args...)
+	if !Default().Enabled(LevelInfo) {
+		return
+	}
+
+	if isStd() {
+		Logger.Print(args...)
+		return
+	}
+
+	Default().Info(fmt.Sprint(args...))
 }
 
-// Println writes a log entry.
+// Println writes a log entry at the info level: it is discarded if the info level is disabled.
 // It uses Logger if not nil, otherwise it uses the default log.Logger.
 func Println(args ...interface{}) {
-	Logger.Println(args...)
+	if !Default().Enabled(LevelInfo) {
+		return
+	}
+
+	if isStd() {
+		Logger.Println(args...)
+		return
+	}
+
+	Default().Info(strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
 }
 
-// Printf writes a log entry.
+// Printf writes a log entry at the info level: it is discarded if the info level is disabled.
 // It uses Logger if not nil, otherwise it uses the default log.Logger.
 func Printf(format string, args ...interface{}) {
-	Logger.Printf(format, args...)
+	if !Default().Enabled(LevelInfo) {
+		return
+	}
+
+	if isStd() {
+		Logger.Printf(format, args...)
+		return
+	}
+
+	Default().Info(fmt.Sprintf(format, args...))
 }
 
 // Warnf writes a log entry.
 func Warnf(format string, args ...interface{}) {
-	Printf("[WARN] "+format, args...)
+	Default().Warn(fmt.Sprintf(format, args...))
 }
 
 // Infof writes a log entry.
 func Infof(format string, args ...interface{}) {
-	Printf("[INFO] "+format, args...)
+	Default().Info(fmt.Sprintf(format, args...))
+}
+
+// isStd returns true if the default FieldLogger writes to Logger.
+func isStd() bool {
+	_, ok := Default().Handler().(*stdHandler)
+	return ok
 }
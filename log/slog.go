@@ -0,0 +1,50 @@
+//go:build go1.21
+
+package log
+
+import (
+	"context"
+	"log/slog"
+)
+
+// slogHandler writes the entries to a log/slog handler.
+type slogHandler struct {
+	handler slog.Handler
+}
+
+// NewSlogHandler creates a handler writing the entries to a log/slog handler.
+//
+//	log.SetDefault(log.New(log.NewSlogHandler(slog.NewJSONHandler(os.Stderr, nil))))
+func NewSlogHandler(handler slog.Handler) Handler {
+	return &slogHandler{handler: handler}
+}
+
+func (h *slogHandler) Enabled(level Level) bool {
+	return h.handler.Enabled(context.Background(), slog.Level(level))
+}
+
+func (h *slogHandler) Handle(record Record) error {
+	r := slog.NewRecord(record.Time, slog.Level(record.Level), record.Message, 0)
+	r.AddAttrs(toAttrs(record.Fields)...)
+
+	return h.handler.Handle(context.Background(), r)
+}
+
+func (h *slogHandler) WithFields(fields []Field) Handler {
+	return &slogHandler{handler: h.handler.WithAttrs(toAttrs(fields))}
+}
+
+func toAttrs(fields []Field) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(fields))
+
+	for _, field := range fields {
+		value := field.Value
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+
+		attrs = append(attrs, slog.Any(field.Key, value))
+	}
+
+	return attrs
+}
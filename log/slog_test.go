@@ -0,0 +1,34 @@
+//go:build go1.21
+
+package log
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlogHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	handler := slog.NewTextHandler(buf, &slog.HandlerOptions{
+		Level: slog.LevelWarn,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+
+	logger := New(NewSlogHandler(handler)).With(Provider("cloudflare"))
+
+	assert.False(t, logger.Enabled(LevelInfo))
+
+	logger.Info("hidden")
+	logger.Warn("failed to delete TXT record", Domain("example.com"))
+
+	assert.Equal(t, "level=WARN msg=\"failed to delete TXT record\" provider=cloudflare domain=example.com\n", buf.String())
+}
@@ -0,0 +1,227 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level the importance of a log entry.
+// The values are the same as the levels of log/slog.
+type Level int
+
+const (
+	LevelDebug Level = -4
+	LevelInfo  Level = 0
+	LevelWarn  Level = 4
+	LevelError Level = 8
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	default:
+		return fmt.Sprintf("LEVEL(%d)", int(l))
+	}
+}
+
+// ParseLevel parses the name of a level (debug, info, warn, error), case-insensitively.
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return 0, fmt.Errorf("unknown log level: %q", name)
+	}
+}
+
+// The keys of the common fields.
+const (
+	KeyDomain    = "domain"
+	KeyOrder     = "order"
+	KeyChallenge = "challenge"
+	KeyProvider  = "provider"
+	KeyError     = "error"
+)
+
+// Field a key/value pair attached to a log entry.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Any creates a field.
+func Any(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Domain creates the field of a domain.
+func Domain(domain string) Field {
+	return Field{Key: KeyDomain, Value: domain}
+}
+
+// Order creates the field of an order URL.
+func Order(url string) Field {
+	return Field{Key: KeyOrder, Value: url}
+}
+
+// Challenge creates the field of a challenge type.
+func Challenge(chlgType string) Field {
+	return Field{Key: KeyChallenge, Value: chlgType}
+}
+
+// Provider creates the field of a challenge provider name.
+func Provider(name string) Field {
+	return Field{Key: KeyProvider, Value: name}
+}
+
+// Err creates the field of an error.
+func Err(err error) Field {
+	return Field{Key: KeyError, Value: err}
+}
+
+// Record a log entry.
+type Record struct {
+	Time    time.Time
+	Level   Level
+	Message string
+	Fields  []Field
+}
+
+// Handler writes the log entries.
+// It follows the design of the log/slog handlers.
+type Handler interface {
+	// Enabled reports whether the entries of the level are written.
+	Enabled(level Level) bool
+
+	// Handle writes the entry.
+	Handle(record Record) error
+
+	// WithFields returns a handler adding the fields to all the entries.
+	WithFields(fields []Field) Handler
+}
+
+// FieldLogger writes leveled log entries with key/value fields.
+type FieldLogger struct {
+	handler Handler
+}
+
+// New creates a new FieldLogger.
+func New(handler Handler) *FieldLogger {
+	return &FieldLogger{handler: handler}
+}
+
+// Handler returns the handler of the logger.
+func (l *FieldLogger) Handler() Handler {
+	return l.handler
+}
+
+// With returns a logger adding the fields to all the entries.
+func (l *FieldLogger) With(fields ...Field) *FieldLogger {
+	if len(fields) == 0 {
+		return l
+	}
+
+	return &FieldLogger{handler: l.handler.WithFields(fields)}
+}
+
+// Enabled reports whether the entries of the level are written.
+func (l *FieldLogger) Enabled(level Level) bool {
+	return l.handler.Enabled(level)
+}
+
+// Log writes an entry.
+func (l *FieldLogger) Log(level Level, msg string, fields ...Field) {
+	if !l.handler.Enabled(level) {
+		return
+	}
+
+	_ = l.handler.Handle(Record{Time: time.Now(), Level: level, Message: msg, Fields: fields})
+}
+
+// Debug writes an entry at LevelDebug.
+func (l *FieldLogger) Debug(msg string, fields ...Field) {
+	l.Log(LevelDebug, msg, fields...)
+}
+
+// Info writes an entry at LevelInfo.
+func (l *FieldLogger) Info(msg string, fields ...Field) {
+	l.Log(LevelInfo, msg, fields...)
+}
+
+// Warn writes an entry at LevelWarn.
+func (l *FieldLogger) Warn(msg string, fields ...Field) {
+	l.Log(LevelWarn, msg, fields...)
+}
+
+// Error writes an entry at LevelError.
+func (l *FieldLogger) Error(msg string, fields ...Field) {
+	l.Log(LevelError, msg, fields...)
+}
+
+var (
+	defaultLogger   = New(NewStdHandler(LevelInfo))
+	muDefaultLogger sync.RWMutex
+)
+
+// Default returns the default FieldLogger.
+// The default logger writes the entries to Logger, in the historical format of lego.
+func Default() *FieldLogger {
+	muDefaultLogger.RLock()
+	defer muDefaultLogger.RUnlock()
+
+	return defaultLogger
+}
+
+// SetDefault replaces the default FieldLogger.
+// It is also used by the functions of the package (Infof, Warnf, Printf, ...).
+func SetDefault(logger *FieldLogger) {
+	muDefaultLogger.Lock()
+	defaultLogger = logger
+	muDefaultLogger.Unlock()
+}
+
+// LoggerSetter is implemented by the components accepting a logger (i.e. challenge providers).
+type LoggerSetter interface {
+	SetLogger(logger *FieldLogger)
+}
+
+// ProviderLogger implements LoggerSetter for the challenge providers.
+// It is embedded in a provider, and adds the name of the provider to the entries.
+type ProviderLogger struct {
+	name   string
+	logger *FieldLogger
+}
+
+// NewProviderLogger creates a ProviderLogger for the provider name.
+func NewProviderLogger(name string) ProviderLogger {
+	return ProviderLogger{name: name}
+}
+
+// SetLogger sets the logger of the provider (see LoggerSetter).
+func (l *ProviderLogger) SetLogger(logger *FieldLogger) {
+	l.logger = logger.With(Provider(l.name))
+}
+
+// Logger returns the logger set by SetLogger, or the current default logger.
+func (l *ProviderLogger) Logger() *FieldLogger {
+	if l.logger != nil {
+		return l.logger
+	}
+
+	return Default().With(Provider(l.name))
+}
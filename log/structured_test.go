@@ -0,0 +1,160 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLevel(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected Level
+	}{
+		{name: "debug", expected: LevelDebug},
+		{name: "INFO", expected: LevelInfo},
+		{name: "warn", expected: LevelWarn},
+		{name: "warning", expected: LevelWarn},
+		{name: "Error", expected: LevelError},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			level, err := ParseLevel(test.name)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, level)
+		})
+	}
+
+	_, err := ParseLevel("verbose")
+	require.EqualError(t, err, `unknown log level: "verbose"`)
+}
+
+func TestStdHandler(t *testing.T) {
+	buf := setupStdLogger(t)
+
+	logger := New(NewStdHandler(LevelInfo)).With(Challenge("dns-01"))
+
+	logger.Debug("hidden")
+	logger.Info("acme: Trying to solve DNS-01", Domain("example.com"))
+	logger.Warn("acme: cleaning up failed", Domain("example.com"), Err(errors.New("oops: not found")))
+
+	expected := `[INFO] [example.com] acme: Trying to solve DNS-01 challenge=dns-01
+[WARN] [example.com] acme: cleaning up failed challenge=dns-01 error="oops: not found"
+`
+
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestJSONHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	handler := NewJSONHandler(buf, LevelWarn).WithFields([]Field{Provider("cloudflare")})
+
+	require.False(t, handler.Enabled(LevelInfo))
+	require.True(t, handler.Enabled(LevelError))
+
+	err := handler.Handle(Record{
+		Time:    time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Level:   LevelWarn,
+		Message: `failed to delete "TXT" record`,
+		Fields:  []Field{Domain("example.com"), Err(errors.New("oops")), Any("ttl", 120), Any("wait", time.Minute)},
+	})
+	require.NoError(t, err)
+
+	expected := `{"time":"2024-01-01T00:00:00Z","level":"WARN","msg":"failed to delete \"TXT\" record","provider":"cloudflare","domain":"example.com","error":"oops","ttl":120,"wait":"1m0s"}
+`
+
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestDefault(t *testing.T) {
+	buf := setupStdLogger(t)
+
+	Infof("[%s] acme: legacy", "example.com")
+	Printf("raw %d", 1)
+
+	assert.Equal(t, "[INFO] [example.com] acme: legacy\nraw 1\n", buf.String())
+
+	jsonBuf := &bytes.Buffer{}
+	SetDefault(New(NewJSONHandler(jsonBuf, LevelInfo)))
+
+	Warnf("oops %d", 1)
+	Printf("raw %d", 2)
+
+	assert.Contains(t, jsonBuf.String(), `"level":"WARN","msg":"oops 1"}`)
+	assert.Contains(t, jsonBuf.String(), `"level":"INFO","msg":"raw 2"}`)
+
+	// the legacy logger is not used.
+	assert.Equal(t, "[INFO] [example.com] acme: legacy\nraw 1\n", buf.String())
+}
+
+func TestDefault_level(t *testing.T) {
+	buf := setupStdLogger(t)
+
+	SetDefault(New(NewStdHandler(LevelWarn)))
+
+	Printf("raw %d", 1)
+	Println("raw", 2)
+	Print("raw 3")
+	Warnf("oops %d", 1)
+
+	assert.Equal(t, "[WARN] oops 1\n", buf.String())
+
+	jsonBuf := &bytes.Buffer{}
+	SetDefault(New(NewJSONHandler(jsonBuf, LevelError)))
+
+	Printf("raw %d", 4)
+
+	assert.Empty(t, jsonBuf.String())
+}
+
+func TestProviderLogger(t *testing.T) {
+	previousDefault := Default()
+	t.Cleanup(func() { SetDefault(previousDefault) })
+
+	var _ LoggerSetter = &ProviderLogger{}
+
+	l := NewProviderLogger("foo")
+
+	// the default logger is resolved when used, not when the provider is created.
+	defaultBuf := &bytes.Buffer{}
+	SetDefault(New(NewJSONHandler(defaultBuf, LevelInfo)))
+
+	l.Logger().Info("default")
+	assert.Contains(t, defaultBuf.String(), `"msg":"default","provider":"foo"}`)
+
+	buf := &bytes.Buffer{}
+	l.SetLogger(New(NewJSONHandler(buf, LevelInfo)))
+
+	l.Logger().Info("custom")
+	assert.Contains(t, buf.String(), `"msg":"custom","provider":"foo"}`)
+	assert.NotContains(t, defaultBuf.String(), "custom")
+}
+
+// setupStdLogger replaces Logger and the default FieldLogger during the test.
+func setupStdLogger(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+
+	previousLogger, previousDefault := Logger, Default()
+	t.Cleanup(func() {
+		Logger = previousLogger
+		SetDefault(previousDefault)
+	})
+
+	Logger = log.New(buf, "", 0)
+	SetDefault(New(NewStdHandler(LevelInfo)))
+
+	return buf
+}
@@ -29,6 +29,13 @@ func SetupFakeAPI(t *testing.T) (*http.ServeMux, string) {
 			NewOrderURL:   server.URL + "/newOrder",
 			RevokeCertURL: server.URL + "/revokeCert",
 			KeyChangeURL:  server.URL + "/keyChange",
+			RenewalInfo:   server.URL + "/renewalInfo",
+			Meta: acme.Meta{
+				Profiles: map[string]string{
+					"classic":    "The same profile you're accustomed to",
+					"shortlived": "A short-lived certificate profile",
+				},
+			},
 		})
 
 		mux.HandleFunc("/nonce", func(w http.ResponseWriter, r *http.Request) {
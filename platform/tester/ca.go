@@ -0,0 +1,105 @@
+package tester
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/LukasDeco/lego/v4/certcrypto"
+	"github.com/stretchr/testify/require"
+)
+
+// CA a self-signed certificate authority issuing test certificates, CRLs, and OCSP responses.
+type CA struct {
+	Key  crypto.Signer
+	Cert *x509.Certificate
+}
+
+// NewCA Creates a self-signed CA.
+func NewCA(t *testing.T, name string) *CA {
+	t.Helper()
+
+	return newCA(t, name, nil)
+}
+
+// NewIntermediate creates an intermediate CA issued by the CA.
+func (ca *CA) NewIntermediate(t *testing.T, name string) *CA {
+	t.Helper()
+
+	return newCA(t, name, ca)
+}
+
+// newCA creates a CA issued by parent, or self-signed if parent is nil.
+func newCA(t *testing.T, name string, parent *CA) *CA {
+	t.Helper()
+
+	key, err := certcrypto.GeneratePrivateKey(certcrypto.EC256)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+	}
+
+	parentCert, parentKey := template, key.(crypto.Signer)
+	if parent != nil {
+		parentCert, parentKey = parent.Cert, parent.Key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, key.(crypto.Signer).Public(), parentKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &CA{Key: key.(crypto.Signer), Cert: cert}
+}
+
+// PEM returns the PEM encoded certificate of the CA.
+func (ca *CA) PEM() []byte {
+	return certcrypto.PEMEncode(certcrypto.DERCertificateBytes(ca.Cert.Raw))
+}
+
+// Issue issues a certificate from the template, with a new key.
+// The validity period defaults to one hour in the past to 24 hours in the future.
+// It returns the certificate and its PEM encoding.
+func (ca *CA) Issue(t *testing.T, template *x509.Certificate) (*x509.Certificate, []byte) {
+	t.Helper()
+
+	key, err := certcrypto.GeneratePrivateKey(certcrypto.EC256)
+	require.NoError(t, err)
+
+	return ca.IssueForKey(t, template, key.(crypto.Signer).Public())
+}
+
+// IssueForKey issues a certificate from the template, for the public key.
+// The validity period defaults to one hour in the past to 24 hours in the future.
+// It returns the certificate and its PEM encoding.
+func (ca *CA) IssueForKey(t *testing.T, template *x509.Certificate, pub crypto.PublicKey) (*x509.Certificate, []byte) {
+	t.Helper()
+
+	if template.NotBefore.IsZero() {
+		template.NotBefore = time.Now().Add(-time.Hour)
+	}
+
+	if template.NotAfter.IsZero() {
+		template.NotAfter = time.Now().Add(24 * time.Hour)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Cert, pub, ca.Key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert, certcrypto.PEMEncode(certcrypto.DERCertificateBytes(der))
+}
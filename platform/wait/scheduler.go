@@ -0,0 +1,151 @@
+package wait
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+)
+
+const (
+	schedulerInitialInterval = time.Minute
+	schedulerMaxInterval     = time.Hour
+)
+
+// Task a background task of a Scheduler.
+// It returns the delay before its next run.
+type Task func() (time.Duration, error)
+
+// Scheduler runs background tasks, identified by a key (e.g. the renewal of the certificate of a domain).
+// A failed task is retried with an exponential backoff (from one minute to one hour), until it succeeds.
+type Scheduler struct {
+	initialInterval time.Duration
+	maxInterval     time.Duration
+
+	mu     sync.Mutex
+	tasks  map[string]*scheduledTask
+	closed bool
+}
+
+// scheduledTask the next run of a task.
+type scheduledTask struct {
+	task    Task
+	timer   *time.Timer
+	retries *backoff.ExponentialBackOff
+}
+
+// NewScheduler creates a new Scheduler.
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		initialInterval: schedulerInitialInterval,
+		maxInterval:     schedulerMaxInterval,
+		tasks:           map[string]*scheduledTask{},
+	}
+}
+
+// Schedule runs the task after the delay, and replaces the task previously scheduled for the key.
+// A negative delay runs the task immediately.
+func (s *Scheduler) Schedule(key string, delay time.Duration, task Task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.schedule(key, delay, task, nil)
+}
+
+// Retry runs the task after the first backoff interval,
+// and replaces the task previously scheduled for the key.
+// It's intended for a task whose first attempt failed outside the Scheduler.
+func (s *Scheduler) Retry(key string, task Task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	retries := s.newRetries()
+
+	s.schedule(key, retries.NextBackOff(), task, retries)
+}
+
+// Scheduled returns true if a task is scheduled for the key,
+// and retrying is true if the task is retried after a failure.
+func (s *Scheduler) Scheduled(key string) (scheduled, retrying bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tasks[key]
+	if !ok {
+		return false, false
+	}
+
+	return true, t.retries != nil
+}
+
+// Close stops the scheduled tasks.
+// The tasks in progress are not interrupted, but they are not scheduled again.
+func (s *Scheduler) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+
+	for key, t := range s.tasks {
+		t.timer.Stop()
+		delete(s.tasks, key)
+	}
+}
+
+// schedule schedules the next run of the task.
+// When retries is not nil, the previous run failed.
+// Must be called with the lock held.
+func (s *Scheduler) schedule(key string, delay time.Duration, task Task, retries *backoff.ExponentialBackOff) {
+	if s.closed {
+		return
+	}
+
+	if t, ok := s.tasks[key]; ok {
+		t.timer.Stop()
+	}
+
+	if delay < 0 {
+		delay = 0
+	}
+
+	t := &scheduledTask{task: task, retries: retries}
+	t.timer = time.AfterFunc(delay, func() { s.run(key, t) })
+
+	s.tasks[key] = t
+}
+
+// run runs the task, and schedules its next run.
+func (s *Scheduler) run(key string, t *scheduledTask) {
+	delay, err := t.task()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The task has been canceled or replaced in the meantime.
+	if s.tasks[key] != t {
+		return
+	}
+
+	if err != nil {
+		retries := t.retries
+		if retries == nil {
+			retries = s.newRetries()
+		}
+
+		s.schedule(key, retries.NextBackOff(), t.task, retries)
+
+		return
+	}
+
+	s.schedule(key, delay, t.task, nil)
+}
+
+func (s *Scheduler) newRetries() *backoff.ExponentialBackOff {
+	bo := backoff.NewExponentialBackOff()
+	bo.InitialInterval = s.initialInterval
+	bo.MaxInterval = s.maxInterval
+	bo.MaxElapsedTime = 0 // retry forever
+	bo.Reset()
+
+	return bo
+}
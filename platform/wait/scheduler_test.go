@@ -0,0 +1,140 @@
+package wait
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestScheduler(t *testing.T) *Scheduler {
+	t.Helper()
+
+	s := NewScheduler()
+	s.initialInterval = 10 * time.Millisecond
+	s.maxInterval = 10 * time.Millisecond
+
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func TestScheduler_Schedule(t *testing.T) {
+	s := newTestScheduler(t)
+
+	var runs int32
+	s.Schedule("foo", 0, func() (time.Duration, error) {
+		if atomic.AddInt32(&runs, 1) == 1 {
+			return 0, nil
+		}
+
+		return time.Hour, nil
+	})
+
+	// the task is run again after the returned delay.
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&runs) == 2 }, time.Second, 5*time.Millisecond)
+
+	scheduled, retrying := s.Scheduled("foo")
+	assert.True(t, scheduled)
+	assert.False(t, retrying)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&runs))
+}
+
+func TestScheduler_Schedule_error(t *testing.T) {
+	s := newTestScheduler(t)
+
+	var runs int32
+	s.Schedule("foo", 0, func() (time.Duration, error) {
+		if atomic.AddInt32(&runs, 1) < 3 {
+			return 0, errors.New("oops")
+		}
+
+		return time.Hour, nil
+	})
+
+	// the failed task is retried, until it succeeds.
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&runs) == 3 }, time.Second, 5*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		scheduled, retrying := s.Scheduled("foo")
+		return scheduled && !retrying
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestScheduler_Retry(t *testing.T) {
+	s := NewScheduler()
+	t.Cleanup(s.Close)
+
+	s.Retry("foo", func() (time.Duration, error) {
+		t.Error("the task must not run before the first backoff interval")
+		return 0, nil
+	})
+
+	scheduled, retrying := s.Scheduled("foo")
+	assert.True(t, scheduled)
+	assert.True(t, retrying)
+
+	scheduled, _ = s.Scheduled("bar")
+	assert.False(t, scheduled)
+}
+
+func TestScheduler_replaced(t *testing.T) {
+	s := newTestScheduler(t)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	s.Schedule("foo", 0, func() (time.Duration, error) {
+		close(started)
+		<-release
+
+		return 0, errors.New("oops")
+	})
+
+	<-started
+
+	// the task is replaced while it's running.
+	var replaced int32
+	s.Schedule("foo", time.Hour, func() (time.Duration, error) {
+		atomic.AddInt32(&replaced, 1)
+		return time.Hour, nil
+	})
+
+	close(release)
+
+	time.Sleep(50 * time.Millisecond)
+
+	// the failed task is not retried.
+	scheduled, retrying := s.Scheduled("foo")
+	assert.True(t, scheduled)
+	assert.False(t, retrying)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&replaced))
+}
+
+func TestScheduler_Close(t *testing.T) {
+	s := newTestScheduler(t)
+
+	var runs int32
+	s.Schedule("foo", 20*time.Millisecond, func() (time.Duration, error) {
+		atomic.AddInt32(&runs, 1)
+		return 0, nil
+	})
+
+	s.Close()
+
+	scheduled, _ := s.Scheduled("foo")
+	assert.False(t, scheduled)
+
+	// no tasks are scheduled after Close.
+	s.Schedule("bar", 0, func() (time.Duration, error) {
+		atomic.AddInt32(&runs, 1)
+		return 0, nil
+	})
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&runs))
+}
@@ -0,0 +1,182 @@
+package wait
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/LukasDeco/lego/v4/log"
+)
+
+// Strategy a polling strategy: an exponential backoff with caps,
+// which honours the delays requested by the server (i.e. the Retry-After header).
+type Strategy struct {
+	// Timeout the maximum duration of the polling.
+	Timeout time.Duration
+
+	// InitialInterval the delay between the first two attempts.
+	InitialInterval time.Duration
+
+	// MaxInterval caps the delay between two attempts.
+	// It doesn't apply to the delays requested by the server, only the Timeout does.
+	MaxInterval time.Duration
+
+	// Multiplier the factor applied to the delay after each attempt.
+	Multiplier float64
+}
+
+// WithDefaults returns a copy of the strategy where the zero fields are replaced by the values from defaults.
+func (s Strategy) WithDefaults(defaults Strategy) Strategy {
+	if s.Timeout <= 0 {
+		s.Timeout = defaults.Timeout
+	}
+
+	if s.InitialInterval <= 0 {
+		s.InitialInterval = defaults.InitialInterval
+	}
+
+	if s.MaxInterval <= 0 {
+		s.MaxInterval = defaults.MaxInterval
+	}
+
+	if s.Multiplier < 1 {
+		s.Multiplier = defaults.Multiplier
+	}
+
+	return s
+}
+
+// Poll calls f until it returns true or a permanent error (see Permanent), the timeout is reached, or the context is canceled.
+// The other errors are retried: the last one is kept in the TimeoutError.
+//
+// The delay before the next attempt is the one returned by f (e.g. from a Retry-After header) if it's positive,
+// otherwise the delay grows exponentially from InitialInterval up to MaxInterval.
+// The delays are always bounded by the remaining time before the timeout.
+//
+// The phase identifies the polling in the logs and in the TimeoutError.
+func (s Strategy) Poll(ctx context.Context, phase string, f func() (done bool, retryAfter time.Duration, err error)) error {
+	log.Infof("Wait for %s [timeout: %s, interval: %s-%s]", phase, s.Timeout, s.InitialInterval, s.MaxInterval)
+
+	deadline := time.Now().Add(s.Timeout)
+	interval := s.InitialInterval
+
+	var lastErr error
+
+	for {
+		done, retryAfter, err := f()
+		if err != nil {
+			var permanent *permanentError
+			if errors.As(err, &permanent) {
+				return permanent.err
+			}
+
+			log.Infof("%s: retrying after an error: %v", phase, err)
+			lastErr = err
+		} else if done {
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return &TimeoutError{Phase: phase, Timeout: s.Timeout, LastErr: lastErr}
+		}
+
+		delay := interval
+		if retryAfter > 0 {
+			delay = retryAfter
+		}
+
+		if delay > remaining {
+			delay = remaining
+		}
+
+		interval = s.nextInterval(interval)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+
+			if lastErr != nil {
+				return fmt.Errorf("%s: %w: last error: %v", phase, ctx.Err(), lastErr)
+			}
+
+			return fmt.Errorf("%s: %w", phase, ctx.Err())
+		}
+	}
+}
+
+func (s Strategy) nextInterval(interval time.Duration) time.Duration {
+	if s.Multiplier > 1 {
+		interval = time.Duration(float64(interval) * s.Multiplier)
+	}
+
+	if s.MaxInterval > 0 && interval > s.MaxInterval {
+		return s.MaxInterval
+	}
+
+	return interval
+}
+
+// TimeoutError is returned by Strategy.Poll when the timeout is reached.
+type TimeoutError struct {
+	// Phase the polling phase (e.g. "validation", "finalization").
+	Phase   string
+	Timeout time.Duration
+
+	// LastErr the last error returned by the polled function, if any.
+	LastErr error
+}
+
+func (e *TimeoutError) Error() string {
+	if e.LastErr != nil {
+		return fmt.Sprintf("%s: time limit exceeded (%s): last error: %v", e.Phase, e.Timeout, e.LastErr)
+	}
+
+	return fmt.Sprintf("%s: time limit exceeded (%s)", e.Phase, e.Timeout)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.LastErr
+}
+
+// Permanent wraps an error to stop Strategy.Poll: the error is returned without retrying.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &permanentError{err: err}
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// ParseRetryAfter parses the value of a Retry-After header,
+// which can be either a number of seconds or an HTTP date.
+// - https://www.rfc-editor.org/rfc/rfc9110.html#section-10.2.3
+func ParseRetryAfter(value string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Retry-After value %q: %w", value, err)
+	}
+
+	return time.Until(date), nil
+}
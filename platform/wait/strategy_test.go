@@ -0,0 +1,141 @@
+package wait
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrategy_Poll(t *testing.T) {
+	strategy := Strategy{Timeout: time.Second, InitialInterval: time.Millisecond, MaxInterval: 5 * time.Millisecond, Multiplier: 2}
+
+	var calls int
+	err := strategy.Poll(context.Background(), "test", func() (bool, time.Duration, error) {
+		calls++
+		return calls == 5, 0, nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 5, calls)
+}
+
+func TestStrategy_Poll_error(t *testing.T) {
+	strategy := Strategy{Timeout: time.Second, InitialInterval: time.Millisecond}
+
+	var calls int
+	err := strategy.Poll(context.Background(), "test", func() (bool, time.Duration, error) {
+		calls++
+		if calls < 3 {
+			return false, 0, errors.New("oops")
+		}
+
+		return true, 0, nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, calls)
+}
+
+func TestStrategy_Poll_permanentError(t *testing.T) {
+	strategy := Strategy{Timeout: time.Second, InitialInterval: time.Millisecond}
+
+	var calls int
+	err := strategy.Poll(context.Background(), "test", func() (bool, time.Duration, error) {
+		calls++
+		return false, 0, Permanent(errors.New("oops"))
+	})
+	require.EqualError(t, err, "oops")
+
+	assert.Equal(t, 1, calls)
+}
+
+func TestStrategy_Poll_timeoutLastError(t *testing.T) {
+	strategy := Strategy{Timeout: 50 * time.Millisecond, InitialInterval: 10 * time.Millisecond}
+
+	oops := errors.New("oops")
+
+	err := strategy.Poll(context.Background(), "finalization", func() (bool, time.Duration, error) {
+		return false, 0, oops
+	})
+	require.EqualError(t, err, "finalization: time limit exceeded (50ms): last error: oops")
+
+	assert.ErrorIs(t, err, oops)
+}
+
+func TestStrategy_Poll_timeout(t *testing.T) {
+	strategy := Strategy{Timeout: 100 * time.Millisecond, InitialInterval: 10 * time.Millisecond}
+
+	err := strategy.Poll(context.Background(), "finalization", func() (bool, time.Duration, error) {
+		return false, 0, nil
+	})
+	require.EqualError(t, err, "finalization: time limit exceeded (100ms)")
+
+	var timeoutErr *TimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	assert.Equal(t, "finalization", timeoutErr.Phase)
+}
+
+func TestStrategy_Poll_retryAfter(t *testing.T) {
+	strategy := Strategy{Timeout: 5 * time.Second, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond}
+
+	var calls []time.Time
+	err := strategy.Poll(context.Background(), "test", func() (bool, time.Duration, error) {
+		calls = append(calls, time.Now())
+		return len(calls) == 2, 200 * time.Millisecond, nil
+	})
+	require.NoError(t, err)
+
+	require.Len(t, calls, 2)
+	assert.GreaterOrEqual(t, calls[1].Sub(calls[0]), 200*time.Millisecond)
+}
+
+func TestStrategy_Poll_canceled(t *testing.T) {
+	strategy := Strategy{Timeout: 5 * time.Second, InitialInterval: time.Second}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err := strategy.Poll(ctx, "validation", func() (bool, time.Duration, error) {
+		cancel()
+		return false, 0, nil
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	assert.EqualError(t, err, "validation: context canceled")
+}
+
+func TestStrategy_nextInterval(t *testing.T) {
+	strategy := Strategy{InitialInterval: time.Second, MaxInterval: 3 * time.Second, Multiplier: 2}
+
+	assert.Equal(t, 2*time.Second, strategy.nextInterval(time.Second))
+	assert.Equal(t, 3*time.Second, strategy.nextInterval(2*time.Second))
+	assert.Equal(t, 3*time.Second, strategy.nextInterval(3*time.Second))
+}
+
+func TestStrategy_WithDefaults(t *testing.T) {
+	defaults := Strategy{Timeout: time.Minute, InitialInterval: time.Second, MaxInterval: 10 * time.Second, Multiplier: 1.5}
+
+	strategy := Strategy{Timeout: time.Hour}.WithDefaults(defaults)
+
+	expected := Strategy{Timeout: time.Hour, InitialInterval: time.Second, MaxInterval: 10 * time.Second, Multiplier: 1.5}
+	assert.Equal(t, expected, strategy)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	delay, err := ParseRetryAfter("120")
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Minute, delay)
+
+	delay, err = ParseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	require.NoError(t, err)
+	assert.InDelta(t, time.Hour.Seconds(), delay.Seconds(), 2)
+
+	_, err = ParseRetryAfter("foo")
+	require.Error(t, err)
+
+	_, err = ParseRetryAfter("")
+	require.Error(t, err)
+}
@@ -1,6 +1,7 @@
 package wait
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
@@ -10,6 +11,12 @@ import (
 
 // For polls the given function 'f', once every 'interval', up to 'timeout'.
 func For(msg string, timeout, interval time.Duration, f func() (bool, error)) error {
+	return ForContext(context.Background(), msg, timeout, interval, f)
+}
+
+// ForContext polls the given function 'f', once every 'interval', up to 'timeout',
+// or until the context is canceled.
+func ForContext(ctx context.Context, msg string, timeout, interval time.Duration, f func() (bool, error)) error {
 	log.Infof("Wait for %s [timeout: %s, interval: %s]", msg, timeout, interval)
 
 	var lastErr error
@@ -21,6 +28,11 @@ func For(msg string, timeout, interval time.Duration, f func() (bool, error)) er
 				return errors.New("time limit exceeded")
 			}
 			return fmt.Errorf("time limit exceeded: last error: %w", lastErr)
+		case <-ctx.Done():
+			if lastErr == nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("%w: last error: %v", ctx.Err(), lastErr)
 		default:
 		}
 
@@ -32,6 +44,9 @@ func For(msg string, timeout, interval time.Duration, f func() (bool, error)) er
 			lastErr = err
 		}
 
-		time.Sleep(interval)
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+		}
 	}
 }
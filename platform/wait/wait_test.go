@@ -1,6 +1,8 @@
 package wait
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 )
@@ -24,3 +26,26 @@ func TestForTimeout(t *testing.T) {
 		t.Logf("%v", err)
 	}
 }
+
+func TestForContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	c := make(chan error)
+	go func() {
+		c <- ForContext(ctx, "", 30*time.Second, 1*time.Second, func() (bool, error) {
+			return false, nil
+		})
+	}()
+
+	cancel()
+
+	timeout := time.After(3 * time.Second)
+	select {
+	case <-timeout:
+		t.Fatal("timeout exceeded")
+	case err := <-c:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context canceled error; got %v", err)
+		}
+	}
+}
@@ -52,6 +52,8 @@ type DNSProvider struct {
 
 	recordIDs   map[string]string
 	recordIDsMu sync.Mutex
+
+	log.ProviderLogger
 }
 
 // NewDNSProvider returns a DNSProvider instance configured for Cloudflare.
@@ -111,6 +113,8 @@ func NewDNSProviderConfig(config *Config) (*DNSProvider, error) {
 		client:    client,
 		config:    config,
 		recordIDs: make(map[string]string),
+
+		ProviderLogger: log.NewProviderLogger("cloudflare"),
 	}, nil
 }
 
@@ -154,7 +158,7 @@ func (d *DNSProvider) Present(domain, token, keyAuth string) error {
 	d.recordIDs[token] = response.Result.ID
 	d.recordIDsMu.Unlock()
 
-	log.Infof("cloudflare: new record for %s, ID %s", domain, response.Result.ID)
+	d.Logger().Info("cloudflare: new record, ID "+response.Result.ID, log.Domain(domain))
 
 	return nil
 }
@@ -183,7 +187,7 @@ func (d *DNSProvider) CleanUp(domain, token, keyAuth string) error {
 
 	err = d.client.DeleteDNSRecord(context.Background(), zoneID, recordID)
 	if err != nil {
-		log.Printf("cloudflare: failed to delete TXT record: %w", err)
+		d.Logger().Warn("cloudflare: failed to delete TXT record", log.Domain(domain), log.Err(err))
 	}
 
 	// Delete record ID from map
@@ -1,9 +1,11 @@
 package cloudflare
 
 import (
+	"bytes"
 	"testing"
 	"time"
 
+	"github.com/LukasDeco/lego/v4/log"
 	"github.com/LukasDeco/lego/v4/platform/tester"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -265,6 +267,31 @@ func TestNewDNSProviderConfig(t *testing.T) {
 	}
 }
 
+func TestDNSProvider_Logger(t *testing.T) {
+	config := NewDefaultConfig()
+	config.AuthToken = "012345abcdef"
+
+	p, err := NewDNSProviderConfig(config)
+	require.NoError(t, err)
+
+	previousDefault := log.Default()
+	t.Cleanup(func() { log.SetDefault(previousDefault) })
+
+	// the default logger is resolved when used, not when the provider is created.
+	defaultBuf := &bytes.Buffer{}
+	log.SetDefault(log.New(log.NewJSONHandler(defaultBuf, log.LevelInfo)))
+
+	p.Logger().Info("default")
+	assert.Contains(t, defaultBuf.String(), `"provider":"cloudflare"`)
+
+	buf := &bytes.Buffer{}
+	p.SetLogger(log.New(log.NewJSONHandler(buf, log.LevelInfo)))
+
+	p.Logger().Info("custom")
+	assert.Contains(t, buf.String(), `"msg":"custom"`)
+	assert.NotContains(t, defaultBuf.String(), "custom")
+}
+
 func TestLivePresent(t *testing.T) {
 	if !envTest.IsLiveTest() {
 		t.Skip("skipping live test")
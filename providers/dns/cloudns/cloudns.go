@@ -55,6 +55,8 @@ func NewDefaultConfig() *Config {
 type DNSProvider struct {
 	config *Config
 	client *internal.Client
+
+	log.ProviderLogger
 }
 
 // NewDNSProvider returns a DNSProvider instance configured for ClouDNS.
@@ -97,7 +99,7 @@ func NewDNSProviderConfig(config *Config) (*DNSProvider, error) {
 
 	client.HTTPClient = config.HTTPClient
 
-	return &DNSProvider{client: client, config: config}, nil
+	return &DNSProvider{client: client, config: config, ProviderLogger: log.NewProviderLogger("cloudns")}, nil
 }
 
 // Present creates a TXT record to fulfill the dns-01 challenge.
@@ -160,7 +162,7 @@ func (d *DNSProvider) waitNameservers(domain string, zone *internal.Zone) error
 			return false, err
 		}
 
-		log.Infof("[%s] Sync %d/%d complete", domain, syncProgress.Updated, syncProgress.Total)
+		d.Logger().Info(fmt.Sprintf("Sync %d/%d complete", syncProgress.Updated, syncProgress.Total), log.Domain(domain))
 
 		return syncProgress.Complete, nil
 	})
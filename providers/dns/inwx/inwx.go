@@ -53,6 +53,8 @@ func NewDefaultConfig() *Config {
 type DNSProvider struct {
 	config *Config
 	client *goinwx.Client
+
+	log.ProviderLogger
 }
 
 // NewDNSProvider returns a DNSProvider instance configured for Dyn DNS.
@@ -88,7 +90,7 @@ func NewDNSProviderConfig(config *Config) (*DNSProvider, error) {
 
 	client := goinwx.NewClient(config.Username, config.Password, &goinwx.ClientOptions{Sandbox: config.Sandbox})
 
-	return &DNSProvider{config: config, client: client}, nil
+	return &DNSProvider{config: config, client: client, ProviderLogger: log.NewProviderLogger("inwx")}, nil
 }
 
 // Present creates a TXT record using the specified parameters.
@@ -108,7 +110,7 @@ func (d *DNSProvider) Present(domain, token, keyAuth string) error {
 	defer func() {
 		errL := d.client.Account.Logout()
 		if errL != nil {
-			log.Infof("inwx: failed to logout: %v", errL)
+			d.Logger().Info("inwx: failed to logout", log.Domain(domain), log.Err(errL))
 		}
 	}()
 
@@ -158,7 +160,7 @@ func (d *DNSProvider) CleanUp(domain, token, keyAuth string) error {
 	defer func() {
 		errL := d.client.Account.Logout()
 		if errL != nil {
-			log.Infof("inwx: failed to logout: %v", errL)
+			d.Logger().Info("inwx: failed to logout", log.Domain(domain), log.Err(errL))
 		}
 	}()
 
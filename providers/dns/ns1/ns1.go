@@ -51,6 +51,8 @@ func NewDefaultConfig() *Config {
 type DNSProvider struct {
 	client *rest.Client
 	config *Config
+
+	log.ProviderLogger
 }
 
 // NewDNSProvider returns a DNSProvider instance configured for NS1.
@@ -79,7 +81,7 @@ func NewDNSProviderConfig(config *Config) (*DNSProvider, error) {
 
 	client := rest.NewClient(config.HTTPClient, rest.SetAPIKey(config.APIKey))
 
-	return &DNSProvider{client: client, config: config}, nil
+	return &DNSProvider{client: client, config: config, ProviderLogger: log.NewProviderLogger("ns1")}, nil
 }
 
 // Present creates a TXT record to fulfill the dns-01 challenge.
@@ -95,7 +97,7 @@ func (d *DNSProvider) Present(domain, token, keyAuth string) error {
 
 	// Create a new record
 	if errors.Is(err, rest.ErrRecordMissing) || record == nil {
-		log.Infof("Create a new record for [zone: %s, fqdn: %s, domain: %s]", zone.Zone, info.EffectiveFQDN, domain)
+		d.Logger().Info("Create a new record", log.Domain(domain), log.Any("zone", zone.Zone), log.Any("fqdn", info.EffectiveFQDN))
 
 		record = dns.NewRecord(zone.Zone, dns01.UnFqdn(info.EffectiveFQDN), "TXT")
 		record.TTL = d.config.TTL
@@ -116,7 +118,7 @@ func (d *DNSProvider) Present(domain, token, keyAuth string) error {
 	// Update the existing records
 	record.Answers = append(record.Answers, &dns.Answer{Rdata: []string{info.Value}})
 
-	log.Infof("Update an existing record for [zone: %s, fqdn: %s, domain: %s]", zone.Zone, info.EffectiveFQDN, domain)
+	d.Logger().Info("Update an existing record", log.Domain(domain), log.Any("zone", zone.Zone), log.Any("fqdn", info.EffectiveFQDN))
 
 	_, err = d.client.Records.Update(record)
 	if err != nil {
@@ -57,6 +57,8 @@ func NewDefaultConfig() *Config {
 type DNSProvider struct {
 	apiVersion int
 	config     *Config
+
+	log.ProviderLogger
 }
 
 // NewDNSProvider returns a DNSProvider instance configured for pdns.
@@ -94,11 +96,11 @@ func NewDNSProviderConfig(config *Config) (*DNSProvider, error) {
 		return nil, errors.New("pdns: API URL missing")
 	}
 
-	d := &DNSProvider{config: config}
+	d := &DNSProvider{config: config, ProviderLogger: log.NewProviderLogger("pdns")}
 
 	apiVersion, err := d.getAPIVersion()
 	if err != nil {
-		log.Warnf("pdns: failed to get API version %v", err)
+		d.Logger().Warn("pdns: failed to get API version", log.Err(err))
 	}
 	d.apiVersion = apiVersion
 
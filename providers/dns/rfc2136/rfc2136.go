@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/LukasDeco/lego/v4/challenge"
 	"github.com/LukasDeco/lego/v4/challenge/dns01"
 	"github.com/LukasDeco/lego/v4/platform/config/env"
 	"github.com/miekg/dns"
@@ -54,7 +55,7 @@ func NewDefaultConfig() *Config {
 	}
 }
 
-// DNSProvider implements the challenge.Provider interface.
+// DNSProvider implements the challenge.Provider and challenge.BatchProvider interfaces.
 type DNSProvider struct {
 	config *Config
 }
@@ -119,7 +120,8 @@ func (d *DNSProvider) Timeout() (timeout, interval time.Duration) {
 	return d.config.PropagationTimeout, d.config.PollingInterval
 }
 
-// Sequential All DNS challenges for this provider will be resolved sequentially.
+// Sequential All DNS challenges for this provider will be resolved sequentially,
+// unless they are presented together (see PresentBatch).
 // Returns the interval between each iteration.
 func (d *DNSProvider) Sequential() time.Duration {
 	return d.config.SequenceInterval
@@ -127,8 +129,15 @@ func (d *DNSProvider) Sequential() time.Duration {
 
 // Present creates a TXT record using the specified parameters.
 func (d *DNSProvider) Present(domain, token, keyAuth string) error {
-	info := dns01.GetChallengeInfo(domain, keyAuth)
+	return d.present(dns01.GetChallengeInfo(domain, keyAuth))
+}
+
+// PresentAccount creates the TXT record of the dns-account-01 challenge.
+func (d *DNSProvider) PresentAccount(domain, token, keyAuth, accountURL string) error {
+	return d.present(dns01.GetAccountChallengeInfo(domain, accountURL, keyAuth))
+}
 
+func (d *DNSProvider) present(info dns01.ChallengeInfo) error {
 	err := d.changeRecord("INSERT", info.EffectiveFQDN, info.Value, d.config.TTL)
 	if err != nil {
 		return fmt.Errorf("rfc2136: failed to insert: %w", err)
@@ -138,8 +147,15 @@ func (d *DNSProvider) Present(domain, token, keyAuth string) error {
 
 // CleanUp removes the TXT record matching the specified parameters.
 func (d *DNSProvider) CleanUp(domain, token, keyAuth string) error {
-	info := dns01.GetChallengeInfo(domain, keyAuth)
+	return d.cleanUp(dns01.GetChallengeInfo(domain, keyAuth))
+}
 
+// CleanUpAccount removes the TXT record of the dns-account-01 challenge.
+func (d *DNSProvider) CleanUpAccount(domain, token, keyAuth, accountURL string) error {
+	return d.cleanUp(dns01.GetAccountChallengeInfo(domain, accountURL, keyAuth))
+}
+
+func (d *DNSProvider) cleanUp(info dns01.ChallengeInfo) error {
 	err := d.changeRecord("REMOVE", info.EffectiveFQDN, info.Value, d.config.TTL)
 	if err != nil {
 		return fmt.Errorf("rfc2136: failed to remove: %w", err)
@@ -147,6 +163,54 @@ func (d *DNSProvider) CleanUp(domain, token, keyAuth string) error {
 	return nil
 }
 
+// PresentBatch creates the TXT records of several challenges, with one dynamic update by zone.
+func (d *DNSProvider) PresentBatch(records []challenge.Record) error {
+	err := d.changeRecords("INSERT", records)
+	if err != nil {
+		return fmt.Errorf("rfc2136: failed to insert: %w", err)
+	}
+	return nil
+}
+
+// CleanUpBatch removes the TXT records of several challenges, with one dynamic update by zone.
+func (d *DNSProvider) CleanUpBatch(records []challenge.Record) error {
+	err := d.changeRecords("REMOVE", records)
+	if err != nil {
+		return fmt.Errorf("rfc2136: failed to remove: %w", err)
+	}
+	return nil
+}
+
+func (d *DNSProvider) changeRecords(action string, records []challenge.Record) error {
+	var zones []string
+	rrsByZone := map[string][]dns.RR{}
+
+	for _, record := range records {
+		info := dns01.GetRecordChallengeInfo(record)
+
+		// Find the zone for the given fqdn
+		zone, err := dns01.FindZoneByFqdnCustom(info.EffectiveFQDN, []string{d.config.Nameserver})
+		if err != nil {
+			return err
+		}
+
+		if _, ok := rrsByZone[zone]; !ok {
+			zones = append(zones, zone)
+		}
+
+		rrsByZone[zone] = append(rrsByZone[zone], newTXT(info.EffectiveFQDN, info.Value, d.config.TTL))
+	}
+
+	for _, zone := range zones {
+		err := d.update(action, zone, rrsByZone[zone])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (d *DNSProvider) changeRecord(action, fqdn, value string, ttl int) error {
 	// Find the zone for the given fqdn
 	zone, err := dns01.FindZoneByFqdnCustom(fqdn, []string{d.config.Nameserver})
@@ -154,12 +218,18 @@ func (d *DNSProvider) changeRecord(action, fqdn, value string, ttl int) error {
 		return err
 	}
 
-	// Create RR
+	return d.update(action, zone, []dns.RR{newTXT(fqdn, value, ttl)})
+}
+
+func newTXT(fqdn, value string, ttl int) *dns.TXT {
 	rr := new(dns.TXT)
 	rr.Hdr = dns.RR_Header{Name: fqdn, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: uint32(ttl)}
 	rr.Txt = []string{value}
-	rrs := []dns.RR{rr}
 
+	return rr
+}
+
+func (d *DNSProvider) update(action, zone string, rrs []dns.RR) error {
 	// Create dynamic update packet
 	m := new(dns.Msg)
 	m.SetUpdate(zone)
@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/LukasDeco/lego/v4/challenge"
 	"github.com/LukasDeco/lego/v4/challenge/dns01"
 	"github.com/miekg/dns"
 	"github.com/stretchr/testify/assert"
@@ -157,6 +158,57 @@ func TestValidUpdatePacket(t *testing.T) {
 	}
 }
 
+func TestValidBatchUpdatePacket(t *testing.T) {
+	reqChan := make(chan *dns.Msg, 10)
+
+	dns01.ClearFqdnCache()
+	dns.HandleFunc(fakeZone, serverHandlerPassBackRequest(reqChan))
+	defer dns.HandleRemove(fakeZone)
+
+	server, addr, err := runLocalDNSTestServer(false)
+	require.NoError(t, err, "Failed to start test server")
+	defer func() { _ = server.Shutdown() }()
+
+	records := []challenge.Record{
+		{Domain: fakeDomain, KeyAuth: fakeKeyAuth},
+		{Domain: "www.example.com", KeyAuth: "456d=="},
+		// dns-account-01 challenge.
+		{Domain: "www.example.com", KeyAuth: "789d==", AccountURL: "https://example.com/acme/acct/ExampleAccount"},
+	}
+
+	var rrs []dns.RR
+	for _, record := range records {
+		info := dns01.GetRecordChallengeInfo(record)
+
+		txtRR, errR := dns.NewRR(fmt.Sprintf("%s %d IN TXT %s", info.EffectiveFQDN, fakeTTL, info.Value))
+		require.NoError(t, errR)
+
+		rrs = append(rrs, txtRR)
+	}
+
+	m := new(dns.Msg)
+	m.SetUpdate(fakeZone)
+	m.RemoveRRset(rrs)
+	m.Insert(rrs)
+
+	config := NewDefaultConfig()
+	config.Nameserver = addr
+
+	provider, err := NewDNSProviderConfig(config)
+	require.NoError(t, err)
+
+	err = provider.PresentBatch(records)
+	require.NoError(t, err)
+
+	// a single update for the zone.
+	require.Len(t, reqChan, 1)
+
+	rcvMsg := <-reqChan
+	rcvMsg.Id = m.Id
+
+	assert.Equal(t, m.String(), rcvMsg.String())
+}
+
 func runLocalDNSTestServer(tsig bool) (*dns.Server, string, error) {
 	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
 	if err != nil {
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/LukasDeco/lego/v4/challenge"
 	"github.com/LukasDeco/lego/v4/challenge/dns01"
 	"github.com/LukasDeco/lego/v4/platform/config/env"
 	"github.com/LukasDeco/lego/v4/platform/wait"
@@ -69,7 +70,7 @@ func NewDefaultConfig() *Config {
 	}
 }
 
-// DNSProvider implements the challenge.Provider interface.
+// DNSProvider implements the challenge.Provider and challenge.BatchProvider interfaces.
 type DNSProvider struct {
 	client *route53.Route53
 	config *Config
@@ -139,8 +140,15 @@ func (d *DNSProvider) Timeout() (timeout, interval time.Duration) {
 
 // Present creates a TXT record using the specified parameters.
 func (d *DNSProvider) Present(domain, token, keyAuth string) error {
-	info := dns01.GetChallengeInfo(domain, keyAuth)
+	return d.present(dns01.GetChallengeInfo(domain, keyAuth))
+}
+
+// PresentAccount creates the TXT record of the dns-account-01 challenge.
+func (d *DNSProvider) PresentAccount(domain, token, keyAuth, accountURL string) error {
+	return d.present(dns01.GetAccountChallengeInfo(domain, accountURL, keyAuth))
+}
 
+func (d *DNSProvider) present(info dns01.ChallengeInfo) error {
 	hostedZoneID, err := d.getHostedZoneID(info.EffectiveFQDN)
 	if err != nil {
 		return fmt.Errorf("route53: failed to determine hosted zone ID: %w", err)
@@ -180,8 +188,15 @@ func (d *DNSProvider) Present(domain, token, keyAuth string) error {
 
 // CleanUp removes the TXT record matching the specified parameters.
 func (d *DNSProvider) CleanUp(domain, token, keyAuth string) error {
-	info := dns01.GetChallengeInfo(domain, keyAuth)
+	return d.cleanUp(dns01.GetChallengeInfo(domain, keyAuth))
+}
 
+// CleanUpAccount removes the TXT record of the dns-account-01 challenge.
+func (d *DNSProvider) CleanUpAccount(domain, token, keyAuth, accountURL string) error {
+	return d.cleanUp(dns01.GetAccountChallengeInfo(domain, accountURL, keyAuth))
+}
+
+func (d *DNSProvider) cleanUp(info dns01.ChallengeInfo) error {
 	hostedZoneID, err := d.getHostedZoneID(info.EffectiveFQDN)
 	if err != nil {
 		return fmt.Errorf("failed to determine Route 53 hosted zone ID: %w", err)
@@ -210,15 +225,169 @@ func (d *DNSProvider) CleanUp(domain, token, keyAuth string) error {
 	return nil
 }
 
+// PresentBatch creates the TXT records of several challenges, with one change batch by hosted zone.
+func (d *DNSProvider) PresentBatch(records []challenge.Record) error {
+	zones, err := d.groupByHostedZone(records)
+	if err != nil {
+		return fmt.Errorf("route53: failed to determine hosted zone ID: %w", err)
+	}
+
+	for _, zone := range zones {
+		var changes []*route53.Change
+
+		for _, fqdn := range zone.fqdns {
+			existing, err := d.getExistingRecordSets(zone.hostedZoneID, fqdn)
+			if err != nil {
+				return fmt.Errorf("route53: %w", err)
+			}
+
+			rrs := existing
+			for _, value := range zone.values[fqdn] {
+				realValue := `"` + value + `"`
+
+				var found bool
+				for _, record := range rrs {
+					if aws.StringValue(record.Value) == realValue {
+						found = true
+					}
+				}
+
+				if !found {
+					rrs = append(rrs, &route53.ResourceRecord{Value: aws.String(realValue)})
+				}
+			}
+
+			changes = append(changes, &route53.Change{
+				Action: aws.String(route53.ChangeActionUpsert),
+				ResourceRecordSet: &route53.ResourceRecordSet{
+					Name:            aws.String(fqdn),
+					Type:            aws.String("TXT"),
+					TTL:             aws.Int64(int64(d.config.TTL)),
+					ResourceRecords: rrs,
+				},
+			})
+		}
+
+		err = d.changeRecords(zone.hostedZoneID, changes)
+		if err != nil {
+			return fmt.Errorf("route53: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// CleanUpBatch removes the TXT records of several challenges, with one change batch by hosted zone.
+func (d *DNSProvider) CleanUpBatch(records []challenge.Record) error {
+	zones, err := d.groupByHostedZone(records)
+	if err != nil {
+		return fmt.Errorf("failed to determine Route 53 hosted zone ID: %w", err)
+	}
+
+	for _, zone := range zones {
+		var changes []*route53.Change
+
+		for _, fqdn := range zone.fqdns {
+			existing, err := d.getExistingRecordSets(zone.hostedZoneID, fqdn)
+			if err != nil {
+				return fmt.Errorf("route53: %w", err)
+			}
+
+			if len(existing) == 0 {
+				continue
+			}
+
+			changes = append(changes, &route53.Change{
+				Action: aws.String(route53.ChangeActionDelete),
+				ResourceRecordSet: &route53.ResourceRecordSet{
+					Name:            aws.String(fqdn),
+					Type:            aws.String("TXT"),
+					TTL:             aws.Int64(int64(d.config.TTL)),
+					ResourceRecords: existing,
+				},
+			})
+		}
+
+		if len(changes) == 0 {
+			continue
+		}
+
+		err = d.changeRecords(zone.hostedZoneID, changes)
+		if err != nil {
+			return fmt.Errorf("route53: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// zoneRecords the TXT values of a hosted zone, by FQDN.
+type zoneRecords struct {
+	hostedZoneID string
+	fqdns        []string
+	values       map[string][]string
+}
+
+// groupByHostedZone groups the values of the records by hosted zone and by FQDN (in the order of the records).
+func (d *DNSProvider) groupByHostedZone(records []challenge.Record) ([]*zoneRecords, error) {
+	var zones []*zoneRecords
+	byID := map[string]*zoneRecords{}
+
+	// hosted zone IDs by authoritative zone.
+	ids := map[string]string{}
+
+	for _, record := range records {
+		info := dns01.GetRecordChallengeInfo(record)
+
+		hostedZoneID := d.config.HostedZoneID
+		if hostedZoneID == "" {
+			authZone, err := dns01.FindZoneByFqdn(info.EffectiveFQDN)
+			if err != nil {
+				return nil, err
+			}
+
+			var ok bool
+			hostedZoneID, ok = ids[authZone]
+			if !ok {
+				hostedZoneID, err = d.getHostedZoneIDByZone(authZone, info.EffectiveFQDN)
+				if err != nil {
+					return nil, err
+				}
+
+				ids[authZone] = hostedZoneID
+			}
+		}
+
+		zone, ok := byID[hostedZoneID]
+		if !ok {
+			zone = &zoneRecords{hostedZoneID: hostedZoneID, values: map[string][]string{}}
+			byID[hostedZoneID] = zone
+			zones = append(zones, zone)
+		}
+
+		if _, ok := zone.values[info.EffectiveFQDN]; !ok {
+			zone.fqdns = append(zone.fqdns, info.EffectiveFQDN)
+		}
+
+		zone.values[info.EffectiveFQDN] = append(zone.values[info.EffectiveFQDN], info.Value)
+	}
+
+	return zones, nil
+}
+
 func (d *DNSProvider) changeRecord(action, hostedZoneID string, recordSet *route53.ResourceRecordSet) error {
+	return d.changeRecords(hostedZoneID, []*route53.Change{{
+		Action:            aws.String(action),
+		ResourceRecordSet: recordSet,
+	}})
+}
+
+func (d *DNSProvider) changeRecords(hostedZoneID string, changes []*route53.Change) error {
 	recordSetInput := &route53.ChangeResourceRecordSetsInput{
 		HostedZoneId: aws.String(hostedZoneID),
 		ChangeBatch: &route53.ChangeBatch{
 			Comment: aws.String("Managed by Lego"),
-			Changes: []*route53.Change{{
-				Action:            aws.String(action),
-				ResourceRecordSet: recordSet,
-			}},
+			Changes: changes,
 		},
 	}
 
@@ -281,6 +450,10 @@ func (d *DNSProvider) getHostedZoneID(fqdn string) (string, error) {
 		return "", err
 	}
 
+	return d.getHostedZoneIDByZone(authZone, fqdn)
+}
+
+func (d *DNSProvider) getHostedZoneIDByZone(authZone, fqdn string) (string, error) {
 	// .DNSName should not have a trailing dot
 	reqParams := &route53.ListHostedZonesByNameInput{
 		DNSName: aws.String(dns01.UnFqdn(authZone)),
@@ -1,10 +1,16 @@
 package route53
 
 import (
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/LukasDeco/lego/v4/challenge"
+	"github.com/LukasDeco/lego/v4/challenge/dns01"
 	"github.com/LukasDeco/lego/v4/platform/tester"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -178,6 +184,61 @@ func TestDNSProvider_Present(t *testing.T) {
 	require.NoError(t, err, "Expected Present to return no error")
 }
 
+func TestDNSProvider_PresentBatch(t *testing.T) {
+	var changes []string
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("/2013-04-01/hostedzone/ABCDEFG/rrset/", func(rw http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		changes = append(changes, string(body))
+
+		rw.Header().Set("Content-Type", "application/xml")
+		_, _ = rw.Write([]byte(ChangeResourceRecordSetsResponse))
+	})
+	mux.HandleFunc("/2013-04-01/hostedzone/ABCDEFG/rrset", func(rw http.ResponseWriter, _ *http.Request) {
+		rw.Header().Set("Content-Type", "application/xml")
+	})
+	mux.HandleFunc("/2013-04-01/change/123456", func(rw http.ResponseWriter, _ *http.Request) {
+		rw.Header().Set("Content-Type", "application/xml")
+		_, _ = rw.Write([]byte(GetChangeResponse))
+	})
+
+	defer envTest.RestoreEnv()
+	envTest.ClearEnv()
+	t.Setenv("LEGO_DISABLE_CNAME_SUPPORT", "true")
+
+	provider := makeTestProvider(t, server.URL)
+	provider.config.HostedZoneID = "ABCDEFG"
+
+	records := []challenge.Record{
+		{Domain: "example.com", KeyAuth: "123456d=="},
+		{Domain: "example.com", KeyAuth: "654321d=="},
+		{Domain: "www.example.com", KeyAuth: "123456d=="},
+	}
+
+	err := provider.PresentBatch(records)
+	require.NoError(t, err)
+
+	// a single change batch for the hosted zone.
+	require.Len(t, changes, 1)
+
+	assert.Equal(t, 2, strings.Count(changes[0], "<Action>UPSERT</Action>"))
+	assert.Contains(t, changes[0], "<Name>_acme-challenge.example.com.</Name>")
+	assert.Contains(t, changes[0], "<Name>_acme-challenge.www.example.com.</Name>")
+
+	for _, record := range records {
+		assert.Contains(t, changes[0], dns01.GetChallengeInfo(record.Domain, record.KeyAuth).Value)
+	}
+}
+
 func TestCreateSession(t *testing.T) {
 	testCases := []struct {
 		desc             string
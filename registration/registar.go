@@ -1,12 +1,12 @@
 package registration
 
 import (
+	"crypto"
 	"errors"
 	"net/http"
 
 	"github.com/LukasDeco/lego/v4/acme"
 	"github.com/LukasDeco/lego/v4/acme/api"
-	"github.com/LukasDeco/lego/v4/log"
 )
 
 // Resource represents all important information about a registration
@@ -51,7 +51,7 @@ func (r *Registrar) Register(options RegisterOptions) (*Resource, error) {
 	}
 
 	if r.user.GetEmail() != "" {
-		log.Infof("acme: Registering account for %s", r.user.GetEmail())
+		r.core.Logger().Info("acme: Registering account for " + r.user.GetEmail())
 		accMsg.Contact = []string{"mailto:" + r.user.GetEmail()}
 	}
 
@@ -75,7 +75,7 @@ func (r *Registrar) RegisterWithExternalAccountBinding(options RegisterEABOption
 	}
 
 	if r.user.GetEmail() != "" {
-		log.Infof("acme: Registering account for %s", r.user.GetEmail())
+		r.core.Logger().Info("acme: Registering account for " + r.user.GetEmail())
 		accMsg.Contact = []string{"mailto:" + r.user.GetEmail()}
 	}
 
@@ -101,7 +101,7 @@ func (r *Registrar) QueryRegistration() (*Resource, error) {
 	}
 
 	// Log the URL here instead of the email as the email may not be set
-	log.Infof("acme: Querying account for %s", r.user.GetRegistration().URI)
+	r.core.Logger().Info("acme: Querying account for " + r.user.GetRegistration().URI)
 
 	account, err := r.core.Accounts.Get(r.user.GetRegistration().URI)
 	if err != nil {
@@ -127,7 +127,7 @@ func (r *Registrar) UpdateRegistration(options RegisterOptions) (*Resource, erro
 	}
 
 	if r.user.GetEmail() != "" {
-		log.Infof("acme: Registering account for %s", r.user.GetEmail())
+		r.core.Logger().Info("acme: Registering account for " + r.user.GetEmail())
 		accMsg.Contact = []string{"mailto:" + r.user.GetEmail()}
 	}
 
@@ -147,15 +147,28 @@ func (r *Registrar) DeleteRegistration() error {
 		return errors.New("acme: cannot unregister a nil client or user")
 	}
 
-	log.Infof("acme: Deleting account for %s", r.user.GetEmail())
+	r.core.Logger().Info("acme: Deleting account for " + r.user.GetEmail())
 
 	return r.core.Accounts.Deactivate(r.user.GetRegistration().URI)
 }
 
+// RolloverKey replaces the account key with newKey on the ACME server.
+// On success, the client signs all the subsequent requests with newKey,
+// the caller is responsible for persisting the new key.
+func (r *Registrar) RolloverKey(newKey crypto.PrivateKey) error {
+	if r == nil || r.user == nil || r.user.GetRegistration() == nil {
+		return errors.New("acme: cannot rollover the key of a nil client or user")
+	}
+
+	r.core.Logger().Info("acme: Rolling over the key of the account " + r.user.GetRegistration().URI)
+
+	return r.core.Accounts.KeyChange(newKey)
+}
+
 // ResolveAccountByKey will attempt to look up an account using the given account key
 // and return its registration resource.
 func (r *Registrar) ResolveAccountByKey() (*Resource, error) {
-	log.Infof("acme: Trying to resolve account by key")
+	r.core.Logger().Info("acme: Trying to resolve account by key")
 
 	accMsg := acme.Account{OnlyReturnExisting: true}
 	account, err := r.core.Accounts.New(accMsg)
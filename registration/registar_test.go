@@ -46,3 +46,51 @@ func TestRegistrar_ResolveAccountByKey(t *testing.T) {
 
 	assert.Equal(t, "valid", res.Body.Status, "Unexpected account status")
 }
+
+func TestRegistrar_RolloverKey(t *testing.T) {
+	mux, apiURL := tester.SetupFakeAPI(t)
+
+	mux.HandleFunc("/keyChange", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	key, err := rsa.GenerateKey(rand.Reader, 512)
+	require.NoError(t, err, "Could not generate test key")
+
+	newKey, err := rsa.GenerateKey(rand.Reader, 512)
+	require.NoError(t, err, "Could not generate test key")
+
+	user := mockUser{
+		email:      "test@test.com",
+		regres:     &Resource{URI: apiURL + "/account"},
+		privatekey: key,
+	}
+
+	core, err := api.New(http.DefaultClient, "lego-test", apiURL+"/dir", user.regres.URI, key)
+	require.NoError(t, err)
+
+	registrar := NewRegistrar(core, user)
+
+	err = registrar.RolloverKey(newKey)
+	require.NoError(t, err)
+}
+
+func TestRegistrar_RolloverKey_notRegistered(t *testing.T) {
+	_, apiURL := tester.SetupFakeAPI(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 512)
+	require.NoError(t, err, "Could not generate test key")
+
+	user := mockUser{
+		email:      "test@test.com",
+		privatekey: key,
+	}
+
+	core, err := api.New(http.DefaultClient, "lego-test", apiURL+"/dir", "", key)
+	require.NoError(t, err)
+
+	registrar := NewRegistrar(core, user)
+
+	err = registrar.RolloverKey(key)
+	require.EqualError(t, err, "acme: cannot rollover the key of a nil client or user")
+}
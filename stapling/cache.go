@@ -0,0 +1,18 @@
+package stapling
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrCacheMiss is returned by a Cache when the name is not found.
+var ErrCacheMiss = errors.New("stapling: cache miss")
+
+// Cache stores the raw OCSP responses (DER encoded) of the certificates.
+type Cache interface {
+	// Get returns the OCSP response of the certificate, or ErrCacheMiss.
+	Get(ctx context.Context, name string) ([]byte, error)
+
+	// Put stores the OCSP response of the certificate.
+	Put(ctx context.Context, name string, data []byte) error
+}
@@ -0,0 +1,265 @@
+// Package stapling fetches the OCSP responses of certificates, to staple them to the TLS handshakes,
+// and keeps them fresh.
+package stapling
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/LukasDeco/lego/v4/certcrypto"
+	"github.com/LukasDeco/lego/v4/certificate"
+	"github.com/LukasDeco/lego/v4/log"
+	"github.com/LukasDeco/lego/v4/platform/wait"
+	"golang.org/x/crypto/ocsp"
+)
+
+const (
+	// defaultRefreshDelay the refresh delay of the responses without NextUpdate.
+	defaultRefreshDelay = time.Hour
+
+	defaultHTTPTimeout = 30 * time.Second
+)
+
+// Options the options of a Stapler.
+type Options struct {
+	// HTTPClient requests the OCSP responders and the issuer certificates.
+	// Defaults to a client with a 30 seconds timeout.
+	HTTPClient *http.Client
+
+	// Cache stores the OCSP responses.
+	// If nil, the responses are only kept in memory, and fetched again after a restart.
+	Cache Cache
+}
+
+// Staple an OCSP response.
+type Staple struct {
+	// Raw the DER encoded OCSP response, as stapled to the TLS handshakes (tls.Certificate.OCSPStaple).
+	Raw []byte
+
+	// Response the parsed OCSP response.
+	Response *ocsp.Response
+
+	// Cached is true when the response has been loaded from the cache instead of the OCSP responders.
+	Cached bool
+}
+
+// RefreshTime returns the time at which the response should be refreshed:
+// halfway through its validity period, or one hour after ThisUpdate if the response has no NextUpdate.
+func (s *Staple) RefreshTime() time.Time {
+	if s.Response.NextUpdate.IsZero() {
+		return s.Response.ThisUpdate.Add(defaultRefreshDelay)
+	}
+
+	return s.Response.ThisUpdate.Add(s.Response.NextUpdate.Sub(s.Response.ThisUpdate) / 2)
+}
+
+// Stapler fetches and caches the OCSP responses of certificates.
+//
+// The certificates are identified by a name (e.g. the domain), used as the key of the cache,
+// and are provided as PEM bundles: the certificate, optionally followed by its issuer certificate.
+// When the issuer certificate is not in the bundle, it's downloaded from the IssuingCertificateURL of the certificate.
+type Stapler struct {
+	cache Cache
+
+	fetch func(ctx context.Context, bundle []byte) ([]byte, *ocsp.Response, error)
+	now   func() time.Time
+
+	// refreshes the background refreshes of the staples, by name.
+	refreshes *wait.Scheduler
+
+	mu      sync.Mutex
+	staples map[string]*Staple
+}
+
+// New creates a new Stapler.
+func New(options Options) *Stapler {
+	httpClient := options.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+
+	return &Stapler{
+		cache: options.Cache,
+		fetch: func(ctx context.Context, bundle []byte) ([]byte, *ocsp.Response, error) {
+			return certificate.FetchOCSP(ctx, httpClient, bundle)
+		},
+		now:       time.Now,
+		staples:   map[string]*Staple{},
+		refreshes: wait.NewScheduler(),
+	}
+}
+
+// Staple returns a valid OCSP response of the certificate: from memory, from the cache, or from the OCSP responders.
+// The response is fetched again from the OCSP responders once its refresh time is reached (see Staple.RefreshTime).
+// If the OCSP responders are not available, a response that is still valid is returned.
+func (s *Stapler) Staple(ctx context.Context, name string, bundle []byte) (*Staple, error) {
+	leaf, issuer, err := parseBundle(bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	staple := s.staples[name]
+	s.mu.Unlock()
+
+	if staple == nil || staple.Response.SerialNumber.Cmp(leaf.SerialNumber) != 0 || !s.isValid(staple) {
+		staple, err = s.loadFromCache(ctx, name, leaf, issuer)
+		if err != nil && !errors.Is(err, ErrCacheMiss) {
+			log.Default().Warn("stapling: unable to load the OCSP response from the cache", log.Domain(name), log.Err(err))
+		}
+	}
+
+	if staple != nil && s.now().Before(staple.RefreshTime()) {
+		s.store(name, staple)
+		return staple, nil
+	}
+
+	fresh, err := s.Refresh(ctx, name, bundle)
+	if err != nil {
+		if staple == nil {
+			return nil, err
+		}
+
+		log.Default().Warn("stapling: unable to refresh the OCSP response, using the previous one", log.Domain(name), log.Err(err))
+
+		s.store(name, staple)
+
+		return staple, nil
+	}
+
+	return fresh, nil
+}
+
+// Refresh fetches a new OCSP response from the OCSP responders, and stores it in the cache.
+func (s *Stapler) Refresh(ctx context.Context, name string, bundle []byte) (*Staple, error) {
+	raw, resp, err := s.fetch(ctx, bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Status == ocsp.Revoked {
+		log.Default().Warn("stapling: the certificate is revoked", log.Domain(name))
+	}
+
+	staple := &Staple{Raw: raw, Response: resp}
+
+	if s.cache != nil {
+		err = s.cache.Put(ctx, name, raw)
+		if err != nil {
+			log.Default().Warn("stapling: unable to store the OCSP response in the cache", log.Domain(name), log.Err(err))
+		}
+	}
+
+	s.store(name, staple)
+
+	return staple, nil
+}
+
+// Manage gets the OCSP response of the certificate (see Stapler.Staple),
+// and refreshes it in the background halfway through its validity period.
+// The failed refreshes are retried with an exponential backoff, even when the first request fails.
+// The current OCSP response is returned by Stapler.Get.
+func (s *Stapler) Manage(ctx context.Context, name string, bundle []byte) error {
+	staple, err := s.Staple(ctx, name, bundle)
+
+	task := func() (time.Duration, error) {
+		return s.refresh(name, bundle)
+	}
+
+	if err != nil {
+		s.refreshes.Retry(name, task)
+		return err
+	}
+
+	s.refreshes.Schedule(name, staple.RefreshTime().Sub(s.now()), task)
+
+	return nil
+}
+
+// Get returns the current OCSP response (DER encoded) of the certificate,
+// or nil if there is no valid response.
+// It's intended to fill tls.Certificate.OCSPStaple.
+func (s *Stapler) Get(name string) []byte {
+	s.mu.Lock()
+	staple := s.staples[name]
+	s.mu.Unlock()
+
+	if staple == nil || !s.isValid(staple) {
+		return nil
+	}
+
+	return staple.Raw
+}
+
+// Close stops the background refreshes.
+func (s *Stapler) Close() {
+	s.refreshes.Close()
+}
+
+func (s *Stapler) store(name string, staple *Staple) {
+	s.mu.Lock()
+	s.staples[name] = staple
+	s.mu.Unlock()
+}
+
+// loadFromCache loads a valid OCSP response of the certificate from the cache.
+// Without the issuer certificate, the signature of the response cannot be checked:
+// the cached response is ignored, and a new one is fetched from the OCSP responders.
+func (s *Stapler) loadFromCache(ctx context.Context, name string, leaf, issuer *x509.Certificate) (*Staple, error) {
+	if s.cache == nil || issuer == nil {
+		return nil, ErrCacheMiss
+	}
+
+	raw, err := s.cache.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := certificate.VerifyOCSPResponse(raw, leaf, issuer, s.now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Staple{Raw: raw, Response: resp, Cached: true}, nil
+}
+
+// refresh fetches a new OCSP response of the certificate in the background,
+// and returns the delay before its next refresh.
+func (s *Stapler) refresh(name string, bundle []byte) (time.Duration, error) {
+	log.Default().Info("stapling: refreshing the OCSP response", log.Domain(name))
+
+	staple, err := s.Refresh(context.Background(), name, bundle)
+	if err != nil {
+		log.Default().Warn("stapling: refresh failed", log.Domain(name), log.Err(err))
+		return 0, err
+	}
+
+	return staple.RefreshTime().Sub(s.now()), nil
+}
+
+// isValid returns true if the OCSP response is not expired.
+func (s *Stapler) isValid(staple *Staple) bool {
+	return staple.Response.NextUpdate.IsZero() || s.now().Before(staple.Response.NextUpdate)
+}
+
+// parseBundle returns the certificate, and its issuer certificate if it's in the bundle.
+func parseBundle(bundle []byte) (leaf, issuer *x509.Certificate, err error) {
+	certificates, err := certcrypto.ParsePEMBundle(bundle)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	leaf = certificates[0]
+
+	for _, cert := range certificates[1:] {
+		if leaf.CheckSignatureFrom(cert) == nil {
+			return leaf, cert, nil
+		}
+	}
+
+	return leaf, nil, nil
+}
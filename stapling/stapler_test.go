@@ -0,0 +1,273 @@
+package stapling
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/LukasDeco/lego/v4/certcrypto"
+	"github.com/LukasDeco/lego/v4/platform/tester"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ocsp"
+)
+
+type memoryCache struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{data: map[string][]byte{}}
+}
+
+func (c *memoryCache) Get(_ context.Context, name string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, ok := c.data[name]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+
+	return data, nil
+}
+
+func (c *memoryCache) Put(_ context.Context, name string, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.data[name] = data
+
+	return nil
+}
+
+func TestStaple_RefreshTime(t *testing.T) {
+	thisUpdate := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	staple := &Staple{Response: &ocsp.Response{ThisUpdate: thisUpdate, NextUpdate: thisUpdate.Add(4 * 24 * time.Hour)}}
+	assert.Equal(t, thisUpdate.Add(2*24*time.Hour), staple.RefreshTime())
+
+	staple = &Staple{Response: &ocsp.Response{ThisUpdate: thisUpdate}}
+	assert.Equal(t, thisUpdate.Add(time.Hour), staple.RefreshTime())
+}
+
+func TestStapler_Staple(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+
+	fixture := newFixture(t)
+
+	cache := newMemoryCache()
+
+	stapler := New(Options{Cache: cache})
+	stapler.now = func() time.Time { return now }
+
+	var fetched int32
+	stapler.fetch = func(_ context.Context, _ []byte) ([]byte, *ocsp.Response, error) {
+		atomic.AddInt32(&fetched, 1)
+
+		raw := fixture.response(t, now, now.Add(48*time.Hour))
+		resp, err := ocsp.ParseResponse(raw, nil)
+
+		return raw, resp, err
+	}
+
+	ctx := context.Background()
+
+	// fresh in the cache.
+	cached := fixture.response(t, now.Add(-time.Hour), now.Add(47*time.Hour))
+	require.NoError(t, cache.Put(ctx, "example.com", cached))
+
+	staple, err := stapler.Staple(ctx, "example.com", fixture.bundle)
+	require.NoError(t, err)
+
+	assert.True(t, staple.Cached)
+	assert.Equal(t, cached, staple.Raw)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&fetched))
+	assert.Equal(t, cached, stapler.Get("example.com"))
+
+	// stale in the cache: fetched, and stored in the cache.
+	stale := fixture.response(t, now.Add(-30*time.Hour), now.Add(18*time.Hour))
+	require.NoError(t, cache.Put(ctx, "stale.example.com", stale))
+
+	staple, err = stapler.Staple(ctx, "stale.example.com", fixture.bundle)
+	require.NoError(t, err)
+
+	assert.False(t, staple.Cached)
+	assert.True(t, now.Equal(staple.Response.ThisUpdate))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetched))
+
+	stored, err := cache.Get(ctx, "stale.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, staple.Raw, stored)
+
+	// kept in memory.
+	again, err := stapler.Staple(ctx, "stale.example.com", fixture.bundle)
+	require.NoError(t, err)
+
+	assert.Same(t, staple, again)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetched))
+
+	// expired in the cache: fetched.
+	expired := fixture.response(t, now.Add(-48*time.Hour), now.Add(-time.Hour))
+	require.NoError(t, cache.Put(ctx, "expired.example.com", expired))
+
+	staple, err = stapler.Staple(ctx, "expired.example.com", fixture.bundle)
+	require.NoError(t, err)
+
+	assert.False(t, staple.Cached)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&fetched))
+
+	// without the issuer certificate in the bundle, the cached response cannot be verified: fetched.
+	require.NoError(t, cache.Put(ctx, "leaf.example.com", cached))
+
+	leafPEM := certcrypto.PEMEncode(certcrypto.DERCertificateBytes(fixture.cert.Raw))
+
+	staple, err = stapler.Staple(ctx, "leaf.example.com", leafPEM)
+	require.NoError(t, err)
+
+	assert.False(t, staple.Cached)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&fetched))
+}
+
+func TestStapler_Staple_fetchError(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+
+	fixture := newFixture(t)
+
+	cache := newMemoryCache()
+
+	stapler := New(Options{Cache: cache})
+	stapler.now = func() time.Time { return now }
+	stapler.fetch = func(_ context.Context, _ []byte) ([]byte, *ocsp.Response, error) {
+		return nil, nil, errors.New("oops")
+	}
+
+	ctx := context.Background()
+
+	// no previous response.
+	_, err := stapler.Staple(ctx, "example.com", fixture.bundle)
+	require.EqualError(t, err, "oops")
+
+	assert.Nil(t, stapler.Get("example.com"))
+
+	// stale but still valid in the cache.
+	stale := fixture.response(t, now.Add(-30*time.Hour), now.Add(18*time.Hour))
+	require.NoError(t, cache.Put(ctx, "example.com", stale))
+
+	staple, err := stapler.Staple(ctx, "example.com", fixture.bundle)
+	require.NoError(t, err)
+
+	assert.Equal(t, stale, staple.Raw)
+}
+
+func TestStapler_Manage(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+
+	fixture := newFixture(t)
+
+	stapler := New(Options{})
+	t.Cleanup(stapler.Close)
+
+	stapler.now = func() time.Time { return now }
+
+	first := fixture.response(t, now.Add(-time.Hour), now.Add(time.Hour))
+	second := fixture.response(t, now, now.Add(2*time.Hour))
+
+	var fetched int32
+	stapler.fetch = func(_ context.Context, _ []byte) ([]byte, *ocsp.Response, error) {
+		raw := first
+		if atomic.AddInt32(&fetched, 1) > 1 {
+			raw = second
+		}
+
+		resp, err := ocsp.ParseResponse(raw, nil)
+
+		return raw, resp, err
+	}
+
+	err := stapler.Manage(context.Background(), "example.com", fixture.bundle)
+	require.NoError(t, err)
+
+	// the first response has reached its refresh time: refreshed immediately,
+	// and the second response is refreshed later.
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&fetched) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		stapler.mu.Lock()
+		defer stapler.mu.Unlock()
+
+		scheduled, _ := stapler.refreshes.Scheduled("example.com")
+
+		return scheduled && stapler.staples["example.com"].Response.ThisUpdate.Equal(now)
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, second, stapler.Get("example.com"))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&fetched))
+
+	stapler.Close()
+
+	scheduled, _ := stapler.refreshes.Scheduled("example.com")
+	assert.False(t, scheduled)
+}
+
+func TestStapler_Manage_error(t *testing.T) {
+	fixture := newFixture(t)
+
+	stapler := New(Options{})
+	t.Cleanup(stapler.Close)
+
+	stapler.fetch = func(_ context.Context, _ []byte) ([]byte, *ocsp.Response, error) {
+		return nil, nil, errors.New("oops")
+	}
+
+	err := stapler.Manage(context.Background(), "example.com", fixture.bundle)
+	require.EqualError(t, err, "oops")
+
+	// the request is retried in the background.
+	scheduled, retrying := stapler.refreshes.Scheduled("example.com")
+	assert.True(t, scheduled)
+	assert.True(t, retrying)
+}
+
+// fixture a CA, and a certificate issued by the CA.
+type fixture struct {
+	ca     *tester.CA
+	cert   *x509.Certificate
+	bundle []byte
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+
+	ca := tester.NewCA(t, "Test CA")
+
+	cert, certPEM := ca.Issue(t, &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		DNSNames:     []string{"example.com"},
+	})
+
+	return &fixture{ca: ca, cert: cert, bundle: append(certPEM, ca.PEM()...)}
+}
+
+// response returns a raw OCSP response signed by the CA.
+func (f *fixture) response(t *testing.T, thisUpdate, nextUpdate time.Time) []byte {
+	t.Helper()
+
+	raw, err := ocsp.CreateResponse(f.ca.Cert, f.ca.Cert, ocsp.Response{
+		Status:       ocsp.Good,
+		SerialNumber: f.cert.SerialNumber,
+		ThisUpdate:   thisUpdate,
+		NextUpdate:   nextUpdate,
+	}, f.ca.Key)
+	require.NoError(t, err)
+
+	return raw
+}